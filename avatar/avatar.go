@@ -0,0 +1,115 @@
+// Package avatar 头像处理：解码、按 EXIF 方向摆正、居中裁剪、生成各尺寸缩略图，以及默认头像（identicon）
+package avatar
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+
+	// 注册支持的输入格式
+	_ "image/gif"
+	_ "image/jpeg"
+
+	_ "golang.org/x/image/webp"
+
+	"golang.org/x/image/draw"
+)
+
+// 输出格式
+const (
+	FormatWebP = "webp"
+	FormatPNG  = "png"
+)
+
+// Sizes 生成的缩略图边长（像素），从小到大
+var Sizes = []int{64, 128, 512}
+
+// DefaultSize 未指定尺寸时使用的边长
+const DefaultSize = 128
+
+// Formats 支持的输出格式，按优先顺序
+var Formats = []string{FormatWebP, FormatPNG}
+
+// maxPixels 允许解码的最大像素数，防止体积很小但尺寸巨大的图片耗尽内存
+const maxPixels = 4096 * 4096
+
+// ErrInvalidImage 图片无法解析或尺寸不合要求
+var ErrInvalidImage = errors.New("图片已损坏或无法解析")
+
+// Decode 解码图片，按 EXIF 方向摆正并居中裁剪为正方形；解码后的图片不再包含 EXIF 等元数据
+func Decode(data []byte) (image.Image, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+	if cfg.Width < 1 || cfg.Height < 1 || cfg.Width*cfg.Height > maxPixels {
+		return nil, fmt.Errorf("%w: 尺寸 %dx%d 超出限制", ErrInvalidImage, cfg.Width, cfg.Height)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+	// 居中裁剪与旋转、翻转可以交换顺序，先裁剪可以减少旋转的像素
+	return orient(squareCrop(img), exifOrientation(data)), nil
+}
+
+// squareCrop 居中裁剪为正方形
+func squareCrop(img image.Image) image.Image {
+	b := img.Bounds()
+	side := min(b.Dx(), b.Dy())
+	x0 := b.Min.X + (b.Dx()-side)/2
+	y0 := b.Min.Y + (b.Dy()-side)/2
+	crop := image.Rect(x0, y0, x0+side, y0+side)
+	if sub, ok := img.(interface {
+		SubImage(image.Rectangle) image.Image
+	}); ok {
+		return sub.SubImage(crop)
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, side, side))
+	draw.Draw(dst, dst.Bounds(), img, crop.Min, draw.Src)
+	return dst
+}
+
+// Thumbnail 将正方形图片缩放为 size×size
+func Thumbnail(img image.Image, size int) *image.NRGBA {
+	dst := image.NewNRGBA(image.Rect(0, 0, size, size))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, img.Bounds(), draw.Src, nil)
+	return dst
+}
+
+// Encode 按格式编码图片
+func Encode(w io.Writer, img image.Image, format string) error {
+	switch format {
+	case FormatWebP:
+		return EncodeWebP(w, img)
+	case FormatPNG:
+		enc := png.Encoder{CompressionLevel: png.BestCompression}
+		return enc.Encode(w, img)
+	default:
+		return fmt.Errorf("不支持的头像格式: %s", format)
+	}
+}
+
+// ContentType 输出格式对应的 MIME 类型
+func ContentType(format string) string {
+	return "image/" + format
+}
+
+// ValidSize 判断是否为生成的尺寸之一
+func ValidSize(size int) bool {
+	for _, s := range Sizes {
+		if s == size {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidFormat 判断是否为支持的输出格式
+func ValidFormat(format string) bool {
+	return format == FormatWebP || format == FormatPNG
+}
@@ -0,0 +1,239 @@
+package avatar
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"math/rand"
+	"testing"
+
+	"golang.org/x/image/webp"
+)
+
+func TestEncodeWebP(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	noise := image.NewNRGBA(image.Rect(0, 0, 37, 23))
+	rng.Read(noise.Pix)
+
+	gradient := image.NewNRGBA(image.Rect(0, 0, 300, 200))
+	for y := 0; y < 200; y++ {
+		for x := 0; x < 300; x++ {
+			gradient.SetNRGBA(x, y, color.NRGBA{uint8(x), uint8(y), uint8(x + y), 0xff})
+		}
+	}
+
+	// 大面积纯色，覆盖游程复制（超过 4096 个像素需要拆成多段）
+	flat := image.NewNRGBA(image.Rect(0, 0, 128, 128))
+	for i := range flat.Pix {
+		flat.Pix[i] = 0x80
+	}
+
+	// 只有少数几种颜色：前缀码使用 simple code
+	twoColors := image.NewNRGBA(image.Rect(0, 0, 10, 10))
+	for y := 0; y < 10; y++ {
+		for x := 0; x < 10; x++ {
+			if (x+y)%2 == 0 {
+				twoColors.SetNRGBA(x, y, color.NRGBA{0, 0, 0, 0xff})
+			} else {
+				twoColors.SetNRGBA(x, y, color.NRGBA{0xff, 0xff, 0xff, 0xff})
+			}
+		}
+	}
+
+	onePixel := image.NewNRGBA(image.Rect(0, 0, 1, 1))
+	onePixel.SetNRGBA(0, 0, color.NRGBA{1, 2, 3, 4})
+
+	// 非零起点的子图
+	offset := gradient.SubImage(image.Rect(10, 20, 74, 84))
+
+	tests := map[string]image.Image{
+		"noise":      noise,
+		"gradient":   gradient,
+		"flat":       flat,
+		"two colors": twoColors,
+		"one pixel":  onePixel,
+		"identicon":  Identicon("alice", 128),
+		"sub image":  offset,
+	}
+	for name, img := range tests {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := EncodeWebP(&buf, img); err != nil {
+				t.Fatalf("编码失败: %v", err)
+			}
+			got, err := webp.Decode(bytes.NewReader(buf.Bytes()))
+			if err != nil {
+				t.Fatalf("解码失败: %v", err)
+			}
+			assertSamePixels(t, img, got)
+		})
+	}
+}
+
+func TestEncodeWebPSize(t *testing.T) {
+	var buf bytes.Buffer
+	if err := EncodeWebP(&buf, Identicon("alice", 512)); err != nil {
+		t.Fatalf("编码失败: %v", err)
+	}
+	// 纯色块组成的图片应压缩得很小
+	if buf.Len() > 8<<10 {
+		t.Errorf("512px identicon 编码后 %d 字节，压缩效果异常", buf.Len())
+	}
+}
+
+func TestOrient(t *testing.T) {
+	// 2×3 的图片，每个像素的红色通道为 10*x+y
+	src := image.NewNRGBA(image.Rect(0, 0, 2, 3))
+	for y := 0; y < 3; y++ {
+		for x := 0; x < 2; x++ {
+			src.SetNRGBA(x, y, color.NRGBA{uint8(10*x + y), 0, 0, 0xff})
+		}
+	}
+
+	// 各方向摆正后左上角与右上角像素（原图坐标编码）
+	tests := []struct {
+		orientation   int
+		w, h          int
+		topLeft, topR uint8
+	}{
+		{1, 2, 3, 0, 10},
+		{2, 2, 3, 10, 0},
+		{3, 2, 3, 12, 2},
+		{4, 2, 3, 2, 12},
+		{5, 3, 2, 0, 2},
+		{6, 3, 2, 2, 0},
+		{7, 3, 2, 12, 10},
+		{8, 3, 2, 10, 12},
+	}
+	for _, tt := range tests {
+		got := orient(src, tt.orientation)
+		b := got.Bounds()
+		r0, _, _, _ := got.At(0, 0).RGBA()
+		r1, _, _, _ := got.At(b.Dx()-1, 0).RGBA()
+		if b.Dx() != tt.w || b.Dy() != tt.h || uint8(r0>>8) != tt.topLeft || uint8(r1>>8) != tt.topR {
+			t.Errorf("orientation %d: %dx%d 左上 %d 右上 %d", tt.orientation, b.Dx(), b.Dy(), r0>>8, r1>>8)
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	// 左红右蓝的横向图片，EXIF 方向为 6（需要顺时针旋转 90°）
+	src := image.NewNRGBA(image.Rect(0, 0, 120, 80))
+	for y := 0; y < 80; y++ {
+		for x := 0; x < 120; x++ {
+			c := color.NRGBA{0xff, 0, 0, 0xff}
+			if x >= 60 {
+				c = color.NRGBA{0, 0, 0xff, 0xff}
+			}
+			src.SetNRGBA(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, src, &jpeg.Options{Quality: 95}); err != nil {
+		t.Fatal(err)
+	}
+	data := withEXIFOrientation(buf.Bytes(), 6)
+	if got := exifOrientation(data); got != 6 {
+		t.Fatalf("exifOrientation = %d, want 6", got)
+	}
+
+	img, err := Decode(data)
+	if err != nil {
+		t.Fatalf("Decode 失败: %v", err)
+	}
+	if b := img.Bounds(); b.Dx() != 80 || b.Dy() != 80 {
+		t.Fatalf("应裁剪为正方形: %v", b)
+	}
+	// 旋转后原图左侧的红色在上方，右侧的蓝色在下方
+	if r, _, b, _ := img.At(40, 5).RGBA(); r>>8 < 200 || b>>8 > 60 {
+		t.Errorf("上方应为红色: r=%d b=%d", r>>8, b>>8)
+	}
+	if r, _, b, _ := img.At(40, 74).RGBA(); b>>8 < 200 || r>>8 > 60 {
+		t.Errorf("下方应为蓝色: r=%d b=%d", r>>8, b>>8)
+	}
+
+	thumb := Thumbnail(img, 64)
+	if b := thumb.Bounds(); b.Dx() != 64 || b.Dy() != 64 {
+		t.Errorf("缩略图尺寸 = %v", b)
+	}
+
+	if _, err := Decode([]byte("not an image")); !errors.Is(err, ErrInvalidImage) {
+		t.Errorf("无法解析的内容: %v", err)
+	}
+	if _, err := Decode(pngWithSize(20000, 20000)); !errors.Is(err, ErrInvalidImage) {
+		t.Errorf("尺寸过大的图片: %v", err)
+	}
+}
+
+func TestIdenticon(t *testing.T) {
+	a, b := Identicon("1", 64), Identicon("1", 64)
+	if !bytes.Equal(a.Pix, b.Pix) {
+		t.Error("相同的 seed 应生成相同的图片")
+	}
+	if bytes.Equal(a.Pix, Identicon("2", 64).Pix) {
+		t.Error("不同的 seed 应生成不同的图片")
+	}
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 32; x++ {
+			if a.NRGBAAt(x, y) != a.NRGBAAt(63-x, y) {
+				t.Fatalf("图案应左右对称: (%d,%d)", x, y)
+			}
+		}
+	}
+}
+
+// assertSamePixels 比较两张图片的每个像素
+func assertSamePixels(t *testing.T, want, got image.Image) {
+	t.Helper()
+	wb, gb := want.Bounds(), got.Bounds()
+	if wb.Dx() != gb.Dx() || wb.Dy() != gb.Dy() {
+		t.Fatalf("尺寸 = %v, want %v", gb, wb)
+	}
+	for y := 0; y < wb.Dy(); y++ {
+		for x := 0; x < wb.Dx(); x++ {
+			w := color.NRGBAModel.Convert(want.At(wb.Min.X+x, wb.Min.Y+y)).(color.NRGBA)
+			g := color.NRGBAModel.Convert(got.At(gb.Min.X+x, gb.Min.Y+y)).(color.NRGBA)
+			if w != g {
+				t.Fatalf("(%d,%d) = %v, want %v", x, y, g, w)
+			}
+		}
+	}
+}
+
+// withEXIFOrientation 在 JPEG 的 SOI 之后插入只包含 Orientation 的 EXIF 段
+func withEXIFOrientation(data []byte, orientation uint16) []byte {
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08")
+	tiff = binary.BigEndian.AppendUint16(tiff, 1)
+	tiff = binary.BigEndian.AppendUint16(tiff, 0x0112)
+	tiff = binary.BigEndian.AppendUint16(tiff, 3) // SHORT
+	tiff = binary.BigEndian.AppendUint32(tiff, 1)
+	tiff = binary.BigEndian.AppendUint16(tiff, orientation)
+	tiff = append(tiff, 0, 0, 0, 0, 0, 0)
+
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+	app1 := []byte{0xff, 0xe1}
+	app1 = binary.BigEndian.AppendUint16(app1, uint16(len(segment)+2))
+	app1 = append(app1, segment...)
+
+	out := append([]byte{}, data[:2]...)
+	out = append(out, app1...)
+	return append(out, data[2:]...)
+}
+
+// pngWithSize 只包含 IHDR 的 PNG，用于检查尺寸限制
+func pngWithSize(w, h uint32) []byte {
+	ihdr := []byte("IHDR")
+	ihdr = binary.BigEndian.AppendUint32(ihdr, w)
+	ihdr = binary.BigEndian.AppendUint32(ihdr, h)
+	ihdr = append(ihdr, 8, 6, 0, 0, 0)
+
+	out := []byte("\x89PNG\r\n\x1a\n")
+	out = binary.BigEndian.AppendUint32(out, uint32(len(ihdr)-4))
+	out = append(out, ihdr...)
+	return binary.BigEndian.AppendUint32(out, crc32.ChecksumIEEE(ihdr))
+}
@@ -0,0 +1,82 @@
+package avatar
+
+import (
+	"crypto/sha256"
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// identiconGrid identicon 的格子数（左右对称）
+const identiconGrid = 5
+
+// identiconBackground identicon 的背景色
+var identiconBackground = color.NRGBA{0xf0, 0xf0, 0xf0, 0xff}
+
+// Identicon 根据 seed 生成对称的 5×5 格子图案作为默认头像；相同的 seed 总是生成相同的图片
+func Identicon(seed string, size int) *image.NRGBA {
+	sum := sha256.Sum256([]byte(seed))
+	fg := identiconColor(sum[identiconGrid*3:])
+
+	img := image.NewNRGBA(image.Rect(0, 0, size, size))
+	draw.Draw(img, img.Bounds(), &image.Uniform{identiconBackground}, image.Point{}, draw.Src)
+
+	// 四周留白约为边长的 1/12，格子取整后多出的像素平分到两侧
+	margin := size / 12
+	cell := (size - 2*margin) / identiconGrid
+	offset := (size - cell*identiconGrid) / 2
+	for y := 0; y < identiconGrid; y++ {
+		for x := 0; x < (identiconGrid+1)/2; x++ {
+			if sum[y*3+x]&1 == 0 {
+				continue
+			}
+			for _, col := range []int{x, identiconGrid - 1 - x} {
+				r := image.Rect(offset+col*cell, offset+y*cell, offset+(col+1)*cell, offset+(y+1)*cell)
+				draw.Draw(img, r, &image.Uniform{fg}, image.Point{}, draw.Src)
+			}
+		}
+	}
+	return img
+}
+
+// identiconColor 由哈希选择色相，饱和度与亮度固定，保证在浅色背景上清晰可见
+func identiconColor(b []byte) color.NRGBA {
+	hue := float64(uint16(b[0])<<8|uint16(b[1])) / 65536 * 360
+	return hslToRGB(hue, 0.55, 0.5)
+}
+
+// hslToRGB 将 HSL（色相 0–360，饱和度与亮度 0–1）转换为 RGB
+func hslToRGB(h, s, l float64) color.NRGBA {
+	c := (1 - abs64(2*l-1)) * s
+	hp := h / 60
+	x := c * (1 - abs64(mod2(hp)-1))
+	var r, g, b float64
+	switch {
+	case hp < 1:
+		r, g = c, x
+	case hp < 2:
+		r, g = x, c
+	case hp < 3:
+		g, b = c, x
+	case hp < 4:
+		g, b = x, c
+	case hp < 5:
+		r, b = x, c
+	default:
+		r, b = c, x
+	}
+	m := l - c/2
+	return color.NRGBA{uint8((r + m) * 255), uint8((g + m) * 255), uint8((b + m) * 255), 0xff}
+}
+
+func abs64(v float64) float64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// mod2 返回 v 除以 2 的余数（v 非负）
+func mod2(v float64) float64 {
+	return v - 2*float64(int(v/2))
+}
@@ -0,0 +1,592 @@
+package avatar
+
+import (
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/draw"
+	"io"
+	"sort"
+)
+
+// VP8L（WebP 无损格式）编码器
+//
+// 只使用 subtract-green 与 predictor 两种变换，以及与左侧像素相同时的游程复制（距离为 1 的 backward reference），
+// 不使用颜色缓存与多组前缀码。压缩率不如 libwebp，但对头像这种尺寸足够，也不需要 cgo。
+// 格式见 RFC 9649。
+
+const (
+	vp8lSignature      = 0x2f
+	vp8lMaxDimension   = 1 << 14
+	vp8lPredictorBits  = 4 // 预测模式按 16x16 的块选择
+	vp8lMinCopyLength  = 3
+	vp8lMaxCopyLength  = 4096
+	vp8lMaxCodeLength  = 15
+	vp8lMaxCLCodeBits  = 7   // code length code 的最大码长
+	vp8lCopyDistance   = 121 // 距离 1（左侧像素）对应的距离码：大于 120 的距离码表示线性距离加 120
+	vp8lNumLiterals    = 256
+	vp8lNumLengthCodes = 24
+	vp8lNumDistCodes   = 40
+
+	transformPredictor     = 0
+	transformSubtractGreen = 2
+)
+
+// 预测模式（RFC 9649 4.1），只选用不依赖右上方像素的几种
+const (
+	predictLeft      = 1
+	predictTop       = 2
+	predictAverageLT = 7
+	predictSelect    = 11
+	predictClampFull = 12
+	predictClampHalf = 13
+)
+
+var predictModes = []int{predictLeft, predictTop, predictAverageLT, predictSelect, predictClampFull, predictClampHalf}
+
+// codeLengthCodeOrder code length code 的码长按此顺序写入
+var codeLengthCodeOrder = [19]int{17, 18, 0, 1, 2, 3, 4, 5, 16, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+
+// EncodeWebP 将图片编码为无损 WebP
+func EncodeWebP(w io.Writer, img image.Image) error {
+	b := img.Bounds()
+	width, height := b.Dx(), b.Dy()
+	if width < 1 || height < 1 || width > vp8lMaxDimension || height > vp8lMaxDimension {
+		return errors.New("WebP 图片尺寸超出范围")
+	}
+
+	src := image.NewNRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(src, src.Bounds(), img, b.Min, draw.Src)
+	pix := make([]uint32, width*height)
+	hasAlpha := false
+	for i := range pix {
+		p := src.Pix[i*4 : i*4+4]
+		if p[3] != 0xff {
+			hasAlpha = true
+		}
+		// subtract green：红、蓝通道减去绿色通道
+		g := p[1]
+		pix[i] = uint32(p[3])<<24 | uint32(p[0]-g)<<16 | uint32(g)<<8 | uint32(p[2]-g)
+	}
+	modes, residuals := predict(pix, width, height)
+
+	bw := &bitWriter{}
+	bw.write(vp8lSignature, 8)
+	bw.write(uint32(width-1), 14)
+	bw.write(uint32(height-1), 14)
+	bw.writeBool(hasAlpha)
+	bw.write(0, 3) // 版本
+
+	// 解码时按相反的顺序还原：先还原预测，再加回绿色通道
+	bw.write(1, 1)
+	bw.write(transformSubtractGreen, 2)
+	bw.write(1, 1)
+	bw.write(transformPredictor, 2)
+	bw.write(vp8lPredictorBits-2, 3)
+	writeEntropyImage(bw, modes, false)
+	bw.write(0, 1)
+
+	writeEntropyImage(bw, residuals, true)
+	data := bw.bytes()
+
+	// RIFF 容器
+	pad := len(data) & 1
+	header := make([]byte, 20)
+	copy(header[0:], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:], uint32(4+8+len(data)+pad))
+	copy(header[8:], "WEBPVP8L")
+	binary.LittleEndian.PutUint32(header[16:], uint32(len(data)))
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	if pad == 1 {
+		data = append(data, 0)
+	}
+	_, err := w.Write(data)
+	return err
+}
+
+// predict 为每个块选择残差最小的预测模式，返回模式图（绿色通道为模式）与残差
+func predict(pix []uint32, width, height int) (modes, residuals []uint32) {
+	block := 1 << vp8lPredictorBits
+	tilesX := (width + block - 1) >> vp8lPredictorBits
+	tilesY := (height + block - 1) >> vp8lPredictorBits
+	modes = make([]uint32, tilesX*tilesY)
+	residuals = make([]uint32, len(pix))
+
+	for ty := 0; ty < tilesY; ty++ {
+		for tx := 0; tx < tilesX; tx++ {
+			x0, y0 := tx*block, ty*block
+			x1, y1 := min(x0+block, width), min(y0+block, height)
+
+			best, bestCost := predictModes[0], -1
+			for _, mode := range predictModes {
+				cost := 0
+				for y := y0; y < y1; y++ {
+					for x := x0; x < x1; x++ {
+						cost += residualCost(sub(pix[y*width+x], predictPixel(pix, width, x, y, mode)))
+					}
+				}
+				if bestCost < 0 || cost < bestCost {
+					best, bestCost = mode, cost
+				}
+			}
+
+			modes[ty*tilesX+tx] = 0xff000000 | uint32(best)<<8
+			for y := y0; y < y1; y++ {
+				for x := x0; x < x1; x++ {
+					residuals[y*width+x] = sub(pix[y*width+x], predictPixel(pix, width, x, y, best))
+				}
+			}
+		}
+	}
+	return modes, residuals
+}
+
+// predictPixel 按模式预测 (x, y) 的像素；第一行与第一列的规则是固定的
+func predictPixel(pix []uint32, width, x, y, mode int) uint32 {
+	switch {
+	case x == 0 && y == 0:
+		return 0xff000000
+	case y == 0:
+		return pix[x-1]
+	case x == 0:
+		return pix[(y-1)*width]
+	}
+	l, t, tl := pix[y*width+x-1], pix[(y-1)*width+x], pix[(y-1)*width+x-1]
+	switch mode {
+	case predictLeft:
+		return l
+	case predictTop:
+		return t
+	case predictAverageLT:
+		return average2(l, t)
+	case predictSelect:
+		return selectPixel(l, t, tl)
+	case predictClampFull:
+		return perChannel(func(c int) int { return ch(l, c) + ch(t, c) - ch(tl, c) })
+	case predictClampHalf:
+		avg := average2(l, t)
+		return perChannel(func(c int) int { a := ch(avg, c); return a + (a-ch(tl, c))/2 })
+	}
+	panic("avatar: 不支持的预测模式")
+}
+
+// ch 取像素的一个通道（0 为 alpha，3 为蓝色）
+func ch(p uint32, c int) int {
+	return int(p>>(24-8*c)) & 0xff
+}
+
+// perChannel 按通道计算并截断到 0–255
+func perChannel(f func(c int) int) uint32 {
+	var p uint32
+	for c := 0; c < 4; c++ {
+		p |= uint32(max(0, min(255, f(c)))) << (24 - 8*c)
+	}
+	return p
+}
+
+func average2(a, b uint32) uint32 {
+	return perChannel(func(c int) int { return (ch(a, c) + ch(b, c)) / 2 })
+}
+
+// selectPixel 在左侧与上方像素中选择更接近 L+T-TL 的一个
+func selectPixel(l, t, tl uint32) uint32 {
+	pl, pt := 0, 0
+	for c := 0; c < 4; c++ {
+		estimate := ch(l, c) + ch(t, c) - ch(tl, c)
+		pl += abs(estimate - ch(l, c))
+		pt += abs(estimate - ch(t, c))
+	}
+	if pl < pt {
+		return l
+	}
+	return t
+}
+
+// sub 按通道相减（模 256）
+func sub(a, b uint32) uint32 {
+	var p uint32
+	for shift := 0; shift < 32; shift += 8 {
+		p |= ((a>>shift - b>>shift) & 0xff) << shift
+	}
+	return p
+}
+
+// residualCost 残差的大致代价：各通道到 0 的距离之和
+func residualCost(p uint32) int {
+	cost := 0
+	for c := 0; c < 4; c++ {
+		v := ch(p, c)
+		cost += min(v, 256-v)
+	}
+	return cost
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// token 字面像素，或 length 大于 0 时表示重复左侧像素 length 次
+type token struct {
+	argb   uint32
+	length int
+}
+
+// tokenize 将连续相同的像素合并为复制
+func tokenize(pix []uint32) []token {
+	tokens := make([]token, 0, len(pix))
+	for i := 0; i < len(pix); {
+		if i > 0 {
+			n := 0
+			for i+n < len(pix) && n < vp8lMaxCopyLength && pix[i+n] == pix[i-1] {
+				n++
+			}
+			if n >= vp8lMinCopyLength {
+				tokens = append(tokens, token{length: n})
+				i += n
+				continue
+			}
+		}
+		tokens = append(tokens, token{argb: pix[i]})
+		i++
+	}
+	return tokens
+}
+
+// prefixEncode 将长度或距离码编码为前缀码与附加位
+func prefixEncode(v int) (prefix int, extraBits uint, extra uint32) {
+	d := v - 1
+	if d < 4 {
+		return d, 0, 0
+	}
+	high := 0
+	for d>>(high+1) != 0 {
+		high++
+	}
+	second := (d >> (high - 1)) & 1
+	extraBits = uint(high - 1)
+	return 2*high + second, extraBits, uint32(d) & (1<<extraBits - 1)
+}
+
+// writeEntropyImage 写入一张熵编码的图片：只有主图片需要写 meta prefix code 标志
+func writeEntropyImage(bw *bitWriter, pix []uint32, main bool) {
+	bw.write(0, 1) // 不使用颜色缓存
+	if main {
+		bw.write(0, 1) // 只有一组前缀码
+	}
+
+	tokens := tokenize(pix)
+	hist := [5][]uint32{
+		make([]uint32, vp8lNumLiterals+vp8lNumLengthCodes),
+		make([]uint32, vp8lNumLiterals),
+		make([]uint32, vp8lNumLiterals),
+		make([]uint32, vp8lNumLiterals),
+		make([]uint32, vp8lNumDistCodes),
+	}
+	distPrefix, distExtraBits, distExtra := prefixEncode(vp8lCopyDistance)
+	for _, t := range tokens {
+		if t.length > 0 {
+			prefix, _, _ := prefixEncode(t.length)
+			hist[0][vp8lNumLiterals+prefix]++
+			hist[4][distPrefix]++
+			continue
+		}
+		hist[0][ch(t.argb, 2)]++
+		hist[1][ch(t.argb, 1)]++
+		hist[2][ch(t.argb, 3)]++
+		hist[3][ch(t.argb, 0)]++
+	}
+
+	var codes [5]*prefixCode
+	for i := range codes {
+		codes[i] = newPrefixCode(hist[i])
+		codes[i].writeTo(bw)
+	}
+
+	green, red, blue, alpha, dist := codes[0], codes[1], codes[2], codes[3], codes[4]
+	for _, t := range tokens {
+		if t.length > 0 {
+			prefix, extraBits, extra := prefixEncode(t.length)
+			green.writeSymbol(bw, vp8lNumLiterals+prefix)
+			bw.write(extra, extraBits)
+			dist.writeSymbol(bw, distPrefix)
+			bw.write(distExtra, distExtraBits)
+			continue
+		}
+		green.writeSymbol(bw, ch(t.argb, 2))
+		red.writeSymbol(bw, ch(t.argb, 1))
+		blue.writeSymbol(bw, ch(t.argb, 3))
+		alpha.writeSymbol(bw, ch(t.argb, 0))
+	}
+}
+
+// prefixCode 规范 Huffman 码，codes 已按位反转以便低位先写
+type prefixCode struct {
+	lengths []uint8
+	codes   []uint16
+	simple  []int // 不超过两个符号且都小于 256 时使用 simple code
+}
+
+// newPrefixCode 根据符号频次构造前缀码
+func newPrefixCode(hist []uint32) *prefixCode {
+	var used []int
+	for sym, n := range hist {
+		if n > 0 {
+			used = append(used, sym)
+		}
+	}
+	c := &prefixCode{lengths: make([]uint8, len(hist))}
+	switch {
+	case len(used) == 0:
+		c.simple = []int{0}
+	case len(used) <= 2 && used[len(used)-1] < vp8lNumLiterals:
+		// 只有一个符号时不占用任何位
+		c.simple = used
+		if len(used) == 2 {
+			c.lengths[used[0]], c.lengths[used[1]] = 1, 1
+		}
+	default:
+		c.lengths = huffmanLengths(hist, vp8lMaxCodeLength)
+	}
+	c.codes = canonicalCodes(c.lengths)
+	return c
+}
+
+// writeSymbol 写入一个符号
+func (c *prefixCode) writeSymbol(bw *bitWriter, sym int) {
+	bw.write(uint32(c.codes[sym]), uint(c.lengths[sym]))
+}
+
+// writeTo 写入前缀码本身
+func (c *prefixCode) writeTo(bw *bitWriter) {
+	if c.simple != nil {
+		bw.write(1, 1)
+		bw.write(uint32(len(c.simple)-1), 1)
+		if first := c.simple[0]; first < 2 {
+			bw.write(0, 1)
+			bw.write(uint32(first), 1)
+		} else {
+			bw.write(1, 1)
+			bw.write(uint32(first), 8)
+		}
+		if len(c.simple) == 2 {
+			bw.write(uint32(c.simple[1]), 8)
+		}
+		return
+	}
+
+	bw.write(0, 1)
+	tokens := codeLengthTokens(c.lengths)
+	hist := make([]uint32, len(codeLengthCodeOrder))
+	for _, t := range tokens {
+		hist[t.sym]++
+	}
+	clLengths := huffmanLengths(hist, vp8lMaxCLCodeBits)
+	clCodes := canonicalCodes(clLengths)
+
+	n := len(codeLengthCodeOrder)
+	for n > 4 && clLengths[codeLengthCodeOrder[n-1]] == 0 {
+		n--
+	}
+	bw.write(uint32(n-4), 4)
+	for _, sym := range codeLengthCodeOrder[:n] {
+		bw.write(uint32(clLengths[sym]), 3)
+	}
+	bw.write(0, 1) // 码长覆盖整个字母表
+
+	for _, t := range tokens {
+		bw.write(uint32(clCodes[t.sym]), uint(clLengths[t.sym]))
+		bw.write(t.extra, t.extraBits)
+	}
+}
+
+// clToken code length code 的一个符号：0–15 为码长，16 重复上一个非零码长，17、18 为连续的 0
+type clToken struct {
+	sym       int
+	extra     uint32
+	extraBits uint
+}
+
+// codeLengthTokens 将码长序列编码为 code length code 的符号
+func codeLengthTokens(lengths []uint8) []clToken {
+	var tokens []clToken
+	prev := uint8(8) // 规范规定的初始“上一个非零码长”
+	for i := 0; i < len(lengths); {
+		l := lengths[i]
+		run := 1
+		for i+run < len(lengths) && lengths[i+run] == l {
+			run++
+		}
+		i += run
+
+		if l == 0 {
+			for run >= 3 {
+				n := min(run, 138)
+				if n >= 11 {
+					tokens = append(tokens, clToken{sym: 18, extra: uint32(n - 11), extraBits: 7})
+				} else {
+					tokens = append(tokens, clToken{sym: 17, extra: uint32(n - 3), extraBits: 3})
+				}
+				run -= n
+			}
+		} else {
+			if l != prev {
+				tokens = append(tokens, clToken{sym: int(l)})
+				prev = l
+				run--
+			}
+			for run >= 3 {
+				n := min(run, 6)
+				tokens = append(tokens, clToken{sym: 16, extra: uint32(n - 3), extraBits: 2})
+				run -= n
+			}
+		}
+		for ; run > 0; run-- {
+			tokens = append(tokens, clToken{sym: int(l)})
+		}
+	}
+	return tokens
+}
+
+// huffmanLengths 计算不超过 maxLength 的 Huffman 码长；超出时压平频次后重算
+//
+// 只有一个符号时额外补一个不会用到的符号，保证码表完整。
+func huffmanLengths(hist []uint32, maxLength int) []uint8 {
+	counts := append([]uint32(nil), hist...)
+	var used []int
+	for sym, n := range counts {
+		if n > 0 {
+			used = append(used, sym)
+		}
+	}
+	lengths := make([]uint8, len(hist))
+	switch len(used) {
+	case 0:
+		return lengths
+	case 1:
+		lengths[used[0]] = 1
+		lengths[(used[0]+1)%len(lengths)] = 1
+		return lengths
+	}
+
+	for {
+		if huffmanTree(counts, used, lengths) <= maxLength {
+			return lengths
+		}
+		for _, sym := range used {
+			counts[sym] = counts[sym]>>1 | 1
+		}
+	}
+}
+
+// huffmanTree 构造 Huffman 树并写入各符号的深度，返回最大深度
+func huffmanTree(counts []uint32, used []int, lengths []uint8) int {
+	leaves := append([]int(nil), used...)
+	sort.SliceStable(leaves, func(i, j int) bool { return counts[leaves[i]] < counts[leaves[j]] })
+
+	// 两个队列：按频次排好序的叶子与依次生成的内部节点（频次单调不减）
+	n := len(leaves)
+	weight := make([]uint64, 2*n-1)
+	parent := make([]int, 2*n-1)
+	for i, sym := range leaves {
+		weight[i] = uint64(counts[sym])
+	}
+	leaf, inner := 0, n
+	next := n
+	pick := func() int {
+		if leaf < n && (inner >= next || weight[leaf] <= weight[inner]) {
+			leaf++
+			return leaf - 1
+		}
+		inner++
+		return inner - 1
+	}
+	for ; next < 2*n-1; next++ {
+		a, b := pick(), pick()
+		weight[next] = weight[a] + weight[b]
+		parent[a], parent[b] = next, next
+	}
+
+	depth := make([]int, 2*n-1)
+	maxDepth := 0
+	for i := 2*n - 3; i >= 0; i-- {
+		depth[i] = depth[parent[i]] + 1
+	}
+	for i, sym := range leaves {
+		lengths[sym] = uint8(min(depth[i], 255))
+		maxDepth = max(maxDepth, depth[i])
+	}
+	return maxDepth
+}
+
+// canonicalCodes 由码长生成规范 Huffman 码（与 DEFLATE 相同的分配方式），并按位反转
+func canonicalCodes(lengths []uint8) []uint16 {
+	var count [vp8lMaxCodeLength + 1]int
+	for _, l := range lengths {
+		if l > 0 {
+			count[l]++
+		}
+	}
+	var next [vp8lMaxCodeLength + 2]int
+	code := 0
+	for bits := 1; bits <= vp8lMaxCodeLength; bits++ {
+		code = (code + count[bits-1]) << 1
+		next[bits] = code
+	}
+
+	codes := make([]uint16, len(lengths))
+	for sym, l := range lengths {
+		if l == 0 {
+			continue
+		}
+		codes[sym] = reverseBits(uint16(next[l]), l)
+		next[l]++
+	}
+	return codes
+}
+
+func reverseBits(v uint16, n uint8) uint16 {
+	var r uint16
+	for i := uint8(0); i < n; i++ {
+		r = r<<1 | v&1
+		v >>= 1
+	}
+	return r
+}
+
+// bitWriter 按 VP8L 的顺序（低位在前）写入比特流
+type bitWriter struct {
+	buf   []byte
+	acc   uint64
+	nbits uint
+}
+
+func (w *bitWriter) write(v uint32, n uint) {
+	w.acc |= uint64(v) << w.nbits
+	w.nbits += n
+	for w.nbits >= 8 {
+		w.buf = append(w.buf, byte(w.acc))
+		w.acc >>= 8
+		w.nbits -= 8
+	}
+}
+
+func (w *bitWriter) writeBool(b bool) {
+	if b {
+		w.write(1, 1)
+	} else {
+		w.write(0, 1)
+	}
+}
+
+func (w *bitWriter) bytes() []byte {
+	if w.nbits > 0 {
+		w.buf = append(w.buf, byte(w.acc))
+		w.acc, w.nbits = 0, 0
+	}
+	return w.buf
+}
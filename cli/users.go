@@ -0,0 +1,203 @@
+// Package cli 实现命令行子命令：在不启动 HTTP 服务的情况下加载配置、连接数据库并执行运维操作
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"iris-cn-sample-project/config"
+	"iris-cn-sample-project/database"
+	"iris-cn-sample-project/export"
+	"iris-cn-sample-project/logging"
+	"iris-cn-sample-project/models"
+	"iris-cn-sample-project/services"
+)
+
+// RunUsers 执行 users 子命令，返回进程退出码（有失败的行时为 1）
+//
+//	users import [-dry-run] [-mode atomic|best_effort] [-invite] [-format csv|json] [-config 文件] 文件|-
+//	users export [-format csv|jsonl|xlsx] [-o 文件] [-fields 字段] [-q 关键字] [-role 角色] [-status 状态] [-sort 排序] [-config 文件]
+func RunUsers(args []string) int {
+	if len(args) == 0 || (args[0] != "import" && args[0] != "export") {
+		fmt.Fprintln(os.Stderr, "用法: users import [-dry-run] [-mode atomic|best_effort] [-invite] 文件 | users export [-format csv|jsonl|xlsx] [-o 文件]")
+		return 2
+	}
+
+	fs := flag.NewFlagSet("users "+args[0], flag.ContinueOnError)
+	var opts config.Options
+	opts.Register(fs)
+	if args[0] == "import" {
+		return runUsersImport(fs, &opts, args[1:])
+	}
+	return runUsersExport(fs, &opts, args[1:])
+}
+
+// runUsersImport 从文件（- 为标准输入）批量导入用户，结果以 JSON 输出到标准输出
+func runUsersImport(fs *flag.FlagSet, opts *config.Options, args []string) int {
+	var importOpts services.ImportOptions
+	fs.BoolVar(&importOpts.DryRun, "dry-run", false, "试运行：只校验，不创建用户")
+	fs.StringVar(&importOpts.Mode, "mode", services.ImportAtomic, "atomic：任一行失败时不创建任何用户；best_effort：跳过失败的行")
+	fs.BoolVar(&importOpts.Invite, "invite", false, "向创建的用户发送邀请邮件（未提供密码的用户生成临时密码）")
+	format := fs.String("format", "", "文件格式：csv 或 json，默认按扩展名判断")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "用法: users import [选项] 文件|-")
+		return 2
+	}
+
+	name := fs.Arg(0)
+	if *format == "" {
+		*format = strings.TrimPrefix(strings.ToLower(filepath.Ext(name)), ".")
+	}
+	var r io.Reader = os.Stdin
+	if name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "打开导入文件失败: %v\n", err)
+			return 1
+		}
+		defer f.Close()
+		r = f
+	}
+	rows, err := services.ParseUserImport(r, *format)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	if err := setupCommand(opts); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer closeCommand()
+
+	result, err := services.ImportUsers(context.Background(), rows, importOpts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "批量导入用户失败: %v\n", err)
+		return 1
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.Encode(result)
+
+	fmt.Fprintf(os.Stderr, "共 %d 行，创建 %d 个用户，失败 %d 行", result.Total, result.Created, result.Failed)
+	if importOpts.DryRun {
+		fmt.Fprint(os.Stderr, "（试运行，没有写入）")
+	}
+	fmt.Fprintln(os.Stderr)
+	if result.Failed > 0 || len(result.InviteErrors) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// runUsersExport 按条件导出用户到文件（默认标准输出）
+func runUsersExport(fs *flag.FlagSet, opts *config.Options, args []string) int {
+	format := fs.String("format", export.FormatCSV, "导出格式："+strings.Join(export.Formats(), "、"))
+	output := fs.String("o", "-", "输出文件，- 为标准输出")
+	fieldList := fs.String("fields", "", "逗号分隔的导出字段，默认全部")
+	keyword := fs.String("q", "", "在用户名、邮箱、姓名中搜索")
+	role := fs.String("role", "", "角色：admin 或 user")
+	status := fs.String("status", "active", "状态：active、inactive 或 all")
+	sortList := fs.String("sort", "", "逗号分隔的排序字段，前加 - 表示倒序")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if export.ContentType(*format) == "" {
+		fmt.Fprintf(os.Stderr, "format 必须是 %s 之一\n", strings.Join(export.Formats(), "、"))
+		return 2
+	}
+	if *role != "" && *role != "admin" && *role != "user" {
+		fmt.Fprintln(os.Stderr, "role 必须是 admin 或 user")
+		return 2
+	}
+	if *status != "active" && *status != "inactive" && *status != "all" {
+		fmt.Fprintln(os.Stderr, "status 必须是 active、inactive 或 all")
+		return 2
+	}
+	fields, err := models.UserInfoFields.Parse(*fieldList)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	sorts, err := services.ParseUserSort(*sortList)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	filter := services.UserFilter{Keyword: *keyword, Role: *role, Status: *status, Sort: sorts, Columns: models.UserInfoFields.Columns(fields)}
+	if filter.Status == "all" {
+		filter.Status = ""
+	}
+	if len(fields) == 0 {
+		fields = models.UserInfoFields.Names()
+	}
+
+	if err := setupCommand(opts); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer closeCommand()
+
+	var out io.Writer = os.Stdout
+	if *output != "-" {
+		f, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "创建输出文件失败: %v\n", err)
+			return 1
+		}
+		defer f.Close()
+		out = f
+	}
+	w, err := export.NewWriter(out, *format, fields)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	count := 0
+	err = services.ExportUsers(context.Background(), filter, func(user *models.UserInfo) error {
+		count++
+		return w.Write(models.UserInfoFields.Project(user, fields))
+	})
+	if err = errors.Join(err, w.Close()); err != nil {
+		fmt.Fprintf(os.Stderr, "导出用户失败: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "已导出 %d 个用户\n", count)
+	return 0
+}
+
+// setupCommand 为命令行子命令加载配置、初始化日志与数据库；日志输出到标准输出时改为标准错误，避免与导出内容混在一起
+func setupCommand(opts *config.Options) error {
+	cfg, err := config.Load(*opts)
+	if err = errors.Join(err, cfg.Validate()); err != nil {
+		return fmt.Errorf("配置无效:\n%v", err)
+	}
+	if cfg.Log.Output == "" || strings.EqualFold(cfg.Log.Output, "stdout") {
+		cfg.Log.Output = "stderr"
+	}
+	config.SetConfig(cfg)
+	if _, err := logging.Init(cfg.Log); err != nil {
+		return fmt.Errorf("日志初始化失败: %v", err)
+	}
+	if err := database.InitDB(); err != nil {
+		return fmt.Errorf("数据库初始化失败: %v", err)
+	}
+	return nil
+}
+
+// closeCommand 关闭数据库并刷新日志
+func closeCommand() {
+	database.CloseDB()
+	logging.Close()
+}
@@ -1,16 +1,33 @@
 package config
 
 import (
-	"os"
-	"strconv"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
 )
 
 // Config 应用程序配置结构体
 type Config struct {
-	Server   ServerConfig   `json:"server"`
-	Database DatabaseConfig `json:"database"`
-	JWT      JWTConfig      `json:"jwt"`
-	Log      LogConfig      `json:"log"`
+	Profile     string            `json:"profile"` // 运行环境（development、test、production），由 --profile 或 APP_ENV 指定
+	Server      ServerConfig      `json:"server"`
+	Database    DatabaseConfig    `json:"database"`
+	JWT         JWTConfig         `json:"jwt"`
+	Log         LogConfig         `json:"log"`
+	Upload      UploadConfig      `json:"upload"`
+	Mail        MailConfig        `json:"mail"`
+	Health      HealthConfig      `json:"health"`
+	Metrics     MetricsConfig     `json:"metrics"`
+	Tracing     TracingConfig     `json:"tracing"`
+	CORS        CORSConfig        `json:"cors"`
+	RateLimit   RateLimitConfig   `json:"rate_limit"`
+	ErrorReport ErrorReportConfig `json:"error_report"`
+	RequestID   RequestIDConfig   `json:"request_id"`
+	Security    SecurityConfig    `json:"security"`
+	Storage     StorageConfig     `json:"storage"`
+	Features    map[string]bool   `json:"features"` // 功能开关，可热更新
 }
 
 // ServerConfig 服务器配置
@@ -19,6 +36,33 @@ type ServerConfig struct {
 	Mode         string `json:"mode"`
 	ReadTimeout  int    `json:"read_timeout"`
 	WriteTimeout int    `json:"write_timeout"`
+
+	ReadHeaderTimeout int `json:"read_header_timeout"` // 读取请求头的超时时间（秒），防止慢速请求头占用连接
+	IdleTimeout       int `json:"idle_timeout"`        // keep-alive 空闲连接的超时时间（秒）
+	MaxHeaderBytes    int `json:"max_header_bytes"`    // 请求头大小上限（字节）
+	ShutdownTimeout   int `json:"shutdown_timeout"`    // 优雅停机时等待进行中请求完成的最长时间（秒）
+	DrainDelay        int `json:"drain_delay"`         // 收到停止信号后先标记为未就绪、继续服务的时间（秒），供负载均衡摘除实例
+
+	// TrustedProxies 可信代理的 CIDR 或 IP，只有来自这些地址的连接才读取转发头
+	TrustedProxies []string `json:"trusted_proxies"`
+	// ClientIPHeaders 按顺序读取的客户端IP请求头（X-Forwarded-For、Forwarded、X-Real-IP）
+	ClientIPHeaders []string `json:"client_ip_headers"`
+
+	TLS TLSConfig `json:"tls"`
+}
+
+// TLSConfig HTTPS 配置（无反向代理直接对外提供服务时使用）
+type TLSConfig struct {
+	Enabled        bool     `json:"enabled"`
+	CertFile       string   `json:"cert_file"`
+	KeyFile        string   `json:"key_file"`
+	MinVersion     string   `json:"min_version"`     // 最低协议版本：1.2 或 1.3
+	CipherSuites   []string `json:"cipher_suites"`   // TLS 1.2 使用的加密套件名称（如 TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256），为空时使用 Go 的默认值
+	ClientCAFile   string   `json:"client_ca_file"`  // 校验客户端证书的 CA，配置后启用双向 TLS
+	ClientAuth     string   `json:"client_auth"`     // 客户端证书要求：require（必须提供）或 optional（提供时校验）
+	ReloadInterval int      `json:"reload_interval"` // 检查证书文件变化的间隔（秒），0 表示不自动重新加载
+	RedirectPort   string   `json:"redirect_port"`   // HTTP 重定向到 HTTPS 的监听端口，为空时不监听
+	RedirectHost   string   `json:"redirect_host"`   // 重定向目标主机名，配置了 RedirectPort 时必须设置（不使用请求的 Host）
 }
 
 // DatabaseConfig 数据库配置
@@ -28,89 +72,559 @@ type DatabaseConfig struct {
 	Port     string `json:"port"`
 	Database string `json:"database"`
 	Username string `json:"username"`
-	Password string `json:"password"`
+	Password string `json:"password" secret:"true"`
 	SSL      string `json:"ssl"`
 }
 
 // JWTConfig JWT 配置
 type JWTConfig struct {
-	Secret         string `json:"secret"`
+	Secret         string `json:"secret" secret:"true"`
 	ExpirationTime int    `json:"expiration_time"`
 	Issuer         string `json:"issuer"`
 }
 
 // LogConfig 日志配置
 type LogConfig struct {
-	Level  string `json:"level"`
-	Format string `json:"format"`
-	Output string `json:"output"`
+	Level         string `json:"level"`           // debug、info、warn、error
+	Format        string `json:"format"`          // json、text
+	Output        string `json:"output"`          // stdout、stderr、file
+	File          string `json:"file"`            // Output 为 file 时的日志文件路径
+	MaxSizeMB     int    `json:"max_size_mb"`     // 单个日志文件的最大大小（MB），超过后滚动
+	MaxBackups    int    `json:"max_backups"`     // 保留的历史日志文件数量
+	SlowSQLMillis int    `json:"slow_sql_millis"` // 慢查询阈值（毫秒）
+
+	LogBodies      bool         `json:"log_bodies"`       // 是否记录请求/响应体（debug 级别下始终记录）
+	BodyMaxBytes   int          `json:"body_max_bytes"`   // 记录请求/响应体的最大字节数
+	BodySampleRate float64      `json:"body_sample_rate"` // 成功请求记录请求/响应体的采样率（0~1），失败请求始终记录
+	Redact         RedactConfig `json:"redact"`           // 脱敏规则
+}
+
+// RedactConfig 日志脱敏配置
+type RedactConfig struct {
+	Fields   []string `json:"fields"`   // 需要脱敏的 JSON 字段/表单参数名（忽略大小写）
+	Headers  []string `json:"headers"`  // 需要脱敏的请求/响应头
+	Patterns []string `json:"patterns"` // 需要脱敏的正则表达式（如邮箱、手机号）
 }
 
-var appConfig *Config
+// DefaultRedactConfig 默认脱敏规则
+func DefaultRedactConfig() RedactConfig {
+	return RedactConfig{
+		Fields: []string{
+			"password", "old_password", "new_password", "confirm_password",
+			"token", "access_token", "refresh_token", "id_token",
+			"secret", "client_secret", "api_key", "authorization",
+			"signature", "x_amz_signature",
+		},
+		Headers: []string{
+			"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key",
+		},
+		Patterns: []string{
+			`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`,     // 邮箱
+			`\b(?:\+?86[- ]?)?1[3-9]\d{9}\b`,                       // 手机号
+			`eyJ[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]*`, // JWT
+		},
+	}
+}
 
-// GetConfig 获取应用程序配置
+// UploadConfig 文件上传配置
+type UploadConfig struct {
+	Dir                string                 `json:"dir"`                  // 本地存储目录，不能位于公开的静态文件目录内
+	MaxSizeMB          int                    `json:"max_size_mb"`          // 普通附件的大小上限，读取时即时限制
+	QuarantineDir      string                 `json:"quarantine_dir"`       // 未通过扫描的文件的隔离目录（不对外提供访问）
+	UserQuotaMB        int                    `json:"user_quota_mb"`        // 每个用户可占用的存储空间，0 表示不限制
+	UserMaxFiles       int                    `json:"user_max_files"`       // 每个用户可保存的文件数，0 表示不限制
+	RoleQuotas         map[string]QuotaConfig `json:"role_quotas"`          // 按角色覆盖 user_quota_mb 与 user_max_files
+	AnonymousQuotaMB   int                    `json:"anonymous_quota_mb"`   // 同一客户端IP匿名上传可占用的存储空间，0 表示不限制
+	AnonymousMaxFiles  int                    `json:"anonymous_max_files"`  // 同一客户端IP匿名上传可保存的文件数，0 表示不限制
+	ShareMaxExpiration int                    `json:"share_max_expiration"` // 分享下载链接的最长有效期（秒）
+	ReconcileInterval  int                    `json:"reconcile_interval"`   // 核对存储用量、清理无主对象的间隔（秒），0 表示不运行
+	Scanner            ScannerConfig          `json:"scanner"`
+	Tus                TusConfig              `json:"tus"`
+}
+
+// QuotaConfig 存储配额，0 表示不限制
+type QuotaConfig struct {
+	QuotaMB  int `json:"quota_mb"`
+	MaxFiles int `json:"max_files"`
+}
+
+// TusConfig 可续传上传（tus 协议）配置
+type TusConfig struct {
+	MaxSizeMB       int `json:"max_size_mb"`      // 单个文件的大小上限
+	Expiration      int `json:"expiration"`       // 未完成的上传在最后一次写入后保留的时间（秒）
+	CleanupInterval int `json:"cleanup_interval"` // 清理过期上传的间隔（秒）
+}
+
+// ScannerConfig 上传文件恶意软件扫描配置
+type ScannerConfig struct {
+	Driver   string `json:"driver"`    // none（不扫描）或 clamav
+	Address  string `json:"address"`   // clamd 地址：unix:///var/run/clamav/clamd.ctl 或 tcp://host:3310
+	Timeout  int    `json:"timeout"`   // 单个文件的扫描超时（秒）
+	FailOpen bool   `json:"fail_open"` // 扫描服务不可用时放行（默认拒绝上传）
+}
+
+// StorageConfig 文件存储配置
+type StorageConfig struct {
+	Driver     string   `json:"driver"`                    // local（保存在 upload.dir）或 s3（S3 兼容的对象存储）
+	BaseURL    string   `json:"base_url"`                  // 本地存储目录由其他服务器对外提供时的地址前缀，应用本身不公开该目录
+	SigningKey string   `json:"signing_key" secret:"true"` // 签名下载地址的密钥，为空时由 JWT 密钥派生
+	S3         S3Config `json:"s3"`
+}
+
+// S3Config S3 兼容对象存储配置（AWS S3、MinIO 等）
+type S3Config struct {
+	Endpoint        string `json:"endpoint"` // 服务地址，如 https://s3.amazonaws.com、http://minio:9000
+	Region          string `json:"region"`
+	Bucket          string `json:"bucket"`
+	AccessKeyID     string `json:"access_key_id"`
+	SecretAccessKey string `json:"secret_access_key" secret:"true"`
+	PathStyle       bool   `json:"path_style"` // 使用路径风格地址（endpoint/bucket/key），MinIO 通常需要开启
+	Timeout         int    `json:"timeout"`    // 请求超时（秒）
+}
+
+// MailConfig 邮件服务配置
+type MailConfig struct {
+	Host     string `json:"host"`
+	Port     string `json:"port"`
+	Username string `json:"username"`
+	Password string `json:"password" secret:"true"`
+	From     string `json:"from"`
+}
+
+// HealthConfig 健康检查配置
+type HealthConfig struct {
+	CheckTimeout  int `json:"check_timeout"`    // 单个检查的超时时间（毫秒）
+	CacheTTL      int `json:"cache_ttl"`        // 检查结果缓存时间（毫秒）
+	MinFreeDiskMB int `json:"min_free_disk_mb"` // 上传目录所在分区的最低剩余空间（MB）
+	MaxGoroutines int `json:"max_goroutines"`   // 存活检查允许的最大 goroutine 数，0 表示不检查
+}
+
+// MetricsConfig 指标配置
+type MetricsConfig struct {
+	PushgatewayURL string `json:"pushgateway_url"` // 停机前推送最终指标的 Pushgateway 地址，为空时不推送
+	PushJob        string `json:"push_job"`        // 推送时使用的 job 名称
+}
+
+// TracingConfig 链路追踪配置
+type TracingConfig struct {
+	Enabled     bool              `json:"enabled"`
+	ServiceName string            `json:"service_name"`
+	Exporter    string            `json:"exporter"`              // otlp、stdout、none
+	Endpoint    string            `json:"endpoint"`              // OTLP/HTTP 接收地址（host:port）
+	URLPath     string            `json:"url_path"`              // OTLP/HTTP 路径
+	Insecure    bool              `json:"insecure"`              // 使用 HTTP 而非 HTTPS 发送
+	Headers     map[string]string `json:"headers" secret:"true"` // 发送时附带的请求头（如认证信息）
+	SampleRatio float64           `json:"sample_ratio"`          // 根 span 采样率（0~1），有上游 span 时跟随上游决定
+}
+
+// CORSPolicy 跨域策略
+type CORSPolicy struct {
+	AllowedOrigins   []string `json:"allowed_origins"`   // 允许的源：精确匹配、子域名通配（https://*.example.com）或 *
+	AllowedMethods   []string `json:"allowed_methods"`   // 允许的请求方法
+	AllowedHeaders   []string `json:"allowed_headers"`   // 允许的请求头，* 表示任意请求头
+	ExposedHeaders   []string `json:"exposed_headers"`   // 允许浏览器读取的响应头
+	AllowCredentials bool     `json:"allow_credentials"` // 是否允许携带凭据（与 * 源同时配置时不生效）
+	MaxAge           int      `json:"max_age"`           // 预检结果缓存时间（秒）
+}
+
+// Clone 深拷贝跨域策略
+func (p CORSPolicy) Clone() CORSPolicy {
+	p.AllowedOrigins = slices.Clone(p.AllowedOrigins)
+	p.AllowedMethods = slices.Clone(p.AllowedMethods)
+	p.AllowedHeaders = slices.Clone(p.AllowedHeaders)
+	p.ExposedHeaders = slices.Clone(p.ExposedHeaders)
+	return p
+}
+
+// CORSOverride 按路径前缀覆盖的跨域策略，未配置的字段沿用默认策略
+type CORSOverride struct {
+	PathPrefix string `json:"path_prefix"`
+	CORSPolicy
+}
+
+// CORSConfig 跨域配置
+type CORSConfig struct {
+	CORSPolicy
+	Overrides []CORSOverride `json:"overrides"`
+}
+
+// RateLimitPolicy 限流策略
+type RateLimitPolicy struct {
+	Name      string   `json:"name"`
+	Routes    []string `json:"routes"`    // 匹配的路由："POST /api/auth/login" 或路径前缀 "/api"，为空时匹配所有请求
+	Key       string   `json:"key"`       // 限流维度：ip、user（未登录时按 IP）、api_key（未提供或不在 api_keys 中时按 IP）
+	Algorithm string   `json:"algorithm"` // token_bucket、sliding_window
+	Requests  int      `json:"requests"`  // 每个窗口允许的请求数
+	Window    int      `json:"window"`    // 窗口长度（秒）
+	Burst     int      `json:"burst"`     // 令牌桶容量，默认与 Requests 相同
+}
+
+// RateLimitConfig 限流配置
+type RateLimitConfig struct {
+	Enabled     bool              `json:"enabled"`
+	Store       string            `json:"store"`        // 限流状态存储：memory
+	ExemptRoles []string          `json:"exempt_roles"` // 不受限流的用户角色
+	APIKeys     []string          `json:"api_keys"`     // 已签发的 API Key 的 SHA-256 摘要（十六进制），api_key 维度只按其中的 Key 计数
+	Policies    []RateLimitPolicy `json:"policies"`
+}
+
+// ErrorReportConfig 错误上报配置（panic 恢复后的上报）
+type ErrorReportConfig struct {
+	Reporter     string `json:"reporter"`          // 上报方式：log、file 或 sentry
+	SpoolDir     string `json:"spool_dir"`         // file 上报的落盘目录
+	DSN          string `json:"dsn" secret:"true"` // Sentry 兼容服务的 DSN
+	Timeout      int    `json:"timeout"`           // 上报请求超时（秒）
+	DedupWindow  int    `json:"dedup_window"`      // 相同 panic 的去重窗口（秒），0 表示不去重
+	BodyMaxBytes int    `json:"body_max_bytes"`    // 随上报附带的请求体大小上限
+}
+
+// RequestIDConfig 请求ID配置
+type RequestIDConfig struct {
+	Header        string `json:"header"`         // 读取与返回请求ID的请求头
+	Format        string `json:"format"`         // 生成格式：uuidv7 或 ulid
+	TrustIncoming bool   `json:"trust_incoming"` // 是否沿用客户端传入的请求ID（校验通过时）
+	MaxLength     int    `json:"max_length"`     // 客户端传入的请求ID长度上限
+	SQLComment    bool   `json:"sql_comment"`    // 是否在 SQL 前添加请求ID注释
+}
+
+// SecurityHeadersPolicy 安全响应头策略，空字符串表示不发送对应的响应头
+type SecurityHeadersPolicy struct {
+	CSP               string `json:"csp"`                // Content-Security-Policy，{nonce} 会替换为每个请求的随机 nonce
+	FrameOptions      string `json:"frame_options"`      // X-Frame-Options（兼容不支持 frame-ancestors 的旧浏览器）
+	ReferrerPolicy    string `json:"referrer_policy"`    // Referrer-Policy
+	PermissionsPolicy string `json:"permissions_policy"` // Permissions-Policy
+}
+
+// SecurityHeadersOverride 按路径前缀覆盖的安全响应头策略，未配置的字段沿用默认策略
+type SecurityHeadersOverride struct {
+	PathPrefix string `json:"path_prefix"`
+	SecurityHeadersPolicy
+}
+
+// SecurityConfig 安全响应头配置
+type SecurityConfig struct {
+	Enabled               bool   `json:"enabled"`
+	HSTSMaxAge            int    `json:"hsts_max_age"`            // Strict-Transport-Security 的 max-age（秒），0 表示不发送；只在 HTTPS 请求中发送
+	HSTSIncludeSubdomains bool   `json:"hsts_include_subdomains"` // HSTS 是否包含子域名
+	HSTSPreload           bool   `json:"hsts_preload"`            // HSTS 是否声明 preload
+	CSPReportOnly         bool   `json:"csp_report_only"`         // 以 Content-Security-Policy-Report-Only 发送，只上报不拦截
+	CSPReportURI          string `json:"csp_report_uri"`          // CSP 违规上报地址，为空时不上报
+
+	SecurityHeadersPolicy                           // 默认策略（页面与静态资源）
+	Overrides             []SecurityHeadersOverride `json:"overrides"`
+}
+
+// 运行环境（配置 profile）
+const (
+	ProfileDevelopment = "development"
+	ProfileTest        = "test"
+	ProfileProduction  = "production"
+)
+
+// DefaultJWTSecret 默认的 JWT 密钥，仅供本地开发使用，生产环境拒绝以此启动
+const DefaultJWTSecret = "your-secret-key-change-this-in-production"
+
+// current 当前生效的配置快照，热更新时整体原子替换，快照本身不会被修改
+var (
+	current  atomic.Pointer[Config]
+	initOnce sync.Once
+)
+
+// GetConfig 获取当前生效的配置快照（启动时未通过 SetConfig 设置时，按默认来源加载）
+//
+// 返回的快照不应被修改；需要跟随热更新的组件应每次调用 GetConfig 或通过 Subscribe 订阅变化。
 func GetConfig() *Config {
-	if appConfig == nil {
-		appConfig = loadConfig()
+	if cfg := current.Load(); cfg != nil {
+		return cfg
 	}
-	return appConfig
+	initOnce.Do(func() {
+		cfg, _ := Load(Options{})
+		current.CompareAndSwap(nil, cfg)
+	})
+	return current.Load()
+}
+
+// SetConfig 设置应用程序配置（启动时传入加载并校验通过的配置），不通知订阅者
+func SetConfig(cfg *Config) {
+	current.Store(cfg)
 }
 
-// loadConfig 加载配置
-func loadConfig() *Config {
+// Feature 判断功能开关是否开启（未配置的开关视为关闭），随配置热更新
+func Feature(name string) bool {
+	return GetConfig().Features[name]
+}
+
+// Defaults 返回所有配置项的默认值（不读取配置文件与环境变量）
+func Defaults() *Config {
 	return &Config{
+		Profile: ProfileDevelopment,
 		Server: ServerConfig{
-			Port:         getEnv("SERVER_PORT", "8080"),
-			Mode:         getEnv("GIN_MODE", "debug"),
-			ReadTimeout:  getEnvAsInt("READ_TIMEOUT", 30),
-			WriteTimeout: getEnvAsInt("WRITE_TIMEOUT", 30),
+			Port:         "8080",
+			Mode:         "debug",
+			ReadTimeout:  30,
+			WriteTimeout: 30,
+
+			ReadHeaderTimeout: 10,
+			IdleTimeout:       120,
+			MaxHeaderBytes:    1 << 20,
+			ShutdownTimeout:   30,
+			DrainDelay:        5,
+
+			TrustedProxies:  []string{"127.0.0.0/8", "::1/128"},
+			ClientIPHeaders: []string{"X-Forwarded-For"},
+
+			TLS: TLSConfig{
+				CertFile:       "certs/server.crt",
+				KeyFile:        "certs/server.key",
+				MinVersion:     "1.2",
+				ClientAuth:     "require",
+				ReloadInterval: 30,
+			},
 		},
 		Database: DatabaseConfig{
-			Driver:   getEnv("DB_DRIVER", "sqlite"),
-			Host:     getEnv("DB_HOST", "localhost"),
-			Port:     getEnv("DB_PORT", "3306"),
-			Database: getEnv("DB_NAME", "iris_sample.db"),
-			Username: getEnv("DB_USER", ""),
-			Password: getEnv("DB_PASSWORD", ""),
-			SSL:      getEnv("DB_SSL", "disable"),
+			Driver:   "sqlite",
+			Host:     "localhost",
+			Port:     "3306",
+			Database: "iris_sample.db",
+			SSL:      "disable",
 		},
 		JWT: JWTConfig{
-			Secret:         getEnv("JWT_SECRET", "your-secret-key-change-this-in-production"),
-			ExpirationTime: getEnvAsInt("JWT_EXPIRATION_TIME", 24*60*60), // 24小时
-			Issuer:         getEnv("JWT_ISSUER", "iris-sample-project"),
+			Secret:         DefaultJWTSecret,
+			ExpirationTime: 24 * 60 * 60, // 24小时
+			Issuer:         "iris-sample-project",
 		},
 		Log: LogConfig{
-			Level:  getEnv("LOG_LEVEL", "info"),
-			Format: getEnv("LOG_FORMAT", "json"),
-			Output: getEnv("LOG_OUTPUT", "stdout"),
+			Level:         "info",
+			Format:        "json",
+			Output:        "stdout",
+			File:          "logs/app.log",
+			MaxSizeMB:     100,
+			MaxBackups:    5,
+			SlowSQLMillis: 200,
+
+			BodyMaxBytes:   4096,
+			BodySampleRate: 0.1,
+			Redact:         DefaultRedactConfig(),
+		},
+		Upload: UploadConfig{
+			Dir:                "data/uploads",
+			MaxSizeMB:          10,
+			QuarantineDir:      "data/quarantine",
+			UserQuotaMB:        2048,
+			RoleQuotas:         map[string]QuotaConfig{},
+			AnonymousQuotaMB:   100,
+			AnonymousMaxFiles:  20,
+			ShareMaxExpiration: 7 * 24 * 3600,
+			ReconcileInterval:  3600,
+			Scanner: ScannerConfig{
+				Driver:  "none",
+				Address: "unix:///var/run/clamav/clamd.ctl",
+				Timeout: 30,
+			},
+			Tus: TusConfig{
+				MaxSizeMB:       1024,
+				Expiration:      86400,
+				CleanupInterval: 600,
+			},
+		},
+		Storage: StorageConfig{
+			Driver: "local",
+			S3: S3Config{
+				Region:  "us-east-1",
+				Timeout: 30,
+			},
+		},
+		Mail: MailConfig{
+			Port: "25",
+		},
+		Health: HealthConfig{
+			CheckTimeout:  2000,
+			CacheTTL:      3000,
+			MinFreeDiskMB: 100,
+			MaxGoroutines: 10000,
+		},
+		Metrics: MetricsConfig{
+			PushJob: "iris-sample-project",
+		},
+		Tracing: TracingConfig{
+			ServiceName: "iris-sample-project",
+			Exporter:    "otlp",
+			Endpoint:    "localhost:4318",
+			URLPath:     "/v1/traces",
+			Insecure:    true,
+			Headers:     map[string]string{},
+			SampleRatio: 1,
+		},
+		CORS: CORSConfig{
+			CORSPolicy: CORSPolicy{
+				AllowedOrigins: []string{"*"},
+				AllowedMethods: []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+				AllowedHeaders: []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Request-ID", "X-CSRF-Token",
+					"Tus-Resumable", "Upload-Length", "Upload-Metadata", "Upload-Offset", "Upload-Checksum", "X-HTTP-Method-Override"},
+				ExposedHeaders: []string{"Content-Length", "Content-Type", "X-Request-ID", "X-Trace-ID",
+					"Location", "Tus-Resumable", "Tus-Version", "Tus-Extension", "Tus-Max-Size", "Tus-Checksum-Algorithm",
+					"Upload-Offset", "Upload-Length", "Upload-Metadata", "Upload-Expires", "X-File-ID",
+					"Link", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy", "Retry-After",
+					"Content-Disposition"},
+				MaxAge: 600,
+			},
 		},
+		RateLimit: RateLimitConfig{
+			Enabled:     true,
+			Store:       "memory",
+			ExemptRoles: []string{"admin"},
+			Policies: []RateLimitPolicy{
+				{Name: "login", Routes: []string{"POST /api/auth/login"}, Key: "ip", Algorithm: "sliding_window", Requests: 10, Window: 60},
+				{Name: "register", Routes: []string{"POST /api/auth/register"}, Key: "ip", Algorithm: "sliding_window", Requests: 5, Window: 3600},
+				{Name: "refresh", Routes: []string{"POST /api/auth/refresh"}, Key: "ip", Algorithm: "sliding_window", Requests: 30, Window: 60},
+				{Name: "upload", Routes: []string{"POST /api/upload", "PUT /api/protected/avatar"}, Key: "user", Algorithm: "token_bucket", Requests: 10, Window: 60, Burst: 5},
+				{Name: "csp-report", Routes: []string{"POST /csp-report"}, Key: "ip", Algorithm: "token_bucket", Requests: 60, Window: 60, Burst: 20},
+				{Name: "api", Routes: []string{"/api"}, Key: "user", Algorithm: "token_bucket", Requests: 300, Window: 60, Burst: 100},
+			},
+		},
+		ErrorReport: ErrorReportConfig{
+			Reporter:     "log",
+			SpoolDir:     "logs/errors",
+			Timeout:      3,
+			DedupWindow:  300,
+			BodyMaxBytes: 4096,
+		},
+		RequestID: RequestIDConfig{
+			Header:        "X-Request-ID",
+			Format:        "uuidv7",
+			TrustIncoming: true,
+			MaxLength:     64,
+			SQLComment:    true,
+		},
+		Security: defaultSecurityConfig(),
+		Features: map[string]bool{},
 	}
 }
 
-// getEnv 获取环境变量，如果不存在则返回默认值
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+// defaultSecurityConfig 安全响应头的默认配置
+//
+// 默认策略面向 HTML 页面：脚本与内联样式块需要带上模板中的 nonce；/api 下的 JSON 接口使用更严格的策略。
+func defaultSecurityConfig() SecurityConfig {
+	policy := SecurityHeadersPolicy{
+		CSP: "default-src 'self'; script-src 'self' 'nonce-{nonce}'; style-src 'self' 'nonce-{nonce}'; " +
+			"style-src-attr 'unsafe-inline'; img-src 'self' data:; object-src 'none'; base-uri 'self'; form-action 'self'; frame-ancestors 'self'",
+		FrameOptions:      "SAMEORIGIN",
+		ReferrerPolicy:    "strict-origin-when-cross-origin",
+		PermissionsPolicy: "camera=(), microphone=(), geolocation=(), payment=(), usb=()",
+	}
+
+	return SecurityConfig{
+		Enabled:               true,
+		HSTSMaxAge:            31536000,
+		HSTSIncludeSubdomains: true,
+		CSPReportURI:          "/csp-report",
+		SecurityHeadersPolicy: policy,
+		Overrides: []SecurityHeadersOverride{
+			{
+				PathPrefix: "/api",
+				SecurityHeadersPolicy: SecurityHeadersPolicy{
+					CSP:               "default-src 'none'; frame-ancestors 'none'",
+					FrameOptions:      "DENY",
+					ReferrerPolicy:    "no-referrer",
+					PermissionsPolicy: policy.PermissionsPolicy,
+				},
+			},
+		},
 	}
-	return defaultValue
 }
 
-// getEnvAsInt 获取环境变量并转换为 int 类型
-func getEnvAsInt(key string, defaultValue int) int {
-	if value := os.Getenv(key); value != "" {
-		if intValue, err := strconv.Atoi(value); err == nil {
-			return intValue
+// UnmarshalJSON 解析跨域配置：路径覆盖以默认策略为基础，与已有的同一路径前缀的覆盖合并
+//
+//	{"allowed_origins": ["https://example.com"], "overrides": [{"path_prefix": "/api/public", "allowed_origins": ["*"]}]}
+func (c *CORSConfig) UnmarshalJSON(data []byte) error {
+	type plain CORSConfig
+	aux := struct {
+		*plain
+		Overrides []json.RawMessage `json:"overrides"`
+	}{plain: (*plain)(c)}
+	if err := strictUnmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	overrides, err := mergeCORSOverrides(c.Overrides, aux.Overrides, c.CORSPolicy)
+	if err != nil {
+		return err
+	}
+	c.Overrides = overrides
+	return nil
+}
+
+// mergeCORSOverrides 合并路径覆盖：已有的路径前缀在原覆盖上修改，新的路径前缀以默认策略为基础
+func mergeCORSOverrides(existing []CORSOverride, items []json.RawMessage, base CORSPolicy) ([]CORSOverride, error) {
+	result := slices.Clone(existing)
+	for _, item := range items {
+		var key struct {
+			PathPrefix string `json:"path_prefix"`
+		}
+		if err := json.Unmarshal(item, &key); err != nil || key.PathPrefix == "" {
+			return nil, fmt.Errorf("跨域路径覆盖缺少 path_prefix: %s", item)
+		}
+
+		i := slices.IndexFunc(result, func(o CORSOverride) bool { return o.PathPrefix == key.PathPrefix })
+		if i < 0 {
+			// 深拷贝默认策略，避免反序列化时覆盖默认策略的切片
+			result = append(result, CORSOverride{CORSPolicy: base.Clone()})
+			i = len(result) - 1
+		} else {
+			result[i].CORSPolicy = result[i].CORSPolicy.Clone()
+		}
+		if err := strictUnmarshal(item, &result[i]); err != nil {
+			return nil, err
 		}
 	}
-	return defaultValue
+	return result, nil
+}
+
+// UnmarshalJSON 解析安全响应头配置：路径覆盖以默认策略为基础，与已有的同一路径前缀的覆盖（如内置的 /api）合并
+func (c *SecurityConfig) UnmarshalJSON(data []byte) error {
+	type plain SecurityConfig
+	aux := struct {
+		*plain
+		Overrides []json.RawMessage `json:"overrides"`
+	}{plain: (*plain)(c)}
+	if err := strictUnmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	overrides, err := mergeSecurityOverrides(c.Overrides, aux.Overrides, c.SecurityHeadersPolicy)
+	if err != nil {
+		return err
+	}
+	c.Overrides = overrides
+	return nil
 }
 
-// getEnvAsBool 获取环境变量并转换为 bool 类型
-func getEnvAsBool(key string, defaultValue bool) bool {
-	if value := os.Getenv(key); value != "" {
-		if boolValue, err := strconv.ParseBool(value); err == nil {
-			return boolValue
+// mergeSecurityOverrides 合并路径覆盖：已有的路径前缀在原覆盖上修改，新的路径前缀以默认策略为基础
+func mergeSecurityOverrides(existing []SecurityHeadersOverride, items []json.RawMessage, base SecurityHeadersPolicy) ([]SecurityHeadersOverride, error) {
+	result := slices.Clone(existing)
+	for _, item := range items {
+		var key struct {
+			PathPrefix string `json:"path_prefix"`
+		}
+		if err := json.Unmarshal(item, &key); err != nil || key.PathPrefix == "" {
+			return nil, fmt.Errorf("安全响应头路径覆盖缺少 path_prefix: %s", item)
+		}
+
+		i := slices.IndexFunc(result, func(o SecurityHeadersOverride) bool { return o.PathPrefix == key.PathPrefix })
+		if i < 0 {
+			result = append(result, SecurityHeadersOverride{SecurityHeadersPolicy: base})
+			i = len(result) - 1
+		}
+		if err := strictUnmarshal(item, &result[i]); err != nil {
+			return nil, err
 		}
 	}
-	return defaultValue
+	return result, nil
+}
+
+// strictUnmarshal 解析 JSON，出现未知字段时返回错误（避免配置项拼写错误被静默忽略）
+func strictUnmarshal(data []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
 }
\ No newline at end of file
@@ -0,0 +1,309 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeFile 写入测试配置文件
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// TestLoadPrecedence 验证配置的优先级：默认值 < 配置文件 < 运行环境配置文件 < 环境变量 < 命令行参数
+func TestLoadPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	file := writeFile(t, dir, "config.yaml", `
+server:
+  port: "9000"
+  read_timeout: 15
+log:
+  level: debug
+  format: text
+rate_limit:
+  policies:
+    - name: api
+      routes: ["/api"]
+      requests: 100
+      window: 60
+security:
+  overrides:
+    - path_prefix: /api
+      csp: "default-src 'none'"
+`)
+	writeFile(t, dir, "config.production.yaml", `
+log:
+  level: warn
+jwt:
+  secret: from-profile-file
+`)
+	t.Setenv("LOG_LEVEL", "error")
+	t.Setenv("JWT_EXPIRATION_TIME", "3600")
+
+	cfg, err := Load(Options{File: file, Profile: ProfileProduction, Sets: []string{"server.port=9100", "cors.allowed_origins=https://a.example,https://b.example"}})
+	if err != nil {
+		t.Fatalf("加载配置失败: %v", err)
+	}
+
+	checks := map[string][2]interface{}{
+		"命令行参数覆盖配置文件":    {cfg.Server.Port, "9100"},
+		"配置文件覆盖默认值":      {cfg.Server.ReadTimeout, 15},
+		"未配置的项保留默认值":     {cfg.Server.WriteTimeout, 30},
+		"环境变量覆盖运行环境配置文件": {cfg.Log.Level, "error"},
+		"运行环境配置文件覆盖默认值":  {cfg.JWT.Secret, "from-profile-file"},
+		"主配置文件对运行环境依然有效": {cfg.Log.Format, "text"},
+		"环境变量覆盖默认值":      {cfg.JWT.ExpirationTime, 3600},
+		"运行环境默认值":        {cfg.Server.Mode, "release"},
+		"运行环境":           {cfg.Profile, ProfileProduction},
+		"列表整体替换":         {len(cfg.RateLimit.Policies), 1},
+		"逗号分隔的列表":        {strings.Join(cfg.CORS.AllowedOrigins, " "), "https://a.example https://b.example"},
+	}
+	for name, c := range checks {
+		if c[0] != c[1] {
+			t.Errorf("%s: 实际为 %v，期望 %v", name, c[0], c[1])
+		}
+	}
+
+	// 路径覆盖按 path_prefix 合并：只修改 csp，其余字段保留内置 /api 覆盖的值
+	if o := cfg.Security.Overrides; len(o) != 1 || o[0].CSP != "default-src 'none'" || o[0].FrameOptions != "DENY" {
+		t.Errorf("安全响应头路径覆盖应按 path_prefix 合并: %+v", o)
+	}
+	if p := cfg.RateLimit.Policies[0]; p.Algorithm != "" || p.Key != "" {
+		t.Errorf("替换后的策略不应残留默认策略的字段: %+v", p)
+	}
+}
+
+// TestLoadTOML 验证 TOML 配置文件与 APP_ENV 选择运行环境
+func TestLoadTOML(t *testing.T) {
+	file := writeFile(t, t.TempDir(), "app.toml", `
+[server]
+port = "8443"
+
+[tracing]
+enabled = true
+sample_ratio = 0.25
+
+[tracing.headers]
+authorization = "Bearer x"
+`)
+	t.Setenv("APP_ENV", ProfileTest)
+
+	cfg, err := Load(Options{File: file})
+	if err != nil {
+		t.Fatalf("加载配置失败: %v", err)
+	}
+	if cfg.Profile != ProfileTest || cfg.Server.Mode != "test" || cfg.Server.Port != "8443" {
+		t.Errorf("TOML 配置未正确应用: profile=%s mode=%s port=%s", cfg.Profile, cfg.Server.Mode, cfg.Server.Port)
+	}
+	if !cfg.Tracing.Enabled || cfg.Tracing.SampleRatio != 0.25 || cfg.Tracing.Headers["authorization"] != "Bearer x" {
+		t.Errorf("TOML 嵌套配置未正确应用: %+v", cfg.Tracing)
+	}
+}
+
+// TestLoadErrors 验证无效的配置来源返回错误，而不是静默使用默认值
+func TestLoadErrors(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name string
+		env  map[string]string
+		opts Options
+		want string
+	}{
+		{"环境变量不是整数", map[string]string{"READ_TIMEOUT": "30s"}, Options{}, "READ_TIMEOUT"},
+		{"环境变量不是布尔值", map[string]string{"TLS_ENABLED": "yes please"}, Options{}, "TLS_ENABLED"},
+		{"环境变量 JSON 无效", map[string]string{"RATE_LIMIT_POLICIES": "[{"}, Options{}, "RATE_LIMIT_POLICIES"},
+		{"配置文件中的未知配置项", nil, Options{File: writeFile(t, dir, "typo.yaml", "server:\n  prot: \"80\"\n")}, "prot"},
+		{"配置文件类型错误", nil, Options{File: writeFile(t, dir, "type.json", `{"server": {"read_timeout": "slow"}}`)}, "type.json"},
+		{"指定的配置文件不存在", nil, Options{File: filepath.Join(dir, "missing.yaml")}, "missing.yaml"},
+		{"未知的命令行配置项", nil, Options{Sets: []string{"server.prot=80"}}, "server.prot"},
+		{"命令行配置值类型错误", nil, Options{Sets: []string{"server.read_timeout=slow"}}, "server.read_timeout"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			cfg, err := Load(tt.opts)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("应返回包含 %q 的错误，实际为 %v", tt.want, err)
+			}
+			if cfg == nil {
+				t.Error("出错时仍应返回尽力加载的配置")
+			}
+		})
+	}
+}
+
+// TestValidate 验证启动时的配置校验
+func TestValidate(t *testing.T) {
+	if err := Defaults().Validate(); err != nil {
+		t.Fatalf("默认配置应通过校验: %v", err)
+	}
+
+	production := func() *Config {
+		c := Defaults()
+		c.Profile = ProfileProduction
+		c.Server.Mode = "release"
+		c.JWT.Secret = strings.Repeat("s", minProductionSecretLength)
+		return c
+	}
+	if err := production().Validate(); err != nil {
+		t.Fatalf("有效的生产配置应通过校验: %v", err)
+	}
+
+	tests := map[string]func(*Config){
+		"生产环境使用默认密钥":    func(c *Config) { c.JWT.Secret = DefaultJWTSecret },
+		"生产环境密钥过短":      func(c *Config) { c.JWT.Secret = "short" },
+		"生产环境使用 debug":  func(c *Config) { c.Server.Mode = "debug" },
+		"端口不是数字":        func(c *Config) { c.Server.Port = "http" },
+		"端口超出范围":        func(c *Config) { c.Server.Port = "70000" },
+		"端口为 0":         func(c *Config) { c.Server.Port = "0" },
+		"未知的日志级别":       func(c *Config) { c.Log.Level = "verbose" },
+		"采样率超出范围":       func(c *Config) { c.Tracing.SampleRatio = 2 },
+		"未知的追踪导出器":      func(c *Config) { c.Tracing.Exporter = "memory" },
+		"sentry 缺少 DSN": func(c *Config) { c.ErrorReport.Reporter = "sentry" },
+		"限流窗口为 0":       func(c *Config) { c.RateLimit.Policies[0].Window = 0 },
+		"上传目录可公开访问":     func(c *Config) { c.Upload.Dir = "static/uploads" },
+		"隔离目录可公开访问":     func(c *Config) { c.Upload.QuarantineDir = "./static" },
+		"角色配额为负数":       func(c *Config) { c.Upload.RoleQuotas["user"] = QuotaConfig{MaxFiles: -1} },
+	}
+	for name, mutate := range tests {
+		c := production()
+		mutate(c)
+		if err := c.Validate(); err == nil {
+			t.Errorf("%s: 应校验失败", name)
+		}
+	}
+
+	// 开发环境允许默认密钥
+	dev := Defaults()
+	dev.JWT.Secret = DefaultJWTSecret
+	if err := dev.Validate(); err != nil {
+		t.Errorf("开发环境应允许默认密钥: %v", err)
+	}
+}
+
+// TestDefaultExposedHeaders 接口写入的响应头都要在默认跨域配置中暴露，否则浏览器中的前端读不到
+func TestDefaultExposedHeaders(t *testing.T) {
+	exposed := Defaults().CORS.ExposedHeaders
+	for _, header := range []string{
+		"X-Request-ID", "Location", "Upload-Offset", // 请求追踪与 tus 上传
+		"Link",                                                                                         // 分页
+		"RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy", "Retry-After", // 限流
+		"Content-Disposition", // 下载与导出的文件名
+	} {
+		if !slices.Contains(exposed, header) {
+			t.Errorf("默认跨域配置未暴露 %s", header)
+		}
+	}
+}
+
+// TestRedactedPrint 验证脱敏输出不泄露敏感配置项，也不修改原配置
+func TestRedactedPrint(t *testing.T) {
+	cfg := Defaults()
+	cfg.JWT.Secret = "super-secret-value"
+	cfg.Database.Password = "db-password"
+	cfg.Tracing.Headers = map[string]string{"authorization": "Bearer token-value"}
+
+	var buf bytes.Buffer
+	if err := cfg.Redacted().Print(&buf, "yaml"); err != nil {
+		t.Fatalf("输出配置失败: %v", err)
+	}
+	out := buf.String()
+	for _, secret := range []string{"super-secret-value", "db-password", "token-value"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("脱敏输出中不应包含 %q", secret)
+		}
+	}
+	if !strings.Contains(out, "port: \"8080\"") || !strings.Contains(out, "read_timeout: 30") || !strings.Contains(out, redactedValue) {
+		t.Errorf("输出应包含非敏感配置项与遮盖标记:\n%s", out)
+	}
+
+	if cfg.JWT.Secret != "super-secret-value" || cfg.Tracing.Headers["authorization"] != "Bearer token-value" {
+		t.Error("脱敏不应修改原配置")
+	}
+
+	// 输出的 YAML 可以作为配置文件重新加载
+	file := writeFile(t, t.TempDir(), "printed.yaml", out)
+	if _, err := Load(Options{File: file}); err != nil {
+		t.Errorf("输出的配置应能重新加载: %v", err)
+	}
+}
+
+// TestReload 验证配置热更新：通知订阅者、保留需要重启的配置、拒绝无效的新配置
+func TestReload(t *testing.T) {
+	previous := GetConfig()
+	t.Cleanup(func() { SetConfig(previous) })
+
+	file := writeFile(t, t.TempDir(), "config.yaml", "log:\n  level: info\nfeatures:\n  new_ui: false\n")
+	opts := Options{File: file}
+	cfg, err := Load(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetConfig(cfg)
+	reloader := NewReloader(opts)
+
+	var notified []string
+	Subscribe("test", func(old, cur *Config) {
+		notified = append(notified, old.Log.Level+"->"+cur.Log.Level)
+	})
+
+	// 修改时间推后，避免与原文件落在同一时间精度内
+	rewrite := func(content string) {
+		t.Helper()
+		writeFile(t, filepath.Dir(file), "config.yaml", content)
+		later := time.Now().Add(time.Minute)
+		os.Chtimes(file, later, later)
+	}
+
+	if reloader.Changed() {
+		t.Error("配置文件未修改时不应报告变化")
+	}
+	rewrite("log:\n  level: debug\nserver:\n  port: \"9999\"\nfeatures:\n  new_ui: true\n")
+	if !reloader.Changed() {
+		t.Fatal("应检测到配置文件的变化")
+	}
+
+	pending, err := reloader.Reload()
+	if err != nil {
+		t.Fatalf("热更新失败: %v", err)
+	}
+	if got := GetConfig(); got.Log.Level != "debug" || !Feature("new_ui") {
+		t.Errorf("可热更新的配置应生效: level=%s new_ui=%v", got.Log.Level, Feature("new_ui"))
+	}
+	if got := GetConfig().Server.Port; got != "8080" || strings.Join(pending, ",") != "server" {
+		t.Errorf("监听端口需要重启才能生效: port=%s pending=%v", got, pending)
+	}
+	if strings.Join(notified, ",") != "info->debug" {
+		t.Errorf("订阅者通知 = %v", notified)
+	}
+	if reloader.Changed() {
+		t.Error("重新加载后不应再报告变化")
+	}
+
+	// 无效的新配置被拒绝，继续使用旧配置
+	before := GetConfig()
+	rewrite("log:\n  level: verbose\n")
+	if _, err := reloader.Reload(); err == nil {
+		t.Error("无效的配置应被拒绝")
+	}
+	rewrite("log:\n  levle: warn\n")
+	if _, err := reloader.Reload(); err == nil {
+		t.Error("包含未知配置项的配置应被拒绝")
+	}
+	if GetConfig() != before || len(notified) != 1 {
+		t.Error("热更新失败时应保留旧配置且不通知订阅者")
+	}
+}
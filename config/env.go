@@ -0,0 +1,331 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// applyEnv 使用环境变量覆盖配置，只覆盖已设置的环境变量；值无法解析时返回错误而不是静默忽略
+func applyEnv(c *Config) error {
+	e := &envReader{}
+
+	e.string("SERVER_PORT", &c.Server.Port)
+	e.string("GIN_MODE", &c.Server.Mode)
+	e.int("READ_TIMEOUT", &c.Server.ReadTimeout)
+	e.int("WRITE_TIMEOUT", &c.Server.WriteTimeout)
+	e.int("READ_HEADER_TIMEOUT", &c.Server.ReadHeaderTimeout)
+	e.int("IDLE_TIMEOUT", &c.Server.IdleTimeout)
+	e.int("MAX_HEADER_BYTES", &c.Server.MaxHeaderBytes)
+	e.int("SHUTDOWN_TIMEOUT", &c.Server.ShutdownTimeout)
+	e.int("SHUTDOWN_DRAIN_DELAY", &c.Server.DrainDelay)
+	e.slice("TRUSTED_PROXIES", ",", &c.Server.TrustedProxies)
+	e.slice("CLIENT_IP_HEADERS", ",", &c.Server.ClientIPHeaders)
+
+	e.bool("TLS_ENABLED", &c.Server.TLS.Enabled)
+	e.string("TLS_CERT_FILE", &c.Server.TLS.CertFile)
+	e.string("TLS_KEY_FILE", &c.Server.TLS.KeyFile)
+	e.string("TLS_MIN_VERSION", &c.Server.TLS.MinVersion)
+	e.slice("TLS_CIPHER_SUITES", ",", &c.Server.TLS.CipherSuites)
+	e.string("TLS_CLIENT_CA_FILE", &c.Server.TLS.ClientCAFile)
+	e.string("TLS_CLIENT_AUTH", &c.Server.TLS.ClientAuth)
+	e.int("TLS_RELOAD_INTERVAL", &c.Server.TLS.ReloadInterval)
+	e.string("TLS_REDIRECT_PORT", &c.Server.TLS.RedirectPort)
+	e.string("TLS_REDIRECT_HOST", &c.Server.TLS.RedirectHost)
+
+	e.string("DB_DRIVER", &c.Database.Driver)
+	e.string("DB_HOST", &c.Database.Host)
+	e.string("DB_PORT", &c.Database.Port)
+	e.string("DB_NAME", &c.Database.Database)
+	e.string("DB_USER", &c.Database.Username)
+	e.string("DB_PASSWORD", &c.Database.Password)
+	e.string("DB_SSL", &c.Database.SSL)
+
+	e.string("JWT_SECRET", &c.JWT.Secret)
+	e.int("JWT_EXPIRATION_TIME", &c.JWT.ExpirationTime)
+	e.string("JWT_ISSUER", &c.JWT.Issuer)
+
+	e.string("LOG_LEVEL", &c.Log.Level)
+	e.string("LOG_FORMAT", &c.Log.Format)
+	e.string("LOG_OUTPUT", &c.Log.Output)
+	e.string("LOG_FILE", &c.Log.File)
+	e.int("LOG_MAX_SIZE_MB", &c.Log.MaxSizeMB)
+	e.int("LOG_MAX_BACKUPS", &c.Log.MaxBackups)
+	e.int("LOG_SLOW_SQL_MS", &c.Log.SlowSQLMillis)
+	e.bool("LOG_BODIES", &c.Log.LogBodies)
+	e.int("LOG_BODY_MAX_BYTES", &c.Log.BodyMaxBytes)
+	e.float("LOG_BODY_SAMPLE_RATE", &c.Log.BodySampleRate)
+	// 脱敏规则追加到已有规则之后；正则中常含逗号，使用分号分隔
+	e.append("LOG_REDACT_FIELDS", ",", &c.Log.Redact.Fields)
+	e.append("LOG_REDACT_HEADERS", ",", &c.Log.Redact.Headers)
+	e.append("LOG_REDACT_PATTERNS", ";", &c.Log.Redact.Patterns)
+
+	e.string("UPLOAD_DIR", &c.Upload.Dir)
+	e.int("UPLOAD_MAX_SIZE_MB", &c.Upload.MaxSizeMB)
+	e.string("UPLOAD_QUARANTINE_DIR", &c.Upload.QuarantineDir)
+	e.int("UPLOAD_USER_QUOTA_MB", &c.Upload.UserQuotaMB)
+	e.int("UPLOAD_USER_MAX_FILES", &c.Upload.UserMaxFiles)
+	e.int("UPLOAD_ANONYMOUS_QUOTA_MB", &c.Upload.AnonymousQuotaMB)
+	e.int("UPLOAD_ANONYMOUS_MAX_FILES", &c.Upload.AnonymousMaxFiles)
+	e.int("UPLOAD_RECONCILE_INTERVAL", &c.Upload.ReconcileInterval)
+	e.int("UPLOAD_SHARE_MAX_EXPIRATION", &c.Upload.ShareMaxExpiration)
+	e.int("TUS_MAX_SIZE_MB", &c.Upload.Tus.MaxSizeMB)
+	e.int("TUS_EXPIRATION", &c.Upload.Tus.Expiration)
+	e.int("TUS_CLEANUP_INTERVAL", &c.Upload.Tus.CleanupInterval)
+	e.string("UPLOAD_SCANNER", &c.Upload.Scanner.Driver)
+	e.string("CLAMAV_ADDRESS", &c.Upload.Scanner.Address)
+	e.int("UPLOAD_SCAN_TIMEOUT", &c.Upload.Scanner.Timeout)
+	e.bool("UPLOAD_SCAN_FAIL_OPEN", &c.Upload.Scanner.FailOpen)
+
+	e.string("STORAGE_DRIVER", &c.Storage.Driver)
+	e.string("STORAGE_BASE_URL", &c.Storage.BaseURL)
+	e.string("STORAGE_SIGNING_KEY", &c.Storage.SigningKey)
+	e.string("S3_ENDPOINT", &c.Storage.S3.Endpoint)
+	e.string("S3_REGION", &c.Storage.S3.Region)
+	e.string("S3_BUCKET", &c.Storage.S3.Bucket)
+	e.string("S3_ACCESS_KEY_ID", &c.Storage.S3.AccessKeyID)
+	e.string("S3_SECRET_ACCESS_KEY", &c.Storage.S3.SecretAccessKey)
+	e.bool("S3_PATH_STYLE", &c.Storage.S3.PathStyle)
+	e.int("S3_TIMEOUT", &c.Storage.S3.Timeout)
+
+	e.string("SMTP_HOST", &c.Mail.Host)
+	e.string("SMTP_PORT", &c.Mail.Port)
+	e.string("SMTP_USER", &c.Mail.Username)
+	e.string("SMTP_PASSWORD", &c.Mail.Password)
+	e.string("SMTP_FROM", &c.Mail.From)
+
+	e.int("HEALTH_CHECK_TIMEOUT", &c.Health.CheckTimeout)
+	e.int("HEALTH_CACHE_TTL", &c.Health.CacheTTL)
+	e.int("HEALTH_MIN_FREE_DISK_MB", &c.Health.MinFreeDiskMB)
+	e.int("HEALTH_MAX_GOROUTINES", &c.Health.MaxGoroutines)
+
+	e.string("METRICS_PUSHGATEWAY_URL", &c.Metrics.PushgatewayURL)
+	e.string("METRICS_PUSH_JOB", &c.Metrics.PushJob)
+
+	e.bool("TRACING_ENABLED", &c.Tracing.Enabled)
+	e.string("OTEL_SERVICE_NAME", &c.Tracing.ServiceName)
+	e.string("TRACING_EXPORTER", &c.Tracing.Exporter)
+	e.string("OTEL_EXPORTER_OTLP_ENDPOINT", &c.Tracing.Endpoint)
+	e.string("OTEL_EXPORTER_OTLP_TRACES_PATH", &c.Tracing.URLPath)
+	e.bool("OTEL_EXPORTER_OTLP_INSECURE", &c.Tracing.Insecure)
+	e.stringMap("OTEL_EXPORTER_OTLP_HEADERS", &c.Tracing.Headers)
+	e.float("TRACING_SAMPLE_RATIO", &c.Tracing.SampleRatio)
+
+	e.slice("CORS_ALLOWED_ORIGINS", ",", &c.CORS.AllowedOrigins)
+	e.slice("CORS_ALLOWED_METHODS", ",", &c.CORS.AllowedMethods)
+	e.slice("CORS_ALLOWED_HEADERS", ",", &c.CORS.AllowedHeaders)
+	e.slice("CORS_EXPOSED_HEADERS", ",", &c.CORS.ExposedHeaders)
+	e.bool("CORS_ALLOW_CREDENTIALS", &c.CORS.AllowCredentials)
+	e.int("CORS_MAX_AGE", &c.CORS.MaxAge)
+	// 路径覆盖以 JSON 数组配置，例如：[{"path_prefix": "/api/public", "allowed_origins": ["*"], "allow_credentials": false}]
+	if items := e.rawList("CORS_OVERRIDES"); items != nil {
+		overrides, err := mergeCORSOverrides(c.CORS.Overrides, items, c.CORS.CORSPolicy)
+		e.check("CORS_OVERRIDES", err)
+		if err == nil {
+			c.CORS.Overrides = overrides
+		}
+	}
+
+	e.bool("RATE_LIMIT_ENABLED", &c.RateLimit.Enabled)
+	e.string("RATE_LIMIT_STORE", &c.RateLimit.Store)
+	e.slice("RATE_LIMIT_EXEMPT_ROLES", ",", &c.RateLimit.ExemptRoles)
+	e.slice("RATE_LIMIT_API_KEYS", ",", &c.RateLimit.APIKeys)
+	// 以 JSON 数组整体替换已有的限流策略
+	if value, ok := e.lookup("RATE_LIMIT_POLICIES"); ok {
+		var policies []RateLimitPolicy
+		if err := strictUnmarshal([]byte(value), &policies); err != nil {
+			e.check("RATE_LIMIT_POLICIES", err)
+		} else {
+			c.RateLimit.Policies = policies
+		}
+	}
+
+	e.string("ERROR_REPORTER", &c.ErrorReport.Reporter)
+	e.string("ERROR_SPOOL_DIR", &c.ErrorReport.SpoolDir)
+	e.string("SENTRY_DSN", &c.ErrorReport.DSN)
+	e.int("ERROR_REPORT_TIMEOUT", &c.ErrorReport.Timeout)
+	e.int("ERROR_DEDUP_WINDOW", &c.ErrorReport.DedupWindow)
+	e.int("ERROR_BODY_MAX_BYTES", &c.ErrorReport.BodyMaxBytes)
+
+	e.string("REQUEST_ID_HEADER", &c.RequestID.Header)
+	e.string("REQUEST_ID_FORMAT", &c.RequestID.Format)
+	e.bool("REQUEST_ID_TRUST_INCOMING", &c.RequestID.TrustIncoming)
+	e.int("REQUEST_ID_MAX_LENGTH", &c.RequestID.MaxLength)
+	e.bool("REQUEST_ID_SQL_COMMENT", &c.RequestID.SQLComment)
+
+	e.bool("SECURITY_HEADERS_ENABLED", &c.Security.Enabled)
+	e.int("HSTS_MAX_AGE", &c.Security.HSTSMaxAge)
+	e.bool("HSTS_INCLUDE_SUBDOMAINS", &c.Security.HSTSIncludeSubdomains)
+	e.bool("HSTS_PRELOAD", &c.Security.HSTSPreload)
+	e.bool("CSP_REPORT_ONLY", &c.Security.CSPReportOnly)
+	e.string("CSP_REPORT_URI", &c.Security.CSPReportURI)
+	e.string("CSP_POLICY", &c.Security.CSP)
+	e.string("FRAME_OPTIONS", &c.Security.FrameOptions)
+	e.string("REFERRER_POLICY", &c.Security.ReferrerPolicy)
+	e.string("PERMISSIONS_POLICY", &c.Security.PermissionsPolicy)
+	if value, ok := e.lookup("CSP_API_POLICY"); ok {
+		overrides, err := mergeSecurityOverrides(c.Security.Overrides,
+			[]json.RawMessage{mustJSON(map[string]string{"path_prefix": "/api", "csp": value})}, c.Security.SecurityHeadersPolicy)
+		e.check("CSP_API_POLICY", err)
+		if err == nil {
+			c.Security.Overrides = overrides
+		}
+	}
+	if items := e.rawList("SECURITY_HEADERS_OVERRIDES"); items != nil {
+		overrides, err := mergeSecurityOverrides(c.Security.Overrides, items, c.Security.SecurityHeadersPolicy)
+		e.check("SECURITY_HEADERS_OVERRIDES", err)
+		if err == nil {
+			c.Security.Overrides = overrides
+		}
+	}
+
+	// 功能开关以 "name=true,other=false" 配置，与已有的开关合并
+	if value, ok := e.lookup("FEATURE_FLAGS"); ok {
+		features := maps.Clone(c.Features)
+		if features == nil {
+			features = make(map[string]bool)
+		}
+		for _, pair := range splitList(value, ",") {
+			name, raw, _ := strings.Cut(pair, "=")
+			enabled, err := strconv.ParseBool(strings.TrimSpace(raw))
+			if err != nil {
+				e.errs = append(e.errs, fmt.Errorf("环境变量 FEATURE_FLAGS 中的 %q 不是 name=true/false 形式", pair))
+				continue
+			}
+			features[strings.TrimSpace(name)] = enabled
+		}
+		c.Features = features
+	}
+
+	return errors.Join(e.errs...)
+}
+
+// envReader 读取环境变量并记录解析错误
+type envReader struct {
+	errs []error
+}
+
+// lookup 返回已设置且非空的环境变量
+func (e *envReader) lookup(key string) (string, bool) {
+	value := os.Getenv(key)
+	return value, value != ""
+}
+
+// check 记录解析错误
+func (e *envReader) check(key string, err error) {
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("环境变量 %s 无效: %v", key, err))
+	}
+}
+
+// string 读取字符串
+func (e *envReader) string(key string, dst *string) {
+	if value, ok := e.lookup(key); ok {
+		*dst = value
+	}
+}
+
+// int 读取整数
+func (e *envReader) int(key string, dst *int) {
+	if value, ok := e.lookup(key); ok {
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			e.errs = append(e.errs, fmt.Errorf("环境变量 %s=%q 不是有效的整数", key, value))
+			return
+		}
+		*dst = n
+	}
+}
+
+// bool 读取布尔值
+func (e *envReader) bool(key string, dst *bool) {
+	if value, ok := e.lookup(key); ok {
+		b, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			e.errs = append(e.errs, fmt.Errorf("环境变量 %s=%q 不是有效的布尔值", key, value))
+			return
+		}
+		*dst = b
+	}
+}
+
+// float 读取浮点数
+func (e *envReader) float(key string, dst *float64) {
+	if value, ok := e.lookup(key); ok {
+		f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			e.errs = append(e.errs, fmt.Errorf("环境变量 %s=%q 不是有效的数字", key, value))
+			return
+		}
+		*dst = f
+	}
+}
+
+// slice 按分隔符拆分为字符串切片（忽略空项）并替换原值
+func (e *envReader) slice(key, sep string, dst *[]string) {
+	if value, ok := e.lookup(key); ok {
+		*dst = splitList(value, sep)
+	}
+}
+
+// append 按分隔符拆分后追加到原值之后
+func (e *envReader) append(key, sep string, dst *[]string) {
+	if value, ok := e.lookup(key); ok {
+		*dst = append(*dst, splitList(value, sep)...)
+	}
+}
+
+// stringMap 解析 "k1=v1,k2=v2" 形式的环境变量并替换原值
+func (e *envReader) stringMap(key string, dst *map[string]string) {
+	value, ok := e.lookup(key)
+	if !ok {
+		return
+	}
+
+	result := make(map[string]string)
+	for _, pair := range splitList(value, ",") {
+		k, v, ok := strings.Cut(pair, "=")
+		if !ok {
+			e.errs = append(e.errs, fmt.Errorf("环境变量 %s 中的 %q 不是 key=value 形式", key, pair))
+			return
+		}
+		result[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	*dst = result
+}
+
+// rawList 解析 JSON 数组形式的环境变量，未设置或解析失败时返回 nil
+func (e *envReader) rawList(key string) []json.RawMessage {
+	value, ok := e.lookup(key)
+	if !ok {
+		return nil
+	}
+
+	var items []json.RawMessage
+	if err := json.Unmarshal([]byte(value), &items); err != nil {
+		e.check(key, err)
+		return nil
+	}
+	return items
+}
+
+// splitList 按分隔符拆分字符串（忽略空项）
+func splitList(value, sep string) []string {
+	result := []string{}
+	for _, item := range strings.Split(value, sep) {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// mustJSON 序列化不会失败的值
+func mustJSON(v interface{}) json.RawMessage {
+	data, _ := json.Marshal(v)
+	return data
+}
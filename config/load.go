@@ -0,0 +1,260 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// configExts 支持的配置文件格式，按查找顺序排列
+var configExts = []string{".yaml", ".yml", ".toml", ".json"}
+
+// Options 配置加载选项（通常来自命令行参数）
+type Options struct {
+	File    string   // 配置文件路径，为空时使用 CONFIG_FILE 或当前目录下的 config.yaml/.yml/.toml/.json
+	Profile string   // 运行环境，为空时使用 APP_ENV，默认 development
+	Sets    []string // key.path=value 形式的单项覆盖，优先级最高
+}
+
+// Register 注册配置相关的命令行参数
+func (o *Options) Register(fs *flag.FlagSet) {
+	fs.StringVar(&o.File, "config", "", "配置文件路径（YAML、TOML 或 JSON）")
+	fs.StringVar(&o.Profile, "profile", "", "运行环境：development、test、production（默认读取 APP_ENV）")
+	fs.Func("set", "覆盖单个配置项，如 -set server.port=9090（可重复）", func(value string) error {
+		o.Sets = append(o.Sets, value)
+		return nil
+	})
+	fs.Func("port", "监听端口（等同于 -set server.port=...）", func(value string) error {
+		o.Sets = append(o.Sets, "server.port="+value)
+		return nil
+	})
+	fs.Func("log-level", "日志级别（等同于 -set log.level=...）", func(value string) error {
+		o.Sets = append(o.Sets, "log.level="+value)
+		return nil
+	})
+}
+
+// Load 按优先级逐层加载配置：默认值 < 运行环境默认值 < 配置文件 < 运行环境配置文件 < 环境变量 < 命令行参数
+//
+// 运行环境配置文件与主配置文件同目录、同格式，文件名中插入运行环境，如 config.production.yaml。
+// 任何一层解析失败（未知配置项、环境变量不是有效的数字等）都会返回错误，同时返回尽力加载的配置。
+func Load(opts Options) (*Config, error) {
+	profile := opts.profile()
+	cfg := Defaults()
+	applyProfileDefaults(cfg, profile)
+
+	var errs []error
+	for _, file := range configFiles(opts.File, profile, &errs) {
+		if err := loadFile(cfg, file); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if err := applyEnv(cfg); err != nil {
+		errs = append(errs, err)
+	}
+
+	for _, set := range opts.Sets {
+		if err := applySet(cfg, set); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	cfg.Profile = profile
+	return cfg, errors.Join(errs...)
+}
+
+// profile 运行环境：参数 > APP_ENV > development
+func (o Options) profile() string {
+	if o.Profile != "" {
+		return o.Profile
+	}
+	if profile := os.Getenv("APP_ENV"); profile != "" {
+		return profile
+	}
+	return ProfileDevelopment
+}
+
+// applyProfileDefaults 运行环境对应的默认值
+func applyProfileDefaults(c *Config, profile string) {
+	switch profile {
+	case ProfileProduction:
+		c.Server.Mode = "release"
+	case ProfileTest:
+		c.Server.Mode = "test"
+	}
+}
+
+// configFiles 返回需要加载的配置文件：主配置文件与存在的运行环境配置文件
+//
+// 显式指定（参数或 CONFIG_FILE）的文件不存在时记录错误；未指定时在当前目录查找，找不到则只使用默认值与环境变量。
+func configFiles(file, profile string, errs *[]error) []string {
+	if file == "" {
+		file = os.Getenv("CONFIG_FILE")
+	}
+
+	var files []string
+	if file != "" {
+		if _, err := os.Stat(file); err != nil {
+			*errs = append(*errs, fmt.Errorf("读取配置文件失败: %v", err))
+			return nil
+		}
+		files = append(files, file)
+	} else if found := findFile("config"); found != "" {
+		files = append(files, found)
+	}
+
+	if len(files) > 0 {
+		ext := filepath.Ext(files[0])
+		profileFile := strings.TrimSuffix(files[0], ext) + "." + profile + ext
+		if _, err := os.Stat(profileFile); err == nil {
+			files = append(files, profileFile)
+		}
+	} else if found := findFile("config." + profile); found != "" {
+		files = append(files, found)
+	}
+	return files
+}
+
+// findFile 在当前目录查找任一支持格式的配置文件
+func findFile(base string) string {
+	for _, ext := range configExts {
+		if _, err := os.Stat(base + ext); err == nil {
+			return base + ext
+		}
+	}
+	return ""
+}
+
+// loadFile 读取配置文件并覆盖到配置上，格式由扩展名决定
+func loadFile(c *Config, file string) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("读取配置文件失败: %v", err)
+	}
+
+	var values map[string]interface{}
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &values)
+	case ".toml":
+		err = toml.Unmarshal(data, &values)
+	case ".json":
+		err = json.Unmarshal(data, &values)
+	default:
+		return fmt.Errorf("不支持的配置文件格式: %s（可选 %s）", file, strings.Join(configExts, "、"))
+	}
+	if err != nil {
+		return fmt.Errorf("解析配置文件 %s 失败: %v", file, err)
+	}
+
+	if err := apply(c, values); err != nil {
+		return fmt.Errorf("配置文件 %s 无效: %v", file, err)
+	}
+	return nil
+}
+
+// applySet 应用 key.path=value 形式的单项覆盖
+//
+// 字符串配置项直接使用原值；字符串列表可用逗号分隔；其余类型按 JSON 解析（如 true、30、["a","b"]）。
+func applySet(c *Config, set string) error {
+	key, raw, ok := strings.Cut(set, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("配置覆盖 %q 不是 key.path=value 形式", set)
+	}
+
+	path := strings.Split(key, ".")
+	t := reflect.TypeOf(*c)
+	for _, name := range path {
+		if t.Kind() != reflect.Struct {
+			return fmt.Errorf("配置覆盖 %q: 未知的配置项 %s", set, key)
+		}
+		f, ok := jsonField(t, name)
+		if !ok {
+			return fmt.Errorf("配置覆盖 %q: 未知的配置项 %s", set, key)
+		}
+		t = f.Type
+	}
+
+	var value interface{}
+	switch {
+	case t.Kind() == reflect.String:
+		value = raw
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.String && !strings.HasPrefix(strings.TrimSpace(raw), "["):
+		value = splitList(raw, ",")
+	default:
+		if err := json.Unmarshal([]byte(raw), &value); err != nil {
+			return fmt.Errorf("配置覆盖 %q: 值不是有效的 JSON: %v", set, err)
+		}
+	}
+
+	// 由路径构造嵌套的配置片段
+	values := map[string]interface{}{path[len(path)-1]: value}
+	for i := len(path) - 2; i >= 0; i-- {
+		values = map[string]interface{}{path[i]: values}
+	}
+	if err := apply(c, values); err != nil {
+		return fmt.Errorf("配置覆盖 %q 无效: %v", set, err)
+	}
+	return nil
+}
+
+// apply 将配置片段覆盖到配置上，未知的配置项返回错误
+//
+// 片段中出现的列表与 map 整体替换原值，而不是与原值逐项合并（路径覆盖 overrides 除外，按 path_prefix 合并）。
+func apply(c *Config, values map[string]interface{}) error {
+	if len(values) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+	resetCollections(reflect.ValueOf(c).Elem(), values)
+	return strictUnmarshal(data, c)
+}
+
+// resetCollections 清空片段中出现的列表与 map，使解析结果整体替换原值
+func resetCollections(v reflect.Value, values map[string]interface{}) {
+	for key, value := range values {
+		f, ok := jsonField(v.Type(), key)
+		if !ok {
+			continue
+		}
+		field := v.FieldByIndex(f.Index)
+		switch field.Kind() {
+		case reflect.Slice, reflect.Map:
+			field.SetZero()
+		case reflect.Struct:
+			// 自定义解析的配置（如 cors、security）自行处理合并
+			if _, custom := field.Addr().Interface().(json.Unmarshaler); custom {
+				continue
+			}
+			if sub, ok := value.(map[string]interface{}); ok {
+				resetCollections(field, sub)
+			}
+		}
+	}
+}
+
+// jsonField 按 JSON 名称查找结构体字段（包括嵌入结构体提升的字段）
+func jsonField(t reflect.Type, name string) (reflect.StructField, bool) {
+	for _, f := range reflect.VisibleFields(t) {
+		if !f.IsExported() || (f.Anonymous && f.Tag.Get("json") == "") {
+			continue
+		}
+		tag, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if tag == name {
+			return f, true
+		}
+	}
+	return reflect.StructField{}, false
+}
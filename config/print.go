@@ -0,0 +1,116 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+
+	"gopkg.in/yaml.v3"
+)
+
+// redactedValue 脱敏后的取值
+const redactedValue = "******"
+
+// Redacted 返回敏感配置项（带 secret:"true" 标签的字段）被遮盖的副本，原配置不受影响
+func (c *Config) Redacted() *Config {
+	cp := *c
+	redact(reflect.ValueOf(&cp).Elem())
+	return &cp
+}
+
+// redact 遍历结构体，遮盖敏感字段；切片与 map 先复制再修改，避免影响原配置
+func redact(v reflect.Value) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f, field := t.Field(i), v.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		if f.Tag.Get("secret") == "true" {
+			switch field.Kind() {
+			case reflect.String:
+				if field.Len() > 0 {
+					field.SetString(redactedValue)
+				}
+			case reflect.Map:
+				if field.Len() > 0 {
+					masked := reflect.MakeMapWithSize(field.Type(), field.Len())
+					for _, key := range field.MapKeys() {
+						masked.SetMapIndex(key, reflect.ValueOf(redactedValue).Convert(field.Type().Elem()))
+					}
+					field.Set(masked)
+				}
+			}
+			continue
+		}
+
+		switch field.Kind() {
+		case reflect.Struct:
+			redact(field)
+		case reflect.Slice:
+			if field.Type().Elem().Kind() == reflect.Struct && field.Len() > 0 {
+				items := reflect.MakeSlice(field.Type(), field.Len(), field.Len())
+				reflect.Copy(items, field)
+				for j := 0; j < items.Len(); j++ {
+					redact(items.Index(j))
+				}
+				field.Set(items)
+			}
+		}
+	}
+}
+
+// Print 以 yaml 或 json 格式输出配置，yaml 输出可直接作为配置文件使用
+func (c *Config) Print(w io.Writer, format string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case "json":
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	case "yaml", "":
+		// 经 JSON 转换，使 YAML 的键名与配置文件一致
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		var values map[string]interface{}
+		if err := dec.Decode(&values); err != nil {
+			return err
+		}
+
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(convertNumbers(values)); err != nil {
+			return err
+		}
+		return enc.Close()
+	default:
+		return fmt.Errorf("不支持的输出格式: %s（可选 yaml、json）", format)
+	}
+}
+
+// convertNumbers 将 json.Number 转换为整数或浮点数，避免 YAML 中输出为字符串
+func convertNumbers(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = convertNumbers(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = convertNumbers(item)
+		}
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		f, _ := v.Float64()
+		return f
+	}
+	return value
+}
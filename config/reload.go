@@ -0,0 +1,152 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// restartOnly 需要重启才能生效的配置（启动时已用于创建服务器、连接池、中间件等）
+//
+// 热更新时这些配置保留旧值，使配置快照与实际运行状态一致；日志配置中只有 level 与 redact 可以热更新。
+var restartOnly = []string{
+	"Server", "Database", "JWT", "Upload", "Mail", "Health", "Metrics", "Tracing", "ErrorReport", "RequestID", "Security", "Storage",
+}
+
+// subscriber 配置变化的订阅者
+type subscriber struct {
+	name string
+	fn   func(old, cur *Config)
+}
+
+var (
+	subscribersMu sync.Mutex
+	subscribers   []subscriber
+)
+
+// Subscribe 订阅配置变化，配置热更新生效后按注册顺序调用 fn（old 与 cur 均为只读快照）
+func Subscribe(name string, fn func(old, cur *Config)) {
+	subscribersMu.Lock()
+	defer subscribersMu.Unlock()
+
+	subscribers = append(subscribers, subscriber{name: name, fn: fn})
+}
+
+// updateMu 串行化配置更新，避免并发的热更新相互覆盖
+var updateMu sync.Mutex
+
+// Update 校验并原子替换当前配置，然后通知订阅者
+//
+// 校验失败时保留旧配置并返回错误。需要重启才能生效的配置保留旧值，发生变化的配置项通过 pending 返回。
+func Update(cfg *Config) (pending []string, err error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	updateMu.Lock()
+	defer updateMu.Unlock()
+
+	old := GetConfig()
+	pending = keepRestartOnly(old, cfg)
+	current.Store(cfg)
+
+	subscribersMu.Lock()
+	subs := slices.Clone(subscribers)
+	subscribersMu.Unlock()
+	for _, s := range subs {
+		s.fn(old, cfg)
+	}
+	return pending, nil
+}
+
+// keepRestartOnly 将需要重启才能生效的配置恢复为旧值，返回发生变化的配置项
+func keepRestartOnly(old, cfg *Config) []string {
+	var pending []string
+	oldValue, newValue := reflect.ValueOf(old).Elem(), reflect.ValueOf(cfg).Elem()
+	for _, name := range restartOnly {
+		if !reflect.DeepEqual(oldValue.FieldByName(name).Interface(), newValue.FieldByName(name).Interface()) {
+			f, _ := newValue.Type().FieldByName(name)
+			pending = append(pending, strings.Split(f.Tag.Get("json"), ",")[0])
+			newValue.FieldByName(name).Set(oldValue.FieldByName(name))
+		}
+	}
+
+	// 日志配置只有级别与脱敏规则可以热更新
+	log := old.Log
+	log.Level, log.Redact = cfg.Log.Level, cfg.Log.Redact
+	if !reflect.DeepEqual(log, cfg.Log) {
+		pending = append(pending, "log")
+		cfg.Log = log
+	}
+
+	if cfg.Profile != old.Profile {
+		pending = append(pending, "profile")
+		cfg.Profile = old.Profile
+	}
+	return pending
+}
+
+// Reloader 按启动时的加载选项重新加载配置，并检测配置文件的变化
+type Reloader struct {
+	opts Options
+
+	mu      sync.Mutex
+	version string
+}
+
+// NewReloader 创建配置重新加载器，opts 应与启动时加载配置的选项相同
+func NewReloader(opts Options) *Reloader {
+	r := &Reloader{opts: opts}
+	r.version = r.filesVersion()
+	return r
+}
+
+// Reload 重新加载配置（配置文件、环境变量与命令行参数），校验通过后原子替换当前配置
+func (r *Reloader) Reload() (pending []string, err error) {
+	r.mu.Lock()
+	r.version = r.filesVersion()
+	r.mu.Unlock()
+
+	cfg, err := Load(r.opts)
+	if err != nil {
+		return nil, err
+	}
+	return Update(cfg)
+}
+
+// Changed 配置文件自上次加载后是否发生变化（修改、新增或删除）
+func (r *Reloader) Changed() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.filesVersion() != r.version
+}
+
+// filesVersion 以修改时间与大小标识配置文件（包括尚不存在的运行环境配置文件）的版本
+func (r *Reloader) filesVersion() string {
+	profile := r.opts.profile()
+	var errs []error
+	files := configFiles(r.opts.File, profile, &errs)
+	if len(files) > 0 {
+		// 运行环境配置文件可能在启动后才创建
+		ext := filepath.Ext(files[0])
+		files = append(files, strings.TrimSuffix(files[0], ext)+"."+profile+ext)
+	} else {
+		// 尚未创建配置文件时，关注默认位置的文件
+		for _, ext := range configExts {
+			files = append(files, "config"+ext, "config."+profile+ext)
+		}
+	}
+
+	var b strings.Builder
+	for _, file := range files {
+		if info, err := os.Stat(file); err == nil {
+			fmt.Fprintf(&b, "%s:%d:%d;", file, info.ModTime().UnixNano(), info.Size())
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,211 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// publicDir 以 /static、/assets 对外公开的静态文件目录
+const publicDir = "static"
+
+// minProductionSecretLength 生产环境 JWT 密钥的最短长度
+const minProductionSecretLength = 32
+
+// sha256Hex 十六进制的 SHA-256 摘要
+var sha256Hex = regexp.MustCompile(`^[0-9a-fA-F]{64}$`)
+
+// Validate 校验配置，返回所有不合法的配置项；启动时校验失败拒绝启动
+func (c *Config) Validate() error {
+	v := &validator{}
+
+	v.port("server.port", c.Server.Port, false)
+	v.oneOf("server.mode", c.Server.Mode, "debug", "release", "test")
+	v.nonNegative("server.read_timeout", c.Server.ReadTimeout)
+	v.nonNegative("server.write_timeout", c.Server.WriteTimeout)
+	v.nonNegative("server.read_header_timeout", c.Server.ReadHeaderTimeout)
+	v.nonNegative("server.idle_timeout", c.Server.IdleTimeout)
+	v.nonNegative("server.max_header_bytes", c.Server.MaxHeaderBytes)
+	v.nonNegative("server.shutdown_timeout", c.Server.ShutdownTimeout)
+	v.nonNegative("server.drain_delay", c.Server.DrainDelay)
+
+	if c.Server.TLS.Enabled {
+		v.require("server.tls.cert_file", c.Server.TLS.CertFile)
+		v.require("server.tls.key_file", c.Server.TLS.KeyFile)
+		v.port("server.tls.redirect_port", c.Server.TLS.RedirectPort, true)
+		if c.Server.TLS.RedirectPort != "" && c.Server.TLS.RedirectPort == c.Server.Port {
+			v.fail("server.tls.redirect_port", "不能与 server.port 相同")
+		}
+		if c.Server.TLS.RedirectPort != "" {
+			v.require("server.tls.redirect_host", c.Server.TLS.RedirectHost)
+		}
+	}
+
+	v.require("jwt.secret", c.JWT.Secret)
+	if c.JWT.ExpirationTime <= 0 {
+		v.fail("jwt.expiration_time", "必须大于 0")
+	}
+
+	v.oneOf("log.level", strings.ToLower(c.Log.Level), "", "debug", "info", "warn", "warning", "error")
+	v.oneOf("log.format", strings.ToLower(c.Log.Format), "", "json", "text")
+	v.oneOf("log.output", strings.ToLower(c.Log.Output), "", "stdout", "stderr", "file")
+	if c.Log.BodySampleRate < 0 || c.Log.BodySampleRate > 1 {
+		v.fail("log.body_sample_rate", "必须在 0 到 1 之间")
+	}
+
+	for _, pattern := range c.Log.Redact.Patterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			v.fail("log.redact.patterns", fmt.Sprintf("正则表达式 %q 无效: %v", pattern, err))
+		}
+	}
+
+	v.oneOf("tracing.exporter", strings.ToLower(c.Tracing.Exporter), "", "otlp", "stdout", "none")
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		v.fail("tracing.sample_ratio", "必须在 0 到 1 之间")
+	}
+
+	v.oneOf("error_report.reporter", c.ErrorReport.Reporter, "", "log", "file", "sentry")
+	if c.ErrorReport.Reporter == "sentry" {
+		v.require("error_report.dsn", c.ErrorReport.DSN)
+	}
+
+	if c.Upload.MaxSizeMB <= 0 {
+		v.fail("upload.max_size_mb", "必须大于 0")
+	}
+	v.nonNegative("upload.user_quota_mb", c.Upload.UserQuotaMB)
+	v.nonNegative("upload.user_max_files", c.Upload.UserMaxFiles)
+	for role, quota := range c.Upload.RoleQuotas {
+		v.nonNegative("upload.role_quotas."+role+".quota_mb", quota.QuotaMB)
+		v.nonNegative("upload.role_quotas."+role+".max_files", quota.MaxFiles)
+	}
+	v.nonNegative("upload.anonymous_quota_mb", c.Upload.AnonymousQuotaMB)
+	v.nonNegative("upload.anonymous_max_files", c.Upload.AnonymousMaxFiles)
+	v.nonNegative("upload.reconcile_interval", c.Upload.ReconcileInterval)
+	if c.Upload.ShareMaxExpiration <= 0 {
+		v.fail("upload.share_max_expiration", "必须大于 0")
+	}
+	v.private("upload.dir", c.Upload.Dir)
+	v.private("upload.quarantine_dir", c.Upload.QuarantineDir)
+	if c.Upload.Tus.MaxSizeMB <= 0 {
+		v.fail("upload.tus.max_size_mb", "必须大于 0")
+	}
+	if c.Upload.Tus.Expiration <= 0 {
+		v.fail("upload.tus.expiration", "必须大于 0")
+	}
+	if c.Upload.Tus.CleanupInterval <= 0 {
+		v.fail("upload.tus.cleanup_interval", "必须大于 0")
+	}
+	v.oneOf("upload.scanner.driver", c.Upload.Scanner.Driver, "none", "clamav")
+	if c.Upload.Scanner.Driver == "clamav" {
+		if u, err := url.Parse(c.Upload.Scanner.Address); err != nil || (u.Scheme != "unix" && u.Scheme != "tcp") {
+			v.fail("upload.scanner.address", fmt.Sprintf("%q 不是有效的 unix:// 或 tcp:// 地址", c.Upload.Scanner.Address))
+		}
+		v.nonNegative("upload.scanner.timeout", c.Upload.Scanner.Timeout)
+	}
+
+	v.oneOf("storage.driver", c.Storage.Driver, "local", "s3")
+	if c.Storage.Driver == "s3" {
+		if u, err := url.Parse(c.Storage.S3.Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			v.fail("storage.s3.endpoint", fmt.Sprintf("%q 不是有效的 http(s) 地址", c.Storage.S3.Endpoint))
+		}
+		v.require("storage.s3.region", c.Storage.S3.Region)
+		v.require("storage.s3.bucket", c.Storage.S3.Bucket)
+		v.require("storage.s3.access_key_id", c.Storage.S3.AccessKeyID)
+		v.require("storage.s3.secret_access_key", c.Storage.S3.SecretAccessKey)
+	}
+
+	v.oneOf("request_id.format", c.RequestID.Format, "", "uuidv7", "ulid")
+	v.nonNegative("health.max_goroutines", c.Health.MaxGoroutines)
+
+	for i, policy := range c.RateLimit.Policies {
+		if policy.Requests <= 0 || policy.Window <= 0 {
+			v.fail(fmt.Sprintf("rate_limit.policies[%d]", i), fmt.Sprintf("策略 %s 的 requests 与 window 必须大于 0", policy.Name))
+		}
+	}
+	for i, digest := range c.RateLimit.APIKeys {
+		if !sha256Hex.MatchString(digest) {
+			v.fail(fmt.Sprintf("rate_limit.api_keys[%d]", i), "必须是 API Key 的 SHA-256 摘要（64 位十六进制）")
+		}
+	}
+
+	if c.Profile == ProfileProduction {
+		if c.JWT.Secret == DefaultJWTSecret {
+			v.fail("jwt.secret", "生产环境不能使用默认密钥，请通过 JWT_SECRET 配置")
+		} else if len(c.JWT.Secret) < minProductionSecretLength {
+			v.fail("jwt.secret", fmt.Sprintf("生产环境的密钥长度不能少于 %d 个字符", minProductionSecretLength))
+		}
+		if c.Server.Mode == "debug" {
+			v.fail("server.mode", "生产环境不能使用 debug 模式（会在错误页面中暴露堆栈）")
+		}
+	}
+
+	return errors.Join(v.errs...)
+}
+
+// validator 收集配置校验错误
+type validator struct {
+	errs []error
+}
+
+// fail 记录校验错误
+func (v *validator) fail(key, reason string) {
+	v.errs = append(v.errs, fmt.Errorf("配置项 %s 无效: %s", key, reason))
+}
+
+// require 校验必填项
+func (v *validator) require(key, value string) {
+	if strings.TrimSpace(value) == "" {
+		v.fail(key, "不能为空")
+	}
+}
+
+// port 校验端口号（1~65535），optional 为 true 时允许为空
+func (v *validator) port(key, value string, optional bool) {
+	if value == "" && optional {
+		return
+	}
+	if n, err := strconv.Atoi(value); err != nil || n < 1 || n > 65535 {
+		v.fail(key, fmt.Sprintf("%q 不是有效的端口号（1~65535）", value))
+	}
+}
+
+// nonNegative 校验非负数
+func (v *validator) nonNegative(key string, value int) {
+	if value < 0 {
+		v.fail(key, "不能为负数")
+	}
+}
+
+// oneOf 校验取值范围
+func (v *validator) oneOf(key, value string, allowed ...string) {
+	if !slices.Contains(allowed, value) {
+		var names []string
+		for _, name := range allowed {
+			if name != "" {
+				names = append(names, name)
+			}
+		}
+		v.fail(key, fmt.Sprintf("%q 不在可选值 %s 中", value, strings.Join(names, "、")))
+	}
+}
+
+// private 校验目录不在公开的静态文件目录内，否则其中的文件可以绕过权限检查被直接访问
+func (v *validator) private(key, dir string) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		v.fail(key, fmt.Sprintf("无法解析路径 %q: %v", dir, err))
+		return
+	}
+	public, err := filepath.Abs(publicDir)
+	if err != nil {
+		return
+	}
+	if rel, err := filepath.Rel(public, abs); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		v.fail(key, fmt.Sprintf("%q 位于公开的静态文件目录 %s 内", dir, publicDir))
+	}
+}
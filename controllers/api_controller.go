@@ -5,8 +5,11 @@ import (
 	"strconv"
 	"time"
 
+	"iris-cn-sample-project/health"
+	"iris-cn-sample-project/metrics"
 	"iris-cn-sample-project/models"
 	"iris-cn-sample-project/utils"
+	"iris-cn-sample-project/version"
 
 	"github.com/kataras/iris/v12"
 )
@@ -49,9 +52,13 @@ func APIDocs(ctx iris.Context) {
 			},
 			"用户管理": []string{
 				"GET /api/users",
+				"GET /api/users/search",
+				"POST /api/users/import",
+				"GET /api/users/export",
 				"GET /api/users/{id}",
 				"PUT /api/users/{id}",
 				"DELETE /api/users/{id}",
+				"PUT /api/users/{id}/quota",
 			},
 			"示例接口": []string{
 				"GET /api/hello",
@@ -62,6 +69,26 @@ func APIDocs(ctx iris.Context) {
 			"受保护接口": []string{
 				"GET /api/protected/profile",
 				"PUT /api/protected/profile",
+				"PUT /api/protected/avatar",
+				"DELETE /api/protected/avatar",
+			},
+			"文件管理": []string{
+				"GET /api/files",
+				"GET /api/files/usage",
+				"DELETE /api/files/{id}",
+				"POST /api/files/reconcile",
+				"GET /api/files/{id}/download",
+				"POST /api/files/{id}/share",
+			},
+			"头像": []string{
+				"GET /api/avatars/{id}",
+			},
+			"可续传上传": []string{
+				"OPTIONS /api/uploads",
+				"POST /api/uploads",
+				"HEAD /api/uploads/{id}",
+				"PATCH /api/uploads/{id}",
+				"DELETE /api/uploads/{id}",
 			},
 		},
 		"authentication": iris.Map{
@@ -86,48 +113,67 @@ func APIDocs(ctx iris.Context) {
 	ctx.JSON(models.NewResponse(200, "API 文档", documentation))
 }
 
-// HealthCheck 健康检查接口
+// HealthCheck 健康检查接口（全部就绪检查项的状态与构建信息）
 func HealthCheck(ctx iris.Context) {
-	health := iris.Map{
-		"status":    "healthy",
-		"timestamp": time.Now().Format("2006-01-02 15:04:05"),
-		"uptime":    "0h 0m 0s", // 简化的运行时间
-		"version":   "1.0.0",
-		"service":   "iris-sample-project",
-		"checks": iris.Map{
-			"database": "ok",
-			"memory":   "ok",
-			"disk":     "ok",
-		},
+	writeHealthReport(ctx, health.Run(ctx.Request().Context(), health.Readiness))
+}
+
+// Liveness 存活检查接口，进程能够响应即视为存活
+func Liveness(ctx iris.Context) {
+	writeHealthReport(ctx, health.Run(ctx.Request().Context(), health.Liveness))
+}
+
+// Readiness 就绪检查接口，依赖项（数据库、磁盘、邮件服务等）全部正常才视为就绪
+func Readiness(ctx iris.Context) {
+	writeHealthReport(ctx, health.Run(ctx.Request().Context(), health.Readiness))
+}
+
+// writeHealthReport 输出健康检查结果，不健康时返回 503
+//
+// 健康检查接口无需认证，只返回各检查项的名称与状态；失败原因（可能包含连接地址、路径）由 health 包写入日志。
+func writeHealthReport(ctx iris.Context, report *health.Report) {
+	code := iris.StatusOK
+	message := "健康检查通过"
+	if !report.Healthy() {
+		code = iris.StatusServiceUnavailable
+		message = "健康检查未通过"
+	}
+
+	checks := make([]iris.Map, 0, len(report.Checks))
+	for _, result := range report.Checks {
+		checks = append(checks, iris.Map{"name": result.Name, "status": result.Status})
 	}
 
-	ctx.JSON(models.NewResponse(200, "健康检查", health))
+	ctx.StatusCode(code)
+	ctx.JSON(models.NewResponse(code, message, iris.Map{
+		"status":     report.Status,
+		"timestamp":  time.Now().Format("2006-01-02 15:04:05"),
+		"started_at": version.StartTime().Format("2006-01-02 15:04:05"),
+		"uptime":     version.Uptime().Round(time.Second).String(),
+		"build":      version.Info(),
+		"service":    "iris-sample-project",
+		"checks":     checks,
+	}))
 }
 
-// Metrics 系统指标接口
+// Metrics 系统指标接口（JSON 视图，数据与 /metrics 同源）
 func Metrics(ctx iris.Context) {
-	metrics := iris.Map{
-		"timestamp": time.Now().Format("2006-01-02 15:04:05"),
-		"requests": iris.Map{
-			"total":   1000,
-			"success": 950,
-			"error":   50,
-		},
-		"response_time": iris.Map{
-			"avg":     "120ms",
-			"p50":     "100ms",
-			"p95":     "200ms",
-			"p99":     "300ms",
-		},
-		"system": iris.Map{
-			"cpu_usage":    "15.5%",
-			"memory_usage": "45.2%",
-			"disk_usage":   "22.8%",
-			"goroutines":   25,
-		},
+	snapshot, err := metrics.Snapshot()
+	if err != nil {
+		ctx.StatusCode(iris.StatusInternalServerError)
+		ctx.JSON(models.NewResponse(500, "采集系统指标失败: "+err.Error(), nil))
+		return
 	}
 
-	ctx.JSON(models.NewResponse(200, "系统指标", metrics))
+	ctx.JSON(models.NewResponse(200, "系统指标", iris.Map{
+		"timestamp": time.Now().Format("2006-01-02 15:04:05"),
+		"metrics":   snapshot,
+	}))
+}
+
+// PrometheusMetrics Prometheus 文本格式的指标接口
+func PrometheusMetrics() iris.Handler {
+	return iris.FromStd(metrics.Handler())
 }
 
 // Echo 回显接口（用于测试）
@@ -138,7 +184,8 @@ func Echo(ctx iris.Context) {
 		"path":       ctx.Path(),
 		"query":      ctx.URLParams(),
 		"headers":    ctx.Request().Header,
-		"remote_addr": ctx.RemoteAddr(),
+		"remote_addr": ctx.Request().RemoteAddr,
+		"client_ip":  utils.ClientIP(ctx),
 		"user_agent": ctx.GetHeader("User-Agent"),
 		"content_type": ctx.GetContentType(),
 		"content_length": ctx.GetContentLength(),
@@ -194,15 +241,15 @@ func Headers(ctx iris.Context) {
 
 // IP 获取客户端IP信息
 func IP(ctx iris.Context) {
-	clientIP := ctx.RemoteAddr()
+	remoteAddr := ctx.Request().RemoteAddr
 	realIP := ctx.GetHeader("X-Real-IP")
 	forwardedFor := ctx.GetHeader("X-Forwarded-For")
 
 	ipInfo := iris.Map{
-		"remote_addr":    clientIP,
+		"remote_addr":    remoteAddr,
 		"real_ip":        realIP,
 		"forwarded_for":  forwardedFor,
-		"client_ip":      utils.GetClientIP(ctx.Request()),
+		"client_ip":      utils.ClientIP(ctx),
 		"timestamp":      time.Now().Format("2006-01-02 15:04:05"),
 	}
 
@@ -285,9 +332,26 @@ func getRouteDescription(path, method string) string {
 		"POST /api/auth/login":  "用户登录接口",
 		"POST /api/auth/register": "用户注册接口",
 		"GET /api/users":        "获取用户列表（需要认证）",
-		"GET /api/users/{id}":   "获取指定用户信息（需要认证）",
+		"GET /api/users/search": "搜索用户，支持过滤与排序（需要认证）",
+		"POST /api/users/import": "从 CSV 或 JSON 批量导入用户，支持试运行（仅管理员）",
+		"GET /api/users/export":  "导出用户为 CSV、JSON Lines 或 XLSX（仅管理员）",
+		"GET /api/users/{id}":   "获取指定用户信息，支持 fields 与 expand 参数（需要认证）",
 		"PUT /api/users/{id}":   "更新用户信息（需要认证）",
 		"DELETE /api/users/{id}": "删除用户（需要认证）",
+		"POST /api/uploads":      "创建可续传上传（tus 1.0，需要认证）",
+		"HEAD /api/uploads/{id}": "查询可续传上传的偏移量（需要认证）",
+		"PATCH /api/uploads/{id}": "追加可续传上传的内容（需要认证）",
+		"DELETE /api/uploads/{id}": "取消可续传上传（需要认证）",
+		"PUT /api/users/{id}/quota": "设置用户的存储配额（仅管理员）",
+		"GET /api/files":          "分页查询文件（需要认证）",
+		"GET /api/files/usage":    "查询存储用量与配额（需要认证）",
+		"DELETE /api/files/{id}":  "删除文件（上传者本人或管理员）",
+		"POST /api/files/reconcile": "核对存储后端与文件记录（仅管理员）",
+		"GET /api/files/{id}/download": "下载文件（签名地址，或上传者本人、管理员的令牌）",
+		"POST /api/files/{id}/share":   "生成文件分享链接（需要认证）",
+		"PUT /api/protected/avatar": "上传头像并生成缩略图（需要认证）",
+		"DELETE /api/protected/avatar": "删除头像（需要认证）",
+		"GET /api/avatars/{id}":   "获取用户头像，支持 size、format 参数",
 	}
 
 	key := method + " " + path
@@ -16,8 +16,9 @@ func Login(ctx iris.Context) {
     var loginReq models.LoginRequest
     if err := ctx.ReadJSON(&loginReq); err != nil {
         ctx.JSON(iris.Map{
-            "code":    400,
-            "message": "请求数据格式错误: " + err.Error(),
+            "code":       400,
+            "message":    "请求数据格式错误: " + err.Error(),
+            "request_id": utils.RequestID(ctx),
         })
         return
     }
@@ -25,19 +26,21 @@ func Login(ctx iris.Context) {
     // 验证输入数据
     if err := utils.ValidateStruct(&loginReq); err != nil {
         ctx.JSON(iris.Map{
-            "code":    400,
-            "message": "输入数据验证失败",
-            "errors":  err,
+            "code":       400,
+            "message":    "输入数据验证失败",
+            "errors":     err,
+            "request_id": utils.RequestID(ctx),
         })
         return
     }
 
     // 调用服务层进行登录验证
-    user, err := services.LoginUser(loginReq.Username, loginReq.Password)
+    user, err := services.LoginUser(ctx.Request().Context(), loginReq.Username, loginReq.Password)
     if err != nil {
         ctx.JSON(iris.Map{
-            "code":    401,
-            "message": "用户名或密码错误",
+            "code":       401,
+            "message":    "用户名或密码错误",
+            "request_id": utils.RequestID(ctx),
         })
         return
     }
@@ -46,14 +49,15 @@ func Login(ctx iris.Context) {
     token, expiresAt, err := utils.GenerateJWT(user.ID, user.Username, user.Role)
     if err != nil {
         ctx.JSON(iris.Map{
-            "code":    500,
-            "message": "生成令牌失败: " + err.Error(),
+            "code":       500,
+            "message":    "生成令牌失败: " + err.Error(),
+            "request_id": utils.RequestID(ctx),
         })
         return
     }
 
     // 更新用户最后登录时间
-    services.UpdateUserLastLogin(user.ID)
+    services.UpdateUserLastLogin(ctx.Request().Context(), user.ID)
 
     // 返回登录成功响应
     loginResp := models.LoginResponse{
@@ -65,7 +69,7 @@ func Login(ctx iris.Context) {
             Email:     user.Email,
             FirstName: user.FirstName,
             LastName:  user.LastName,
-            Avatar:    user.Avatar,
+            Avatar:    user.AvatarURL(),
             Role:      user.Role,
             Status:    user.Status,
             CreatedAt: user.CreatedAt,
@@ -82,8 +86,9 @@ func Register(ctx iris.Context) {
     var registerReq models.RegisterRequest
     if err := ctx.ReadJSON(&registerReq); err != nil {
         ctx.JSON(iris.Map{
-            "code":    400,
-            "message": "请求数据格式错误: " + err.Error(),
+            "code":       400,
+            "message":    "请求数据格式错误: " + err.Error(),
+            "request_id": utils.RequestID(ctx),
         })
         return
     }
@@ -91,19 +96,21 @@ func Register(ctx iris.Context) {
     // 验证输入数据
     if err := utils.ValidateStruct(&registerReq); err != nil {
         ctx.JSON(iris.Map{
-            "code":    400,
-            "message": "输入数据验证失败",
-            "errors":  err,
+            "code":       400,
+            "message":    "输入数据验证失败",
+            "errors":     err,
+            "request_id": utils.RequestID(ctx),
         })
         return
     }
 
     // 调用服务层创建用户
-    user, err := services.CreateUser(&registerReq)
+    user, err := services.CreateUser(ctx.Request().Context(), &registerReq)
     if err != nil {
         ctx.JSON(iris.Map{
-            "code":    400,
-            "message": "用户注册失败: " + err.Error(),
+            "code":       400,
+            "message":    "用户注册失败: " + err.Error(),
+            "request_id": utils.RequestID(ctx),
         })
         return
     }
@@ -115,7 +122,7 @@ func Register(ctx iris.Context) {
         Email:     user.Email,
         FirstName: user.FirstName,
         LastName:  user.LastName,
-        Avatar:    user.Avatar,
+        Avatar:    user.AvatarURL(),
         Role:      user.Role,
         Status:    user.Status,
         CreatedAt: user.CreatedAt,
@@ -131,8 +138,9 @@ func RefreshToken(ctx iris.Context) {
     authHeader := ctx.GetHeader("Authorization")
     if authHeader == "" {
         ctx.JSON(iris.Map{
-            "code":    401,
-            "message": "缺少认证令牌",
+            "code":       401,
+            "message":    "缺少认证令牌",
+            "request_id": utils.RequestID(ctx),
         })
         return
     }
@@ -141,8 +149,9 @@ func RefreshToken(ctx iris.Context) {
     const bearerPrefix = "Bearer "
     if len(authHeader) <= len(bearerPrefix) {
         ctx.JSON(iris.Map{
-            "code":    401,
-            "message": "认证令牌格式错误",
+            "code":       401,
+            "message":    "认证令牌格式错误",
+            "request_id": utils.RequestID(ctx),
         })
         return
     }
@@ -153,26 +162,29 @@ func RefreshToken(ctx iris.Context) {
     claims, err := utils.ParseJWTWithoutValidation(tokenString)
     if err != nil {
         ctx.JSON(iris.Map{
-            "code":    401,
-            "message": "无效的认证令牌",
+            "code":       401,
+            "message":    "无效的认证令牌",
+            "request_id": utils.RequestID(ctx),
         })
         return
     }
 
     // 检查用户是否仍然存在且有效
-    user, err := services.GetUserByID(claims.UserID)
+    user, err := services.GetUserByID(ctx.Request().Context(), claims.UserID)
     if err != nil {
         ctx.JSON(iris.Map{
-            "code":    401,
-            "message": "用户不存在或已被禁用",
+            "code":       401,
+            "message":    "用户不存在或已被禁用",
+            "request_id": utils.RequestID(ctx),
         })
         return
     }
 
     if !user.IsActive() {
         ctx.JSON(iris.Map{
-            "code":    401,
-            "message": "用户账户已被禁用",
+            "code":       401,
+            "message":    "用户账户已被禁用",
+            "request_id": utils.RequestID(ctx),
         })
         return
     }
@@ -181,8 +193,9 @@ func RefreshToken(ctx iris.Context) {
     newToken, expiresAt, err := utils.GenerateJWT(user.ID, user.Username, user.Role)
     if err != nil {
         ctx.JSON(iris.Map{
-            "code":    500,
-            "message": "生成新令牌失败: " + err.Error(),
+            "code":       500,
+            "message":    "生成新令牌失败: " + err.Error(),
+            "request_id": utils.RequestID(ctx),
         })
         return
     }
@@ -211,8 +224,9 @@ func ChangePassword(ctx iris.Context) {
     userID := ctx.Values().GetUintDefault("user_id", 0)
     if userID == 0 {
         ctx.JSON(iris.Map{
-            "code":    401,
-            "message": "无效的用户信息",
+            "code":       401,
+            "message":    "无效的用户信息",
+            "request_id": utils.RequestID(ctx),
         })
         return
     }
@@ -221,8 +235,9 @@ func ChangePassword(ctx iris.Context) {
     var changePwdReq models.ChangePasswordRequest
     if err := ctx.ReadJSON(&changePwdReq); err != nil {
         ctx.JSON(iris.Map{
-            "code":    400,
-            "message": "请求数据格式错误: " + err.Error(),
+            "code":       400,
+            "message":    "请求数据格式错误: " + err.Error(),
+            "request_id": utils.RequestID(ctx),
         })
         return
     }
@@ -230,18 +245,20 @@ func ChangePassword(ctx iris.Context) {
     // 验证输入数据
     if err := utils.ValidateStruct(&changePwdReq); err != nil {
         ctx.JSON(iris.Map{
-            "code":    400,
-            "message": "输入数据验证失败",
-            "errors":  err,
+            "code":       400,
+            "message":    "输入数据验证失败",
+            "errors":     err,
+            "request_id": utils.RequestID(ctx),
         })
         return
     }
 
     // 调用服务层修改密码
-    if err := services.ChangeUserPassword(userID, changePwdReq.OldPassword, changePwdReq.NewPassword); err != nil {
+    if err := services.ChangeUserPassword(ctx.Request().Context(), userID, changePwdReq.OldPassword, changePwdReq.NewPassword); err != nil {
         ctx.JSON(iris.Map{
-            "code":    400,
-            "message": "密码修改失败: " + err.Error(),
+            "code":       400,
+            "message":    "密码修改失败: " + err.Error(),
+            "request_id": utils.RequestID(ctx),
         })
         return
     }
@@ -257,11 +274,12 @@ func GetAuthInfo(ctx iris.Context) {
     role := ctx.Values().GetStringDefault("role", "")
 
     // 获取完整的用户信息
-    user, err := services.GetUserByID(userID)
+    user, err := services.GetUserByID(ctx.Request().Context(), userID)
     if err != nil {
         ctx.JSON(iris.Map{
-            "code":    404,
-            "message": "用户信息不存在",
+            "code":       404,
+            "message":    "用户信息不存在",
+            "request_id": utils.RequestID(ctx),
         })
         return
     }
@@ -297,8 +315,9 @@ func ValidateToken(ctx iris.Context) {
     authHeader := ctx.GetHeader("Authorization")
     if authHeader == "" {
         ctx.JSON(iris.Map{
-            "code":    401,
-            "message": "缺少认证令牌",
+            "code":       401,
+            "message":    "缺少认证令牌",
+            "request_id": utils.RequestID(ctx),
         })
         return
     }
@@ -307,8 +326,9 @@ func ValidateToken(ctx iris.Context) {
     const bearerPrefix = "Bearer "
     if len(authHeader) <= len(bearerPrefix) {
         ctx.JSON(iris.Map{
-            "code":    401,
-            "message": "认证令牌格式错误",
+            "code":       401,
+            "message":    "认证令牌格式错误",
+            "request_id": utils.RequestID(ctx),
         })
         return
     }
@@ -319,18 +339,20 @@ func ValidateToken(ctx iris.Context) {
     claims, err := utils.ValidateJWT(tokenString)
     if err != nil {
         ctx.JSON(iris.Map{
-            "code":    401,
-            "message": "令牌验证失败: " + err.Error(),
+            "code":       401,
+            "message":    "令牌验证失败: " + err.Error(),
+            "request_id": utils.RequestID(ctx),
         })
         return
     }
 
     // 检查用户是否仍然有效
-    user, err := services.GetUserByID(claims.UserID)
+    user, err := services.GetUserByID(ctx.Request().Context(), claims.UserID)
     if err != nil || !user.IsActive() {
         ctx.JSON(iris.Map{
-            "code":    401,
-            "message": "用户不存在或已被禁用",
+            "code":       401,
+            "message":    "用户不存在或已被禁用",
+            "request_id": utils.RequestID(ctx),
         })
         return
     }
@@ -0,0 +1,157 @@
+package controllers
+
+import (
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+
+	"iris-cn-sample-project/avatar"
+	"iris-cn-sample-project/logging"
+	"iris-cn-sample-project/models"
+	"iris-cn-sample-project/services"
+	"iris-cn-sample-project/upload"
+
+	"github.com/kataras/iris/v12"
+)
+
+// avatarImmutableMaxAge 带版本号的头像地址的缓存时间（秒）
+const avatarImmutableMaxAge = 365 * 24 * 3600
+
+// avatarMaxAge 不带版本号的头像地址的缓存时间（秒），更换头像后最多这么久生效
+const avatarMaxAge = 300
+
+// UploadAvatar 上传头像（multipart 的 file 字段），生成各尺寸的缩略图并替换原来的头像
+func UploadAvatar(ctx iris.Context) {
+	policy, _ := upload.Lookup(upload.PurposeAvatar)
+	part, ok := openUploadPart(ctx, policy)
+	if !ok {
+		return
+	}
+	defer part.Close()
+
+	userID := ctx.Values().GetUintDefault("user_id", 0)
+	user, err := services.SetAvatar(ctx.Request().Context(), userID, clientReader{part}, part.FileName())
+	if err != nil {
+		switch {
+		case errors.Is(err, avatar.ErrInvalidImage):
+			writeError(ctx, iris.StatusUnprocessableEntity, avatar.ErrInvalidImage.Error())
+		case errors.Is(err, services.ErrUserNotFound):
+			writeError(ctx, iris.StatusNotFound, err.Error())
+		default:
+			writeUploadError(ctx, policy, err)
+		}
+		return
+	}
+
+	ctx.JSON(models.NewResponse(200, "头像已更新", avatarInfo(user)))
+}
+
+// RemoveAvatar 删除头像，之后显示默认头像
+func RemoveAvatar(ctx iris.Context) {
+	userID := ctx.Values().GetUintDefault("user_id", 0)
+	if err := services.DeleteAvatar(ctx.Request().Context(), userID); err != nil {
+		if errors.Is(err, services.ErrUserNotFound) {
+			writeError(ctx, iris.StatusNotFound, err.Error())
+			return
+		}
+		logging.L().ErrorContext(ctx.Request().Context(), "删除头像失败", "error", err)
+		writeError(ctx, iris.StatusInternalServerError, "删除头像失败")
+		return
+	}
+
+	ctx.JSON(models.NewResponse(200, "头像已删除", avatarInfo(&models.User{ID: userID})))
+}
+
+// GetAvatar 返回用户头像（不需要认证）
+//
+// size 为 avatar.Sizes 之一（默认 128）；format 为 webp 或 png，未指定时按 Accept 协商。
+// 没有上传头像的用户返回根据用户ID生成的 identicon。
+func GetAvatar(ctx iris.Context) {
+	userID, err := ctx.Params().GetUint("id")
+	if err != nil {
+		writeError(ctx, iris.StatusBadRequest, "无效的用户ID")
+		return
+	}
+	size := ctx.URLParamIntDefault("size", avatar.DefaultSize)
+	if !avatar.ValidSize(size) {
+		writeError(ctx, iris.StatusBadRequest, "size 只能是 "+joinInts(avatar.Sizes, "、")+" 之一")
+		return
+	}
+	format := ctx.URLParam("format")
+	switch {
+	case format == "":
+		ctx.ResponseWriter().Header().Add("Vary", "Accept")
+		format = avatar.FormatPNG
+		if strings.Contains(ctx.GetHeader("Accept"), avatar.ContentType(avatar.FormatWebP)) {
+			format = avatar.FormatWebP
+		}
+	case !avatar.ValidFormat(format):
+		writeError(ctx, iris.StatusBadRequest, "format 只能是 "+strings.Join(avatar.Formats, "、")+" 之一")
+		return
+	}
+
+	img, err := services.OpenAvatar(ctx.Request().Context(), userID, size, format)
+	if err != nil {
+		if errors.Is(err, services.ErrUserNotFound) {
+			writeError(ctx, iris.StatusNotFound, err.Error())
+			return
+		}
+		logging.L().ErrorContext(ctx.Request().Context(), "读取头像失败", "user_id", userID, "error", err)
+		writeError(ctx, iris.StatusInternalServerError, "读取头像失败")
+		return
+	}
+	defer img.Body.Close()
+
+	// 带版本号的地址内容不会变化，可以长期缓存；不带版本号时只短期缓存，以便更换头像后尽快生效
+	header := ctx.ResponseWriter().Header()
+	if img.Version != "" && ctx.URLParam("v") == img.Version {
+		header.Set("Cache-Control", "public, max-age="+strconv.Itoa(avatarImmutableMaxAge)+", immutable")
+	} else {
+		header.Set("Cache-Control", "public, max-age="+strconv.Itoa(avatarMaxAge))
+	}
+	header.Set("ETag", img.ETag)
+	if etagMatches(ctx.GetHeader("If-None-Match"), img.ETag) {
+		ctx.StatusCode(iris.StatusNotModified)
+		return
+	}
+
+	header.Set("Content-Type", img.ContentType)
+	header.Set("Content-Length", strconv.FormatInt(img.Size, 10))
+	ctx.StatusCode(iris.StatusOK)
+	if _, err := io.Copy(ctx.ResponseWriter(), img.Body); err != nil {
+		logging.L().WarnContext(ctx.Request().Context(), "发送头像失败", "user_id", userID, "error", err)
+	}
+}
+
+// avatarInfo 头像地址与可选的尺寸、格式
+func avatarInfo(user *models.User) iris.Map {
+	return iris.Map{
+		"avatar":  user.AvatarURL(),
+		"sizes":   avatar.Sizes,
+		"formats": avatar.Formats,
+	}
+}
+
+// etagMatches 判断 If-None-Match 是否包含 etag（弱比较）
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// joinInts 用 sep 连接整数
+func joinInts(values []int, sep string) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = strconv.Itoa(v)
+	}
+	return strings.Join(parts, sep)
+}
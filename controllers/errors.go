@@ -0,0 +1,16 @@
+package controllers
+
+import (
+	"iris-cn-sample-project/utils"
+
+	"github.com/kataras/iris/v12"
+)
+
+// writeError 返回带 request_id 的错误响应并停止执行后续的处理器
+func writeError(ctx iris.Context, status int, message string) {
+	ctx.StopWithJSON(status, iris.Map{
+		"code":       status,
+		"message":    message,
+		"request_id": utils.RequestID(ctx),
+	})
+}
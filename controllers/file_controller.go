@@ -0,0 +1,312 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"iris-cn-sample-project/config"
+	"iris-cn-sample-project/logging"
+	"iris-cn-sample-project/models"
+	"iris-cn-sample-project/services"
+	"iris-cn-sample-project/utils"
+
+	"github.com/kataras/iris/v12"
+)
+
+// shareDefaultExpiration 分享链接的默认有效期
+const shareDefaultExpiration = 24 * time.Hour
+
+// fileCacheControl 下载响应只允许浏览器缓存，每次使用前用 ETag 或 Last-Modified 重新验证，以便权限变化后立即生效
+const fileCacheControl = "private, no-cache"
+
+// inlineContentTypes 可以用 disposition=inline 在浏览器中直接打开的类型，其他类型总是作为附件下载
+var inlineContentTypes = map[string]bool{
+	"image/jpeg":      true,
+	"image/png":       true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"application/pdf": true,
+	"text/plain":      true,
+}
+
+// DownloadFile 下载文件（GET、HEAD）
+//
+// 持有签名下载地址（expires、signature 参数）的任何人都可以下载；否则需要上传者本人或管理员的令牌。
+// 支持 Range、If-Range、If-None-Match 与 If-Modified-Since，ETag 为文件的 SHA-256。
+func DownloadFile(ctx iris.Context) {
+	id, err := ctx.Params().GetUint("id")
+	if err != nil {
+		writeError(ctx, iris.StatusBadRequest, "无效的文件ID")
+		return
+	}
+	signed := ctx.URLParamExists("signature") || ctx.URLParamExists("expires")
+	userID := ctx.Values().GetUintDefault("user_id", 0)
+	if !signed && userID == 0 {
+		writeError(ctx, iris.StatusUnauthorized, "缺少认证令牌")
+		return
+	}
+
+	file, err := services.FindFile(ctx.Request().Context(), id)
+	if err != nil {
+		writeFileError(ctx, err)
+		return
+	}
+	switch {
+	case signed:
+		if !services.VerifyFileDownload(file, ctx.URLParam("expires"), ctx.URLParam("signature")) {
+			writeError(ctx, iris.StatusForbidden, "下载链接无效或已过期")
+			return
+		}
+	case !services.CanAccessFile(file, userID, ctx.Values().GetStringDefault("role", "")):
+		// 与文件不存在时的响应相同，不暴露其他用户的文件是否存在
+		writeFileError(ctx, services.ErrFileNotFound)
+		return
+	}
+
+	body, err := services.OpenFile(ctx.Request().Context(), file)
+	if err != nil {
+		writeFileError(ctx, err)
+		return
+	}
+	defer body.Close()
+
+	contentType := file.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	disposition := "attachment"
+	if ctx.URLParam("disposition") == "inline" && inlineContentTypes[mediaType(contentType)] {
+		disposition = "inline"
+	}
+
+	header := ctx.ResponseWriter().Header()
+	header.Set("Content-Type", contentType)
+	header.Set("Content-Disposition", utils.ContentDisposition(disposition, file.OriginalName))
+	header.Set("Cache-Control", fileCacheControl)
+	if file.SHA256 != "" {
+		header.Set("ETag", `"`+file.SHA256+`"`)
+	}
+	// 由 http.ServeContent 处理条件请求与范围请求；大文件的传输时间可能超过服务器的 WriteTimeout
+	http.ServeContent(streamResponseWriter(ctx), ctx.Request(), "", file.CreatedAt, body)
+}
+
+// ShareFile 生成文件的分享链接（需要认证，仅上传者本人或管理员）
+func ShareFile(ctx iris.Context) {
+	id, err := ctx.Params().GetUint("id")
+	if err != nil {
+		writeError(ctx, iris.StatusBadRequest, "无效的文件ID")
+		return
+	}
+	var req models.ShareFileRequest
+	if ctx.GetContentLength() > 0 {
+		if err := ctx.ReadJSON(&req); err != nil {
+			writeError(ctx, iris.StatusBadRequest, "请求数据格式错误: "+err.Error())
+			return
+		}
+	}
+	maxExpiresIn := config.GetConfig().Upload.ShareMaxExpiration
+	if req.ExpiresIn < 0 || req.ExpiresIn > maxExpiresIn {
+		writeError(ctx, iris.StatusBadRequest, "expires_in 必须在 1 到 "+strconv.Itoa(maxExpiresIn)+" 秒之间")
+		return
+	}
+	expires := time.Duration(req.ExpiresIn) * time.Second
+	if req.ExpiresIn == 0 {
+		expires = min(shareDefaultExpiration, time.Duration(maxExpiresIn)*time.Second)
+	}
+
+	file, err := services.FindFile(ctx.Request().Context(), id)
+	if err != nil {
+		writeFileError(ctx, err)
+		return
+	}
+	userID := ctx.Values().GetUintDefault("user_id", 0)
+	if !services.CanAccessFile(file, userID, ctx.Values().GetStringDefault("role", "")) {
+		writeFileError(ctx, services.ErrFileNotFound)
+		return
+	}
+
+	url, expiresAt := services.FileDownloadURL(file, expires)
+	logging.L().InfoContext(ctx.Request().Context(), "生成文件分享链接", "file_id", file.ID, "user_id", userID, "expires_at", expiresAt)
+	ctx.JSON(models.NewResponse(200, "分享链接已生成", iris.Map{
+		"url":        url,
+		"expires_at": expiresAt.UTC().Format(time.RFC3339),
+	}))
+}
+
+// 文件列表每页的默认与最大条数
+const (
+	defaultFilePageSize = 20
+	maxFilePageSize     = 100
+)
+
+// ListFiles 分页查询文件（需要认证）：普通用户只能查看自己的文件，管理员查看所有文件或用 user_id 指定用户
+//
+// 支持页码分页（page、page_size）与游标分页（cursor、limit），按上传时间倒序。
+func ListFiles(ctx iris.Context) {
+	if cursorRequested(ctx) {
+		listFilesByCursor(ctx)
+		return
+	}
+	page, pageSize, ok := pageParams(ctx, defaultFilePageSize, maxFilePageSize)
+	if !ok {
+		return
+	}
+	userID, ok := fileOwnerParam(ctx)
+	if !ok {
+		return
+	}
+
+	files, total, err := services.ListFiles(ctx.Request().Context(), userID, page, pageSize)
+	if err != nil {
+		writeFileError(ctx, err)
+		return
+	}
+	setPageLinks(ctx, page, pageSize, total)
+	ctx.JSON(models.NewPageResponse(200, "获取文件列表成功", files, page, pageSize, total))
+}
+
+// listFilesByCursor 游标分页查询文件
+func listFilesByCursor(ctx iris.Context) {
+	cur, limit, ok := cursorParams(ctx, defaultFilePageSize, maxFilePageSize)
+	if !ok {
+		return
+	}
+	userID, ok := fileOwnerParam(ctx)
+	if !ok {
+		return
+	}
+
+	files, next, prev, err := services.ListFilesByCursor(ctx.Request().Context(), userID, cur, limit)
+	if err != nil {
+		writeFileError(ctx, err)
+		return
+	}
+	writeCursorPage(ctx, "获取文件列表成功", files, limit, next, prev)
+}
+
+// GetStorageUsage 查询存储用量与配额（需要认证），管理员可以用 user_id 指定用户
+func GetStorageUsage(ctx iris.Context) {
+	userID, ok := fileOwnerParam(ctx)
+	if !ok {
+		return
+	}
+	if userID == 0 {
+		userID = ctx.Values().GetUintDefault("user_id", 0)
+	}
+
+	usage, err := services.StorageUsage(ctx.Request().Context(), userID)
+	if err != nil {
+		writeFileError(ctx, err)
+		return
+	}
+	quota, err := services.UserQuota(ctx.Request().Context(), userID)
+	if errors.Is(err, services.ErrUserNotFound) {
+		writeError(ctx, iris.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		writeFileError(ctx, err)
+		return
+	}
+	ctx.JSON(models.NewResponse(200, "获取存储用量成功", iris.Map{
+		"user_id": userID,
+		"usage":   usage,
+		"quota":   quota,
+	}))
+}
+
+// RemoveFile 删除文件（需要认证，仅上传者本人或管理员）
+func RemoveFile(ctx iris.Context) {
+	id, err := ctx.Params().GetUint("id")
+	if err != nil {
+		writeError(ctx, iris.StatusBadRequest, "无效的文件ID")
+		return
+	}
+	userID := ctx.Values().GetUintDefault("user_id", 0)
+	if err := services.DeleteFile(ctx.Request().Context(), id, userID, ctx.Values().GetStringDefault("role", "")); err != nil {
+		writeFileError(ctx, err)
+		return
+	}
+	ctx.JSON(models.NewResponse(200, "文件已删除", nil))
+}
+
+// UpdateUserQuota 设置用户的存储配额（仅管理员）
+func UpdateUserQuota(ctx iris.Context) {
+	userID, err := ctx.Params().GetInt("id")
+	if err != nil || userID <= 0 {
+		writeError(ctx, iris.StatusBadRequest, "无效的用户ID")
+		return
+	}
+	var req models.UpdateQuotaRequest
+	if err := ctx.ReadJSON(&req); err != nil {
+		writeError(ctx, iris.StatusBadRequest, "请求数据格式错误: "+err.Error())
+		return
+	}
+	if (req.QuotaBytes != nil && *req.QuotaBytes < 0) || (req.QuotaFiles != nil && *req.QuotaFiles < 0) {
+		writeError(ctx, iris.StatusBadRequest, "quota_bytes 与 quota_files 不能为负数（0 表示不限制，null 恢复为按角色或全局配置）")
+		return
+	}
+
+	user, err := services.SetUserQuota(ctx.Request().Context(), uint(userID), req.QuotaBytes, req.QuotaFiles)
+	if err != nil {
+		if errors.Is(err, services.ErrUserNotFound) {
+			writeError(ctx, iris.StatusNotFound, err.Error())
+			return
+		}
+		logging.L().ErrorContext(ctx.Request().Context(), "更新存储配额失败", "user_id", userID, "error", err)
+		writeError(ctx, iris.StatusInternalServerError, "更新存储配额失败")
+		return
+	}
+	logging.L().InfoContext(ctx.Request().Context(), "更新存储配额", "user_id", userID,
+		"operator_id", ctx.Values().GetUintDefault("user_id", 0))
+	ctx.JSON(models.NewResponse(200, "存储配额已更新", iris.Map{
+		"user_id": user.ID,
+		"quota":   services.QuotaFor(user),
+	}))
+}
+
+// ReconcileFiles 立即核对存储后端与文件记录（仅管理员）
+func ReconcileFiles(ctx iris.Context) {
+	result, err := services.ReconcileStorage(ctx.Request().Context())
+	if err != nil {
+		logging.L().ErrorContext(ctx.Request().Context(), "核对存储失败", "error", err)
+		writeError(ctx, iris.StatusInternalServerError, "核对存储失败: "+err.Error())
+		return
+	}
+	ctx.JSON(models.NewResponse(200, "存储核对完成", result))
+}
+
+// fileOwnerParam 要查看的文件所属用户：普通用户总是本人；管理员为 user_id 参数，未指定时为 0（所有用户）
+func fileOwnerParam(ctx iris.Context) (uint, bool) {
+	if ctx.Values().GetStringDefault("role", "") != "admin" {
+		return ctx.Values().GetUintDefault("user_id", 0), true
+	}
+	if !ctx.URLParamExists("user_id") {
+		return 0, true
+	}
+	userID, err := strconv.ParseUint(ctx.URLParam("user_id"), 10, 64)
+	if err != nil || userID == 0 {
+		writeError(ctx, iris.StatusBadRequest, "无效的用户ID")
+		return 0, false
+	}
+	return uint(userID), true
+}
+
+// writeFileError 将文件服务的错误转换为响应
+func writeFileError(ctx iris.Context, err error) {
+	if errors.Is(err, services.ErrFileNotFound) {
+		writeError(ctx, iris.StatusNotFound, services.ErrFileNotFound.Error())
+		return
+	}
+	logging.L().ErrorContext(ctx.Request().Context(), "读取文件失败", "error", err)
+	writeError(ctx, iris.StatusInternalServerError, "读取文件失败")
+}
+
+// mediaType 去掉 Content-Type 中的参数部分
+func mediaType(contentType string) string {
+	t, _, _ := strings.Cut(contentType, ";")
+	return strings.ToLower(strings.TrimSpace(t))
+}
@@ -0,0 +1,147 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"iris-cn-sample-project/config"
+	"iris-cn-sample-project/database"
+	"iris-cn-sample-project/models"
+	"iris-cn-sample-project/services"
+	"iris-cn-sample-project/storage"
+
+	"github.com/kataras/iris/v12"
+)
+
+// testFileContent 测试文件的内容
+const testFileContent = "0123456789abcdef"
+
+// newDownloadApp 使用临时目录中的数据库与本地存储创建挂载了下载接口的测试应用，返回应用与其中的一个文件
+//
+// 请求头 X-Test-User 模拟认证中间件设置的用户ID。
+func newDownloadApp(t *testing.T) (*iris.Application, *models.File) {
+	t.Helper()
+
+	dir := t.TempDir()
+	cfg := config.Defaults()
+	cfg.Database.Database = filepath.Join(dir, "test.db")
+	cfg.Upload.Dir = filepath.Join(dir, "uploads")
+	cfg.Storage.Driver = "local"
+	cfg.Storage.SigningKey = "test-signing-key"
+
+	previous, previousStore := config.GetConfig(), storage.Default()
+	config.SetConfig(cfg)
+	t.Cleanup(func() {
+		database.CloseDB()
+		storage.SetDefault(previousStore)
+		config.SetConfig(previous)
+	})
+	if err := database.InitDB(); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+	if err := storage.Init(cfg.Storage, cfg.Upload.Dir); err != nil {
+		t.Fatalf("初始化存储失败: %v", err)
+	}
+
+	userID := uint(2)
+	file := &models.File{
+		Key:          "2024/05/01/report.txt",
+		Storage:      "local",
+		OriginalName: "报告.txt",
+		ContentType:  "text/plain; charset=utf-8",
+		Status:       models.FileStatusActive,
+		Size:         int64(len(testFileContent)),
+		SHA256:       "5d6f3c2a",
+		UserID:       &userID,
+		CreatedAt:    time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC),
+	}
+	if _, err := storage.Default().Put(context.Background(), file.Key, strings.NewReader(testFileContent), storage.PutOptions{Size: file.Size}); err != nil {
+		t.Fatalf("写入对象失败: %v", err)
+	}
+	if err := database.GetDB().Create(file).Error; err != nil {
+		t.Fatalf("创建文件记录失败: %v", err)
+	}
+
+	app := iris.New()
+	app.Get("/api/files/{id:uint}/download", func(ctx iris.Context) {
+		if id, err := strconv.ParseUint(ctx.GetHeader("X-Test-User"), 10, 64); err == nil {
+			ctx.Values().Set("user_id", uint(id))
+			ctx.Values().Set("role", "user")
+		}
+		ctx.Next()
+	}, DownloadFile)
+	if err := app.Build(); err != nil {
+		t.Fatalf("构建应用失败: %v", err)
+	}
+	return app, file
+}
+
+func TestDownloadFile(t *testing.T) {
+	app, file := newDownloadApp(t)
+	signed, _ := services.FileDownloadURL(file, time.Minute)
+	other := &models.File{Key: "2024/05/01/other.txt", Storage: "local", Status: models.FileStatusActive}
+	if err := database.GetDB().Create(other).Error; err != nil {
+		t.Fatalf("创建文件记录失败: %v", err)
+	}
+	etag := `"` + file.SHA256 + `"`
+
+	tests := []struct {
+		name    string
+		url     string
+		headers map[string]string
+		status  int
+		body    string
+		check   func(t *testing.T, h http.Header)
+	}{
+		{"签名地址", signed, nil, http.StatusOK, testFileContent, func(t *testing.T, h http.Header) {
+			if h.Get("ETag") != etag || !strings.HasPrefix(h.Get("Content-Disposition"), "attachment") || h.Get("Accept-Ranges") != "bytes" {
+				t.Errorf("响应头 = %v", h)
+			}
+		}},
+		{"上传者本人", "/api/files/1/download", map[string]string{"X-Test-User": "2"}, http.StatusOK, testFileContent, nil},
+		{"其他用户", "/api/files/1/download", map[string]string{"X-Test-User": "3"}, http.StatusNotFound, "", nil},
+		{"未认证", "/api/files/1/download", nil, http.StatusUnauthorized, "", nil},
+		{"签名被篡改", strings.Replace(signed, "signature=", "signature=0", 1), nil, http.StatusForbidden, "", nil},
+		{"签名用于其他文件", strings.Replace(signed, "/files/1/", "/files/2/", 1), nil, http.StatusForbidden, "", nil},
+		{"范围请求", signed, map[string]string{"Range": "bytes=2-5"}, http.StatusPartialContent, "2345", func(t *testing.T, h http.Header) {
+			if got := h.Get("Content-Range"); got != "bytes 2-5/16" {
+				t.Errorf("Content-Range = %q", got)
+			}
+		}},
+		{"末尾范围", signed, map[string]string{"Range": "bytes=-3"}, http.StatusPartialContent, "def", nil},
+		{"无法满足的范围", signed, map[string]string{"Range": "bytes=100-"}, http.StatusRequestedRangeNotSatisfiable, "", nil},
+		{"If-Range 匹配", signed, map[string]string{"Range": "bytes=0-1", "If-Range": etag}, http.StatusPartialContent, "01", nil},
+		{"If-Range 不匹配", signed, map[string]string{"Range": "bytes=0-1", "If-Range": `"other"`}, http.StatusOK, testFileContent, nil},
+		{"If-None-Match 匹配", signed, map[string]string{"If-None-Match": etag}, http.StatusNotModified, "", func(t *testing.T, h http.Header) {
+			if h.Get("ETag") != etag {
+				t.Errorf("304 响应应包含 ETag: %v", h)
+			}
+		}},
+		{"If-None-Match 不匹配", signed, map[string]string{"If-None-Match": `"other"`}, http.StatusOK, testFileContent, nil},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+		for k, v := range tt.headers {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+
+		if rec.Code != tt.status {
+			t.Errorf("%s: 状态码 = %d, want %d: %s", tt.name, rec.Code, tt.status, rec.Body.String())
+			continue
+		}
+		if tt.body != "" && rec.Body.String() != tt.body {
+			t.Errorf("%s: 内容 = %q, want %q", tt.name, rec.Body.String(), tt.body)
+		}
+		if tt.check != nil {
+			tt.check(t, rec.Header())
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package controllers
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"iris-cn-sample-project/config"
+	"iris-cn-sample-project/models"
+	"iris-cn-sample-project/pagination"
+
+	"github.com/kataras/iris/v12"
+)
+
+// 列表接口支持两种分页方式：
+//   - 页码分页：page、page_size，响应中包含总数；
+//   - 游标分页：cursor、limit（带任一参数即使用游标分页），按 (created_at, id) 定位，适合大表与无限滚动，
+//     不统计总数，游标由服务端签名并绑定查询条件。
+// 两种方式都在 Link 响应头（RFC 8288）中给出相邻页的地址。
+
+// pageParams 解析 page 与 page_size 参数，超出范围时返回 400 而不是悄悄修正
+func pageParams(ctx iris.Context, defaultPageSize, maxPageSize int) (page, pageSize int, ok bool) {
+	if ctx.URLParamExists("cursor") || ctx.URLParamExists("limit") {
+		writeError(ctx, iris.StatusBadRequest, "page、page_size 不能与 cursor、limit 同时使用")
+		return 0, 0, false
+	}
+	page, err := strconv.Atoi(ctx.URLParamDefault("page", "1"))
+	if err != nil || page < 1 {
+		writeError(ctx, iris.StatusBadRequest, "page 必须是大于 0 的整数")
+		return 0, 0, false
+	}
+	pageSize, err = strconv.Atoi(ctx.URLParamDefault("page_size", strconv.Itoa(defaultPageSize)))
+	if err != nil || pageSize < 1 || pageSize > maxPageSize {
+		writeError(ctx, iris.StatusBadRequest, "page_size 必须在 1 到 "+strconv.Itoa(maxPageSize)+" 之间")
+		return 0, 0, false
+	}
+	return page, pageSize, true
+}
+
+// cursorRequested 请求是否使用游标分页
+func cursorRequested(ctx iris.Context) bool {
+	return ctx.URLParamExists("cursor") || ctx.URLParamExists("limit")
+}
+
+// cursorParams 解析 cursor 与 limit 参数，第一页的游标为 nil；参数无效时写入 400 响应并返回 false
+func cursorParams(ctx iris.Context, defaultLimit, maxLimit int) (*pagination.Cursor, int, bool) {
+	if ctx.URLParamExists("page") || ctx.URLParamExists("page_size") {
+		writeError(ctx, iris.StatusBadRequest, "page、page_size 不能与 cursor、limit 同时使用")
+		return nil, 0, false
+	}
+	limit, err := strconv.Atoi(ctx.URLParamDefault("limit", strconv.Itoa(defaultLimit)))
+	if err != nil || limit < 1 || limit > maxLimit {
+		writeError(ctx, iris.StatusBadRequest, "limit 必须在 1 到 "+strconv.Itoa(maxLimit)+" 之间")
+		return nil, 0, false
+	}
+	token := ctx.URLParam("cursor")
+	if token == "" {
+		return nil, limit, true
+	}
+	cur, err := cursorCodec().Decode(token, cursorScope(ctx))
+	if err != nil {
+		writeError(ctx, iris.StatusBadRequest, err.Error())
+		return nil, 0, false
+	}
+	return &cur, limit, true
+}
+
+// writeCursorPage 写入游标分页响应与 Link 头
+func writeCursorPage(ctx iris.Context, message string, data interface{}, limit int, next, prev *pagination.Cursor) {
+	codec, scope := cursorCodec(), cursorScope(ctx)
+	size := strconv.Itoa(limit)
+
+	var nextToken, prevToken string
+	links := []string{pageLink(ctx, "first", map[string]string{"limit": size})}
+	if prev != nil {
+		prevToken = codec.Encode(*prev, scope)
+		links = append(links, pageLink(ctx, "prev", map[string]string{"cursor": prevToken, "limit": size}))
+	}
+	if next != nil {
+		nextToken = codec.Encode(*next, scope)
+		links = append(links, pageLink(ctx, "next", map[string]string{"cursor": nextToken, "limit": size}))
+	}
+	ctx.Header("Link", strings.Join(links, ", "))
+	ctx.JSON(models.NewCursorResponse(200, message, data, limit, nextToken, prevToken))
+}
+
+// setPageLinks 为页码分页的响应写入 Link 头（first、prev、next、last）
+func setPageLinks(ctx iris.Context, page, pageSize int, total int64) {
+	lastPage := int((total + int64(pageSize) - 1) / int64(pageSize))
+	if lastPage < 1 {
+		lastPage = 1
+	}
+	link := func(rel string, page int) string {
+		return pageLink(ctx, rel, map[string]string{"page": strconv.Itoa(page), "page_size": strconv.Itoa(pageSize)})
+	}
+
+	links := []string{link("first", 1)}
+	if page > 1 {
+		links = append(links, link("prev", min(page-1, lastPage)))
+	}
+	if page < lastPage {
+		links = append(links, link("next", page+1))
+	}
+	links = append(links, link("last", lastPage))
+	ctx.Header("Link", strings.Join(links, ", "))
+}
+
+// pageLink 用新的分页参数替换当前请求地址中的分页参数，生成 Link 头中的一项
+func pageLink(ctx iris.Context, rel string, params map[string]string) string {
+	query := ctx.Request().URL.Query()
+	for _, name := range []string{"page", "page_size", "cursor", "limit"} {
+		query.Del(name)
+	}
+	for name, value := range params {
+		query.Set(name, value)
+	}
+	return fmt.Sprintf(`<%s?%s>; rel="%s"`, ctx.Request().URL.Path, query.Encode(), rel)
+}
+
+// cursorScope 游标绑定的查询条件：请求路径与除分页参数外的查询参数（按参数名排序）
+func cursorScope(ctx iris.Context) string {
+	query := url.Values{}
+	for name, values := range ctx.Request().URL.Query() {
+		if name != "cursor" && name != "limit" {
+			query[name] = values
+		}
+	}
+	return ctx.Request().URL.Path + "?" + query.Encode()
+}
+
+// cursorCodec 游标的签名与 JWT 使用相同的密钥（经过派生）
+func cursorCodec() *pagination.Codec {
+	return pagination.NewCodec(config.GetConfig().JWT.Secret)
+}
@@ -0,0 +1,145 @@
+package controllers
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"iris-cn-sample-project/logging"
+	"iris-cn-sample-project/metrics"
+	"iris-cn-sample-project/utils"
+
+	"github.com/kataras/iris/v12"
+)
+
+// cspReportMaxBytes CSP 违规报告请求体的大小上限
+const cspReportMaxBytes = 64 * 1024
+
+// cspReportMaxItems 单个请求中处理的违规报告数量上限（Reporting API 会批量上报）
+const cspReportMaxItems = 20
+
+// cspViolation CSP 违规报告中记录的字段
+type cspViolation struct {
+	DocumentURI        string `json:"document-uri"`
+	ViolatedDirective  string `json:"violated-directive"`
+	EffectiveDirective string `json:"effective-directive"`
+	BlockedURI         string `json:"blocked-uri"`
+	SourceFile         string `json:"source-file"`
+	LineNumber         int    `json:"line-number"`
+	Disposition        string `json:"disposition"`
+}
+
+// reportingAPIReport Reporting API（application/reports+json）的单条报告
+type reportingAPIReport struct {
+	Type string `json:"type"`
+	Body struct {
+		DocumentURL        string `json:"documentURL"`
+		EffectiveDirective string `json:"effectiveDirective"`
+		BlockedURL         string `json:"blockedURL"`
+		SourceFile         string `json:"sourceFile"`
+		LineNumber         int    `json:"lineNumber"`
+		Disposition        string `json:"disposition"`
+	} `json:"body"`
+}
+
+// CSPReport 接收浏览器上报的 CSP 违规报告
+//
+// 同时支持 report-uri 使用的 application/csp-report 与 report-to 使用的 application/reports+json，
+// 报告记录为警告日志并计入 csp_violations_total 指标。
+func CSPReport(ctx iris.Context) {
+	body, err := io.ReadAll(http.MaxBytesReader(ctx.ResponseWriter(), ctx.Request().Body, cspReportMaxBytes))
+	if err != nil {
+		ctx.StopWithJSON(iris.StatusRequestEntityTooLarge, iris.Map{
+			"code":       413,
+			"message":    "违规报告过大",
+			"request_id": utils.RequestID(ctx),
+		})
+		return
+	}
+
+	violations, err := parseCSPReports(body)
+	if err != nil {
+		ctx.StopWithJSON(iris.StatusBadRequest, iris.Map{
+			"code":       400,
+			"message":    "违规报告格式错误",
+			"request_id": utils.RequestID(ctx),
+		})
+		return
+	}
+
+	for _, v := range violations {
+		directive := cspDirective(v)
+		metrics.CSPViolationsTotal.WithLabelValues(directive).Inc()
+		logging.L().LogAttrs(ctx.Request().Context(), slog.LevelWarn, "CSP 违规",
+			slog.String("directive", directive),
+			slog.String("document_uri", v.DocumentURI),
+			slog.String("blocked_uri", v.BlockedURI),
+			slog.String("source_file", v.SourceFile),
+			slog.Int("line_number", v.LineNumber),
+			slog.String("disposition", v.Disposition),
+			slog.String("user_agent", ctx.GetHeader("User-Agent")),
+		)
+	}
+
+	ctx.StatusCode(iris.StatusNoContent)
+}
+
+// parseCSPReports 解析违规报告，兼容 {"csp-report": {...}} 与 Reporting API 的报告数组
+func parseCSPReports(body []byte) ([]cspViolation, error) {
+	trimmed := strings.TrimSpace(string(body))
+
+	if strings.HasPrefix(trimmed, "[") {
+		var reports []reportingAPIReport
+		if err := json.Unmarshal(body, &reports); err != nil {
+			return nil, err
+		}
+		var violations []cspViolation
+		for _, r := range reports {
+			if r.Type != "csp-violation" {
+				continue
+			}
+			violations = append(violations, cspViolation{
+				DocumentURI:        r.Body.DocumentURL,
+				EffectiveDirective: r.Body.EffectiveDirective,
+				BlockedURI:         r.Body.BlockedURL,
+				SourceFile:         r.Body.SourceFile,
+				LineNumber:         r.Body.LineNumber,
+				Disposition:        r.Body.Disposition,
+			})
+			if len(violations) == cspReportMaxItems {
+				break
+			}
+		}
+		return violations, nil
+	}
+
+	var report struct {
+		Violation *cspViolation `json:"csp-report"`
+	}
+	if err := json.Unmarshal(body, &report); err != nil {
+		return nil, err
+	}
+	if report.Violation == nil {
+		return nil, nil
+	}
+	return []cspViolation{*report.Violation}, nil
+}
+
+// cspDirective 获取违规的指令名称，只接受形如 script-src-elem 的名称，避免任意上报内容撑大指标基数
+func cspDirective(v cspViolation) string {
+	directive := v.EffectiveDirective
+	if directive == "" {
+		directive, _, _ = strings.Cut(strings.TrimSpace(v.ViolatedDirective), " ")
+	}
+	if directive == "" || len(directive) > 32 {
+		return "other"
+	}
+	for _, c := range directive {
+		if (c < 'a' || c > 'z') && c != '-' {
+			return "other"
+		}
+	}
+	return directive
+}
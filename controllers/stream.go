@@ -0,0 +1,78 @@
+package controllers
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"iris-cn-sample-project/config"
+
+	"github.com/kataras/iris/v12"
+)
+
+// 服务器的 ReadTimeout、WriteTimeout 限制的是整个请求，大文件下载、导出与可续传上传在慢速网络上会被截断。
+// 长时间传输时在读写数据前把连接的截止时间顺延一个超时周期：持续有数据传输就不会超时，
+// 连接停滞超过一个周期仍会断开。
+
+// deadlines 顺延连接的读、写截止时间，超时为 0（不限制）的不处理
+type deadlines struct {
+	rc          *http.ResponseController
+	read, write time.Duration
+}
+
+// newDeadlines 按服务器配置创建，read 为 false 时只顺延写截止时间
+func newDeadlines(ctx iris.Context, read bool) *deadlines {
+	cfg := config.GetConfig().Server
+	d := &deadlines{
+		rc:    http.NewResponseController(ctx.ResponseWriter().Naive()),
+		write: time.Duration(cfg.WriteTimeout) * time.Second,
+	}
+	if read {
+		d.read = time.Duration(cfg.ReadTimeout) * time.Second
+	}
+	return d
+}
+
+// extend 从现在起顺延一个超时周期
+func (d *deadlines) extend() {
+	now := time.Now()
+	if d.read > 0 {
+		d.rc.SetReadDeadline(now.Add(d.read))
+	}
+	if d.write > 0 {
+		d.rc.SetWriteDeadline(now.Add(d.write))
+	}
+}
+
+// streamingBody 读取请求体时顺延截止时间
+type streamingBody struct {
+	r         io.Reader
+	deadlines *deadlines
+}
+
+func (s *streamingBody) Read(p []byte) (int, error) {
+	s.deadlines.extend()
+	return s.r.Read(p)
+}
+
+// streamRequestBody 包装请求体，读取期间不受服务器 ReadTimeout 的总时长限制；
+// 写截止时间同样从请求开始计算，一并顺延，读完后仍然可以写出响应
+func streamRequestBody(ctx iris.Context, r io.Reader) io.Reader {
+	return &streamingBody{r: r, deadlines: newDeadlines(ctx, true)}
+}
+
+// streamingWriter 写出响应体时顺延写截止时间
+type streamingWriter struct {
+	http.ResponseWriter
+	deadlines *deadlines
+}
+
+func (s *streamingWriter) Write(p []byte) (int, error) {
+	s.deadlines.extend()
+	return s.ResponseWriter.Write(p)
+}
+
+// streamResponseWriter 包装响应，写出期间不受服务器 WriteTimeout 的总时长限制
+func streamResponseWriter(ctx iris.Context) http.ResponseWriter {
+	return &streamingWriter{ResponseWriter: ctx.ResponseWriter(), deadlines: newDeadlines(ctx, false)}
+}
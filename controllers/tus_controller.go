@@ -0,0 +1,246 @@
+package controllers
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"iris-cn-sample-project/models"
+	"iris-cn-sample-project/services"
+	"iris-cn-sample-project/upload"
+	"iris-cn-sample-project/utils"
+
+	"github.com/kataras/iris/v12"
+)
+
+// tus 1.0 协议常量
+const (
+	tusVersion            = "1.0.0"
+	tusExtensions         = "creation,expiration,checksum,termination"
+	tusChecksumAlgorithms = "md5,sha1,sha256"
+	tusContentType        = "application/offset+octet-stream"
+
+	// statusChecksumMismatch tus checksum 扩展定义的状态码
+	statusChecksumMismatch = 460
+)
+
+// tusHashes 支持的校验和算法
+var tusHashes = map[string]func() hash.Hash{
+	"md5":    md5.New,
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+}
+
+// TusOptions 返回服务端支持的 tus 版本、扩展与大小上限（不需要认证）
+func TusOptions(ctx iris.Context) {
+	header := ctx.ResponseWriter().Header()
+	header.Set("Tus-Resumable", tusVersion)
+	header.Set("Tus-Version", tusVersion)
+	header.Set("Tus-Extension", tusExtensions)
+	header.Set("Tus-Max-Size", strconv.FormatInt(upload.Resumable().MaxSize, 10))
+	header.Set("Tus-Checksum-Algorithm", tusChecksumAlgorithms)
+	ctx.StatusCode(iris.StatusNoContent)
+}
+
+// TusResumable 校验请求的 Tus-Resumable 版本，并在所有响应中带上 Tus-Resumable
+func TusResumable(ctx iris.Context) {
+	ctx.Header("Tus-Resumable", tusVersion)
+	if ctx.GetHeader("Tus-Resumable") != tusVersion {
+		ctx.Header("Tus-Version", tusVersion)
+		ctx.StopWithJSON(iris.StatusPreconditionFailed, iris.Map{
+			"code":       412,
+			"message":    "不支持的 Tus-Resumable 版本，仅支持 " + tusVersion,
+			"request_id": utils.RequestID(ctx),
+		})
+		return
+	}
+	ctx.Next()
+}
+
+// TusCreate 创建上传（creation 扩展），Upload-Metadata 中的 filename 作为原始文件名
+func TusCreate(ctx iris.Context) {
+	if ctx.GetHeader("Upload-Defer-Length") != "" {
+		writeError(ctx, iris.StatusBadRequest, "不支持 Upload-Defer-Length，请提供 Upload-Length")
+		return
+	}
+	length, err := strconv.ParseInt(ctx.GetHeader("Upload-Length"), 10, 64)
+	if err != nil || length < 0 {
+		writeError(ctx, iris.StatusBadRequest, "Upload-Length 无效")
+		return
+	}
+	rawMetadata := ctx.GetHeader("Upload-Metadata")
+	metadata, err := parseTusMetadata(rawMetadata)
+	if err != nil {
+		writeError(ctx, iris.StatusBadRequest, err.Error())
+		return
+	}
+	filename := metadata["filename"]
+	if filename == "" {
+		filename = metadata["name"]
+	}
+
+	userID := ctx.Values().GetUintDefault("user_id", 0)
+	u, err := services.CreateUpload(ctx.Request().Context(), userID, length, filename, rawMetadata)
+	if err != nil {
+		handleTusError(ctx, err)
+		return
+	}
+
+	ctx.Header("Location", strings.TrimSuffix(ctx.Path(), "/")+"/"+u.ID)
+	setTusUploadHeaders(ctx, u)
+	ctx.StatusCode(iris.StatusCreated)
+}
+
+// TusHead 返回上传的偏移量，客户端据此继续上传
+func TusHead(ctx iris.Context) {
+	ctx.Header("Cache-Control", "no-store")
+	userID := ctx.Values().GetUintDefault("user_id", 0)
+	u, err := services.GetUpload(ctx.Request().Context(), ctx.Params().Get("id"), userID)
+	if err != nil {
+		// HEAD 响应不能有响应体
+		ctx.StatusCode(tusErrorStatus(err))
+		return
+	}
+
+	ctx.Header("Upload-Length", strconv.FormatInt(u.Length, 10))
+	if u.Metadata != "" {
+		ctx.Header("Upload-Metadata", u.Metadata)
+	}
+	setTusUploadHeaders(ctx, u)
+	ctx.StatusCode(iris.StatusOK)
+}
+
+// TusPatch 在 Upload-Offset 处追加内容，全部收到后登记为文件并在 X-File-ID 中返回文件ID
+func TusPatch(ctx iris.Context) {
+	if ctx.GetContentTypeRequested() != tusContentType {
+		writeError(ctx, iris.StatusUnsupportedMediaType, "Content-Type 必须为 "+tusContentType)
+		return
+	}
+	offset, err := strconv.ParseInt(ctx.GetHeader("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		writeError(ctx, iris.StatusBadRequest, "Upload-Offset 无效")
+		return
+	}
+	checksum, err := parseTusChecksum(ctx.GetHeader("Upload-Checksum"))
+	if err != nil {
+		writeError(ctx, iris.StatusBadRequest, err.Error())
+		return
+	}
+
+	userID := ctx.Values().GetUintDefault("user_id", 0)
+	// 慢速网络上一次 PATCH 的时间可能超过服务器的 ReadTimeout
+	body := clientReader{streamRequestBody(ctx, ctx.Request().Body)}
+	u, _, err := services.AppendUpload(ctx.Request().Context(), ctx.Params().Get("id"), userID, offset, body, checksum)
+	if err != nil {
+		handleTusError(ctx, err)
+		return
+	}
+
+	setTusUploadHeaders(ctx, u)
+	ctx.StatusCode(iris.StatusNoContent)
+}
+
+// TusDelete 取消上传并删除已收到的内容（termination 扩展）
+func TusDelete(ctx iris.Context) {
+	userID := ctx.Values().GetUintDefault("user_id", 0)
+	if err := services.TerminateUpload(ctx.Request().Context(), ctx.Params().Get("id"), userID); err != nil {
+		handleTusError(ctx, err)
+		return
+	}
+	ctx.StatusCode(iris.StatusNoContent)
+}
+
+// TusMethodOverride 处理不支持 PATCH/DELETE 的客户端以 POST 加 X-HTTP-Method-Override 发送的请求
+func TusMethodOverride(ctx iris.Context) {
+	switch strings.ToUpper(ctx.GetHeader("X-HTTP-Method-Override")) {
+	case http.MethodPatch:
+		TusPatch(ctx)
+	case http.MethodDelete:
+		TusDelete(ctx)
+	default:
+		writeError(ctx, iris.StatusMethodNotAllowed, "不支持的请求方法")
+	}
+}
+
+// setTusUploadHeaders 设置上传进度相关的响应头
+func setTusUploadHeaders(ctx iris.Context, u *models.Upload) {
+	ctx.Header("Upload-Offset", strconv.FormatInt(u.Offset, 10))
+	ctx.Header("Upload-Expires", u.ExpiresAt.UTC().Format(http.TimeFormat))
+	if u.FileID != nil {
+		ctx.Header("X-File-ID", strconv.FormatUint(uint64(*u.FileID), 10))
+	}
+}
+
+// tusErrorStatus 可续传上传错误对应的状态码
+func tusErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrUploadNotFound):
+		return iris.StatusNotFound
+	case errors.Is(err, services.ErrUploadExpired):
+		return iris.StatusGone
+	case errors.Is(err, services.ErrUploadOffsetMismatch):
+		return iris.StatusConflict
+	case errors.Is(err, services.ErrUploadChecksumMismatch):
+		return statusChecksumMismatch
+	case errors.Is(err, services.ErrUploadLocked):
+		return iris.StatusLocked
+	default:
+		return 0
+	}
+}
+
+// handleTusError 返回可续传上传的错误；内容校验等上传错误与普通上传的处理方式相同
+func handleTusError(ctx iris.Context, err error) {
+	if status := tusErrorStatus(err); status != 0 {
+		writeError(ctx, status, err.Error())
+		return
+	}
+	writeUploadError(ctx, upload.Resumable(), err)
+}
+
+// parseTusMetadata 解析 Upload-Metadata：以逗号分隔的键值对，键与 Base64 编码的值以空格分隔，值可以省略
+func parseTusMetadata(header string) (map[string]string, error) {
+	metadata := make(map[string]string)
+	if strings.TrimSpace(header) == "" {
+		return metadata, nil
+	}
+	for _, pair := range strings.Split(header, ",") {
+		key, encoded, _ := strings.Cut(strings.TrimSpace(pair), " ")
+		if key == "" || strings.ContainsAny(key, " ,") {
+			return nil, errors.New("Upload-Metadata 格式错误")
+		}
+		if _, ok := metadata[key]; ok {
+			return nil, fmt.Errorf("Upload-Metadata 中的 %s 重复", key)
+		}
+		value, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("Upload-Metadata 中 %s 的值不是有效的 Base64", key)
+		}
+		metadata[key] = string(value)
+	}
+	return metadata, nil
+}
+
+// parseTusChecksum 解析 Upload-Checksum（算法名加空格加 Base64 编码的摘要），未提供时返回 nil
+func parseTusChecksum(header string) (*services.UploadChecksum, error) {
+	if header == "" {
+		return nil, nil
+	}
+	algorithm, encoded, _ := strings.Cut(header, " ")
+	newHash, ok := tusHashes[algorithm]
+	if !ok {
+		return nil, fmt.Errorf("不支持的校验和算法 %q，可选 %s", algorithm, tusChecksumAlgorithms)
+	}
+	expected, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(expected) != newHash().Size() {
+		return nil, errors.New("Upload-Checksum 格式错误")
+	}
+	return &services.UploadChecksum{Hash: newHash(), Expected: expected}, nil
+}
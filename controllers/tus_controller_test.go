@@ -0,0 +1,63 @@
+package controllers
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"iris-cn-sample-project/services"
+
+	"github.com/kataras/iris/v12"
+)
+
+func TestTusErrorStatus(t *testing.T) {
+	tests := map[error]int{
+		services.ErrUploadNotFound:         iris.StatusNotFound,
+		services.ErrUploadExpired:          iris.StatusGone,
+		services.ErrUploadOffsetMismatch:   iris.StatusConflict,
+		services.ErrUploadChecksumMismatch: statusChecksumMismatch,
+		services.ErrUploadLocked:           iris.StatusLocked,
+		errors.New("其他错误"):                 0,
+	}
+	for err, want := range tests {
+		wrapped := fmt.Errorf("%w: 已接收 10 字节", err)
+		if got := tusErrorStatus(wrapped); got != want {
+			t.Errorf("tusErrorStatus(%v) = %d, want %d", wrapped, got, want)
+		}
+	}
+}
+
+func TestParseTusMetadata(t *testing.T) {
+	got, err := parseTusMetadata("filename " + base64.StdEncoding.EncodeToString([]byte("报告.pdf")) + ", is_confidential")
+	want := map[string]string{"filename": "报告.pdf", "is_confidential": ""}
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("parseTusMetadata = %v, %v, want %v", got, err, want)
+	}
+
+	for _, header := range []string{"filename !!!", "a YQ==,a Yg==", ",filename YQ=="} {
+		if _, err := parseTusMetadata(header); err == nil {
+			t.Errorf("parseTusMetadata(%q) 应返回错误", header)
+		}
+	}
+}
+
+func TestParseTusChecksum(t *testing.T) {
+	if c, err := parseTusChecksum(""); c != nil || err != nil {
+		t.Errorf("未提供校验和: %v, %v", c, err)
+	}
+
+	sum := sha1.Sum([]byte("hello"))
+	c, err := parseTusChecksum("sha1 " + base64.StdEncoding.EncodeToString(sum[:]))
+	if err != nil || !reflect.DeepEqual(c.Expected, sum[:]) {
+		t.Fatalf("parseTusChecksum = %+v, %v", c, err)
+	}
+
+	for _, header := range []string{"crc32 AAAAAA==", "sha1 !!!", "sha1 " + base64.StdEncoding.EncodeToString(sum[:10])} {
+		if _, err := parseTusChecksum(header); err == nil {
+			t.Errorf("parseTusChecksum(%q) 应返回错误", header)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"iris-cn-sample-project/export"
+	"iris-cn-sample-project/logging"
+	"iris-cn-sample-project/mailer"
+	"iris-cn-sample-project/models"
+	"iris-cn-sample-project/services"
+	"iris-cn-sample-project/utils"
+
+	"github.com/kataras/iris/v12"
+)
+
+// maxImportSize 导入文件的大小上限
+const maxImportSize = 10 << 20
+
+// importContentTypes 根据 Content-Type 确定导入格式
+var importContentTypes = map[string]string{
+	"text/csv":         services.ImportCSV,
+	"application/csv":  services.ImportCSV,
+	"application/json": services.ImportJSON,
+}
+
+// ImportUsers 批量导入用户（仅管理员）
+//
+// 文件可以放在 multipart 表单的 file 字段中，也可以直接作为请求体；格式依次由 format 参数、文件扩展名、
+// Content-Type 确定（csv 或 json）。参数：dry_run 试运行；mode 为 atomic（默认，任一行失败时不创建任何用户）
+// 或 best_effort（跳过失败的行）；invite 向创建的用户发送邀请邮件，此时可以不提供密码。
+func ImportUsers(ctx iris.Context) {
+	opts, ok := importOptions(ctx)
+	if !ok {
+		return
+	}
+
+	ctx.Request().Body = http.MaxBytesReader(ctx.ResponseWriter(), ctx.Request().Body, maxImportSize)
+	var body io.Reader = ctx.Request().Body
+	format := ctx.URLParam("format")
+	mediaType, _, _ := mime.ParseMediaType(ctx.GetHeader("Content-Type"))
+	if mediaType == "multipart/form-data" {
+		part, err := nextFilePart(ctx, "file")
+		if err != nil {
+			if !errors.Is(err, errNoUploadFile) {
+				err = fmt.Errorf("%w: %w", errBadUpload, err)
+			}
+			writeImportError(ctx, err)
+			return
+		}
+		defer part.Close()
+		body = part
+		if format == "" {
+			format = strings.TrimPrefix(strings.ToLower(filepath.Ext(part.FileName())), ".")
+		}
+	} else if format == "" {
+		format = importContentTypes[mediaType]
+	}
+	if format != services.ImportCSV && format != services.ImportJSON {
+		writeError(ctx, iris.StatusUnsupportedMediaType, "无法确定导入文件的格式，请上传 .csv 或 .json 文件，或使用 format 参数指定 csv、json")
+		return
+	}
+
+	rows, err := services.ParseUserImport(body, format)
+	if err != nil {
+		writeImportError(ctx, err)
+		return
+	}
+	result, err := services.ImportUsers(ctx.Request().Context(), rows, opts)
+	if err != nil {
+		writeImportError(ctx, err)
+		return
+	}
+
+	status, message := iris.StatusOK, "导入完成"
+	switch {
+	case opts.DryRun:
+		message = "试运行完成，没有创建用户"
+	case result.Failed > 0 && result.Created == 0:
+		status, message = iris.StatusUnprocessableEntity, "导入失败，没有创建用户"
+	case result.Failed > 0:
+		message = "部分用户导入失败"
+	}
+	ctx.StopWithJSON(status, models.NewResponse(status, message, result))
+}
+
+// importOptions 解析 dry_run、mode、invite 参数，参数无效时写入 400 响应并返回 false
+func importOptions(ctx iris.Context) (services.ImportOptions, bool) {
+	opts := services.ImportOptions{Mode: ctx.URLParamDefault("mode", services.ImportAtomic)}
+	if opts.Mode != services.ImportAtomic && opts.Mode != services.ImportBestEffort {
+		writeError(ctx, iris.StatusBadRequest, "mode 必须是 "+services.ImportAtomic+" 或 "+services.ImportBestEffort)
+		return opts, false
+	}
+	for name, value := range map[string]*bool{"dry_run": &opts.DryRun, "invite": &opts.Invite} {
+		if !ctx.URLParamExists(name) {
+			continue
+		}
+		v, err := strconv.ParseBool(ctx.URLParamDefault(name, "true"))
+		if err != nil {
+			writeError(ctx, iris.StatusBadRequest, name+" 必须是 true 或 false")
+			return opts, false
+		}
+		*value = v
+	}
+	return opts, true
+}
+
+// writeImportError 根据导入失败的原因返回对应的状态码与提示
+func writeImportError(ctx iris.Context, err error) {
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		writeError(ctx, iris.StatusRequestEntityTooLarge, "导入文件大小不能超过 "+strconv.Itoa(maxImportSize>>20)+"MB")
+	case errors.Is(err, errNoUploadFile):
+		writeError(ctx, iris.StatusBadRequest, "请在表单的 file 字段中上传导入文件")
+	case errors.Is(err, errBadUpload):
+		writeError(ctx, iris.StatusBadRequest, err.Error())
+	case errors.Is(err, mailer.ErrNotConfigured):
+		writeError(ctx, iris.StatusBadRequest, "未配置邮件服务器，无法发送邀请邮件")
+	case errors.Is(err, services.ErrInvalidImport):
+		writeError(ctx, iris.StatusBadRequest, err.Error())
+	default:
+		logging.L().ErrorContext(ctx.Request().Context(), "批量导入用户失败", "error", err)
+		writeError(ctx, iris.StatusInternalServerError, "批量导入用户失败: "+err.Error())
+	}
+}
+
+// ExportUsers 导出用户（仅管理员），边查询边写出，不会把全部用户加载到内存
+//
+// format 为 csv（默认）、jsonl 或 xlsx；过滤与排序参数与 GET /api/users 相同，fields 选择导出的列（默认全部）。
+func ExportUsers(ctx iris.Context) {
+	format := ctx.URLParamDefault("format", export.FormatCSV)
+	if export.ContentType(format) == "" {
+		writeError(ctx, iris.StatusBadRequest, "format 必须是 "+strings.Join(export.Formats(), "、")+" 之一")
+		return
+	}
+	filter, ok := userFilterParams(ctx)
+	if !ok {
+		return
+	}
+	fields, err := models.UserInfoFields.Parse(ctx.URLParam("fields"))
+	if err != nil {
+		writeError(ctx, iris.StatusBadRequest, err.Error())
+		return
+	}
+	filter.Columns = models.UserInfoFields.Columns(fields)
+	if len(fields) == 0 {
+		fields = models.UserInfoFields.Names()
+	}
+
+	// 查到第一个用户（或确认没有用户）后才写出响应头，查询失败时仍然可以返回错误响应；
+	// 导出大量用户的时间可能超过服务器的 WriteTimeout，写出期间顺延截止时间
+	var w export.Writer
+	start := func() (err error) {
+		header := ctx.ResponseWriter().Header()
+		header.Set("Content-Type", export.ContentType(format))
+		header.Set("Content-Disposition", utils.ContentDisposition("attachment", "users-"+time.Now().Format("20060102")+"."+format))
+		header.Set("Cache-Control", "no-store")
+		w, err = export.NewWriter(streamResponseWriter(ctx), format, fields)
+		return err
+	}
+	count := 0
+	err = services.ExportUsers(ctx.Request().Context(), filter, func(user *models.UserInfo) error {
+		if w == nil {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+		count++
+		return w.Write(models.UserInfoFields.Project(user, fields))
+	})
+	if err == nil && w == nil {
+		err = start()
+	}
+	if err == nil {
+		err = w.Close()
+	}
+	if err != nil {
+		if w == nil {
+			writeError(ctx, iris.StatusInternalServerError, "导出用户失败: "+err.Error())
+			return
+		}
+		// 响应已经开始，无法再返回错误，客户端会收到不完整的文件
+		logging.L().ErrorContext(ctx.Request().Context(), "导出用户中断", "format", format, "rows", count, "error", err)
+		return
+	}
+	logging.L().InfoContext(ctx.Request().Context(), "导出用户", "format", format, "rows", count)
+}
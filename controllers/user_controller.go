@@ -1,20 +1,22 @@
 package controllers
 
 import (
+    "errors"
     "fmt"
     "io"
     "mime/multipart"
     "net/http"
     "os"
-    "path/filepath"
-    "strconv"
     "strings"
     "time"
 
     "iris-cn-sample-project/database"
+    "iris-cn-sample-project/logging"
     "iris-cn-sample-project/middleware"
     "iris-cn-sample-project/models"
     "iris-cn-sample-project/services"
+    "iris-cn-sample-project/upload"
+    "iris-cn-sample-project/utils"
 
     "github.com/kataras/iris/v12"
 )
@@ -68,8 +70,9 @@ func GetData(ctx iris.Context) {
     id, err := ctx.Params().GetInt("id")
     if err != nil {
         ctx.JSON(iris.Map{
-            "code":    400,
-            "message": "无效的ID参数",
+            "code":       400,
+            "message":    "无效的ID参数",
+            "request_id": utils.RequestID(ctx),
         })
         return
     }
@@ -103,8 +106,9 @@ func HandleForm(ctx iris.Context) {
     // 绑定表单数据
     if err := ctx.ReadForm(&formData); err != nil {
         ctx.JSON(iris.Map{
-            "code":    400,
-            "message": "表单数据解析失败: " + err.Error(),
+            "code":       400,
+            "message":    "表单数据解析失败: " + err.Error(),
+            "request_id": utils.RequestID(ctx),
         })
         return
     }
@@ -117,58 +121,33 @@ func HandleForm(ctx iris.Context) {
     })
 }
 
-// UploadFile 文件上传接口
-func UploadFile(ctx iris.Context) {
-    // 获取上传的文件
-    file, info, err := ctx.FormFile("file")
-    if err != nil {
-        ctx.JSON(iris.Map{
-            "code":    400,
-            "message": "文件上传失败: " + err.Error(),
-        })
-        return
-    }
-    defer file.Close()
+// uploadURLExpiration 上传结果中下载地址的有效期
+const uploadURLExpiration = time.Hour
 
-    // 验证文件类型
-    if !isValidFileType(info.Filename) {
-        ctx.JSON(iris.Map{
-            "code":    400,
-            "message": "不支持的文件类型",
-        })
-        return
-    }
+// uploadFormOverhead 上传请求中文件以外的部分（multipart 边界、其他表单字段）允许的大小
+const uploadFormOverhead = 64 << 10
 
-    // 验证文件大小（最大 10MB）
-    if info.Size > 10*1024*1024 {
-        ctx.JSON(iris.Map{
-            "code":    400,
-            "message": "文件大小不能超过 10MB",
-        })
+// UploadFile 文件上传接口
+func UploadFile(ctx iris.Context) {
+    saved, ok := receiveUpload(ctx, upload.PurposeAttachment)
+    if !ok {
         return
     }
 
-    // 保存文件
-    filename := generateUniqueFilename(info.Filename)
-    savePath := filepath.Join("static/uploads", filename)
-    
-    if err := saveUploadedFile(file, savePath); err != nil {
-        ctx.JSON(iris.Map{
-            "code":    500,
-            "message": "文件保存失败: " + err.Error(),
-        })
-        return
-    }
+    // 生成带签名的下载地址，匿名上传者也可以凭该地址下载
+    url, _ := services.FileDownloadURL(saved, uploadURLExpiration)
 
     // 返回上传结果
     ctx.JSON(iris.Map{
         "code":    200,
         "message": "文件上传成功",
         "data": iris.Map{
-            "filename": filename,
-            "original": info.Filename,
-            "size":     info.Size,
-            "url":      "/static/uploads/" + filename,
+            "id":           saved.ID,
+            "filename":     saved.Key,
+            "original":     saved.OriginalName,
+            "size":         saved.Size,
+            "content_type": saved.ContentType,
+            "url":          url,
         },
     })
 }
@@ -197,8 +176,9 @@ func UpdateProfile(ctx iris.Context) {
     userID := ctx.Values().GetUintDefault("user_id", 0)
     if userID == 0 {
         ctx.JSON(iris.Map{
-            "code":    401,
-            "message": "无效的用户信息",
+            "code":       401,
+            "message":    "无效的用户信息",
+            "request_id": utils.RequestID(ctx),
         })
         return
     }
@@ -207,18 +187,20 @@ func UpdateProfile(ctx iris.Context) {
     var updateData models.UpdateUserRequest
     if err := ctx.ReadJSON(&updateData); err != nil {
         ctx.JSON(iris.Map{
-            "code":    400,
-            "message": "请求数据格式错误: " + err.Error(),
+            "code":       400,
+            "message":    "请求数据格式错误: " + err.Error(),
+            "request_id": utils.RequestID(ctx),
         })
         return
     }
 
     // 调用服务层更新用户
-    user, err := services.UpdateUser(userID, &updateData)
+    user, err := services.UpdateUser(ctx.Request().Context(), userID, &updateData)
     if err != nil {
         ctx.JSON(iris.Map{
-            "code":    500,
-            "message": "更新用户资料失败: " + err.Error(),
+            "code":       500,
+            "message":    "更新用户资料失败: " + err.Error(),
+            "request_id": utils.RequestID(ctx),
         })
         return
     }
@@ -232,29 +214,56 @@ func UpdateProfile(ctx iris.Context) {
 
 // GetUsers 获取用户列表（需要管理员权限）
 func GetUsers(ctx iris.Context) {
-    // 获取分页参数
-    page, _ := strconv.Atoi(ctx.URLParamDefault("page", "1"))
-    pageSize, _ := strconv.Atoi(ctx.URLParamDefault("page_size", "10"))
-    
-    if page < 1 {
-        page = 1
+    // 解析过滤与排序参数
+    filter, ok := userFilterParams(ctx)
+    if !ok {
+        return
     }
-    if pageSize < 1 || pageSize > 100 {
-        pageSize = 10
+
+    // 解析 fields 与 expand 参数，只查询所选字段对应的列
+    view, ok := userViewParams(ctx)
+    if !ok {
+        return
+    }
+    filter.Columns = view.columns()
+
+    // 带 cursor 或 limit 参数时使用游标分页
+    if cursorRequested(ctx) {
+        listUsersByCursor(ctx, filter, view)
+        return
+    }
+
+    // 获取分页参数，超出范围时返回 400
+    page, pageSize, ok := pageParams(ctx, defaultUserPageSize, maxUserPageSize)
+    if !ok {
+        return
     }
 
     // 调用服务层获取用户列表
-    users, total, err := services.GetUsers(page, pageSize)
+    users, total, err := services.SearchUsers(ctx.Request().Context(), filter, page, pageSize)
     if err != nil {
         ctx.JSON(iris.Map{
-            "code":    500,
-            "message": "获取用户列表失败: " + err.Error(),
+            "code":       500,
+            "message":    "获取用户列表失败: " + err.Error(),
+            "request_id": utils.RequestID(ctx),
+        })
+        return
+    }
+
+    // 按所选字段与关联数据生成响应数据
+    data, err := view.render(ctx.Request().Context(), users)
+    if err != nil {
+        ctx.JSON(iris.Map{
+            "code":       500,
+            "message":    "获取用户列表失败: " + err.Error(),
+            "request_id": utils.RequestID(ctx),
         })
         return
     }
 
     // 返回分页响应
-    ctx.JSON(models.NewPageResponse(200, "获取用户列表成功", users, page, pageSize, total))
+    setPageLinks(ctx, page, pageSize, total)
+    ctx.JSON(models.NewPageResponse(200, "获取用户列表成功", data, page, pageSize, total))
 }
 
 // GetUser 获取单个用户信息
@@ -263,23 +272,40 @@ func GetUser(ctx iris.Context) {
     userID, err := ctx.Params().GetInt("id")
     if err != nil {
         ctx.JSON(iris.Map{
-            "code":    400,
-            "message": "无效的用户ID",
+            "code":       400,
+            "message":    "无效的用户ID",
+            "request_id": utils.RequestID(ctx),
         })
         return
     }
 
-    // 调用服务层获取用户
-    user, err := services.GetUserByID(userID)
+    // 解析 fields 与 expand 参数
+    view, ok := userViewParams(ctx)
+    if !ok {
+        return
+    }
+
+    // 调用服务层获取用户，只查询所选字段对应的列
+    user, err := services.GetUserByID(ctx.Request().Context(), userID, view.columns()...)
     if err != nil {
         ctx.JSON(iris.Map{
-            "code":    404,
-            "message": "用户不存在",
+            "code":       404,
+            "message":    "用户不存在",
+            "request_id": utils.RequestID(ctx),
         })
         return
     }
 
-    ctx.JSON(models.NewResponse(200, "获取用户信息成功", user))
+    data, err := view.renderOne(ctx.Request().Context(), user)
+    if err != nil {
+        ctx.JSON(iris.Map{
+            "code":       500,
+            "message":    "获取用户信息失败: " + err.Error(),
+            "request_id": utils.RequestID(ctx),
+        })
+        return
+    }
+    ctx.JSON(models.NewResponse(200, "获取用户信息成功", data))
 }
 
 // UpdateUser 更新用户信息
@@ -288,8 +314,9 @@ func UpdateUser(ctx iris.Context) {
     userID, err := ctx.Params().GetInt("id")
     if err != nil {
         ctx.JSON(iris.Map{
-            "code":    400,
-            "message": "无效的用户ID",
+            "code":       400,
+            "message":    "无效的用户ID",
+            "request_id": utils.RequestID(ctx),
         })
         return
     }
@@ -298,18 +325,20 @@ func UpdateUser(ctx iris.Context) {
     var updateData models.UpdateUserRequest
     if err := ctx.ReadJSON(&updateData); err != nil {
         ctx.JSON(iris.Map{
-            "code":    400,
-            "message": "请求数据格式错误: " + err.Error(),
+            "code":       400,
+            "message":    "请求数据格式错误: " + err.Error(),
+            "request_id": utils.RequestID(ctx),
         })
         return
     }
 
     // 调用服务层更新用户
-    user, err := services.UpdateUser(userID, &updateData)
+    user, err := services.UpdateUser(ctx.Request().Context(), userID, &updateData)
     if err != nil {
         ctx.JSON(iris.Map{
-            "code":    500,
-            "message": "更新用户失败: " + err.Error(),
+            "code":       500,
+            "message":    "更新用户失败: " + err.Error(),
+            "request_id": utils.RequestID(ctx),
         })
         return
     }
@@ -323,17 +352,19 @@ func DeleteUser(ctx iris.Context) {
     userID, err := ctx.Params().GetInt("id")
     if err != nil {
         ctx.JSON(iris.Map{
-            "code":    400,
-            "message": "无效的用户ID",
+            "code":       400,
+            "message":    "无效的用户ID",
+            "request_id": utils.RequestID(ctx),
         })
         return
     }
 
     // 调用服务层删除用户
-    if err := services.DeleteUser(userID); err != nil {
+    if err := services.DeleteUser(ctx.Request().Context(), userID); err != nil {
         ctx.JSON(iris.Map{
-            "code":    500,
-            "message": "删除用户失败: " + err.Error(),
+            "code":       500,
+            "message":    "删除用户失败: " + err.Error(),
+            "request_id": utils.RequestID(ctx),
         })
         return
     }
@@ -344,7 +375,7 @@ func DeleteUser(ctx iris.Context) {
 // UsersPage 用户列表页面
 func UsersPage(ctx iris.Context) {
     // 获取用户列表
-    users, _, err := services.GetUsers(1, 50)
+    users, _, err := services.GetUsers(ctx.Request().Context(), 1, 50)
     if err != nil {
         ctx.ViewData("error", "获取用户列表失败")
         ctx.View("users.html")
@@ -367,7 +398,7 @@ func UserPage(ctx iris.Context) {
     }
 
     // 获取用户信息
-    user, err := services.GetUserByID(userID)
+    user, err := services.GetUserByID(ctx.Request().Context(), userID)
     if err != nil {
         ctx.ViewData("error", "用户不存在")
         ctx.View("user.html")
@@ -384,6 +415,7 @@ func NotFound(ctx iris.Context) {
     ctx.ViewData("title", "页面未找到")
     ctx.ViewData("message", "抱歉，您访问的页面不存在")
     ctx.ViewData("code", "404")
+    ctx.ViewData("request_id", utils.RequestID(ctx))
     ctx.View("error.html")
 }
 
@@ -392,6 +424,7 @@ func InternalServerError(ctx iris.Context) {
     ctx.ViewData("title", "服务器错误")
     ctx.ViewData("message", "服务器内部错误，请稍后重试")
     ctx.ViewData("code", "500")
+    ctx.ViewData("request_id", utils.RequestID(ctx))
     ctx.View("error.html")
 }
 
@@ -426,43 +459,114 @@ func APIDocs(ctx iris.Context) {
 
 // 辅助函数
 
-// isValidFileType 检查文件类型是否有效
-func isValidFileType(filename string) bool {
-    ext := strings.ToLower(filepath.Ext(filename))
-    validExts := []string{".jpg", ".jpeg", ".png", ".gif", ".pdf", ".doc", ".docx", ".txt"}
-    
-    for _, validExt := range validExts {
-        if ext == validExt {
-            return true
+// errBadUpload 上传请求不完整或格式错误（客户端错误）
+var errBadUpload = errors.New("文件上传失败")
+
+// errNoUploadFile 请求中没有上传文件
+var errNoUploadFile = errors.New("请求中没有上传文件")
+
+// receiveUpload 流式读取 multipart 请求中的 file 字段并按用途保存，失败时写入错误响应并返回 false
+func receiveUpload(ctx iris.Context, purpose string) (*models.File, bool) {
+    policy, _ := upload.Lookup(purpose)
+    part, ok := openUploadPart(ctx, policy)
+    if !ok {
+        return nil, false
+    }
+    defer part.Close()
+
+    userID := ctx.Values().GetUintDefault("user_id", 0)
+    saved, err := services.SaveFile(ctx.Request().Context(), clientReader{part}, part.FileName(), policy, userID, utils.ClientIP(ctx))
+    if err != nil {
+        writeUploadError(ctx, policy, err)
+        return nil, false
+    }
+    return saved, true
+}
+
+// openUploadPart 按策略限制请求体大小，返回 multipart 请求中的 file 字段，失败时写入错误响应并返回 false
+//
+// 不使用 ctx.FormFile：它会先把整个请求体解析到内存或临时文件，之后才能检查大小。
+func openUploadPart(ctx iris.Context, policy *upload.Policy) (*multipart.Part, bool) {
+    // 限制整个请求体，超出时读取立即失败
+    ctx.Request().Body = http.MaxBytesReader(ctx.ResponseWriter(), ctx.Request().Body, policy.MaxSize+uploadFormOverhead)
+
+    part, err := nextFilePart(ctx, "file")
+    if err != nil {
+        writeUploadError(ctx, policy, fmt.Errorf("%w: %w", errBadUpload, err))
+        return nil, false
+    }
+    return part, true
+}
+
+// nextFilePart 跳过其他表单字段，返回名为 name 的文件字段
+func nextFilePart(ctx iris.Context, name string) (*multipart.Part, error) {
+    reader, err := ctx.Request().MultipartReader()
+    if err != nil {
+        return nil, err
+    }
+    for {
+        part, err := reader.NextPart()
+        if err == io.EOF {
+            return nil, errNoUploadFile
+        }
+        if err != nil {
+            return nil, err
+        }
+        if part.FormName() == name && part.FileName() != "" {
+            return part, nil
         }
+        part.Close()
     }
-    return false
 }
 
-// generateUniqueFilename 生成唯一的文件名
-func generateUniqueFilename(originalFilename string) string {
-    ext := filepath.Ext(originalFilename)
-    name := strings.TrimSuffix(originalFilename, ext)
-    return fmt.Sprintf("%s_%d%s", name, 123456789, ext) // 简化的唯一文件名生成
+// clientReader 将读取请求体时的错误标记为 errBadUpload，与服务端错误区分
+type clientReader struct {
+    r io.Reader
 }
 
-// saveUploadedFile 保存上传的文件
-func saveUploadedFile(file multipart.File, dst string) error {
-    // 确保目录存在
-    if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
-        return err
+func (c clientReader) Read(p []byte) (int, error) {
+    n, err := c.r.Read(p)
+    if err != nil && err != io.EOF {
+        err = fmt.Errorf("%w: %w", errBadUpload, err)
     }
+    return n, err
+}
 
-    // 创建目标文件
-    f, err := os.Create(dst)
-    if err != nil {
-        return err
+// writeUploadError 根据上传失败的原因返回对应的状态码与提示
+func writeUploadError(ctx iris.Context, policy *upload.Policy, err error) {
+    var tooLarge *http.MaxBytesError
+    status, message := iris.StatusBadRequest, err.Error()
+
+    switch {
+    case errors.Is(err, upload.ErrTooLarge), errors.As(err, &tooLarge):
+        status, message = iris.StatusRequestEntityTooLarge, "文件大小不能超过 "+policy.MaxSizeText()
+    case errors.Is(err, upload.ErrQuotaExceeded):
+        status, message = iris.StatusRequestEntityTooLarge, err.Error()
+    case errors.Is(err, upload.ErrTypeNotAllowed):
+        status, message = iris.StatusUnsupportedMediaType, err.Error()
+    case errors.Is(err, upload.ErrEmpty):
+        message = upload.ErrEmpty.Error()
+    case errors.Is(err, upload.ErrSuspicious):
+        status, message = iris.StatusUnprocessableEntity, upload.ErrSuspicious.Error()
+    case errors.Is(err, upload.ErrInfected):
+        status, message = iris.StatusUnprocessableEntity, upload.ErrInfected.Error()
+    case errors.Is(err, upload.ErrScanUnavailable):
+        status, message = iris.StatusServiceUnavailable, "文件安全扫描暂不可用，请稍后重试"
+    case errors.Is(err, errNoUploadFile), errors.Is(err, http.ErrNotMultipart), errors.Is(err, http.ErrMissingBoundary):
+        message = errBadUpload.Error() + ": " + errNoUploadFile.Error()
+    case errors.Is(err, errBadUpload):
+        // 客户端中途断开或 multipart 格式错误
+    default:
+        logging.L().ErrorContext(ctx.Request().Context(), "保存上传文件失败", "error", err)
+        status, message = iris.StatusInternalServerError, "文件保存失败"
     }
-    defer f.Close()
 
-    // 复制文件内容
-    _, err = io.Copy(f, file)
-    return err
+    ctx.StatusCode(status)
+    ctx.JSON(iris.Map{
+        "code":       status,
+        "message":    message,
+        "request_id": utils.RequestID(ctx),
+    })
 }
 
 // createDirIfNotExists 创建目录（如果不存在）
@@ -0,0 +1,238 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"iris-cn-sample-project/models"
+	"iris-cn-sample-project/services"
+
+	"github.com/kataras/iris/v12"
+)
+
+// maxSearchKeywordLength 搜索关键字的最大长度（字符数）
+const maxSearchKeywordLength = 100
+
+// 用户列表每页的默认与最大条数
+const (
+	defaultUserPageSize = 10
+	maxUserPageSize     = 100
+)
+
+// SearchUsers 搜索用户（需要认证）
+//
+// q 为必填的关键字，在用户名、邮箱、姓名中搜索，默认按相关度排序；过滤、排序与分页参数同 GET /api/users。
+func SearchUsers(ctx iris.Context) {
+	if strings.TrimSpace(ctx.URLParam("q")) == "" {
+		writeError(ctx, iris.StatusBadRequest, "缺少搜索关键字 q")
+		return
+	}
+	filter, ok := userFilterParams(ctx)
+	if !ok {
+		return
+	}
+	view, ok := userViewParams(ctx)
+	if !ok {
+		return
+	}
+	filter.Columns = view.columns()
+	if cursorRequested(ctx) {
+		listUsersByCursor(ctx, filter, view)
+		return
+	}
+	page, pageSize, ok := pageParams(ctx, defaultUserPageSize, maxUserPageSize)
+	if !ok {
+		return
+	}
+
+	users, total, err := services.SearchUsers(ctx.Request().Context(), filter, page, pageSize)
+	if err != nil {
+		writeError(ctx, iris.StatusInternalServerError, "搜索用户失败: "+err.Error())
+		return
+	}
+	data, err := view.render(ctx.Request().Context(), users)
+	if err != nil {
+		writeError(ctx, iris.StatusInternalServerError, "搜索用户失败: "+err.Error())
+		return
+	}
+	setPageLinks(ctx, page, pageSize, total)
+	ctx.JSON(models.NewPageResponse(200, "搜索用户成功", data, page, pageSize, total))
+}
+
+// listUsersByCursor 游标分页查询用户，只能按注册时间排序（默认倒序，有关键字时也不按相关度排序）
+func listUsersByCursor(ctx iris.Context, filter services.UserFilter, view userView) {
+	if !services.CursorSortable(filter.Sort) {
+		writeError(ctx, iris.StatusBadRequest, "游标分页只支持按 created_at 或 -created_at 排序")
+		return
+	}
+	cur, limit, ok := cursorParams(ctx, defaultUserPageSize, maxUserPageSize)
+	if !ok {
+		return
+	}
+
+	users, next, prev, err := services.SearchUsersByCursor(ctx.Request().Context(), filter, cur, limit)
+	if err != nil {
+		writeError(ctx, iris.StatusInternalServerError, "获取用户列表失败: "+err.Error())
+		return
+	}
+	data, err := view.render(ctx.Request().Context(), users)
+	if err != nil {
+		writeError(ctx, iris.StatusInternalServerError, "获取用户列表失败: "+err.Error())
+		return
+	}
+	writeCursorPage(ctx, "获取用户列表成功", data, limit, next, prev)
+}
+
+// userView 用户信息的表示：fields 为响应中包含的字段（为空时全部），expand 为附加的关联数据
+type userView struct {
+	fields []string
+	expand []string
+}
+
+// userViewParams 解析 fields 与 expand 参数，参数无效时写入 400（无权展开时为 403）响应并返回 false
+//
+//   - fields：逗号分隔的字段，只能是 models.UserInfo 中的字段，如 id,username,avatar
+//   - expand：逗号分隔的关联数据，见 services.UserExpansions
+func userViewParams(ctx iris.Context) (userView, bool) {
+	fields, err := models.UserInfoFields.Parse(ctx.URLParam("fields"))
+	if err != nil {
+		writeError(ctx, iris.StatusBadRequest, err.Error())
+		return userView{}, false
+	}
+	expand, err := services.ParseUserExpand(ctx.URLParam("expand"), ctx.Values().GetStringDefault("role", ""))
+	if err != nil {
+		status := iris.StatusBadRequest
+		if errors.Is(err, services.ErrExpandForbidden) {
+			status = iris.StatusForbidden
+		}
+		writeError(ctx, status, err.Error())
+		return userView{}, false
+	}
+	return userView{fields: fields, expand: expand}, true
+}
+
+// columns 所选字段需要查询的列，未选择字段时为 nil（全部列）
+func (v userView) columns() []string {
+	return models.UserInfoFields.Columns(v.fields)
+}
+
+// render 生成响应数据：只保留所选字段并附加关联数据；两个参数都未指定时原样返回
+func (v userView) render(ctx context.Context, users []*models.UserInfo) (interface{}, error) {
+	if len(v.fields) == 0 && len(v.expand) == 0 {
+		return users, nil
+	}
+	var expanded map[string]map[uint]interface{}
+	if len(v.expand) > 0 {
+		ids := make([]uint, len(users))
+		for i, user := range users {
+			ids[i] = user.ID
+		}
+		var err error
+		if expanded, err = services.ExpandUsers(ctx, ids, v.expand); err != nil {
+			return nil, err
+		}
+	}
+
+	items := make([]map[string]interface{}, len(users))
+	for i, user := range users {
+		item := models.UserInfoFields.Project(user, v.fields)
+		for name, data := range expanded {
+			item[name] = data[user.ID]
+		}
+		items[i] = item
+	}
+	return items, nil
+}
+
+// renderOne 生成单个用户的响应数据
+func (v userView) renderOne(ctx context.Context, user *models.UserInfo) (interface{}, error) {
+	data, err := v.render(ctx, []*models.UserInfo{user})
+	if items, ok := data.([]map[string]interface{}); ok {
+		return items[0], err
+	}
+	return user, err
+}
+
+// userFilterParams 解析用户列表的过滤与排序参数，参数无效时写入 400 响应并返回 false
+//
+//   - q：关键字，在用户名、邮箱、姓名中搜索，多个词之间为“且”
+//   - role：admin 或 user
+//   - status：active（默认）、inactive 或 all
+//   - created_from、created_to：注册时间范围（含），RFC 3339 时间或 YYYY-MM-DD 日期（服务器时区，created_to 含当天）
+//   - sort：逗号分隔的排序字段，前加 "-" 表示倒序，如 role,-created_at
+func userFilterParams(ctx iris.Context) (services.UserFilter, bool) {
+	var filter services.UserFilter
+
+	filter.Keyword = strings.TrimSpace(ctx.URLParam("q"))
+	if utf8.RuneCountInString(filter.Keyword) > maxSearchKeywordLength {
+		writeError(ctx, iris.StatusBadRequest, "搜索关键字过长")
+		return filter, false
+	}
+	switch role := ctx.URLParam("role"); role {
+	case "", "admin", "user":
+		filter.Role = role
+	default:
+		writeError(ctx, iris.StatusBadRequest, "role 必须是 admin 或 user")
+		return filter, false
+	}
+	switch status := ctx.URLParamDefault("status", "active"); status {
+	case "active", "inactive":
+		filter.Status = status
+	case "all":
+	default:
+		writeError(ctx, iris.StatusBadRequest, "status 必须是 active、inactive 或 all")
+		return filter, false
+	}
+
+	var ok bool
+	if filter.CreatedFrom, ok = dateParam(ctx, "created_from", false); !ok {
+		return filter, false
+	}
+	if filter.CreatedTo, ok = dateParam(ctx, "created_to", true); !ok {
+		return filter, false
+	}
+	if filter.CreatedFrom != nil && filter.CreatedTo != nil && filter.CreatedFrom.After(*filter.CreatedTo) {
+		writeError(ctx, iris.StatusBadRequest, "created_from 不能晚于 created_to")
+		return filter, false
+	}
+
+	sorts, err := services.ParseUserSort(ctx.URLParam("sort"))
+	if err != nil {
+		writeError(ctx, iris.StatusBadRequest, err.Error())
+		return filter, false
+	}
+	for _, s := range sorts {
+		if s.Field == services.UserSortRelevance && filter.Keyword == "" {
+			writeError(ctx, iris.StatusBadRequest, "按相关度排序需要搜索关键字 q")
+			return filter, false
+		}
+	}
+	filter.Sort = sorts
+	return filter, true
+}
+
+// dateParam 解析时间参数，YYYY-MM-DD 格式的日期在 endOfDay 为 true 时取当天最后一刻
+//
+// 时间统一转换为服务器时区，与数据库中保存的时间格式一致（SQLite 按文本比较时间）。
+func dateParam(ctx iris.Context, name string, endOfDay bool) (*time.Time, bool) {
+	value := ctx.URLParam(name)
+	if value == "" {
+		return nil, true
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		t = t.In(time.Local)
+		return &t, true
+	}
+	t, err := time.ParseInLocation(time.DateOnly, value, time.Local)
+	if err != nil {
+		writeError(ctx, iris.StatusBadRequest, name+" 必须是 RFC 3339 时间或 YYYY-MM-DD 日期")
+		return nil, false
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+	return &t, true
+}
@@ -1,15 +1,20 @@
 package database
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"time"
 
 	"iris-cn-sample-project/config"
+	"iris-cn-sample-project/health"
+	"iris-cn-sample-project/logging"
+	"iris-cn-sample-project/metrics"
 	"iris-cn-sample-project/models"
+	"iris-cn-sample-project/requestid"
+	"iris-cn-sample-project/tracing"
 
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
 )
 
 var DB *gorm.DB
@@ -18,17 +23,14 @@ var DB *gorm.DB
 func InitDB() error {
 	cfg := config.GetConfig()
 	
-	// 配置数据库连接
+	// 配置数据库连接（SQL 日志输出到结构化日志，只记录带占位符的语句，不内联绑定的参数）
 	var err error
-	logLevel := logger.Silent
-	if cfg.Log.Level == "debug" {
-		logLevel = logger.Info
-	}
+	gormLogger := logging.NewGormLogger(time.Duration(cfg.Log.SlowSQLMillis) * time.Millisecond)
 
 	switch cfg.Database.Driver {
 	case "sqlite":
 		DB, err = gorm.Open(sqlite.Open(cfg.Database.Database), &gorm.Config{
-			Logger: logger.Default.LogMode(logLevel),
+			Logger: gormLogger,
 		})
 	default:
 		return fmt.Errorf("不支持的数据库驱动: %s", cfg.Database.Driver)
@@ -48,25 +50,79 @@ func InitDB() error {
 	sqlDB.SetMaxIdleConns(10)
 	sqlDB.SetMaxOpenConns(100)
 
+	// 注册连接池指标
+	if err := metrics.RegisterDB(sqlDB, cfg.Database.Database); err != nil {
+		logging.L().Warn("注册数据库连接池指标失败", "error", err)
+	}
+
+	// 为请求内的 SQL 创建链路追踪子 span
+	if err := DB.Use(tracing.NewGormPlugin()); err != nil {
+		return fmt.Errorf("注册链路追踪插件失败: %v", err)
+	}
+
+	// 在请求内的 SQL 前添加请求ID注释
+	if cfg.RequestID.SQLComment {
+		if err := DB.Use(requestid.NewGormPlugin()); err != nil {
+			return fmt.Errorf("注册请求ID插件失败: %v", err)
+		}
+	}
+
 	// 自动迁移数据库表结构
 	if err := autoMigrate(); err != nil {
 		return fmt.Errorf("数据库迁移失败: %v", err)
 	}
 
+	// 用户全文索引（仅 SQLite，其他数据库使用 LIKE 查询）
+	if cfg.Database.Driver == "sqlite" {
+		if err := setupUserSearch(); err != nil {
+			return err
+		}
+	}
+
+	// 注册健康检查
+	checkTimeout := time.Duration(cfg.Health.CheckTimeout) * time.Millisecond
+	health.Register("database", health.Readiness, checkTimeout, health.PingCheck(sqlDB))
+	health.Register("migrations", health.Readiness, checkTimeout, func(ctx context.Context) error {
+		return CheckMigrations()
+	})
+
 	// 初始化基础数据
 	if err := seedData(); err != nil {
-		log.Printf("初始化基础数据失败: %v", err)
+		logging.L().Error("初始化基础数据失败", "error", err)
 	}
 
-	log.Println("数据库初始化成功")
+	logging.L().Info("数据库初始化成功", "driver", cfg.Database.Driver, "database", cfg.Database.Database)
 	return nil
 }
 
+// migrationModels 需要自动迁移的模型
+func migrationModels() []interface{} {
+	return []interface{}{
+		&models.User{},
+		&models.File{},
+		&models.Upload{},
+	}
+}
+
 // autoMigrate 自动迁移数据库表结构
 func autoMigrate() error {
-	return DB.AutoMigrate(
-		&models.User{},
-	)
+	return DB.AutoMigrate(migrationModels()...)
+}
+
+// CheckMigrations 检查所有模型对应的数据表是否已创建
+func CheckMigrations() error {
+	if DB == nil {
+		return fmt.Errorf("数据库尚未初始化")
+	}
+
+	migrator := DB.Migrator()
+	for _, model := range migrationModels() {
+		if !migrator.HasTable(model) {
+			return fmt.Errorf("数据表未迁移: %T", model)
+		}
+	}
+
+	return nil
 }
 
 // seedData 初始化基础数据
@@ -102,7 +158,7 @@ func seedData() error {
 			}
 		}
 
-		log.Println("示例用户数据创建成功")
+		logging.L().Info("示例用户数据创建成功", "count", len(users))
 	}
 
 	return nil
@@ -0,0 +1,82 @@
+package database
+
+import (
+	"fmt"
+
+	"iris-cn-sample-project/logging"
+)
+
+// userSearchFTS 是否已启用用户全文索引（users_fts）
+var userSearchFTS bool
+
+// UserSearchFTS 用户搜索能否使用 FTS5 全文索引，为 false 时服务层退回 LIKE 查询
+func UserSearchFTS() bool {
+	return userSearchFTS
+}
+
+// userFTSTriggers users 表变更时同步 users_fts 的触发器
+//
+// users_fts 是外部内容表（content='users'），删除与更新时必须用旧值写入 'delete' 命令，否则索引会损坏。
+var userFTSTriggers = map[string]string{
+	"users_fts_ai": `CREATE TRIGGER IF NOT EXISTS users_fts_ai AFTER INSERT ON users BEGIN
+	INSERT INTO users_fts(rowid, username, email, first_name, last_name)
+	VALUES (new.id, new.username, new.email, new.first_name, new.last_name);
+END`,
+	"users_fts_ad": `CREATE TRIGGER IF NOT EXISTS users_fts_ad AFTER DELETE ON users BEGIN
+	INSERT INTO users_fts(users_fts, rowid, username, email, first_name, last_name)
+	VALUES ('delete', old.id, old.username, old.email, old.first_name, old.last_name);
+END`,
+	"users_fts_au": `CREATE TRIGGER IF NOT EXISTS users_fts_au AFTER UPDATE OF username, email, first_name, last_name ON users BEGIN
+	INSERT INTO users_fts(users_fts, rowid, username, email, first_name, last_name)
+	VALUES ('delete', old.id, old.username, old.email, old.first_name, old.last_name);
+	INSERT INTO users_fts(rowid, username, email, first_name, last_name)
+	VALUES (new.id, new.username, new.email, new.first_name, new.last_name);
+END`,
+}
+
+// setupUserSearch 创建用户全文索引 users_fts 及同步触发器
+//
+// 使用 trigram 分词，支持任意位置的子串匹配（包括中文姓名），与原来的 %关键字% 查询语义一致。
+// SQLite 未编译 FTS5（mattn/go-sqlite3 需要 sqlite_fts5 构建标签）时记录日志并退回 LIKE 查询，不影响启动。
+// 触发器缺失（首次创建，或迁移重建了 users 表）时重建整个索引。
+func setupUserSearch() error {
+	var fts5 bool
+	if err := DB.Raw("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&fts5).Error; err != nil {
+		return fmt.Errorf("查询 SQLite 编译选项失败: %v", err)
+	}
+	if !fts5 {
+		logging.L().Warn("SQLite 未启用 FTS5，用户搜索使用 LIKE 查询")
+		return nil
+	}
+
+	if err := DB.Exec(`CREATE VIRTUAL TABLE IF NOT EXISTS users_fts USING fts5(
+	username, email, first_name, last_name,
+	content='users', content_rowid='id', tokenize='trigram'
+)`).Error; err != nil {
+		return fmt.Errorf("创建用户全文索引失败: %v", err)
+	}
+
+	var existing int64
+	names := make([]string, 0, len(userFTSTriggers))
+	for name := range userFTSTriggers {
+		names = append(names, name)
+	}
+	if err := DB.Raw("SELECT COUNT(*) FROM sqlite_master WHERE type = 'trigger' AND name IN ?", names).
+		Scan(&existing).Error; err != nil {
+		return fmt.Errorf("查询全文索引触发器失败: %v", err)
+	}
+	if int(existing) < len(userFTSTriggers) {
+		for name, ddl := range userFTSTriggers {
+			if err := DB.Exec(ddl).Error; err != nil {
+				return fmt.Errorf("创建触发器 %s 失败: %v", name, err)
+			}
+		}
+		if err := DB.Exec("INSERT INTO users_fts(users_fts) VALUES ('rebuild')").Error; err != nil {
+			return fmt.Errorf("重建用户全文索引失败: %v", err)
+		}
+		logging.L().Info("用户全文索引已重建")
+	}
+
+	userSearchFTS = true
+	return nil
+}
@@ -0,0 +1,171 @@
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// 支持的导出格式
+const (
+	FormatCSV   = "csv"
+	FormatJSONL = "jsonl" // JSON Lines：每行一个 JSON 对象
+	FormatXLSX  = "xlsx"
+)
+
+// formats 导出格式的 MIME 类型
+var formats = map[string]string{
+	FormatCSV:   "text/csv; charset=utf-8",
+	FormatJSONL: "application/x-ndjson",
+	FormatXLSX:  "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+// Formats 支持的导出格式
+func Formats() []string {
+	return []string{FormatCSV, FormatJSONL, FormatXLSX}
+}
+
+// ContentType 导出格式的 MIME 类型，不支持的格式返回空字符串
+func ContentType(format string) string {
+	return formats[format]
+}
+
+// Writer 逐行写出记录：每写一行就编码输出，不在内存中保留已写出的记录
+//
+// 记录按创建时给出的列取值，缺少的列为空；写完后必须调用 Close 输出剩余内容（XLSX 的文件尾）。
+type Writer interface {
+	Write(record map[string]interface{}) error
+	Close() error
+}
+
+// NewWriter 创建指定格式的 Writer，columns 为列名及顺序（CSV、XLSX 的表头，JSON Lines 的字段顺序）；
+// CSV 与 XLSX 会立即写出表头
+func NewWriter(w io.Writer, format string, columns []string) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		c, err := newCSVWriter(w, columns)
+		if err != nil {
+			return nil, err
+		}
+		return c, nil
+	case FormatJSONL:
+		return newJSONLWriter(w, columns), nil
+	case FormatXLSX:
+		x, err := newXLSXWriter(w, columns)
+		if err != nil {
+			return nil, err
+		}
+		return x, nil
+	default:
+		return nil, fmt.Errorf("不支持的导出格式 %q，可选: %s", format, strings.Join(Formats(), ", "))
+	}
+}
+
+// csvWriter CSV，首行为表头；以 UTF-8 BOM 开头，Excel 打开时不会乱码
+type csvWriter struct {
+	w       *csv.Writer
+	columns []string
+	row     []string
+}
+
+func newCSVWriter(w io.Writer, columns []string) (*csvWriter, error) {
+	if _, err := io.WriteString(w, "\uFEFF"); err != nil {
+		return nil, err
+	}
+	c := &csvWriter{w: csv.NewWriter(w), columns: columns, row: make([]string, len(columns))}
+	if err := c.w.Write(columns); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *csvWriter) Write(record map[string]interface{}) error {
+	for i, column := range c.columns {
+		value := record[column]
+		c.row[i] = formatValue(value)
+		if _, ok := value.(string); ok {
+			c.row[i] = escapeFormula(c.row[i])
+		}
+	}
+	return c.w.Write(c.row)
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// jsonlWriter JSON Lines，字段按列的顺序输出，不转义 HTML 字符
+type jsonlWriter struct {
+	w       *bufio.Writer
+	columns []string
+	buf     bytes.Buffer
+	enc     *json.Encoder
+}
+
+func newJSONLWriter(w io.Writer, columns []string) *jsonlWriter {
+	j := &jsonlWriter{w: bufio.NewWriter(w), columns: columns}
+	j.enc = json.NewEncoder(&j.buf)
+	j.enc.SetEscapeHTML(false)
+	return j
+}
+
+func (j *jsonlWriter) Write(record map[string]interface{}) error {
+	j.buf.Reset()
+	j.buf.WriteByte('{')
+	for i, column := range j.columns {
+		if i > 0 {
+			j.buf.WriteByte(',')
+		}
+		// Encode 在每个值后追加换行，写入下一项前去掉
+		j.enc.Encode(column)
+		j.buf.Truncate(j.buf.Len() - 1)
+		j.buf.WriteByte(':')
+		if err := j.enc.Encode(record[column]); err != nil {
+			return fmt.Errorf("编码字段 %s 失败: %v", column, err)
+		}
+		j.buf.Truncate(j.buf.Len() - 1)
+	}
+	j.buf.WriteString("}\n")
+	_, err := j.w.Write(j.buf.Bytes())
+	return err
+}
+
+func (j *jsonlWriter) Close() error {
+	return j.w.Flush()
+}
+
+// formatValue 单元格的文本：时间使用 RFC 3339，nil 为空
+func formatValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case time.Time:
+		if v.IsZero() {
+			return ""
+		}
+		return v.Format(time.RFC3339)
+	case *time.Time:
+		if v == nil {
+			return ""
+		}
+		return formatValue(*v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// escapeFormula 以 = + - @ 或制表符、回车开头的文本在电子表格中会被当作公式执行（CSV 注入），前面加单引号按文本显示
+func escapeFormula(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
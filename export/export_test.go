@@ -0,0 +1,110 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testColumns = []string{"id", "username", "created_at"}
+
+var testRecords = []map[string]interface{}{
+	{"id": uint(1), "username": "alice", "created_at": time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)},
+	{"id": uint(2), "username": "=HYPERLINK(\"x\")", "created_at": time.Time{}},
+	{"id": uint(3), "username": "a<b>&\"c\"\x01"},
+}
+
+func write(t *testing.T, format string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, format, testColumns)
+	if err != nil {
+		t.Fatalf("NewWriter(%s) 失败: %v", format, err)
+	}
+	for _, record := range testRecords {
+		if err := w.Write(record); err != nil {
+			t.Fatalf("Write 失败: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close 失败: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestCSV(t *testing.T) {
+	got := string(write(t, FormatCSV))
+	want := "\uFEFFid,username,created_at\n" +
+		"1,alice,2024-05-01T08:00:00Z\n" +
+		"2,\"'=HYPERLINK(\"\"x\"\")\",\n" +
+		"3,\"a<b>&\"\"c\"\"\x01\",\n"
+	if got != want {
+		t.Errorf("CSV =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestJSONL(t *testing.T) {
+	got := string(write(t, FormatJSONL))
+	want := `{"id":1,"username":"alice","created_at":"2024-05-01T08:00:00Z"}` + "\n" +
+		`{"id":2,"username":"=HYPERLINK(\"x\")","created_at":"0001-01-01T00:00:00Z"}` + "\n" +
+		`{"id":3,"username":"a<b>&\"c\"\u0001","created_at":null}` + "\n"
+	if got != want {
+		t.Errorf("JSON Lines =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestXLSX(t *testing.T) {
+	data := write(t, FormatXLSX)
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("不是有效的 zip 文件: %v", err)
+	}
+	parts := make(map[string]string)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("打开 %s 失败: %v", f.Name, err)
+		}
+		content, _ := io.ReadAll(rc)
+		rc.Close()
+		parts[f.Name] = string(content)
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels"} {
+		if _, ok := parts[name]; !ok {
+			t.Errorf("缺少部件 %s", name)
+		}
+	}
+
+	sheet := parts["xl/worksheets/sheet1.xml"]
+	for _, want := range []string{
+		`<row r="1"><c r="A1" t="inlineStr"><is><t xml:space="preserve">id</t></is></c>`,
+		`<row r="2"><c r="A2"><v>1</v></c><c r="B2" t="inlineStr"><is><t xml:space="preserve">alice</t></is></c>` +
+			`<c r="C2" t="inlineStr"><is><t xml:space="preserve">2024-05-01T08:00:00Z</t></is></c></row>`,
+		`<row r="3"><c r="A3"><v>2</v></c><c r="B3" t="inlineStr"><is><t xml:space="preserve">=HYPERLINK(&#34;x&#34;)</t></is></c></row>`,
+		`<t xml:space="preserve">a&lt;b&gt;&amp;&#34;c&#34;` + "\uFFFD</t>",
+	} {
+		if !strings.Contains(sheet, want) {
+			t.Errorf("工作表中缺少 %s\n%s", want, sheet)
+		}
+	}
+	if !strings.HasSuffix(sheet, "</sheetData></worksheet>") {
+		t.Errorf("工作表未正确结束: %s", sheet)
+	}
+}
+
+func TestColumnName(t *testing.T) {
+	for i, want := range map[int]string{0: "A", 25: "Z", 26: "AA", 51: "AZ", 52: "BA", 701: "ZZ", 702: "AAA"} {
+		if got := columnName(i); got != want {
+			t.Errorf("columnName(%d) = %s, want %s", i, got, want)
+		}
+	}
+}
+
+func TestNewWriterUnsupported(t *testing.T) {
+	if _, err := NewWriter(io.Discard, "xls", testColumns); err == nil {
+		t.Error("不支持的格式应返回错误")
+	}
+}
@@ -9,4 +9,15 @@ require (
     gorm.io/gorm v1.25.5
     github.com/go-playground/validator/v10 v10.15.5
     golang.org/x/crypto v0.14.0
+    github.com/prometheus/client_golang v1.17.0
+    github.com/prometheus/client_model v0.5.0
+    go.opentelemetry.io/otel v1.21.0
+    go.opentelemetry.io/otel/trace v1.21.0
+    go.opentelemetry.io/otel/sdk v1.21.0
+    go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp v1.21.0
+    go.opentelemetry.io/otel/exporters/stdout/stdouttrace v1.21.0
+    gopkg.in/yaml.v3 v3.0.1
+    github.com/BurntSushi/toml v1.3.2
+    github.com/gabriel-vasile/mimetype v1.4.2
+    golang.org/x/image v0.14.0
 )
\ No newline at end of file
@@ -0,0 +1,55 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+	"runtime"
+)
+
+// PingCheck 数据库连通性检查
+func PingCheck(db *sql.DB) CheckFunc {
+	return func(ctx context.Context) error {
+		if err := db.PingContext(ctx); err != nil {
+			return fmt.Errorf("数据库连接失败: %v", err)
+		}
+		return nil
+	}
+}
+
+// DiskSpaceCheck 磁盘剩余空间检查，dir 所在分区可用空间低于 minFreeBytes 时失败
+func DiskSpaceCheck(dir string, minFreeBytes uint64) CheckFunc {
+	return func(ctx context.Context) error {
+		free, err := diskFree(dir)
+		if err != nil {
+			return fmt.Errorf("获取磁盘空间失败: %v", err)
+		}
+		if free < minFreeBytes {
+			return fmt.Errorf("磁盘剩余空间不足: %d 字节（最低要求 %d 字节）", free, minFreeBytes)
+		}
+		return nil
+	}
+}
+
+// TCPDialCheck TCP 可达性检查（如邮件服务器）
+func TCPDialCheck(addr string) CheckFunc {
+	return func(ctx context.Context) error {
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return fmt.Errorf("无法连接 %s: %v", addr, err)
+		}
+		return conn.Close()
+	}
+}
+
+// GoroutineCountCheck goroutine 数量检查（存活检查）：超过 max 时视为 goroutine 泄漏或处理堆积，需要重启进程
+func GoroutineCountCheck(max int) CheckFunc {
+	return func(ctx context.Context) error {
+		if n := runtime.NumGoroutine(); n > max {
+			return fmt.Errorf("goroutine 数量过多: %d（上限 %d）", n, max)
+		}
+		return nil
+	}
+}
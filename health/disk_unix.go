@@ -0,0 +1,14 @@
+//go:build !windows
+
+package health
+
+import "syscall"
+
+// diskFree 获取目录所在分区对非特权用户可用的字节数
+func diskFree(dir string) (uint64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(dir, &stat); err != nil {
+		return 0, err
+	}
+	return stat.Bavail * uint64(stat.Bsize), nil
+}
@@ -0,0 +1,30 @@
+//go:build windows
+
+package health
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// diskFree 获取目录所在分区对当前用户可用的字节数
+func diskFree(dir string) (uint64, error) {
+	path, err := syscall.UTF16PtrFromString(dir)
+	if err != nil {
+		return 0, err
+	}
+
+	var freeBytesAvailable uint64
+	proc := syscall.NewLazyDLL("kernel32.dll").NewProc("GetDiskFreeSpaceExW")
+	ret, _, callErr := proc.Call(
+		uintptr(unsafe.Pointer(path)),
+		uintptr(unsafe.Pointer(&freeBytesAvailable)),
+		0,
+		0,
+	)
+	if ret == 0 {
+		return 0, callErr
+	}
+
+	return freeBytesAvailable, nil
+}
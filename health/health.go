@@ -0,0 +1,254 @@
+package health
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"iris-cn-sample-project/logging"
+)
+
+// Kind 检查类型
+type Kind string
+
+const (
+	// Liveness 存活检查：失败意味着进程需要重启
+	Liveness Kind = "liveness"
+	// Readiness 就绪检查：失败意味着暂时不应接收流量
+	Readiness Kind = "readiness"
+)
+
+// 检查状态
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// 默认参数
+const (
+	DefaultTimeout  = 2 * time.Second
+	DefaultCacheTTL = 3 * time.Second
+)
+
+// CheckFunc 检查函数，返回 nil 表示健康
+type CheckFunc func(ctx context.Context) error
+
+// Checker 已注册的检查项
+type Checker struct {
+	Name    string
+	Kind    Kind
+	Timeout time.Duration
+	Check   CheckFunc
+
+	mu     sync.Mutex
+	cached *Result
+}
+
+// Result 单个检查项的结果
+//
+// Error 可能包含连接地址、文件路径等内部信息，只写入日志，不对外返回。
+type Result struct {
+	Name      string    `json:"name"`
+	Status    string    `json:"status"`
+	Error     string    `json:"-"`
+	Duration  string    `json:"duration"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// Report 一组检查的汇总结果
+type Report struct {
+	Status string    `json:"status"`
+	Checks []*Result `json:"checks"`
+}
+
+// Healthy 判断汇总结果是否健康
+func (r *Report) Healthy() bool {
+	return r.Status == StatusUp
+}
+
+// Registry 检查项注册表
+type Registry struct {
+	mu       sync.RWMutex
+	checkers map[string]*Checker
+	cacheTTL time.Duration
+	ready    atomic.Bool
+}
+
+// NewRegistry 创建检查项注册表
+func NewRegistry(cacheTTL time.Duration) *Registry {
+	r := &Registry{
+		checkers: make(map[string]*Checker),
+		cacheTTL: cacheTTL,
+	}
+	r.ready.Store(true)
+	return r
+}
+
+// defaultRegistry 默认注册表
+var defaultRegistry = NewRegistry(DefaultCacheTTL)
+
+// Register 注册检查项，同名检查项会被替换
+func (r *Registry) Register(name string, kind Kind, timeout time.Duration, check CheckFunc) {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.checkers[name] = &Checker{
+		Name:    name,
+		Kind:    kind,
+		Timeout: timeout,
+		Check:   check,
+	}
+}
+
+// SetCacheTTL 设置检查结果缓存时间
+func (r *Registry) SetCacheTTL(ttl time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.cacheTTL = ttl
+}
+
+// Unregister 注销检查项
+func (r *Registry) Unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.checkers, name)
+}
+
+// SetReady 设置就绪标记（例如优雅停机期间置为 false）
+func (r *Registry) SetReady(ready bool) {
+	r.ready.Store(ready)
+}
+
+// IsReady 获取就绪标记
+func (r *Registry) IsReady() bool {
+	return r.ready.Load()
+}
+
+// Run 并发执行指定类型的所有检查项
+func (r *Registry) Run(ctx context.Context, kind Kind) *Report {
+	r.mu.RLock()
+	checkers := make([]*Checker, 0, len(r.checkers))
+	for _, c := range r.checkers {
+		if c.Kind == kind {
+			checkers = append(checkers, c)
+		}
+	}
+	cacheTTL := r.cacheTTL
+	r.mu.RUnlock()
+
+	results := make([]*Result, len(checkers))
+	var wg sync.WaitGroup
+	for i, c := range checkers {
+		wg.Add(1)
+		go func(i int, c *Checker) {
+			defer wg.Done()
+			results[i] = c.run(ctx, cacheTTL)
+		}(i, c)
+	}
+	wg.Wait()
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Name < results[j].Name
+	})
+
+	report := &Report{Status: StatusUp, Checks: results}
+
+	// 就绪检查额外受就绪标记控制
+	if kind == Readiness && !r.IsReady() {
+		report.Checks = append(report.Checks, &Result{
+			Name:      "accepting_traffic",
+			Status:    StatusDown,
+			Error:     "服务正在停止，不再接收新的流量",
+			Duration:  "0s",
+			CheckedAt: time.Now(),
+		})
+	}
+
+	for _, result := range report.Checks {
+		if result.Status != StatusUp {
+			report.Status = StatusDown
+			break
+		}
+	}
+
+	return report
+}
+
+// run 执行单个检查项（带超时与短时缓存），失败时记录日志（缓存有效期内不重复记录）
+func (c *Checker) run(ctx context.Context, cacheTTL time.Duration) *Result {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.cached != nil && time.Since(c.cached.CheckedAt) < cacheTTL {
+		return c.cached
+	}
+
+	checkCtx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- c.Check(checkCtx)
+	}()
+
+	var err error
+	select {
+	case err = <-errCh:
+	case <-checkCtx.Done():
+		err = checkCtx.Err()
+	}
+
+	result := &Result{
+		Name:      c.Name,
+		Status:    StatusUp,
+		Duration:  time.Since(start).Round(time.Microsecond).String(),
+		CheckedAt: start,
+	}
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+		logging.L().WarnContext(ctx, "健康检查未通过", "check", c.Name, "kind", string(c.Kind), "error", result.Error)
+	}
+
+	c.cached = result
+	return result
+}
+
+// Register 向默认注册表注册检查项
+func Register(name string, kind Kind, timeout time.Duration, check CheckFunc) {
+	defaultRegistry.Register(name, kind, timeout, check)
+}
+
+// Unregister 从默认注册表注销检查项
+func Unregister(name string) {
+	defaultRegistry.Unregister(name)
+}
+
+// SetCacheTTL 设置默认注册表的检查结果缓存时间
+func SetCacheTTL(ttl time.Duration) {
+	defaultRegistry.SetCacheTTL(ttl)
+}
+
+// SetReady 设置默认注册表的就绪标记
+func SetReady(ready bool) {
+	defaultRegistry.SetReady(ready)
+}
+
+// IsReady 获取默认注册表的就绪标记
+func IsReady() bool {
+	return defaultRegistry.IsReady()
+}
+
+// Run 执行默认注册表中指定类型的检查项
+func Run(ctx context.Context, kind Kind) *Report {
+	return defaultRegistry.Run(ctx, kind)
+}
@@ -0,0 +1,153 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingCheck 记录执行次数的检查项
+func countingCheck(calls *atomic.Int32, err error) CheckFunc {
+	return func(ctx context.Context) error {
+		calls.Add(1)
+		return err
+	}
+}
+
+func TestRunCache(t *testing.T) {
+	r := NewRegistry(50 * time.Millisecond)
+	var calls atomic.Int32
+	r.Register("db", Readiness, 0, countingCheck(&calls, nil))
+
+	first := r.Run(context.Background(), Readiness)
+	second := r.Run(context.Background(), Readiness)
+	if calls.Load() != 1 {
+		t.Errorf("缓存有效期内应复用结果，执行了 %d 次", calls.Load())
+	}
+	if first.Checks[0] != second.Checks[0] {
+		t.Error("缓存有效期内应返回同一个结果")
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	r.Run(context.Background(), Readiness)
+	if calls.Load() != 2 {
+		t.Errorf("缓存过期后应重新执行，执行了 %d 次", calls.Load())
+	}
+
+	r.SetCacheTTL(0)
+	r.Run(context.Background(), Readiness)
+	r.Run(context.Background(), Readiness)
+	if calls.Load() != 4 {
+		t.Errorf("不缓存时每次都应执行，执行了 %d 次", calls.Load())
+	}
+
+	// 只执行指定类型的检查项
+	var liveness atomic.Int32
+	r.Register("process", Liveness, 0, countingCheck(&liveness, nil))
+	r.Run(context.Background(), Readiness)
+	if liveness.Load() != 0 {
+		t.Error("就绪检查不应执行存活检查项")
+	}
+}
+
+func TestRunTimeout(t *testing.T) {
+	r := NewRegistry(0)
+	// 不响应 ctx 的检查项也要在自身超时后返回，且不拖慢其他检查项
+	r.Register("hang", Readiness, 50*time.Millisecond, func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	})
+	r.Register("slow", Readiness, 50*time.Millisecond, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	var calls atomic.Int32
+	r.Register("fast", Readiness, time.Second, countingCheck(&calls, nil))
+
+	start := time.Now()
+	report := r.Run(context.Background(), Readiness)
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("检查耗时 %v，超时未生效", elapsed)
+	}
+	if report.Healthy() {
+		t.Error("有检查项超时时不应健康")
+	}
+
+	// 结果按名称排序
+	want := map[string]string{"fast": StatusUp, "hang": StatusDown, "slow": StatusDown}
+	for i, name := range []string{"fast", "hang", "slow"} {
+		result := report.Checks[i]
+		if result.Name != name || result.Status != want[name] {
+			t.Errorf("Checks[%d] = %s %s, want %s %s", i, result.Name, result.Status, name, want[name])
+		}
+		if result.Status == StatusDown && !strings.Contains(result.Error, context.DeadlineExceeded.Error()) {
+			t.Errorf("%s: Error = %q", name, result.Error)
+		}
+	}
+}
+
+func TestRunReady(t *testing.T) {
+	r := NewRegistry(0)
+	var calls atomic.Int32
+	r.Register("db", Readiness, 0, countingCheck(&calls, nil))
+	r.Register("process", Liveness, 0, countingCheck(&calls, nil))
+	if report := r.Run(context.Background(), Readiness); !report.Healthy() {
+		t.Fatalf("检查项全部通过时应健康: %+v", report)
+	}
+
+	r.SetReady(false)
+	report := r.Run(context.Background(), Readiness)
+	if report.Healthy() {
+		t.Error("停止接收流量后就绪检查不应通过")
+	}
+	if last := report.Checks[len(report.Checks)-1]; last.Name != "accepting_traffic" || last.Status != StatusDown {
+		t.Errorf("应包含 accepting_traffic 检查项: %+v", last)
+	}
+	if report := r.Run(context.Background(), Liveness); !report.Healthy() {
+		t.Error("就绪标记不影响存活检查")
+	}
+
+	r.SetReady(true)
+	r.Register("db", Readiness, 0, countingCheck(&calls, errors.New("连接失败")))
+	if report := r.Run(context.Background(), Readiness); report.Healthy() || report.Checks[0].Error != "连接失败" {
+		t.Errorf("检查项失败时应不健康: %+v", report.Checks[0])
+	}
+	r.Unregister("db")
+	if report := r.Run(context.Background(), Readiness); !report.Healthy() || len(report.Checks) != 0 {
+		t.Errorf("注销后不应再执行: %+v", report)
+	}
+}
+
+func TestChecks(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	if err := TCPDialCheck(addr)(context.Background()); err != nil {
+		t.Errorf("TCPDialCheck(%s): %v", addr, err)
+	}
+	ln.Close()
+	if err := TCPDialCheck(addr)(context.Background()); err == nil {
+		t.Error("端口关闭后 TCPDialCheck 应失败")
+	}
+
+	dir := t.TempDir()
+	if err := DiskSpaceCheck(dir, 1)(context.Background()); err != nil {
+		t.Errorf("DiskSpaceCheck: %v", err)
+	}
+	if err := DiskSpaceCheck(dir, 1<<62)(context.Background()); err == nil {
+		t.Error("剩余空间不足时 DiskSpaceCheck 应失败")
+	}
+
+	if err := GoroutineCountCheck(100000)(context.Background()); err != nil {
+		t.Errorf("GoroutineCountCheck: %v", err)
+	}
+	if err := GoroutineCountCheck(1)(context.Background()); err == nil {
+		t.Error("goroutine 数量超过上限时 GoroutineCountCheck 应失败")
+	}
+}
@@ -0,0 +1,60 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+
+	"iris-cn-sample-project/requestid"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// contextHandler 从上下文中提取请求ID与链路追踪信息（request_id、trace_id、span_id）附加到每条日志
+type contextHandler struct {
+	slog.Handler
+}
+
+// withContext 包装日志处理器，已包装过的处理器原样返回
+func withContext(h slog.Handler) slog.Handler {
+	if _, ok := h.(contextHandler); ok {
+		return h
+	}
+	return contextHandler{Handler: h}
+}
+
+// Handle 处理日志记录
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if ctx != nil {
+		// 调用方已显式记录 request_id 时不重复添加
+		if id := requestid.FromContext(ctx); id != "" && !hasAttr(r, "request_id") {
+			r.AddAttrs(slog.String("request_id", id))
+		}
+		if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+			r.AddAttrs(
+				slog.String("trace_id", sc.TraceID().String()),
+				slog.String("span_id", sc.SpanID().String()),
+			)
+		}
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+// WithAttrs 返回附加了属性的处理器
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+// WithGroup 返回带分组的处理器
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{Handler: h.Handler.WithGroup(name)}
+}
+
+// hasAttr 判断日志记录中是否已有指定的顶层属性
+func hasAttr(r slog.Record, key string) bool {
+	found := false
+	r.Attrs(func(a slog.Attr) bool {
+		found = a.Key == key
+		return !found
+	})
+	return found
+}
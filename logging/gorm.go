@@ -0,0 +1,112 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// GormLogger 将 GORM 日志输出到结构化日志
+type GormLogger struct {
+	level                     *gormlogger.LogLevel // 通过 LogMode 设置的级别（如 db.Debug()），为 nil 时跟随全局日志级别
+	SlowThreshold             time.Duration
+	IgnoreRecordNotFoundError bool
+}
+
+// NewGormLogger 创建 GORM 日志适配器，日志级别跟随全局日志级别（运行时调整后立即生效）
+func NewGormLogger(slowThreshold time.Duration) *GormLogger {
+	return &GormLogger{
+		SlowThreshold:             slowThreshold,
+		IgnoreRecordNotFoundError: true,
+	}
+}
+
+// LogMode 设置日志级别，不再跟随全局日志级别
+func (l *GormLogger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	clone := *l
+	clone.level = &level
+	return &clone
+}
+
+// logLevel 当前生效的级别：每次记录时读取全局日志级别，debug 时记录所有 SQL，否则只记录慢查询与错误
+func (l *GormLogger) logLevel() gormlogger.LogLevel {
+	if l.level != nil {
+		return *l.level
+	}
+	if Level() <= slog.LevelDebug {
+		return gormlogger.Info
+	}
+	return gormlogger.Warn
+}
+
+// Info 输出信息日志
+func (l *GormLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	if l.logLevel() >= gormlogger.Info {
+		L().InfoContext(ctx, fmt.Sprintf(msg, args...), "component", "gorm")
+	}
+}
+
+// Warn 输出警告日志
+func (l *GormLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	if l.logLevel() >= gormlogger.Warn {
+		L().WarnContext(ctx, fmt.Sprintf(msg, args...), "component", "gorm")
+	}
+}
+
+// Error 输出错误日志
+func (l *GormLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	if l.logLevel() >= gormlogger.Error {
+		L().ErrorContext(ctx, fmt.Sprintf(msg, args...), "component", "gorm")
+	}
+}
+
+// ParamsFilter 实现 gorm.ParamsFilter：日志中的 SQL 只保留 ? 占位符，不内联绑定的参数（密码哈希、邮箱、重置令牌等）
+func (l *GormLogger) ParamsFilter(ctx context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	return sql, nil
+}
+
+// Trace 记录 SQL 执行情况：出错记为 error，慢查询记为 warn，其余在 Info 级别下记为 debug
+func (l *GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	lvl := l.logLevel()
+	if lvl <= gormlogger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	logger := L()
+
+	switch {
+	case err != nil && lvl >= gormlogger.Error &&
+		(!errors.Is(err, gorm.ErrRecordNotFound) || !l.IgnoreRecordNotFoundError):
+		sql, rows := fc()
+		logger.ErrorContext(ctx, "SQL 执行失败",
+			"component", "gorm",
+			"error", Redact().String(err.Error()),
+			"sql", sql,
+			"rows", rows,
+			"latency", elapsed,
+		)
+	case l.SlowThreshold > 0 && elapsed > l.SlowThreshold && lvl >= gormlogger.Warn:
+		sql, rows := fc()
+		logger.WarnContext(ctx, "慢查询",
+			"component", "gorm",
+			"sql", sql,
+			"rows", rows,
+			"latency", elapsed,
+			"threshold", l.SlowThreshold,
+		)
+	case lvl >= gormlogger.Info:
+		sql, rows := fc()
+		logger.DebugContext(ctx, "SQL 执行",
+			"component", "gorm",
+			"sql", sql,
+			"rows", rows,
+			"latency", elapsed,
+		)
+	}
+}
@@ -0,0 +1,55 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// 运行时调整全局日志级别后，已创建的 GORM 日志适配器立即按新级别记录
+func TestGormLoggerFollowsLevel(t *testing.T) {
+	previous, previousLevel := L(), Level()
+	t.Cleanup(func() {
+		current.Store(previous)
+		level.Set(previousLevel)
+	})
+	var buf bytes.Buffer
+	SetLogger(slog.New(slog.NewTextHandler(&buf, NewHandlerOptions())))
+
+	l := NewGormLogger(time.Second)
+	query := func(logger gormlogger.Interface) string {
+		buf.Reset()
+		logger.Trace(context.Background(), time.Now(), func() (string, int64) { return "SELECT 1", 1 }, nil)
+		return buf.String()
+	}
+
+	if err := SetLevel("info"); err != nil {
+		t.Fatal(err)
+	}
+	if out := query(l); out != "" {
+		t.Errorf("info 级别不应记录普通 SQL: %s", out)
+	}
+
+	if err := SetLevel("debug"); err != nil {
+		t.Fatal(err)
+	}
+	if out := query(l); !strings.Contains(out, "SELECT 1") {
+		t.Errorf("调整为 debug 后应记录 SQL，实际输出: %q", out)
+	}
+	// LogMode 显式设置的级别不受全局级别影响
+	if out := query(l.LogMode(gormlogger.Silent)); out != "" {
+		t.Errorf("Silent 不应记录: %s", out)
+	}
+
+	if err := SetLevel("warn"); err != nil {
+		t.Fatal(err)
+	}
+	if out := query(l); out != "" {
+		t.Errorf("调整回 warn 后不应记录普通 SQL: %s", out)
+	}
+}
@@ -0,0 +1,162 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"iris-cn-sample-project/config"
+)
+
+// level 全局日志级别（可在运行时调整）
+var level = new(slog.LevelVar)
+
+// current 当前使用的日志记录器
+var current atomic.Pointer[slog.Logger]
+
+// output 当前日志输出目标（文件输出时需要在退出前关闭）
+var (
+	outputMu sync.Mutex
+	output   io.Closer
+)
+
+func init() {
+	current.Store(slog.New(withContext(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: level}))))
+}
+
+// Init 根据日志配置初始化全局日志记录器
+func Init(cfg config.LogConfig) (*slog.Logger, error) {
+	lvl, err := ParseLevel(cfg.Level)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := NewRedactor(cfg.Redact)
+	if err != nil {
+		return nil, err
+	}
+
+	w, closer, err := openOutput(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &slog.HandlerOptions{
+		Level:     level,
+		AddSource: lvl <= slog.LevelDebug,
+	}
+
+	var handler slog.Handler
+	switch strings.ToLower(cfg.Format) {
+	case "json", "":
+		handler = slog.NewJSONHandler(w, opts)
+	case "text":
+		handler = slog.NewTextHandler(w, opts)
+	default:
+		if closer != nil {
+			closer.Close()
+		}
+		return nil, fmt.Errorf("不支持的日志格式: %s", cfg.Format)
+	}
+
+	level.Set(lvl)
+	logger := slog.New(withContext(handler))
+	current.Store(logger)
+	SetRedactor(r)
+
+	// 标准库 log 包的输出也转到结构化日志
+	slog.SetDefault(logger)
+	log.SetFlags(0)
+
+	outputMu.Lock()
+	previous := output
+	output = closer
+	outputMu.Unlock()
+	if previous != nil {
+		previous.Close()
+	}
+
+	return logger, nil
+}
+
+// L 获取全局日志记录器
+func L() *slog.Logger {
+	return current.Load()
+}
+
+// SetLogger 替换全局日志记录器（主要用于测试时将日志输出到内存），同样会附加请求ID与链路追踪信息
+func SetLogger(logger *slog.Logger) {
+	current.Store(slog.New(withContext(logger.Handler())))
+}
+
+// NewHandlerOptions 创建与全局日志级别联动的处理器选项
+func NewHandlerOptions() *slog.HandlerOptions {
+	return &slog.HandlerOptions{Level: level}
+}
+
+// SetLevel 调整全局日志级别
+func SetLevel(name string) error {
+	lvl, err := ParseLevel(name)
+	if err != nil {
+		return err
+	}
+	level.Set(lvl)
+	return nil
+}
+
+// Level 获取当前日志级别
+func Level() slog.Level {
+	return level.Level()
+}
+
+// Close 关闭日志输出（刷新并关闭日志文件）
+func Close() error {
+	outputMu.Lock()
+	defer outputMu.Unlock()
+
+	if output == nil {
+		return nil
+	}
+	err := output.Close()
+	output = nil
+	return err
+}
+
+// ParseLevel 解析日志级别名称
+func ParseLevel(name string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info", "":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return slog.LevelInfo, fmt.Errorf("不支持的日志级别: %s", name)
+	}
+}
+
+// openOutput 打开日志输出目标
+func openOutput(cfg config.LogConfig) (io.Writer, io.Closer, error) {
+	switch strings.ToLower(cfg.Output) {
+	case "stdout", "":
+		return os.Stdout, nil, nil
+	case "stderr":
+		return os.Stderr, nil, nil
+	case "file":
+		f, err := NewRotatingFile(cfg.File, int64(cfg.MaxSizeMB)<<20, cfg.MaxBackups)
+		if err != nil {
+			return nil, nil, fmt.Errorf("打开日志文件失败: %v", err)
+		}
+		return f, f, nil
+	default:
+		return nil, nil, fmt.Errorf("不支持的日志输出: %s", cfg.Output)
+	}
+}
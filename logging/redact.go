@@ -0,0 +1,213 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync/atomic"
+
+	"iris-cn-sample-project/config"
+)
+
+// Redacted 脱敏后的占位符
+const Redacted = "[REDACTED]"
+
+// Redactor 日志脱敏器
+type Redactor struct {
+	fields   map[string]struct{}
+	headers  map[string]struct{}
+	patterns []*regexp.Regexp
+}
+
+// redactor 当前使用的脱敏器
+var redactor atomic.Pointer[Redactor]
+
+func init() {
+	r, err := NewRedactor(config.DefaultRedactConfig())
+	if err != nil {
+		panic(err)
+	}
+	redactor.Store(r)
+}
+
+// NewRedactor 根据脱敏配置创建脱敏器
+func NewRedactor(cfg config.RedactConfig) (*Redactor, error) {
+	r := &Redactor{
+		fields:  make(map[string]struct{}, len(cfg.Fields)),
+		headers: make(map[string]struct{}, len(cfg.Headers)),
+	}
+
+	for _, field := range cfg.Fields {
+		r.fields[normalizeFieldName(field)] = struct{}{}
+	}
+	for _, header := range cfg.Headers {
+		r.headers[http.CanonicalHeaderKey(strings.TrimSpace(header))] = struct{}{}
+	}
+	for _, pattern := range cfg.Patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("脱敏正则表达式无效 %q: %v", pattern, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+
+	return r, nil
+}
+
+// Redact 获取全局脱敏器
+func Redact() *Redactor {
+	return redactor.Load()
+}
+
+// SetRedactor 替换全局脱敏器
+func SetRedactor(r *Redactor) {
+	redactor.Store(r)
+}
+
+// IsSensitiveField 判断字段名是否需要脱敏
+func (r *Redactor) IsSensitiveField(name string) bool {
+	_, ok := r.fields[normalizeFieldName(name)]
+	return ok
+}
+
+// queryParam 匹配文本中的 URL 查询参数（?name=value 或 &name=value，& 也可能被 JSON 转义为 \u0026）
+var queryParam = regexp.MustCompile(`([?&]|\\u0026)([^=&?#\\\s"'<>]+)=([^&#\\\s"'<>]*)`)
+
+// String 按正则规则脱敏字符串（如邮箱、手机号），文本中 URL 的敏感查询参数（如签名下载地址的 signature）按参数名脱敏
+func (r *Redactor) String(s string) string {
+	s = queryParam.ReplaceAllStringFunc(s, func(match string) string {
+		sub := queryParam.FindStringSubmatch(match)
+		if !r.IsSensitiveField(sub[2]) {
+			return match
+		}
+		return sub[1] + sub[2] + "=" + Redacted
+	})
+	for _, re := range r.patterns {
+		s = re.ReplaceAllString(s, Redacted)
+	}
+	return s
+}
+
+// Headers 脱敏请求/响应头，返回便于记录的扁平结构
+func (r *Redactor) Headers(h http.Header) map[string]string {
+	result := make(map[string]string, len(h))
+	for name, values := range h {
+		if _, ok := r.headers[http.CanonicalHeaderKey(name)]; ok {
+			result[name] = Redacted
+			continue
+		}
+		result[name] = r.String(strings.Join(values, ", "))
+	}
+	return result
+}
+
+// Query 脱敏查询字符串
+func (r *Redactor) Query(rawQuery string) string {
+	if rawQuery == "" {
+		return ""
+	}
+
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return r.String(rawQuery)
+	}
+
+	for key := range values {
+		if r.IsSensitiveField(key) {
+			values[key] = []string{Redacted}
+			continue
+		}
+		for i, v := range values[key] {
+			values[key][i] = r.String(v)
+		}
+	}
+
+	// url.Values.Encode 会对占位符进行转义，这里还原以便阅读
+	return strings.ReplaceAll(values.Encode(), url.QueryEscape(Redacted), Redacted)
+}
+
+// Body 按内容类型脱敏请求/响应体：JSON 按字段名脱敏，表单按参数名脱敏，其余按正则脱敏
+func (r *Redactor) Body(contentType string, body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+
+	mediaType := strings.ToLower(strings.TrimSpace(strings.SplitN(contentType, ";", 2)[0]))
+
+	switch {
+	case mediaType == "application/x-www-form-urlencoded":
+		return r.Query(string(body))
+	case strings.HasSuffix(mediaType, "json") || json.Valid(body):
+		if redacted, ok := r.jsonBody(body); ok {
+			return redacted
+		}
+	}
+
+	// 无法解析的 JSON（例如被截断）仍然可能包含敏感字段，按字段名做一次文本级脱敏
+	return r.String(r.looseJSON(string(body)))
+}
+
+// jsonBody 解析 JSON 并按字段名递归脱敏
+func (r *Redactor) jsonBody(body []byte) (string, bool) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	var v interface{}
+	if err := decoder.Decode(&v); err != nil {
+		return "", false
+	}
+
+	out, err := json.Marshal(r.walk(v))
+	if err != nil {
+		return "", false
+	}
+	return string(out), true
+}
+
+// walk 递归遍历 JSON 值
+func (r *Redactor) walk(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for key, child := range value {
+			if r.IsSensitiveField(key) {
+				value[key] = Redacted
+				continue
+			}
+			value[key] = r.walk(child)
+		}
+		return value
+	case []interface{}:
+		for i, child := range value {
+			value[i] = r.walk(child)
+		}
+		return value
+	case string:
+		return r.String(value)
+	default:
+		return value
+	}
+}
+
+// looseJSONField 匹配 "key": "value" 形式的字符串字段
+var looseJSONField = regexp.MustCompile(`"([^"\\]+)"\s*:\s*"((?:[^"\\]|\\.)*)("?)`)
+
+// looseJSON 对无法完整解析的 JSON 文本按字段名脱敏
+func (r *Redactor) looseJSON(s string) string {
+	return looseJSONField.ReplaceAllStringFunc(s, func(match string) string {
+		sub := looseJSONField.FindStringSubmatch(match)
+		if !r.IsSensitiveField(sub[1]) {
+			return match
+		}
+		return fmt.Sprintf("%q: %q", sub[1], Redacted)
+	})
+}
+
+// normalizeFieldName 统一字段名格式（忽略大小写、连字符与下划线差异）
+func normalizeFieldName(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	return strings.ReplaceAll(name, "-", "_")
+}
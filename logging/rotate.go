@@ -0,0 +1,144 @@
+package logging
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RotatingFile 按文件大小滚动的日志文件
+type RotatingFile struct {
+	mu         sync.Mutex
+	path       string
+	maxSize    int64
+	maxBackups int
+	file       *os.File
+	size       int64
+}
+
+// NewRotatingFile 创建滚动日志文件，maxSize <= 0 表示不滚动，maxBackups <= 0 表示不清理旧文件
+func NewRotatingFile(path string, maxSize int64, maxBackups int) (*RotatingFile, error) {
+	if path == "" {
+		return nil, fmt.Errorf("日志文件路径不能为空")
+	}
+
+	r := &RotatingFile{
+		path:       path,
+		maxSize:    maxSize,
+		maxBackups: maxBackups,
+	}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Write 写入日志，超过大小限制时先滚动文件
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		if err := r.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	if r.maxSize > 0 && r.size > 0 && r.size+int64(len(p)) > r.maxSize {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// Close 关闭日志文件
+func (r *RotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
+
+// open 打开（或创建）当前日志文件
+func (r *RotatingFile) open() error {
+	if err := os.MkdirAll(filepath.Dir(r.path), 0755); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	r.file = f
+	r.size = info.Size()
+	return nil
+}
+
+// rotate 将当前文件重命名为带时间戳的备份并打开新文件
+func (r *RotatingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return err
+	}
+	r.file = nil
+
+	backup := fmt.Sprintf("%s.%s", r.path, time.Now().Format("20060102-150405.000000"))
+	if err := os.Rename(r.path, backup); err != nil {
+		return err
+	}
+
+	if err := r.open(); err != nil {
+		return err
+	}
+
+	r.pruneBackups()
+	return nil
+}
+
+// pruneBackups 删除超出保留数量的旧备份
+func (r *RotatingFile) pruneBackups() {
+	if r.maxBackups <= 0 {
+		return
+	}
+
+	matches, err := filepath.Glob(r.path + ".*")
+	if err != nil {
+		return
+	}
+
+	backups := make([]string, 0, len(matches))
+	for _, m := range matches {
+		if strings.HasPrefix(filepath.Base(m), filepath.Base(r.path)+".") {
+			backups = append(backups, m)
+		}
+	}
+	if len(backups) <= r.maxBackups {
+		return
+	}
+
+	// 时间戳格式保证按名称排序即按时间排序
+	sort.Strings(backups)
+	for _, old := range backups[:len(backups)-r.maxBackups] {
+		os.Remove(old)
+	}
+}
@@ -0,0 +1,151 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"iris-cn-sample-project/config"
+)
+
+// ErrNotConfigured 未配置 SMTP 服务器（mail.host 或 SMTP_HOST）
+var ErrNotConfigured = errors.New("未配置邮件服务器")
+
+// sendTimeout 发送一封邮件（连接、认证与传输）的超时时间
+const sendTimeout = 30 * time.Second
+
+// Message 纯文本邮件
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer 通过 SMTP 发送邮件：服务器支持时使用 STARTTLS，端口为 465 时直接使用 TLS；
+// 配置了用户名时使用 PLAIN 认证（net/smtp 只允许在 TLS 连接或本机上发送密码）。
+type Mailer struct {
+	cfg  config.MailConfig
+	from string
+}
+
+// New 创建 Mailer，未配置 SMTP 服务器时返回 ErrNotConfigured；发件人默认为 SMTP 用户名
+func New(cfg config.MailConfig) (*Mailer, error) {
+	if cfg.Host == "" {
+		return nil, ErrNotConfigured
+	}
+	from := cfg.From
+	if from == "" {
+		from = cfg.Username
+	}
+	addr, err := mail.ParseAddress(from)
+	if err != nil {
+		return nil, fmt.Errorf("发件人地址无效 %q: %v", from, err)
+	}
+	return &Mailer{cfg: cfg, from: addr.String()}, nil
+}
+
+// Send 发送一封邮件，每次使用新的连接
+func (m *Mailer) Send(ctx context.Context, msg Message) error {
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return fmt.Errorf("收件人地址无效 %q: %v", msg.To, err)
+	}
+	from, _ := mail.ParseAddress(m.from)
+	data, err := buildMessage(m.from, to.String(), msg, time.Now())
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+	c, err := m.dial(ctx)
+	if err != nil {
+		return fmt.Errorf("连接邮件服务器失败: %v", err)
+	}
+	defer c.Close()
+
+	if err := c.Mail(from.Address); err != nil {
+		return fmt.Errorf("发送邮件失败: %v", err)
+	}
+	if err := c.Rcpt(to.Address); err != nil {
+		return fmt.Errorf("发送邮件失败: %v", err)
+	}
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("发送邮件失败: %v", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("发送邮件失败: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("发送邮件失败: %v", err)
+	}
+	return c.Quit()
+}
+
+// dial 连接并认证，连接的读写截止时间与 ctx 相同
+func (m *Mailer) dial(ctx context.Context) (*smtp.Client, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(m.cfg.Host, m.cfg.Port))
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	tlsConfig := &tls.Config{ServerName: m.cfg.Host}
+	if m.cfg.Port == "465" {
+		conn = tls.Client(conn, tlsConfig)
+	}
+
+	c, err := smtp.NewClient(conn, m.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(tlsConfig); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+	if m.cfg.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", m.cfg.Username, m.cfg.Password, m.cfg.Host)); err != nil {
+			c.Close()
+			return nil, fmt.Errorf("SMTP 认证失败: %v", err)
+		}
+	}
+	return c, nil
+}
+
+// buildMessage 生成邮件内容：主题按 RFC 2047 编码，正文为 UTF-8 纯文本并使用 base64 传输
+func buildMessage(from, to string, msg Message, date time.Time) ([]byte, error) {
+	if strings.ContainsAny(from+to, "\r\n") {
+		return nil, errors.New("邮件地址中不能包含换行")
+	}
+	var buf bytes.Buffer
+	buf.WriteString("From: " + from + "\r\n")
+	buf.WriteString("To: " + to + "\r\n")
+	buf.WriteString("Subject: " + mime.BEncoding.Encode("UTF-8", msg.Subject) + "\r\n")
+	buf.WriteString("Date: " + date.Format(time.RFC1123Z) + "\r\n")
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: base64\r\n\r\n")
+
+	body := base64.StdEncoding.EncodeToString([]byte(msg.Body))
+	for len(body) > 76 {
+		buf.WriteString(body[:76] + "\r\n")
+		body = body[76:]
+	}
+	buf.WriteString(body + "\r\n")
+	return buf.Bytes(), nil
+}
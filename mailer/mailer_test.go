@@ -0,0 +1,132 @@
+package mailer
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"iris-cn-sample-project/config"
+)
+
+func TestBuildMessage(t *testing.T) {
+	date := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	body := strings.Repeat("欢迎加入", 20)
+	data, err := buildMessage("noreply@example.com", "alice@example.com", Message{Subject: "账号邀请", Body: body}, date)
+	if err != nil {
+		t.Fatalf("buildMessage 失败: %v", err)
+	}
+	header, encoded, _ := strings.Cut(string(data), "\r\n\r\n")
+	for _, want := range []string{
+		"From: noreply@example.com",
+		"To: alice@example.com",
+		"Subject: =?UTF-8?b?6LSm5Y+36YKA6K+3?=",
+		"Date: Wed, 01 May 2024 08:00:00 +0000",
+		"Content-Transfer-Encoding: base64",
+	} {
+		if !strings.Contains(header+"\r\n", want+"\r\n") {
+			t.Errorf("邮件头中缺少 %q:\n%s", want, header)
+		}
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(encoded, "\r\n"), "\r\n") {
+		if len(line) > 76 {
+			t.Errorf("正文行长度 %d 超过 76", len(line))
+		}
+	}
+	decoded, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(encoded, "\r\n", ""))
+	if err != nil || string(decoded) != body {
+		t.Errorf("正文解码 = %q, %v", decoded, err)
+	}
+
+	if _, err := buildMessage("noreply@example.com", "a@example.com\r\nBcc: b@example.com", Message{}, date); err == nil {
+		t.Error("收件人中包含换行时应返回错误")
+	}
+	data, _ = buildMessage("noreply@example.com", "alice@example.com", Message{Subject: "hi\r\nBcc: b@example.com"}, date)
+	if strings.Contains(string(data), "\r\nBcc:") {
+		t.Errorf("主题中的换行没有被编码:\n%s", data)
+	}
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New(config.MailConfig{}); !errors.Is(err, ErrNotConfigured) {
+		t.Errorf("未配置主机: err = %v, want ErrNotConfigured", err)
+	}
+	if _, err := New(config.MailConfig{Host: "localhost", Port: "25", From: "not an address"}); err == nil {
+		t.Error("发件人地址无效时应返回错误")
+	}
+}
+
+func TestSend(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("监听失败: %v", err)
+	}
+	defer ln.Close()
+
+	received := make(chan []string, 1)
+	go serveSMTP(t, ln, received)
+
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+	m, err := New(config.MailConfig{Host: host, Port: port, From: "Admin <noreply@example.com>"})
+	if err != nil {
+		t.Fatalf("New 失败: %v", err)
+	}
+	if err := m.Send(context.Background(), Message{To: "alice@example.com", Subject: "hi", Body: "hello"}); err != nil {
+		t.Fatalf("Send 失败: %v", err)
+	}
+
+	commands := <-received
+	for _, want := range []string{"MAIL FROM:<noreply@example.com>", "RCPT TO:<alice@example.com>", "DATA", "QUIT"} {
+		found := false
+		for _, cmd := range commands {
+			found = found || strings.HasPrefix(cmd, want)
+		}
+		if !found {
+			t.Errorf("SMTP 会话中缺少 %s: %v", want, commands)
+		}
+	}
+	if err := m.Send(context.Background(), Message{To: "bad address"}); err == nil {
+		t.Error("收件人地址无效时应返回错误")
+	}
+}
+
+// serveSMTP 只处理一个连接的最简 SMTP 服务器，记录收到的命令
+func serveSMTP(t *testing.T, ln net.Listener, received chan<- []string) {
+	conn, err := ln.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	tp := textproto.NewConn(conn)
+
+	var commands []string
+	tp.PrintfLine("220 localhost ESMTP")
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			break
+		}
+		commands = append(commands, line)
+		switch verb := strings.ToUpper(strings.Fields(line + " x")[0]); verb {
+		case "EHLO", "HELO":
+			tp.PrintfLine("250 localhost")
+		case "DATA":
+			tp.PrintfLine("354 go ahead")
+			if _, err := tp.ReadDotBytes(); err != nil {
+				t.Errorf("读取邮件内容失败: %v", err)
+			}
+			tp.PrintfLine("250 ok")
+		case "QUIT":
+			tp.PrintfLine("221 bye")
+			received <- commands
+			return
+		default:
+			tp.PrintfLine("250 ok")
+		}
+	}
+	received <- commands
+}
@@ -1,21 +1,76 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
+	"net"
 	"os"
+	"os/signal"
+	"reflect"
+	"syscall"
+	"time"
 
+	"iris-cn-sample-project/cli"
 	"iris-cn-sample-project/config"
 	"iris-cn-sample-project/controllers"
 	"iris-cn-sample-project/database"
+	"iris-cn-sample-project/health"
+	"iris-cn-sample-project/logging"
+	"iris-cn-sample-project/metrics"
 	"iris-cn-sample-project/middleware"
+	"iris-cn-sample-project/server"
+	"iris-cn-sample-project/services"
+	"iris-cn-sample-project/storage"
+	"iris-cn-sample-project/tracing"
+	"iris-cn-sample-project/upload"
+	"iris-cn-sample-project/utils"
 
 	"github.com/kataras/iris/v12"
-	"github.com/kataras/iris/v12/middleware/logger"
-	"github.com/kataras/iris/v12/middleware/recover"
 )
 
 // main 应用程序入口函数
 func main() {
+	// config 子命令输出或校验生效的配置后退出
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		os.Exit(runConfigCommand(os.Args[2:]))
+	}
+
+	// users 子命令批量导入或导出用户后退出
+	if len(os.Args) > 1 && os.Args[1] == "users" {
+		os.Exit(cli.RunUsers(os.Args[2:]))
+	}
+
+	// 加载配置（默认值 < 配置文件 < 环境变量 < 命令行参数），校验失败时拒绝启动
+	cfg, opts, err := loadConfig(os.Args[1:])
+	if err != nil {
+		log.Fatalf("配置无效，拒绝启动:\n%v", err)
+	}
+	config.SetConfig(cfg)
+	reloader := config.NewReloader(opts)
+
+	// 初始化结构化日志
+	if _, err := logging.Init(config.GetConfig().Log); err != nil {
+		log.Fatalf("日志初始化失败: %v", err)
+	}
+
+	// 初始化链路追踪（停机时导出剩余的 span）
+	if err := tracing.Init(config.GetConfig().Tracing); err != nil {
+		logging.L().Error("链路追踪初始化失败", "error", err)
+		logging.Close()
+		os.Exit(1)
+	}
+
+	// 客户端IP解析（只信任来自可信代理的转发头）
+	serverCfg := config.GetConfig().Server
+	if err := utils.SetTrustedProxies(serverCfg.TrustedProxies, serverCfg.ClientIPHeaders); err != nil {
+		logging.L().Error("可信代理配置无效", "error", err)
+		logging.Close()
+		os.Exit(1)
+	}
+
 	// 创建 Iris 应用实例
 	app := iris.New()
 
@@ -24,22 +79,224 @@ func main() {
 
 	// 初始化数据库
 	if err := database.InitDB(); err != nil {
-		log.Fatalf("数据库初始化失败: %v", err)
+		logging.L().Error("数据库初始化失败", "error", err)
+		logging.Close()
+		os.Exit(1)
 	}
 
+	// 初始化文件存储（未配置签名密钥时由 JWT 密钥派生）
+	storageCfg := cfg.Storage
+	if storageCfg.SigningKey == "" {
+		storageCfg.SigningKey = cfg.JWT.Secret
+	}
+	if err := storage.Init(storageCfg, cfg.Upload.Dir); err != nil {
+		logging.L().Error("文件存储初始化失败", "driver", storageCfg.Driver, "error", err)
+		logging.Close()
+		os.Exit(1)
+	}
+
+	// 上传大小限制、恶意软件扫描与隔离区
+	if err := upload.Init(cfg.Upload); err != nil {
+		logging.L().Error("上传配置初始化失败", "scanner", cfg.Upload.Scanner.Driver, "error", err)
+		logging.Close()
+		os.Exit(1)
+	}
+
+	// 注册健康检查
+	setupHealthChecks()
+
 	// 设置路由
 	setupRoutes(app)
 
-	// 启动服务器
-	port := config.GetConfig().Server.Port
-	app.Listen(":" + port, iris.WithOptimizations)
+	// 跟随配置热更新的组件
+	setupConfigSubscribers(app)
+
+	if err := app.Build(); err != nil {
+		logging.L().Error("应用构建失败", "error", err)
+		logging.Close()
+		os.Exit(1)
+	}
+
+	// 启动服务器，收到 SIGINT/SIGTERM 后优雅停机；再次收到信号时立即退出
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	context.AfterFunc(ctx, stop)
+
+	// 收到 SIGHUP 或配置文件变化时热更新配置
+	go watchConfig(ctx, reloader)
+
+	// 定期清理过期未完成的可续传上传
+	go services.RunUploadCleanup(ctx, time.Duration(config.GetConfig().Upload.Tus.CleanupInterval)*time.Second)
+
+	// 定期核对存储后端：删除无主对象，标记对象已丢失的文件
+	if interval := config.GetConfig().Upload.ReconcileInterval; interval > 0 {
+		go services.RunStorageReconcile(ctx, time.Duration(interval)*time.Second)
+	}
+
+	srv := server.New(config.GetConfig().Server, app)
+	if tlsCfg := config.GetConfig().Server.TLS; tlsCfg.Enabled {
+		if err := srv.ConfigureTLS(tlsCfg); err != nil {
+			logging.L().Error("TLS 配置无效", "error", err)
+			logging.Close()
+			os.Exit(1)
+		}
+		checkHSTS(tlsCfg, config.GetConfig().Security)
+	}
+	setupShutdownHooks(srv)
+	if err := srv.Run(ctx); err != nil {
+		log.Fatalf("服务器异常退出: %v", err)
+	}
+}
+
+// loadConfig 解析命令行参数并加载、校验配置，返回的加载选项用于热更新时重新加载
+func loadConfig(args []string) (*config.Config, config.Options, error) {
+	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	var opts config.Options
+	opts.Register(fs)
+	fs.Parse(args)
+
+	cfg, err := config.Load(opts)
+	if err = errors.Join(err, cfg.Validate()); err != nil {
+		return nil, opts, err
+	}
+	return cfg, opts, nil
+}
+
+// configWatchInterval 检查配置文件变化的间隔
+const configWatchInterval = 5 * time.Second
+
+// watchConfig 收到 SIGHUP 或配置文件变化时重新加载配置，直到 ctx 被取消
+//
+// 新配置校验失败时继续使用旧配置；需要重启才能生效的配置项只记录警告。
+func watchConfig(ctx context.Context, reloader *config.Reloader) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	ticker := time.NewTicker(configWatchInterval)
+	defer ticker.Stop()
+
+	for {
+		var source string
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			source = "SIGHUP"
+		case <-ticker.C:
+			if !reloader.Changed() {
+				continue
+			}
+			source = "file"
+		}
+
+		pending, err := reloader.Reload()
+		if err != nil {
+			logging.L().Error("配置热更新失败，继续使用旧配置", "source", source, "error", err)
+			continue
+		}
+		logging.L().Info("配置已热更新", "source", source)
+		if len(pending) > 0 {
+			logging.L().Warn("部分配置需要重启才能生效", "sections", pending)
+		}
+	}
+}
+
+// setupConfigSubscribers 注册跟随配置热更新的组件（CORS 与限流中间件在创建时自行订阅）
+func setupConfigSubscribers(app *iris.Application) {
+	config.Subscribe("logging", func(old, cur *config.Config) {
+		if cur.Log.Level != old.Log.Level {
+			if err := logging.SetLevel(cur.Log.Level); err != nil {
+				logging.L().Warn("调整日志级别失败", "level", cur.Log.Level, "error", err)
+			} else {
+				app.Logger().SetLevel(cur.Log.Level)
+			}
+		}
+		if !reflect.DeepEqual(cur.Log.Redact, old.Log.Redact) {
+			r, err := logging.NewRedactor(cur.Log.Redact)
+			if err != nil {
+				logging.L().Warn("更新脱敏规则失败", "error", err)
+				return
+			}
+			logging.SetRedactor(r)
+		}
+	})
+}
+
+// runConfigCommand 执行 config 子命令，返回进程退出码
+//
+//	config print [--redacted] [--format yaml|json] [-config 文件] [-profile 环境] [-set key=value]
+//	config validate [-config 文件] [-profile 环境] [-set key=value]
+func runConfigCommand(args []string) int {
+	if len(args) == 0 || (args[0] != "print" && args[0] != "validate") {
+		fmt.Fprintln(os.Stderr, "用法: config print [--redacted] [--format yaml|json] | config validate")
+		return 2
+	}
+
+	fs := flag.NewFlagSet("config "+args[0], flag.ContinueOnError)
+	var opts config.Options
+	opts.Register(fs)
+	redacted := fs.Bool("redacted", false, "遮盖密钥、密码等敏感配置项")
+	format := fs.String("format", "yaml", "输出格式：yaml 或 json")
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	cfg, err := config.Load(opts)
+	err = errors.Join(err, cfg.Validate())
+
+	if args[0] == "print" {
+		if *redacted {
+			cfg = cfg.Redacted()
+		}
+		if perr := cfg.Print(os.Stdout, *format); perr != nil {
+			err = errors.Join(err, perr)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "配置无效:\n%v\n", err)
+		return 1
+	}
+	if args[0] == "validate" {
+		fmt.Fprintf(os.Stderr, "配置有效（profile=%s）\n", cfg.Profile)
+	}
+	return 0
+}
+
+// checkHSTS 直接提供 HTTPS 时检查 HSTS 配置（HSTS 由安全响应头中间件在 HTTPS 响应中发送）
+func checkHSTS(tlsCfg config.TLSConfig, sec config.SecurityConfig) {
+	if !sec.Enabled || sec.HSTSMaxAge <= 0 {
+		logging.L().Warn("已启用 HTTPS 但未发送 HSTS，浏览器仍可能先通过 HTTP 访问")
+		return
+	}
+	if sec.HSTSPreload && (sec.HSTSMaxAge < 31536000 || !sec.HSTSIncludeSubdomains || tlsCfg.RedirectPort == "") {
+		logging.L().Warn("HSTS preload 要求 max-age 不少于一年、包含子域名，并将 HTTP 重定向到 HTTPS（TLS_REDIRECT_PORT）",
+			"max_age", sec.HSTSMaxAge, "include_subdomains", sec.HSTSIncludeSubdomains, "redirect_port", tlsCfg.RedirectPort)
+	}
+}
+
+// setupShutdownHooks 注册停机钩子（按注册的逆序执行：推送指标、导出 span、关闭数据库，最后刷新日志）
+func setupShutdownHooks(srv *server.Server) {
+	cfg := config.GetConfig()
+
+	srv.OnShutdown("logging", func(ctx context.Context) error {
+		return logging.Close()
+	})
+	srv.OnShutdown("database", func(ctx context.Context) error {
+		return database.CloseDB()
+	})
+	srv.OnShutdown("tracing", tracing.Shutdown)
+	srv.OnShutdown("metrics", func(ctx context.Context) error {
+		instance, _ := os.Hostname()
+		return metrics.Push(ctx, cfg.Metrics.PushgatewayURL, cfg.Metrics.PushJob, instance)
+	})
 }
 
 // configureApp 配置应用程序
 func configureApp(app *iris.Application) {
 	// 设置应用配置
 	app.Configure(iris.WithConfiguration(iris.Configuration{
-		DisableInterruptHandler:          false,
+		DisableInterruptHandler:          true, // 停止信号由 server 包处理
 		DisablePathCorrection:            false,
 		EnablePathIntelligence:           true,
 		EnablePathEscape:                 true,
@@ -55,8 +312,6 @@ func configureApp(app *iris.Application) {
 		TranslateLanguageContextKey:      "language",
 		ViewLayoutContextKey:             "layout",
 		ViewDataContextKey:               "data",
-		RemoteAddrHeaders:                []string{"X-Forwarded-For"},
-		RemoteAddrHeadersForce:           false,
 		EnableOptimizationsUse:           true,
 		EnableProtoJSON:                   true,
 		DisableStartupLog:                false,
@@ -65,9 +320,25 @@ func configureApp(app *iris.Application) {
 		DisablePathCorrectionRedirection: false,
 	}))
 
+	// 请求ID在路由之前生成，未匹配路由的 404 与被拒绝的跨域预检请求同样带有请求ID
+	app.UseRouter(middleware.RequestID())
+
+	// 安全响应头同样在路由之前设置，覆盖 404 等错误页面；页面与 /api 使用不同的策略
+	app.UseRouter(middleware.SecurityHeaders())
+
+	// 指标中间件同样在路由之前执行：未匹配路由的 404 也要计入；它在 Recovery 之外，能记录 panic 恢复后的 500 状态
+	app.UseRouter(middleware.Metrics())
+
 	// 添加全局中间件
-	app.Use(recover.New())
-	app.Use(logger.New())
+	app.Use(middleware.Tracing())
+	app.Use(middleware.Recovery())
+	app.Use(middleware.Logger())
+	// 限流按认证得到的用户计数，因此先解析令牌（无效时视为匿名请求）；需要认证的路由仍由 JWTAuthentication 拒绝
+	app.Use(middleware.OptionalAuthentication())
+	app.Use(middleware.RateLimit())
+
+	// Iris 框架自身日志级别与应用保持一致
+	app.Logger().SetLevel(config.GetConfig().Log.Level)
 
 	// 设置模板引擎
 	setupTemplates(app)
@@ -82,17 +353,30 @@ func setupRoutes(app *iris.Application) {
 	app.Handle("GET", "/", controllers.Index)
 	app.Handle("GET", "/home", controllers.Home)
 
+	// Prometheus 指标
+	app.Get("/metrics", controllers.PrometheusMetrics())
+
+	// 存活与就绪检查
+	app.Get("/healthz", controllers.Liveness)
+	app.Get("/readyz", controllers.Readiness)
+
+	// CSP 违规报告收集
+	if uri := config.GetConfig().Security.CSPReportURI; uri != "" {
+		app.Post(uri, controllers.CSPReport)
+	}
+
 	// API 路由组
 	api := app.Party("/api")
 	{
-		// 添加 CORS 中间件
-		api.Use(middleware.CORS())
+		// 添加 CORS 中间件（在路由匹配前执行，以便处理预检请求）
+		api.UseRouter(middleware.CORS())
 
 		// 基础示例接口
 		api.Get("/hello", controllers.Hello)
 		api.Get("/data/{id:int}", controllers.GetData)
 		api.Post("/form", controllers.HandleForm)
-		api.Post("/upload", controllers.UploadFile)
+		// 登录用户上传的文件归属本人并计入存储配额，匿名上传按客户端IP计入匿名配额
+		api.Post("/upload", middleware.OptionalAuthentication(), controllers.UploadFile)
 
 		// 认证相关接口
 		auth := api.Party("/auth")
@@ -108,6 +392,36 @@ func setupRoutes(app *iris.Application) {
 		{
 			protected.Get("/profile", controllers.GetProfile)
 			protected.Put("/profile", controllers.UpdateProfile)
+			protected.Put("/avatar", controllers.UploadAvatar)
+			protected.Delete("/avatar", controllers.RemoveAvatar)
+		}
+
+		// 文件管理与下载：下载可以使用签名地址，其余接口需要上传者本人或管理员的令牌
+		files := api.Party("/files")
+		{
+			files.Get("/", middleware.JWTAuthentication(), controllers.ListFiles)
+			files.Get("/usage", middleware.JWTAuthentication(), controllers.GetStorageUsage)
+			files.Post("/reconcile", middleware.JWTAuthentication(), middleware.RequireAdmin(), controllers.ReconcileFiles)
+			files.Delete("/{id:uint}", middleware.JWTAuthentication(), controllers.RemoveFile)
+			files.Get("/{id:uint}/download", middleware.OptionalAuthentication(), controllers.DownloadFile)
+			files.Head("/{id:uint}/download", middleware.OptionalAuthentication(), controllers.DownloadFile)
+			files.Post("/{id:uint}/share", middleware.JWTAuthentication(), controllers.ShareFile)
+		}
+
+		// 用户头像（公开访问，没有上传头像时返回默认头像）
+		api.Get("/avatars/{id:uint}", controllers.GetAvatar)
+
+		// 可续传上传（tus 1.0），OPTIONS 用于客户端发现服务端能力，不需要认证
+		api.Options("/uploads", controllers.TusOptions)
+		api.Options("/uploads/{id}", controllers.TusOptions)
+		uploads := api.Party("/uploads")
+		uploads.Use(middleware.JWTAuthentication(), controllers.TusResumable)
+		{
+			uploads.Post("/", controllers.TusCreate)
+			uploads.Head("/{id}", controllers.TusHead)
+			uploads.Patch("/{id}", controllers.TusPatch)
+			uploads.Delete("/{id}", controllers.TusDelete)
+			uploads.Post("/{id}", controllers.TusMethodOverride)
 		}
 
 		// 用户管理接口
@@ -115,13 +429,23 @@ func setupRoutes(app *iris.Application) {
 		users.Use(middleware.JWTAuthentication())
 		{
 			users.Get("/", controllers.GetUsers)
+			users.Get("/search", controllers.SearchUsers)
+			users.Get("/export", middleware.RequireAdmin(), controllers.ExportUsers)
+			users.Post("/import", middleware.RequireAdmin(), middleware.OmitRequestBody(), controllers.ImportUsers)
 			users.Get("/{id:int}", controllers.GetUser)
 			users.Put("/{id:int}", controllers.UpdateUser)
 			users.Delete("/{id:int}", controllers.DeleteUser)
+			users.Put("/{id:int}/quota", middleware.RequireAdmin(), controllers.UpdateUserQuota)
 		}
 
 		// API 文档
 		api.Get("/docs", controllers.APIDocs)
+
+		// 健康检查（详细结果）
+		api.Get("/health", controllers.HealthCheck)
+
+		// 系统指标（JSON 视图）
+		api.Get("/metrics", controllers.Metrics)
 	}
 
 	// 用户页面路由
@@ -136,11 +460,37 @@ func setupRoutes(app *iris.Application) {
 	app.OnErrorCode(iris.StatusInternalServerError, controllers.InternalServerError)
 }
 
+// setupHealthChecks 注册与应用运行环境相关的健康检查（数据库相关检查由 database 包注册）
+func setupHealthChecks() {
+	cfg := config.GetConfig()
+	checkTimeout := time.Duration(cfg.Health.CheckTimeout) * time.Millisecond
+
+	health.SetCacheTTL(time.Duration(cfg.Health.CacheTTL) * time.Millisecond)
+
+	// goroutine 泄漏或处理堆积时存活检查失败，由编排系统重启进程
+	if cfg.Health.MaxGoroutines > 0 {
+		health.Register("goroutines", health.Liveness, checkTimeout, health.GoroutineCountCheck(cfg.Health.MaxGoroutines))
+	}
+
+	// 上传目录磁盘空间
+	if err := os.MkdirAll(cfg.Upload.Dir, 0755); err != nil {
+		logging.L().Warn("创建上传目录失败", "dir", cfg.Upload.Dir, "error", err)
+	}
+	minFree := uint64(cfg.Health.MinFreeDiskMB) << 20
+	health.Register("upload_disk", health.Readiness, checkTimeout, health.DiskSpaceCheck(cfg.Upload.Dir, minFree))
+
+	// 邮件服务（仅在配置了 SMTP 时检查）
+	if cfg.Mail.Host != "" {
+		addr := net.JoinHostPort(cfg.Mail.Host, cfg.Mail.Port)
+		health.Register("mailer", health.Readiness, checkTimeout, health.TCPDialCheck(addr))
+	}
+}
+
 // setupTemplates 设置模板引擎
 func setupTemplates(app *iris.Application) {
 	// 创建模板目录
 	if err := os.MkdirAll("templates", 0755); err != nil {
-		log.Printf("创建模板目录失败: %v", err)
+		logging.L().Warn("创建模板目录失败", "error", err)
 	}
 
 	// 注册 HTML 模板引擎
@@ -158,7 +508,7 @@ func setupStaticFiles(app *iris.Application) {
 	dirs := []string{"static", "static/css", "static/js", "static/images"}
 	for _, dir := range dirs {
 		if err := os.MkdirAll(dir, 0755); err != nil {
-			log.Printf("创建静态目录失败 %s: %v", dir, err)
+			logging.L().Warn("创建静态目录失败", "dir", dir, "error", err)
 		}
 	}
 
@@ -0,0 +1,313 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/client_golang/prometheus/push"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// namespace 指标名前缀
+const namespace = "iris_sample"
+
+// Registry 应用专用的指标注册表（不使用全局默认注册表，避免第三方库污染）
+var Registry = prometheus.NewRegistry()
+
+// HTTP 请求指标
+var (
+	// HTTPRequestsTotal 按方法、路由模板、状态码统计的请求总数
+	HTTPRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "HTTP 请求总数",
+	}, []string{"method", "route", "status"})
+
+	// HTTPRequestDuration 按方法、路由模板、状态码统计的请求耗时
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "HTTP 请求处理耗时（秒）",
+		Buckets:   []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+	}, []string{"method", "route", "status"})
+
+	// HTTPRequestsInFlight 正在处理中的请求数
+	HTTPRequestsInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_in_flight",
+		Help:      "正在处理中的 HTTP 请求数",
+	})
+
+	// HTTPResponseSize 按路由模板统计的响应大小
+	HTTPResponseSize = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "response_size_bytes",
+		Help:      "HTTP 响应体大小（字节）",
+		Buckets:   prometheus.ExponentialBuckets(128, 4, 8),
+	}, []string{"method", "route"})
+)
+
+// 业务指标
+var (
+	// LoginsTotal 登录成功次数
+	LoginsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "auth",
+		Name:      "logins_total",
+		Help:      "登录成功次数",
+	})
+
+	// LoginFailuresTotal 登录失败次数（按原因区分）
+	LoginFailuresTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "auth",
+		Name:      "login_failures_total",
+		Help:      "登录失败次数",
+	}, []string{"reason"})
+
+	// RegistrationsTotal 注册成功次数
+	RegistrationsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "auth",
+		Name:      "registrations_total",
+		Help:      "用户注册成功次数",
+	})
+)
+
+// RateLimitedTotal 被限流拒绝的请求数（按策略区分）
+var RateLimitedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: "http",
+	Name:      "rate_limited_total",
+	Help:      "被限流拒绝的请求数",
+}, []string{"policy"})
+
+// CSPViolationsTotal 浏览器上报的 CSP 违规次数（按违反的指令区分）
+var CSPViolationsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: "http",
+	Name:      "csp_violations_total",
+	Help:      "浏览器上报的 CSP 违规次数",
+}, []string{"directive"})
+
+// UploadsRejectedTotal 被拒绝的上传次数（按原因区分）
+var UploadsRejectedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: "upload",
+	Name:      "rejected_total",
+	Help:      "被拒绝的上传次数",
+}, []string{"reason"})
+
+// StorageOrphansDeletedTotal 核对任务删除的无主对象数
+var StorageOrphansDeletedTotal = prometheus.NewCounter(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: "storage",
+	Name:      "orphans_deleted_total",
+	Help:      "核对任务删除的无主对象数",
+})
+
+// 登录失败原因标签值
+const (
+	LoginFailureUserNotFound  = "user_not_found"
+	LoginFailureBadPassword   = "bad_password"
+	LoginFailureUserInactive  = "user_inactive"
+	LoginFailureInternalError = "internal_error"
+)
+
+// 上传被拒绝原因标签值
+const (
+	UploadRejectedEmpty           = "empty"
+	UploadRejectedTooLarge        = "too_large"
+	UploadRejectedTypeNotAllowed  = "type_not_allowed"
+	UploadRejectedSuspicious      = "suspicious"
+	UploadRejectedInfected        = "infected"
+	UploadRejectedScanUnavailable = "scan_unavailable"
+	UploadRejectedQuotaExceeded   = "quota_exceeded"
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequestsTotal,
+		HTTPRequestDuration,
+		HTTPRequestsInFlight,
+		HTTPResponseSize,
+		LoginsTotal,
+		LoginFailuresTotal,
+		RegistrationsTotal,
+		RateLimitedTotal,
+		CSPViolationsTotal,
+		UploadsRejectedTotal,
+		StorageOrphansDeletedTotal,
+	)
+
+	// 预先初始化失败原因标签，保证指标在第一次失败之前就能被抓取到
+	for _, reason := range []string{
+		LoginFailureUserNotFound,
+		LoginFailureBadPassword,
+		LoginFailureUserInactive,
+		LoginFailureInternalError,
+	} {
+		LoginFailuresTotal.WithLabelValues(reason)
+	}
+	for _, reason := range []string{
+		UploadRejectedEmpty,
+		UploadRejectedTooLarge,
+		UploadRejectedTypeNotAllowed,
+		UploadRejectedSuspicious,
+		UploadRejectedInfected,
+		UploadRejectedScanUnavailable,
+		UploadRejectedQuotaExceeded,
+	} {
+		UploadsRejectedTotal.WithLabelValues(reason)
+	}
+}
+
+// dbCollector 当前已注册的数据库连接池采集器
+var (
+	dbCollectorMu sync.Mutex
+	dbCollector   prometheus.Collector
+)
+
+// RegisterDB 注册数据库连接池指标（来源于 sql.DB.Stats()），重复调用时替换旧的采集器
+func RegisterDB(db *sql.DB, dbName string) error {
+	dbCollectorMu.Lock()
+	defer dbCollectorMu.Unlock()
+
+	if dbCollector != nil {
+		Registry.Unregister(dbCollector)
+	}
+
+	collector := collectors.NewDBStatsCollector(db, dbName)
+	if err := Registry.Register(collector); err != nil {
+		return err
+	}
+	dbCollector = collector
+
+	return nil
+}
+
+// Handler 返回 Prometheus 文本格式的指标输出处理器
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{
+		Registry: Registry,
+	})
+}
+
+// Push 将当前指标推送到 Pushgateway（停机前调用，避免最后一次抓取之后的数据丢失）
+//
+// 以 instance 分组，多个实例推送到同一 job 时互不覆盖；url 为空时不推送。
+func Push(ctx context.Context, url, job, instance string) error {
+	if url == "" {
+		return nil
+	}
+	return push.New(url, job).
+		Gatherer(Registry).
+		Grouping("instance", instance).
+		PushContext(ctx)
+}
+
+// Snapshot 将当前所有指标整理为便于 JSON 输出的结构
+func Snapshot() (map[string]interface{}, error) {
+	families, err := Registry.Gather()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]interface{}, len(families))
+	for _, family := range families {
+		samples := make([]map[string]interface{}, 0, len(family.GetMetric()))
+		for _, m := range family.GetMetric() {
+			samples = append(samples, sampleToMap(family.GetType(), m))
+		}
+		result[family.GetName()] = map[string]interface{}{
+			"help":    family.GetHelp(),
+			"type":    strings.ToLower(family.GetType().String()),
+			"samples": samples,
+		}
+	}
+
+	return result, nil
+}
+
+// sampleToMap 将单个采样转换为 map
+func sampleToMap(metricType dto.MetricType, m *dto.Metric) map[string]interface{} {
+	sample := make(map[string]interface{})
+
+	if len(m.GetLabel()) > 0 {
+		labels := make(map[string]string, len(m.GetLabel()))
+		for _, label := range m.GetLabel() {
+			labels[label.GetName()] = label.GetValue()
+		}
+		sample["labels"] = labels
+	}
+
+	switch metricType {
+	case dto.MetricType_COUNTER:
+		sample["value"] = m.GetCounter().GetValue()
+	case dto.MetricType_GAUGE:
+		sample["value"] = m.GetGauge().GetValue()
+	case dto.MetricType_UNTYPED:
+		sample["value"] = m.GetUntyped().GetValue()
+	case dto.MetricType_HISTOGRAM:
+		h := m.GetHistogram()
+		sample["count"] = h.GetSampleCount()
+		sample["sum"] = h.GetSampleSum()
+		sample["quantiles"] = histogramQuantiles(h)
+	case dto.MetricType_SUMMARY:
+		s := m.GetSummary()
+		sample["count"] = s.GetSampleCount()
+		sample["sum"] = s.GetSampleSum()
+		quantiles := make(map[string]float64, len(s.GetQuantile()))
+		for _, q := range s.GetQuantile() {
+			quantiles[formatQuantile(q.GetQuantile())] = q.GetValue()
+		}
+		sample["quantiles"] = quantiles
+	}
+
+	return sample
+}
+
+// histogramQuantiles 根据直方图桶估算 p50/p95/p99（取落入的桶上界，与 Prometheus 的线性插值结果近似）
+func histogramQuantiles(h *dto.Histogram) map[string]float64 {
+	quantiles := map[string]float64{}
+	total := h.GetSampleCount()
+	if total == 0 {
+		return quantiles
+	}
+
+	buckets := h.GetBucket()
+	sort.Slice(buckets, func(i, j int) bool {
+		return buckets[i].GetUpperBound() < buckets[j].GetUpperBound()
+	})
+
+	for _, q := range []float64{0.5, 0.95, 0.99} {
+		rank := q * float64(total)
+		for _, b := range buckets {
+			if float64(b.GetCumulativeCount()) >= rank {
+				quantiles[formatQuantile(q)] = b.GetUpperBound()
+				break
+			}
+		}
+	}
+
+	return quantiles
+}
+
+// formatQuantile 将分位数格式化为 p50 形式
+func formatQuantile(q float64) string {
+	return "p" + strconv.FormatFloat(q*100, 'f', -1, 64)
+}
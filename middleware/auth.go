@@ -16,8 +16,9 @@ func JWTAuthentication() iris.Handler {
 		authHeader := ctx.GetHeader("Authorization")
 		if authHeader == "" {
 			ctx.JSON(iris.Map{
-				"code":    401,
-				"message": "缺少认证令牌",
+				"code":       401,
+				"message":    "缺少认证令牌",
+				"request_id": utils.RequestID(ctx),
 			})
 			ctx.StatusCode(iris.StatusUnauthorized)
 			return
@@ -27,8 +28,9 @@ func JWTAuthentication() iris.Handler {
 		const bearerPrefix = "Bearer "
 		if !strings.HasPrefix(authHeader, bearerPrefix) {
 			ctx.JSON(iris.Map{
-				"code":    401,
-				"message": "认证令牌格式错误",
+				"code":       401,
+				"message":    "认证令牌格式错误",
+				"request_id": utils.RequestID(ctx),
 			})
 			ctx.StatusCode(iris.StatusUnauthorized)
 			return
@@ -41,8 +43,9 @@ func JWTAuthentication() iris.Handler {
 		claims, err := utils.ValidateJWT(tokenString)
 		if err != nil {
 			ctx.JSON(iris.Map{
-				"code":    401,
-				"message": "无效的认证令牌: " + err.Error(),
+				"code":       401,
+				"message":    "无效的认证令牌: " + err.Error(),
+				"request_id": utils.RequestID(ctx),
 			})
 			ctx.StatusCode(iris.StatusUnauthorized)
 			return
@@ -65,8 +68,9 @@ func RequireRole(roles ...string) iris.Handler {
 		userRole, ok := ctx.Values().GetString("role")
 		if !ok {
 			ctx.JSON(iris.Map{
-				"code":    403,
-				"message": "无法获取用户角色信息",
+				"code":       403,
+				"message":    "无法获取用户角色信息",
+				"request_id": utils.RequestID(ctx),
 			})
 			ctx.StatusCode(iris.StatusForbidden)
 			return
@@ -83,8 +87,9 @@ func RequireRole(roles ...string) iris.Handler {
 
 		if !hasPermission {
 			ctx.JSON(iris.Map{
-				"code":    403,
-				"message": "权限不足",
+				"code":       403,
+				"message":    "权限不足",
+				"request_id": utils.RequestID(ctx),
 			})
 			ctx.StatusCode(iris.StatusForbidden)
 			return
@@ -1,98 +1,230 @@
 package middleware
 
 import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"iris-cn-sample-project/config"
+	"iris-cn-sample-project/logging"
+	"iris-cn-sample-project/utils"
+
 	"github.com/kataras/iris/v12"
 )
 
-// CORS 跨域资源共享中间件
+// CORS 跨域资源共享中间件（使用全局跨域配置，随配置热更新）
+//
+// 需要通过 Party.UseRouter 注册：预检请求（OPTIONS）没有对应的路由，Use 注册的中间件不会执行。
 func CORS() iris.Handler {
-	return func(ctx iris.Context) {
-		// 设置 CORS 头
-		ctx.Header("Access-Control-Allow-Origin", "*")
-		ctx.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS, PATCH")
-		ctx.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization")
-		ctx.Header("Access-Control-Expose-Headers", "Content-Length, Access-Control-Allow-Origin, Access-Control-Allow-Headers, Content-Type")
-		ctx.Header("Access-Control-Allow-Credentials", "true")
+	return reloadable("cors", func(c *config.Config) config.CORSConfig { return c.CORS }, CORSWithConfig)
+}
 
-		// 处理预检请求
-		if ctx.Method() == "OPTIONS" {
-			ctx.StatusCode(iris.StatusNoContent)
-			return
-		}
+// CORSWithConfig 使用指定跨域配置的中间件
+//
+// 请求路径匹配到 Overrides 中的路径前缀时使用对应策略（最长前缀优先），否则使用默认策略。
+// 来源不被允许的普通请求不返回 CORS 响应头（由浏览器拦截），不被允许的预检请求直接返回 403。
+func CORSWithConfig(cfg config.CORSConfig) iris.Handler {
+	defaultPolicy := newCORSPolicy(cfg.CORSPolicy)
 
-		ctx.Next()
+	overrides := make([]corsOverride, 0, len(cfg.Overrides))
+	for _, o := range cfg.Overrides {
+		overrides = append(overrides, corsOverride{prefix: o.PathPrefix, policy: newCORSPolicy(o.CORSPolicy)})
 	}
-}
+	sort.SliceStable(overrides, func(i, j int) bool {
+		return len(overrides[i].prefix) > len(overrides[j].prefix)
+	})
 
-// CORSWithOrigin 允许指定源的 CORS 中间件
-func CORSWithOrigin(allowedOrigins ...string) iris.Handler {
 	return func(ctx iris.Context) {
-		origin := ctx.GetHeader("Origin")
-		
-		// 检查请求源是否在允许列表中
-		allowed := false
-		for _, allowedOrigin := range allowedOrigins {
-			if origin == allowedOrigin {
-				allowed = true
+		policy := defaultPolicy
+		for _, o := range overrides {
+			if strings.HasPrefix(ctx.Path(), o.prefix) {
+				policy = o.policy
 				break
 			}
 		}
 
-		// 如果允许则设置对应的源，否则不设置
-		if allowed {
-			ctx.Header("Access-Control-Allow-Origin", origin)
-		} else {
-			ctx.Header("Access-Control-Allow-Origin", "")
-		}
+		// 响应内容随 Origin 变化，避免缓存把某个源的响应返回给其他源
+		header := ctx.ResponseWriter().Header()
+		header.Add("Vary", "Origin")
 
-		ctx.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS, PATCH")
-		ctx.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization")
-		ctx.Header("Access-Control-Expose-Headers", "Content-Length, Access-Control-Allow-Origin, Access-Control-Allow-Headers, Content-Type")
-		ctx.Header("Access-Control-Allow-Credentials", "true")
+		origin := ctx.GetHeader("Origin")
+		if origin == "" {
+			ctx.Next()
+			return
+		}
 
-		// 处理预检请求
-		if ctx.Method() == "OPTIONS" {
-			ctx.StatusCode(iris.StatusNoContent)
+		if ctx.Method() == http.MethodOptions && ctx.GetHeader("Access-Control-Request-Method") != "" {
+			header.Add("Vary", "Access-Control-Request-Method")
+			header.Add("Vary", "Access-Control-Request-Headers")
+			policy.preflight(ctx, origin)
 			return
 		}
 
+		if policy.allowOrigin(origin) {
+			policy.writeOrigin(ctx, origin)
+			if policy.exposedHeaders != "" {
+				ctx.Header("Access-Control-Expose-Headers", policy.exposedHeaders)
+			}
+		}
+
 		ctx.Next()
 	}
 }
 
-// CORSWithCredentials 带凭据的 CORS 中间件
-func CORSWithCredentials(allowedOrigins ...string) iris.Handler {
-	return func(ctx iris.Context) {
-		origin := ctx.GetHeader("Origin")
-		
-		// 检查请求源是否在允许列表中
-		allowed := false
-		for _, allowedOrigin := range allowedOrigins {
-			if origin == allowedOrigin {
-				allowed = true
-				break
-			}
+// corsOverride 路径前缀对应的策略
+type corsOverride struct {
+	prefix string
+	policy *corsPolicy
+}
+
+// corsPolicy 预处理后的跨域策略
+type corsPolicy struct {
+	anyOrigin      bool
+	origins        map[string]struct{}
+	wildcards      []wildcardOrigin
+	methods        map[string]struct{}
+	anyHeader      bool
+	headers        map[string]struct{}
+	exposedHeaders string
+	credentials    bool
+	maxAge         string
+}
+
+// wildcardOrigin 子域名通配源，例如 https://*.example.com 拆分为 "https://" 与 ".example.com"
+type wildcardOrigin struct {
+	prefix string
+	suffix string
+}
+
+// newCORSPolicy 预处理跨域策略
+func newCORSPolicy(p config.CORSPolicy) *corsPolicy {
+	policy := &corsPolicy{
+		origins:        make(map[string]struct{}),
+		methods:        make(map[string]struct{}),
+		headers:        make(map[string]struct{}),
+		exposedHeaders: strings.Join(p.ExposedHeaders, ", "),
+		credentials:    p.AllowCredentials,
+	}
+
+	for _, origin := range p.AllowedOrigins {
+		origin = strings.ToLower(strings.TrimRight(strings.TrimSpace(origin), "/"))
+		switch {
+		case origin == "*":
+			policy.anyOrigin = true
+		case strings.Contains(origin, "://*."):
+			i := strings.Index(origin, "*")
+			policy.wildcards = append(policy.wildcards, wildcardOrigin{prefix: origin[:i], suffix: origin[i+1:]})
+		case origin != "":
+			policy.origins[origin] = struct{}{}
+		}
+	}
+
+	// 浏览器不接受 Access-Control-Allow-Origin: * 与凭据同时出现，这种配置下不发送凭据头
+	if policy.anyOrigin && policy.credentials {
+		logging.L().Warn("CORS 配置允许任意源时不能携带凭据，已忽略 allow_credentials")
+		policy.credentials = false
+	}
+
+	for _, method := range p.AllowedMethods {
+		policy.methods[strings.ToUpper(strings.TrimSpace(method))] = struct{}{}
+	}
+	for _, h := range p.AllowedHeaders {
+		h = strings.ToLower(strings.TrimSpace(h))
+		if h == "*" {
+			policy.anyHeader = true
+			continue
 		}
+		policy.headers[h] = struct{}{}
+	}
+
+	if p.MaxAge > 0 {
+		policy.maxAge = strconv.Itoa(p.MaxAge)
+	}
+
+	return policy
+}
 
-		// 如果允许则设置对应的源，否则不设置
-		if allowed {
-			ctx.Header("Access-Control-Allow-Origin", origin)
-		} else {
-			ctx.Header("Access-Control-Allow-Origin", "")
+// allowOrigin 判断请求源是否被允许
+func (p *corsPolicy) allowOrigin(origin string) bool {
+	if p.anyOrigin {
+		return true
+	}
+
+	origin = strings.ToLower(origin)
+	if _, ok := p.origins[origin]; ok {
+		return true
+	}
+
+	for _, w := range p.wildcards {
+		// 通配部分至少包含一个字符，且只能是子域名（不能包含路径、端口或用户信息）
+		if len(origin) <= len(w.prefix)+len(w.suffix) ||
+			!strings.HasPrefix(origin, w.prefix) || !strings.HasSuffix(origin, w.suffix) {
+			continue
 		}
+		if sub := origin[len(w.prefix) : len(origin)-len(w.suffix)]; !strings.ContainsAny(sub, "/:@") {
+			return true
+		}
+	}
+
+	return false
+}
 
-		ctx.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS, PATCH")
-		ctx.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization")
-		ctx.Header("Access-Control-Expose-Headers", "Content-Length, Access-Control-Allow-Origin, Access-Control-Allow-Headers, Content-Type")
+// writeOrigin 写入允许的源与凭据响应头
+func (p *corsPolicy) writeOrigin(ctx iris.Context, origin string) {
+	if p.anyOrigin {
+		ctx.Header("Access-Control-Allow-Origin", "*")
+	} else {
+		ctx.Header("Access-Control-Allow-Origin", origin)
+	}
+	if p.credentials {
 		ctx.Header("Access-Control-Allow-Credentials", "true")
-		ctx.Header("Vary", "Origin")
+	}
+}
+
+// preflight 处理预检请求：校验源、方法与请求头，通过后原样返回请求的方法与请求头
+func (p *corsPolicy) preflight(ctx iris.Context, origin string) {
+	if !p.allowOrigin(origin) {
+		rejectPreflight(ctx, "请求源不被允许")
+		return
+	}
+
+	method := strings.ToUpper(strings.TrimSpace(ctx.GetHeader("Access-Control-Request-Method")))
+	if _, ok := p.methods[method]; !ok {
+		rejectPreflight(ctx, "请求方法不被允许")
+		return
+	}
 
-		// 处理预检请求
-		if ctx.Method() == "OPTIONS" {
-			ctx.StatusCode(iris.StatusNoContent)
+	var requested []string
+	for _, h := range strings.Split(ctx.GetHeader("Access-Control-Request-Headers"), ",") {
+		h = strings.ToLower(strings.TrimSpace(h))
+		if h == "" {
+			continue
+		}
+		if _, ok := p.headers[h]; !ok && !p.anyHeader {
+			rejectPreflight(ctx, "请求头不被允许: "+h)
 			return
 		}
+		requested = append(requested, h)
+	}
 
-		ctx.Next()
+	p.writeOrigin(ctx, origin)
+	ctx.Header("Access-Control-Allow-Methods", method)
+	if len(requested) > 0 {
+		ctx.Header("Access-Control-Allow-Headers", strings.Join(requested, ", "))
+	}
+	if p.maxAge != "" {
+		ctx.Header("Access-Control-Max-Age", p.maxAge)
 	}
-}
\ No newline at end of file
+
+	ctx.StopWithStatus(iris.StatusNoContent)
+}
+
+// rejectPreflight 拒绝预检请求
+func rejectPreflight(ctx iris.Context, reason string) {
+	ctx.StopWithJSON(iris.StatusForbidden, iris.Map{
+		"code":       403,
+		"message":    "跨域请求被拒绝：" + reason,
+		"request_id": utils.RequestID(ctx),
+	})
+}
@@ -0,0 +1,287 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"iris-cn-sample-project/config"
+
+	"github.com/kataras/iris/v12"
+)
+
+// newCORSApp 创建挂载了跨域中间件的测试应用
+func newCORSApp(t *testing.T, cfg config.CORSConfig) *iris.Application {
+	t.Helper()
+
+	app := iris.New()
+	api := app.Party("/api")
+	api.UseRouter(CORSWithConfig(cfg))
+	api.Get("/users", func(ctx iris.Context) {
+		ctx.JSON(iris.Map{"code": 200})
+	})
+	api.Put("/users", func(ctx iris.Context) {
+		ctx.JSON(iris.Map{"code": 200})
+	})
+	api.Get("/public/info", func(ctx iris.Context) {
+		ctx.JSON(iris.Map{"code": 200})
+	})
+
+	if err := app.Build(); err != nil {
+		t.Fatalf("构建应用失败: %v", err)
+	}
+	return app
+}
+
+// testCORSConfig 测试用跨域配置
+func testCORSConfig() config.CORSConfig {
+	policy := config.CORSPolicy{
+		AllowedOrigins:   []string{"https://app.example.org", "https://*.example.com"},
+		AllowedMethods:   []string{"GET", "POST", "PUT"},
+		AllowedHeaders:   []string{"Content-Type", "Authorization"},
+		ExposedHeaders:   []string{"X-Request-ID"},
+		AllowCredentials: true,
+		MaxAge:           600,
+	}
+
+	public := config.CORSOverride{PathPrefix: "/api/public", CORSPolicy: policy.Clone()}
+	public.AllowedOrigins = []string{"*"}
+
+	return config.CORSConfig{
+		CORSPolicy: policy,
+		Overrides:  []config.CORSOverride{public},
+	}
+}
+
+// TestCORS 跨域策略表驱动测试
+func TestCORS(t *testing.T) {
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		headers map[string]string
+
+		wantStatus      int
+		wantOrigin      string // 期望的 Access-Control-Allow-Origin，空表示不应出现
+		wantCredentials bool
+		wantMethods     string
+		wantHeaders     string
+		wantMaxAge      string
+	}{
+		{
+			name:       "无 Origin 的同源请求",
+			method:     http.MethodGet,
+			path:       "/api/users",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:            "精确匹配的源",
+			method:          http.MethodGet,
+			path:            "/api/users",
+			headers:         map[string]string{"Origin": "https://app.example.org"},
+			wantStatus:      http.StatusOK,
+			wantOrigin:      "https://app.example.org",
+			wantCredentials: true,
+		},
+		{
+			name:            "子域名通配",
+			method:          http.MethodGet,
+			path:            "/api/users",
+			headers:         map[string]string{"Origin": "https://admin.example.com"},
+			wantStatus:      http.StatusOK,
+			wantOrigin:      "https://admin.example.com",
+			wantCredentials: true,
+		},
+		{
+			name:            "多级子域名通配",
+			method:          http.MethodGet,
+			path:            "/api/users",
+			headers:         map[string]string{"Origin": "https://a.b.example.com"},
+			wantStatus:      http.StatusOK,
+			wantOrigin:      "https://a.b.example.com",
+			wantCredentials: true,
+		},
+		{
+			name:       "通配不匹配主域名",
+			method:     http.MethodGet,
+			path:       "/api/users",
+			headers:    map[string]string{"Origin": "https://example.com"},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "通配不匹配相似域名",
+			method:     http.MethodGet,
+			path:       "/api/users",
+			headers:    map[string]string{"Origin": "https://evilexample.com"},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "通配不匹配其他协议",
+			method:     http.MethodGet,
+			path:       "/api/users",
+			headers:    map[string]string{"Origin": "http://admin.example.com"},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "不允许的源仍正常处理但不返回 CORS 头",
+			method:     http.MethodGet,
+			path:       "/api/users",
+			headers:    map[string]string{"Origin": "https://evil.test"},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:   "允许的预检请求",
+			method: http.MethodOptions,
+			path:   "/api/users",
+			headers: map[string]string{
+				"Origin":                         "https://app.example.org",
+				"Access-Control-Request-Method":  "PUT",
+				"Access-Control-Request-Headers": "Content-Type, Authorization",
+			},
+			wantStatus:      http.StatusNoContent,
+			wantOrigin:      "https://app.example.org",
+			wantCredentials: true,
+			wantMethods:     "PUT",
+			wantHeaders:     "content-type, authorization",
+			wantMaxAge:      "600",
+		},
+		{
+			name:   "预检请求源不被允许",
+			method: http.MethodOptions,
+			path:   "/api/users",
+			headers: map[string]string{
+				"Origin":                        "https://evil.test",
+				"Access-Control-Request-Method": "GET",
+			},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:   "预检请求方法不被允许",
+			method: http.MethodOptions,
+			path:   "/api/users",
+			headers: map[string]string{
+				"Origin":                        "https://app.example.org",
+				"Access-Control-Request-Method": "DELETE",
+			},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:   "预检请求头不被允许",
+			method: http.MethodOptions,
+			path:   "/api/users",
+			headers: map[string]string{
+				"Origin":                         "https://app.example.org",
+				"Access-Control-Request-Method":  "GET",
+				"Access-Control-Request-Headers": "X-Custom-Secret",
+			},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "路径覆盖策略允许任意源且不携带凭据",
+			method:     http.MethodGet,
+			path:       "/api/public/info",
+			headers:    map[string]string{"Origin": "https://anyone.test"},
+			wantStatus: http.StatusOK,
+			wantOrigin: "*",
+		},
+	}
+
+	app := newCORSApp(t, testCORSConfig())
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			app.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("状态码 = %d，期望 %d", rec.Code, tt.wantStatus)
+			}
+
+			h := rec.Header()
+			if got := h.Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q，期望 %q", got, tt.wantOrigin)
+			}
+			if got := h.Get("Access-Control-Allow-Credentials") == "true"; got != tt.wantCredentials {
+				t.Errorf("Access-Control-Allow-Credentials = %v，期望 %v", got, tt.wantCredentials)
+			}
+			if got := h.Get("Access-Control-Allow-Methods"); got != tt.wantMethods {
+				t.Errorf("Access-Control-Allow-Methods = %q，期望 %q", got, tt.wantMethods)
+			}
+			if got := h.Get("Access-Control-Allow-Headers"); got != tt.wantHeaders {
+				t.Errorf("Access-Control-Allow-Headers = %q，期望 %q", got, tt.wantHeaders)
+			}
+			if got := h.Get("Access-Control-Max-Age"); got != tt.wantMaxAge {
+				t.Errorf("Access-Control-Max-Age = %q，期望 %q", got, tt.wantMaxAge)
+			}
+			if !strings.Contains(strings.Join(h.Values("Vary"), ","), "Origin") {
+				t.Errorf("响应缺少 Vary: Origin，实际 %q", h.Values("Vary"))
+			}
+		})
+	}
+}
+
+// TestCORSWildcardWithCredentials 验证任意源与凭据同时配置时不发送凭据头
+func TestCORSWildcardWithCredentials(t *testing.T) {
+	cfg := config.CORSConfig{CORSPolicy: config.CORSPolicy{
+		AllowedOrigins:   []string{"*"},
+		AllowedMethods:   []string{"GET"},
+		AllowCredentials: true,
+	}}
+	app := newCORSApp(t, cfg)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/users", nil)
+	req.Header.Set("Origin", "https://anyone.test")
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Errorf("Access-Control-Allow-Origin = %q，期望 *", got)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Credentials"); got != "" {
+		t.Errorf("任意源时不应发送 Access-Control-Allow-Credentials，实际 %q", got)
+	}
+}
+
+// TestCORSReload 验证全局跨域中间件随配置热更新
+func TestCORSReload(t *testing.T) {
+	previous := config.GetConfig()
+	t.Cleanup(func() { config.SetConfig(previous) })
+
+	cfg := config.Defaults()
+	cfg.CORS = testCORSConfig()
+	config.SetConfig(cfg)
+
+	app := iris.New()
+	app.UseRouter(CORS())
+	app.Get("/api/users", func(ctx iris.Context) {})
+	if err := app.Build(); err != nil {
+		t.Fatalf("构建应用失败: %v", err)
+	}
+
+	allowed := func() string {
+		req := httptest.NewRequest(http.MethodGet, "/api/users", nil)
+		req.Header.Set("Origin", "https://new.example.net")
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+		return rec.Header().Get("Access-Control-Allow-Origin")
+	}
+
+	if got := allowed(); got != "" {
+		t.Fatalf("热更新前不应允许新的源，实际 %q", got)
+	}
+
+	next := config.Defaults()
+	next.CORS = testCORSConfig()
+	next.CORS.AllowedOrigins = append(next.CORS.AllowedOrigins, "https://new.example.net")
+	if _, err := config.Update(next); err != nil {
+		t.Fatalf("更新配置失败: %v", err)
+	}
+	if got := allowed(); got != "https://new.example.net" {
+		t.Errorf("热更新后应允许新的源，实际 %q", got)
+	}
+}
@@ -3,166 +3,243 @@ package middleware
 import (
 	"bytes"
 	"io"
+	"log/slog"
+	"math/rand"
+	"mime"
+	"net/http"
+	"strings"
 	"time"
 
+	"iris-cn-sample-project/config"
+	"iris-cn-sample-project/logging"
+	"iris-cn-sample-project/utils"
+
 	"github.com/kataras/iris/v12"
 )
 
-// Logger 自定义日志中间件
+// Logger 结构化请求日志中间件（使用全局日志配置）
 func Logger() iris.Handler {
+	return LoggerWithConfig(config.GetConfig().Log)
+}
+
+// LoggerWithConfig 使用指定日志配置的请求日志中间件
+//
+// 请求/响应体只在开启 LogBodies 或处于 debug 级别时记录：失败请求（状态码 >= 400）始终记录，
+// 成功请求按 BodySampleRate 采样；记录前会按脱敏规则处理，并截断到 BodyMaxBytes。
+// 无法按字段脱敏的请求体（multipart 表单、CSV）以及使用 OmitRequestBody 的路由不记录请求体。
+func LoggerWithConfig(cfg config.LogConfig) iris.Handler {
 	return func(ctx iris.Context) {
 		// 记录开始时间
 		start := time.Now()
 
-		// 读取请求体（用于日志记录）
-		var requestBody []byte
-		if ctx.Request().Body != nil {
-			requestBody, _ = io.ReadAll(ctx.Request().Body)
-			ctx.Request().Body = io.NopCloser(bytes.NewBuffer(requestBody))
+		// 按需捕获请求体与响应体（边读边截取，不会预先把整个请求体读入内存）
+		var body *bodyCapture
+		capture := shouldCaptureBodies(cfg, ctx.Method())
+		if capture {
+			if redactableBody(ctx.GetHeader("Content-Type")) {
+				body = newBodyCapture(ctx.Request().Body, cfg.BodyMaxBytes)
+				ctx.Request().Body = body
+			}
+			ctx.Record()
 		}
 
 		// 处理请求
 		ctx.Next()
 
-		// 计算处理时间
-		duration := time.Since(start)
-
 		// 记录请求日志
-		logRequest(ctx, requestBody, duration)
+		logRequest(ctx, cfg, capture, body, time.Since(start))
+	}
+}
+
+// omitRequestBodyKey 路由要求不记录请求体时在上下文中设置的键
+const omitRequestBodyKey = "log_omit_request_body"
+
+// OmitRequestBody 本路由的请求日志不记录请求体（例如可能包含明文密码的批量导入文件），响应体照常记录
+func OmitRequestBody() iris.Handler {
+	return func(ctx iris.Context) {
+		ctx.Values().Set(omitRequestBodyKey, true)
+		ctx.Next()
+	}
+}
+
+// redactableBody 判断请求体能否按脱敏规则处理：只有 JSON 与 URL 编码表单能按字段名脱敏，
+// 其他类型（CSV、multipart 文件、tus 分块、图片等二进制或未知类型）可能包含明文密码等数据，不捕获
+func redactableBody(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mediaType == "application/json", strings.HasSuffix(mediaType, "+json"):
+		return true
+	case mediaType == "application/x-www-form-urlencoded":
+		return true
+	default:
+		return false
 	}
 }
 
 // logRequest 记录请求日志
-func logRequest(ctx iris.Context, requestBody []byte, duration time.Duration) {
-	// 获取请求信息
-	method := ctx.Method()
-	path := ctx.Path()
+func logRequest(ctx iris.Context, cfg config.LogConfig, capture bool, body *bodyCapture, latency time.Duration) {
 	statusCode := ctx.GetStatusCode()
-	clientIP := ctx.RemoteAddr()
-	userAgent := ctx.GetHeader("User-Agent")
-	referer := ctx.GetHeader("Referer")
+	redactor := logging.Redact()
+
+	// 响应大小（未写入响应体时 Written() 返回 -1）
+	bytesWritten := ctx.ResponseWriter().Written()
+	if bytesWritten < 0 {
+		bytesWritten = 0
+	}
 
-	// 获取响应大小
-	responseSize := ctx.ResponseWriter().Written()
+	attrs := []slog.Attr{
+		slog.String("request_id", ctx.Values().GetString("request_id")),
+		slog.String("method", ctx.Method()),
+		slog.String("path", ctx.Path()),
+		slog.String("route", routeTemplate(ctx)),
+		slog.Int("status", statusCode),
+		slog.Duration("latency", latency),
+		slog.Int("bytes", bytesWritten),
+		slog.String("client_ip", utils.ClientIP(ctx)),
+		slog.String("user_agent", ctx.GetHeader("User-Agent")),
+	}
 
-	// 构建日志消息
-	logMessage := map[string]interface{}{
-		"timestamp":    time.Now().Format("2006-01-02 15:04:05"),
-		"method":       method,
-		"path":         path,
-		"status_code":  statusCode,
-		"duration":     duration.String(),
-		"client_ip":    clientIP,
-		"user_agent":   userAgent,
-		"referer":      referer,
-		"response_size": responseSize,
+	if query := ctx.Request().URL.RawQuery; query != "" {
+		attrs = append(attrs, slog.String("query", redactor.Query(query)))
 	}
 
-	// 添加请求体（仅对特定方法和路径）
-	if shouldLogRequestBody(method, path) && len(requestBody) > 0 {
-		logMessage["request_body"] = string(requestBody)
+	if referer := ctx.GetHeader("Referer"); referer != "" {
+		attrs = append(attrs, slog.String("referer", redactor.String(referer)))
 	}
 
 	// 添加用户信息（如果有认证）
 	if userID := ctx.Values().Get("user_id"); userID != nil {
-		logMessage["user_id"] = userID
-	}
-	if username := ctx.Values().Get("username"); username != nil {
-		logMessage["username"] = username
+		attrs = append(attrs, slog.Any("user_id", userID))
 	}
 
-	// 根据状态码确定日志级别
-	logLevel := getLogLevel(statusCode)
+	// 添加请求/响应体（失败请求始终记录，成功请求按采样率记录）
+	if capture && shouldLogBodies(cfg, statusCode) {
+		attrs = append(attrs, slog.Any("request_headers", redactor.Headers(ctx.Request().Header)))
+
+		if body == nil || ctx.Values().GetBoolDefault(omitRequestBodyKey, false) {
+			if ctx.Request().ContentLength != 0 {
+				attrs = append(attrs, slog.Bool("request_body_omitted", true))
+			}
+		} else {
+			body.fill()
+			if requestBody := body.Bytes(); len(requestBody) > 0 {
+				attrs = append(attrs,
+					slog.String("request_body", redactor.Body(ctx.GetHeader("Content-Type"), requestBody)),
+					slog.Bool("request_body_truncated", body.Truncated()),
+				)
+			}
+		}
 
-	// 输出日志（这里简化处理，实际项目中可以使用 logrus、zap 等日志库）
-	printLog(logLevel, logMessage)
+		if recorder, ok := ctx.IsRecording(); ok {
+			responseBody := recorder.Body()
+			truncated := false
+			if cfg.BodyMaxBytes > 0 && len(responseBody) > cfg.BodyMaxBytes {
+				responseBody = responseBody[:cfg.BodyMaxBytes]
+				truncated = true
+			}
+			if len(responseBody) > 0 {
+				attrs = append(attrs,
+					slog.String("response_body", redactor.Body(ctx.ResponseWriter().Header().Get("Content-Type"), responseBody)),
+					slog.Bool("response_body_truncated", truncated),
+				)
+			}
+		}
+	}
+
+	logging.L().LogAttrs(ctx.Request().Context(), getLogLevel(statusCode), "HTTP 请求", attrs...)
 }
 
-// shouldLogRequestBody 判断是否应该记录请求体
-func shouldLogRequestBody(method, path string) bool {
-	// 不记录敏感接口的请求体
-	sensitivePaths := []string{
-		"/api/auth/login",
-		"/api/auth/register",
-		"/api/auth/refresh",
+// shouldCaptureBodies 判断是否需要捕获请求/响应体
+func shouldCaptureBodies(cfg config.LogConfig, method string) bool {
+	if !cfg.LogBodies && logging.Level() > slog.LevelDebug {
+		return false
 	}
 
-	for _, sensitivePath := range sensitivePaths {
-		if path == sensitivePath {
-			return false
-		}
-	}
+	// 只记录 POST、PUT、PATCH、DELETE 请求
+	return method == "POST" || method == "PUT" || method == "PATCH" || method == "DELETE"
+}
 
-	// 只记录 POST、PUT、PATCH 请求的请求体
-	return method == "POST" || method == "PUT" || method == "PATCH"
+// shouldLogBodies 判断本次请求是否记录请求/响应体：失败始终记录，成功按采样率记录
+func shouldLogBodies(cfg config.LogConfig, statusCode int) bool {
+	if statusCode >= 400 {
+		return true
+	}
+	if cfg.BodySampleRate <= 0 {
+		return false
+	}
+	return cfg.BodySampleRate >= 1 || rand.Float64() < cfg.BodySampleRate
 }
 
 // getLogLevel 根据状态码获取日志级别
-func getLogLevel(statusCode int) string {
+func getLogLevel(statusCode int) slog.Level {
 	switch {
 	case statusCode >= 500:
-		return "ERROR"
+		return slog.LevelError
 	case statusCode >= 400:
-		return "WARN"
-	case statusCode >= 300:
-		return "INFO"
+		return slog.LevelWarn
 	default:
-		return "INFO"
+		return slog.LevelInfo
 	}
 }
 
-// printLog 打印日志（简化版本）
-func printLog(level string, message map[string]interface{}) {
-	// 这里简化处理，实际项目中应该使用专业的日志库
-	switch level {
-	case "ERROR":
-		println("[ERROR]", formatLogMessage(message))
-	case "WARN":
-		println("[WARN]", formatLogMessage(message))
-	default:
-		println("[INFO]", formatLogMessage(message))
+// bodyCapture 在处理器读取请求体的同时截取前 limit 个字节
+type bodyCapture struct {
+	body      io.ReadCloser
+	buf       bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+// newBodyCapture 创建请求体截取器，limit <= 0 表示不限制
+func newBodyCapture(body io.ReadCloser, limit int) *bodyCapture {
+	if body == nil {
+		body = http.NoBody
 	}
+	return &bodyCapture{body: body, limit: limit}
 }
 
-// formatLogMessage 格式化日志消息
-func formatLogMessage(message map[string]interface{}) string {
-	// 简化的日志格式化
-	return message["timestamp"].(string) + " " + 
-		   message["method"].(string) + " " + 
-		   message["path"].(string) + " " + 
-		   string(rune(message["status_code"].(int))) + " " + 
-		   message["duration"].(string)
+// Read 读取请求体并记录
+func (b *bodyCapture) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+	b.record(p[:n])
+	return n, err
 }
 
-// RequestID 请求ID中间件
-func RequestID() iris.Handler {
-	return func(ctx iris.Context) {
-		// 生成或获取请求ID
-		requestID := ctx.GetHeader("X-Request-ID")
-		if requestID == "" {
-			requestID = generateRequestID()
-		}
+// Close 关闭请求体
+func (b *bodyCapture) Close() error {
+	return b.body.Close()
+}
 
-		// 设置请求ID到响应头和上下文
-		ctx.Header("X-Request-ID", requestID)
-		ctx.Values().Set("request_id", requestID)
+// record 记录不超过 limit 的内容
+func (b *bodyCapture) record(p []byte) {
+	if b.limit <= 0 {
+		b.buf.Write(p)
+		return
+	}
 
-		ctx.Next()
+	remaining := b.limit - b.buf.Len()
+	if len(p) > remaining {
+		p = p[:remaining]
+		b.truncated = true
 	}
+	b.buf.Write(p)
 }
 
-// generateRequestID 生成请求ID
-func generateRequestID() string {
-	// 简化的请求ID生成
-	return time.Now().Format("20060102150405") + "-" + randomString(8)
+// fill 处理器未读完请求体时（例如提前返回错误），补读到 limit 以便记录
+func (b *bodyCapture) fill() {
+	if b.truncated || b.limit <= 0 {
+		return
+	}
+	remaining := int64(b.limit - b.buf.Len() + 1)
+	io.CopyN(io.Discard, b, remaining)
 }
 
-// randomString 生成随机字符串
-func randomString(length int) string {
-	const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
-	b := make([]byte, length)
-	for i := range b {
-		b[i] = charset[i%len(charset)]
-	}
-	return string(b)
-}
\ No newline at end of file
+// Bytes 获取已截取的内容
+func (b *bodyCapture) Bytes() []byte {
+	return b.buf.Bytes()
+}
+
+// Truncated 是否被截断
+func (b *bodyCapture) Truncated() bool {
+	return b.truncated
+}
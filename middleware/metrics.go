@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"strconv"
+	"time"
+
+	"iris-cn-sample-project/metrics"
+
+	"github.com/kataras/iris/v12"
+)
+
+// unmatchedRoute 未匹配到路由（如 404）时使用的路由标签，避免原始路径导致标签基数爆炸
+const unmatchedRoute = "unmatched"
+
+// Metrics 请求指标中间件，按路由模板与状态码记录请求数、耗时及进行中的请求
+//
+// 需要用 UseRouter 注册：用 Use 注册时只在匹配到路由后执行，未匹配路由的请求不会被记录。
+func Metrics() iris.Handler {
+	return func(ctx iris.Context) {
+		start := time.Now()
+
+		metrics.HTTPRequestsInFlight.Inc()
+		defer metrics.HTTPRequestsInFlight.Dec()
+
+		ctx.Next()
+
+		route := routeTemplate(ctx)
+		method := ctx.Method()
+		status := strconv.Itoa(ctx.GetStatusCode())
+
+		metrics.HTTPRequestsTotal.WithLabelValues(method, route, status).Inc()
+		metrics.HTTPRequestDuration.WithLabelValues(method, route, status).Observe(time.Since(start).Seconds())
+		// Written() 在未写入响应体时返回 -1
+		size := ctx.ResponseWriter().Written()
+		if size < 0 {
+			size = 0
+		}
+		metrics.HTTPResponseSize.WithLabelValues(method, route).Observe(float64(size))
+	}
+}
+
+// routeTemplate 获取当前请求匹配到的路由模板（例如 /api/users/{id:int}）
+func routeTemplate(ctx iris.Context) string {
+	if route := ctx.GetCurrentRoute(); route != nil {
+		return route.Path()
+	}
+	return unmatchedRoute
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"iris-cn-sample-project/metrics"
+
+	"github.com/kataras/iris/v12"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetrics(t *testing.T) {
+	app := iris.New()
+	app.UseRouter(Metrics())
+	app.Get("/api/users/{id:uint}", func(ctx iris.Context) {
+		ctx.JSON(iris.Map{"code": 200})
+	})
+	if err := app.Build(); err != nil {
+		t.Fatalf("构建应用失败: %v", err)
+	}
+
+	requests := func(route, status string) float64 {
+		return testutil.ToFloat64(metrics.HTTPRequestsTotal.WithLabelValues(http.MethodGet, route, status))
+	}
+	matched, unmatched := requests("/api/users/{id:uint}", "200"), requests(unmatchedRoute, "404")
+
+	for _, path := range []string{"/api/users/1", "/api/users/2", "/api/nope/1"} {
+		app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	// 按路由模板而不是原始路径记录
+	if got := requests("/api/users/{id:uint}", "200") - matched; got != 2 {
+		t.Errorf("匹配路由的请求数 = %v, want 2", got)
+	}
+	// 未匹配路由的 404 同样计入，路由标签统一为 unmatched
+	if got := requests(unmatchedRoute, "404") - unmatched; got != 1 {
+		t.Errorf("未匹配路由的请求数 = %v, want 1", got)
+	}
+}
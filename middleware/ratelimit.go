@@ -0,0 +1,252 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"iris-cn-sample-project/config"
+	"iris-cn-sample-project/logging"
+	"iris-cn-sample-project/metrics"
+	"iris-cn-sample-project/ratelimit"
+	"iris-cn-sample-project/utils"
+
+	"github.com/kataras/iris/v12"
+)
+
+// 限流维度
+const (
+	rateLimitKeyIP     = "ip"
+	rateLimitKeyUser   = "user"
+	rateLimitKeyAPIKey = "api_key"
+)
+
+// RateLimit 限流中间件（使用全局限流配置，策略随配置热更新；计数按策略名保存在存储中，热更新后继续累计）
+func RateLimit() iris.Handler {
+	cfg := config.GetConfig().RateLimit
+
+	var store ratelimit.Store
+	switch cfg.Store {
+	case "memory", "":
+		store = ratelimit.NewMemoryStore()
+	default:
+		logging.L().Warn("不支持的限流存储，使用进程内存储", "store", cfg.Store)
+		store = ratelimit.NewMemoryStore()
+	}
+
+	return reloadable("rate_limit", func(c *config.Config) config.RateLimitConfig { return c.RateLimit },
+		func(cfg config.RateLimitConfig) iris.Handler { return RateLimitWithConfig(cfg, store) })
+}
+
+// RateLimitWithConfig 使用指定限流配置与存储的中间件
+//
+// 请求会依次经过所有匹配的策略，任一策略拒绝即返回 429；响应头中的 RateLimit-* 反映剩余配额最少的策略。
+// 用户角色在 ExemptRoles 中的请求不受限流。存储出错时放行请求，避免限流故障导致整个服务不可用。
+// 用户信息来自之前执行的认证中间件（OptionalAuthentication），限流本身不解析令牌。
+func RateLimitWithConfig(cfg config.RateLimitConfig, store ratelimit.Store) iris.Handler {
+	if !cfg.Enabled {
+		return func(ctx iris.Context) {
+			ctx.Next()
+		}
+	}
+
+	policies := make([]*rateLimitPolicy, 0, len(cfg.Policies))
+	for _, p := range cfg.Policies {
+		policy, err := newRateLimitPolicy(p)
+		if err != nil {
+			logging.L().Warn("忽略无效的限流策略", "policy", p.Name, "error", err)
+			continue
+		}
+		policies = append(policies, policy)
+	}
+
+	exempt := make(map[string]struct{}, len(cfg.ExemptRoles))
+	for _, role := range cfg.ExemptRoles {
+		exempt[role] = struct{}{}
+	}
+	apiKeys := make(map[string]struct{}, len(cfg.APIKeys))
+	for _, digest := range cfg.APIKeys {
+		apiKeys[strings.ToLower(digest)] = struct{}{}
+	}
+
+	return func(ctx iris.Context) {
+		id := rateLimitIdentity(ctx, apiKeys)
+		if _, ok := exempt[id.role]; ok && id.role != "" {
+			ctx.Next()
+			return
+		}
+
+		var (
+			tightest       ratelimit.Result
+			tightestPolicy *rateLimitPolicy
+		)
+
+		now := time.Now()
+		for _, policy := range policies {
+			if !policy.matches(ctx.Method(), ctx.Path()) {
+				continue
+			}
+
+			result, err := store.Take(ctx.Request().Context(), policy.storeKey(ctx, id), policy.limit, now)
+			if err != nil {
+				logging.L().WarnContext(ctx.Request().Context(), "限流存储出错，放行请求", "policy", policy.name, "error", err)
+				continue
+			}
+
+			if !result.Allowed {
+				metrics.RateLimitedTotal.WithLabelValues(policy.name).Inc()
+				writeRateLimitHeaders(ctx, policy, result)
+				ctx.Header("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter, 1)))
+				ctx.StopWithJSON(iris.StatusTooManyRequests, iris.Map{
+					"code":       429,
+					"message":    "请求过于频繁，请稍后重试",
+					"request_id": utils.RequestID(ctx),
+				})
+				return
+			}
+
+			if tightestPolicy == nil || result.Remaining < tightest.Remaining {
+				tightest, tightestPolicy = result, policy
+			}
+		}
+
+		if tightestPolicy != nil {
+			writeRateLimitHeaders(ctx, tightestPolicy, tightest)
+		}
+
+		ctx.Next()
+	}
+}
+
+// rateLimitPolicy 预处理后的限流策略
+type rateLimitPolicy struct {
+	name   string
+	routes []rateLimitRoute
+	key    string
+	limit  ratelimit.Limit
+}
+
+// rateLimitRoute 策略匹配的路由，method 为空表示任意方法
+type rateLimitRoute struct {
+	method string
+	path   string
+}
+
+// newRateLimitPolicy 校验并预处理限流策略
+func newRateLimitPolicy(p config.RateLimitPolicy) (*rateLimitPolicy, error) {
+	policy := &rateLimitPolicy{
+		name: p.Name,
+		key:  p.Key,
+		limit: ratelimit.Limit{
+			Algorithm: ratelimit.Algorithm(p.Algorithm),
+			Requests:  p.Requests,
+			Window:    time.Duration(p.Window) * time.Second,
+			Burst:     p.Burst,
+		},
+	}
+
+	if err := policy.limit.Validate(); err != nil {
+		return nil, err
+	}
+
+	switch policy.key {
+	case rateLimitKeyIP, rateLimitKeyUser, rateLimitKeyAPIKey:
+	case "":
+		policy.key = rateLimitKeyIP
+	default:
+		return nil, fmt.Errorf("不支持的限流维度: %s", p.Key)
+	}
+
+	for _, r := range p.Routes {
+		route := rateLimitRoute{path: strings.TrimSpace(r)}
+		if method, path, ok := strings.Cut(route.path, " "); ok {
+			route.method = strings.ToUpper(method)
+			route.path = strings.TrimSpace(path)
+		}
+		route.path = strings.TrimRight(route.path, "/")
+		policy.routes = append(policy.routes, route)
+	}
+
+	return policy, nil
+}
+
+// matches 判断请求是否匹配策略（路径按前缀匹配）
+func (p *rateLimitPolicy) matches(method, path string) bool {
+	if len(p.routes) == 0 {
+		return true
+	}
+	for _, r := range p.routes {
+		if r.method != "" && r.method != method {
+			continue
+		}
+		if r.path == "" || path == r.path || strings.HasPrefix(path, r.path+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// storeKey 生成限流存储中使用的 key：没有经过验证的用户或 API Key 时按客户端 IP 计数
+func (p *rateLimitPolicy) storeKey(ctx iris.Context, id rateLimitID) string {
+	switch p.key {
+	case rateLimitKeyUser:
+		if id.userID != "" {
+			return p.name + ":user:" + id.userID
+		}
+	case rateLimitKeyAPIKey:
+		if id.apiKey != "" {
+			return p.name + ":key:" + id.apiKey
+		}
+	}
+	return p.name + ":ip:" + utils.ClientIP(ctx)
+}
+
+// rateLimitID 请求中经过验证的身份
+type rateLimitID struct {
+	userID string // 认证中间件设置的用户ID
+	role   string
+	apiKey string // 已签发的 API Key 的摘要，未提供或不认识的 Key 为空
+}
+
+// rateLimitIdentity 获取请求中经过验证的身份
+//
+// 用户只取认证中间件放入上下文的 user_id（令牌无效时视为匿名请求）；X-API-Key 只有摘要在 apiKeys 中时才使用，
+// 否则随意生成的 Key 都会得到一份新的配额。
+func rateLimitIdentity(ctx iris.Context, apiKeys map[string]struct{}) rateLimitID {
+	var id rateLimitID
+	if v, ok := ctx.Values().Get("user_id").(uint); ok && v != 0 {
+		id.userID = strconv.FormatUint(uint64(v), 10)
+		id.role = ctx.Values().GetString("role")
+	}
+	if apiKey := ctx.GetHeader("X-API-Key"); apiKey != "" {
+		// 不在存储中保存 API Key 明文
+		sum := sha256.Sum256([]byte(apiKey))
+		digest := hex.EncodeToString(sum[:])
+		if _, ok := apiKeys[digest]; ok {
+			id.apiKey = digest[:32]
+		}
+	}
+	return id
+}
+
+// writeRateLimitHeaders 写入 RateLimit-* 响应头（IETF RateLimit header fields 草案）
+func writeRateLimitHeaders(ctx iris.Context, policy *rateLimitPolicy, result ratelimit.Result) {
+	header := ctx.ResponseWriter().Header()
+	header.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+	header.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	header.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset, 0)))
+	header.Set("RateLimit-Policy", strconv.Itoa(policy.limit.Requests)+";w="+strconv.Itoa(int(policy.limit.Window/time.Second)))
+}
+
+// ceilSeconds 将时长向上取整为秒，且不小于 floor
+func ceilSeconds(d time.Duration, floor int) int {
+	s := int(math.Ceil(d.Seconds()))
+	if s < floor {
+		return floor
+	}
+	return s
+}
@@ -0,0 +1,211 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"iris-cn-sample-project/config"
+	"iris-cn-sample-project/ratelimit"
+	"iris-cn-sample-project/utils"
+
+	"github.com/kataras/iris/v12"
+)
+
+// newRateLimitApp 创建挂载了限流中间件的测试应用
+func newRateLimitApp(t *testing.T, store ratelimit.Store) *iris.Application {
+	t.Helper()
+
+	cfg := config.RateLimitConfig{
+		Enabled:     true,
+		ExemptRoles: []string{"admin"},
+		APIKeys:     []string{apiKeyDigest("key-a"), apiKeyDigest("key-b")},
+		Policies: []config.RateLimitPolicy{
+			{Name: "login", Routes: []string{"POST /api/auth/login"}, Key: "ip", Algorithm: "sliding_window", Requests: 2, Window: 60},
+			{Name: "api", Routes: []string{"/api"}, Key: "user", Algorithm: "token_bucket", Requests: 60, Window: 60, Burst: 5},
+			{Name: "partner", Routes: []string{"/api/partner"}, Key: "api_key", Algorithm: "token_bucket", Requests: 1, Window: 60},
+		},
+	}
+
+	app := iris.New()
+	app.Use(OptionalAuthentication(), RateLimitWithConfig(cfg, store))
+	ok := func(ctx iris.Context) { ctx.JSON(iris.Map{"code": 200}) }
+	app.Post("/api/auth/login", ok)
+	app.Get("/api/users", ok)
+	app.Get("/api/partner/orders", ok)
+	app.Get("/pages/users", ok)
+	if err := app.Build(); err != nil {
+		t.Fatalf("构建应用失败: %v", err)
+	}
+	return app
+}
+
+// apiKeyDigest 返回 API Key 的 SHA-256 摘要（rate_limit.api_keys 中的格式）
+func apiKeyDigest(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// doRateLimited 发送请求
+func doRateLimited(app *iris.Application, method, target string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, nil)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, req)
+	return rec
+}
+
+// TestRateLimitRejects 验证超出配额返回 429 与 RateLimit-*、Retry-After 响应头
+func TestRateLimitRejects(t *testing.T) {
+	app := newRateLimitApp(t, ratelimit.NewMemoryStore())
+
+	rec := doRateLimited(app, http.MethodPost, "/api/auth/login", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("状态码 = %d，期望 200", rec.Code)
+	}
+	// 同时匹配 login 与 api 策略时，响应头反映剩余配额更少的 login 策略
+	if got := rec.Header().Get("RateLimit-Limit"); got != "2" {
+		t.Errorf("RateLimit-Limit = %q，期望 2", got)
+	}
+	if got := rec.Header().Get("RateLimit-Remaining"); got != "1" {
+		t.Errorf("RateLimit-Remaining = %q，期望 1", got)
+	}
+	if got := rec.Header().Get("RateLimit-Policy"); got != "2;w=60" {
+		t.Errorf("RateLimit-Policy = %q，期望 2;w=60", got)
+	}
+
+	doRateLimited(app, http.MethodPost, "/api/auth/login", nil)
+	rec = doRateLimited(app, http.MethodPost, "/api/auth/login", nil)
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("状态码 = %d，期望 429", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Error("429 响应缺少 Retry-After")
+	}
+	if got := rec.Header().Get("RateLimit-Remaining"); got != "0" {
+		t.Errorf("RateLimit-Remaining = %q，期望 0", got)
+	}
+
+	// 其他路由不受 login 策略影响，未匹配任何策略的路由不返回限流头
+	if rec := doRateLimited(app, http.MethodGet, "/api/users", nil); rec.Code != http.StatusOK {
+		t.Errorf("/api/users 状态码 = %d，期望 200", rec.Code)
+	}
+	if rec := doRateLimited(app, http.MethodGet, "/pages/users", nil); rec.Header().Get("RateLimit-Limit") != "" {
+		t.Error("未匹配策略的路由不应返回 RateLimit-Limit")
+	}
+}
+
+// TestRateLimitKeys 验证按用户与 API Key 分别计数，以及管理员豁免
+func TestRateLimitKeys(t *testing.T) {
+	app := newRateLimitApp(t, ratelimit.NewMemoryStore())
+
+	token := func(id uint, role string) map[string]string {
+		tok, _, err := utils.GenerateJWT(id, "user", role)
+		if err != nil {
+			t.Fatalf("生成令牌失败: %v", err)
+		}
+		return map[string]string{"Authorization": "Bearer " + tok}
+	}
+
+	// 用户 1 用尽突发容量后被拒绝，同一 IP 下的用户 2 不受影响
+	alice := token(1, "user")
+	for i := 0; i < 5; i++ {
+		doRateLimited(app, http.MethodGet, "/api/users", alice)
+	}
+	if rec := doRateLimited(app, http.MethodGet, "/api/users", alice); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("用户 1 状态码 = %d，期望 429", rec.Code)
+	}
+	if rec := doRateLimited(app, http.MethodGet, "/api/users", token(2, "user")); rec.Code != http.StatusOK {
+		t.Errorf("用户 2 状态码 = %d，期望 200", rec.Code)
+	}
+
+	// 管理员不受限流，也不返回限流头
+	admin := token(3, "admin")
+	for i := 0; i < 10; i++ {
+		rec := doRateLimited(app, http.MethodGet, "/api/users", admin)
+		if rec.Code != http.StatusOK || rec.Header().Get("RateLimit-Limit") != "" {
+			t.Fatalf("管理员第 %d 次请求: 状态码 %d，RateLimit-Limit %q", i+1, rec.Code, rec.Header().Get("RateLimit-Limit"))
+		}
+	}
+
+	// 不同 API Key 分别计数
+	if rec := doRateLimited(app, http.MethodGet, "/api/partner/orders", map[string]string{"X-API-Key": "key-a"}); rec.Code != http.StatusOK {
+		t.Errorf("key-a 第 1 次状态码 = %d，期望 200", rec.Code)
+	}
+	if rec := doRateLimited(app, http.MethodGet, "/api/partner/orders", map[string]string{"X-API-Key": "key-a"}); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("key-a 第 2 次状态码 = %d，期望 429", rec.Code)
+	}
+	if rec := doRateLimited(app, http.MethodGet, "/api/partner/orders", map[string]string{"X-API-Key": "key-b"}); rec.Code != http.StatusOK {
+		t.Errorf("key-b 状态码 = %d，期望 200", rec.Code)
+	}
+
+	// 不认识的 API Key 按客户端 IP 计数，随意更换 Key 不能绕过限流
+	if rec := doRateLimited(app, http.MethodGet, "/api/partner/orders", map[string]string{"X-API-Key": "random-1"}); rec.Code != http.StatusOK {
+		t.Errorf("random-1 状态码 = %d，期望 200", rec.Code)
+	}
+	if rec := doRateLimited(app, http.MethodGet, "/api/partner/orders", map[string]string{"X-API-Key": "random-2"}); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("random-2 状态码 = %d，期望 429", rec.Code)
+	}
+}
+
+// TestRateLimitUsesAuthenticatedUser 验证限流只使用认证中间件设置的用户
+func TestRateLimitUsesAuthenticatedUser(t *testing.T) {
+	cfg := config.RateLimitConfig{
+		Enabled:  true,
+		Policies: []config.RateLimitPolicy{{Name: "api", Key: "user", Algorithm: "token_bucket", Requests: 1, Window: 60}},
+	}
+	app := iris.New()
+	app.Use(func(ctx iris.Context) {
+		if ctx.GetHeader("X-Test-User") != "" {
+			ctx.Values().Set("user_id", uint(9))
+		}
+		ctx.Next()
+	}, RateLimitWithConfig(cfg, ratelimit.NewMemoryStore()))
+	app.Get("/api/users", func(ctx iris.Context) { ctx.JSON(iris.Map{"code": 200}) })
+	if err := app.Build(); err != nil {
+		t.Fatalf("构建应用失败: %v", err)
+	}
+
+	// 限流本身不解析令牌：没有经过认证中间件的令牌不区分用户，按 IP 计数
+	token := func(id uint) map[string]string {
+		tok, _, err := utils.GenerateJWT(id, "user", "user")
+		if err != nil {
+			t.Fatalf("生成令牌失败: %v", err)
+		}
+		return map[string]string{"Authorization": "Bearer " + tok}
+	}
+	if rec := doRateLimited(app, http.MethodGet, "/api/users", token(1)); rec.Code != http.StatusOK {
+		t.Fatalf("第 1 次状态码 = %d，期望 200", rec.Code)
+	}
+	if rec := doRateLimited(app, http.MethodGet, "/api/users", token(2)); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("未经认证的令牌状态码 = %d，期望 429", rec.Code)
+	}
+	if rec := doRateLimited(app, http.MethodGet, "/api/users", map[string]string{"X-Test-User": "1"}); rec.Code != http.StatusOK {
+		t.Errorf("认证用户状态码 = %d，期望 200", rec.Code)
+	}
+}
+
+// failingStore 总是返回错误的存储
+type failingStore struct{}
+
+func (failingStore) Take(context.Context, string, ratelimit.Limit, time.Time) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errors.New("存储不可用")
+}
+
+// TestRateLimitStoreFailure 验证存储出错时放行请求
+func TestRateLimitStoreFailure(t *testing.T) {
+	app := newRateLimitApp(t, failingStore{})
+
+	for i := 0; i < 5; i++ {
+		if rec := doRateLimited(app, http.MethodPost, "/api/auth/login", nil); rec.Code != http.StatusOK {
+			t.Fatalf("状态码 = %d，期望 200", rec.Code)
+		}
+	}
+}
@@ -1,95 +1,174 @@
 package middleware
 
 import (
+	"context"
 	"fmt"
+	"html"
+	"net/http"
 	"runtime/debug"
 
+	"iris-cn-sample-project/config"
+	"iris-cn-sample-project/logging"
+	"iris-cn-sample-project/reporting"
+	"iris-cn-sample-project/tracing"
+	"iris-cn-sample-project/utils"
+
 	"github.com/kataras/iris/v12"
 )
 
-// Recovery 恢复中间件，用于捕获 panic 并优雅处理
+// PanicHandler panic 恢复后生成响应的处理器
+type PanicHandler func(ctx iris.Context, event *reporting.Event)
+
+// Recovery 恢复中间件（使用全局错误上报配置），用于捕获 panic 并优雅处理
 func Recovery() iris.Handler {
+	return CustomRecovery(nil)
+}
+
+// CustomRecovery 使用自定义响应处理器的恢复中间件，customHandler 为 nil 时使用默认响应
+func CustomRecovery(customHandler PanicHandler) iris.Handler {
+	cfg := config.GetConfig()
+
+	reporter, err := reporting.New(cfg.ErrorReport)
+	if err != nil {
+		logging.L().Warn("错误上报配置无效，使用日志上报", "reporter", cfg.ErrorReport.Reporter, "error", err)
+		reporter = reporting.NewLogReporter()
+	}
+
+	if customHandler == nil {
+		debugMode := cfg.Server.Mode == "debug"
+		customHandler = func(ctx iris.Context, event *reporting.Event) {
+			handlePanic(ctx, event, debugMode)
+		}
+	}
+
+	return RecoveryWithConfig(cfg.ErrorReport, reporter, customHandler)
+}
+
+// RecoveryWithConfig 使用指定上报器与响应处理器的恢复中间件
+//
+// 捕获 panic 后收集调用栈、请求ID、路由、用户ID与脱敏后的请求体交给 reporter（去重由 reporter 负责），
+// 再由 handler 返回 500 响应。http.ErrAbortHandler 按 net/http 的约定继续向上抛出。
+func RecoveryWithConfig(cfg config.ErrorReportConfig, reporter reporting.Reporter, handler PanicHandler) iris.Handler {
 	return func(ctx iris.Context) {
+		// 边读边截取请求体，panic 时随上报附带
+		var body *bodyCapture
+		if cfg.BodyMaxBytes > 0 && hasRequestBody(ctx.Method()) && redactableBody(ctx.GetHeader("Content-Type")) {
+			body = newBodyCapture(ctx.Request().Body, cfg.BodyMaxBytes)
+			ctx.Request().Body = body
+		}
+
 		defer func() {
-			if err := recover(); err != nil {
-				// 记录 panic 信息
-				logPanic(ctx, err)
-				
-				// 返回友好的错误响应
-				handlePanic(ctx, err)
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			if recovered == http.ErrAbortHandler {
+				panic(recovered)
 			}
+
+			event := newPanicEvent(ctx, recovered, body)
+			if err := reporter.Report(context.WithoutCancel(ctx.Request().Context()), event); err != nil {
+				logging.L().ErrorContext(ctx.Request().Context(), "错误上报失败",
+					"error", err, "event_id", event.ID, "panic", event.Message, "stack", event.Stack)
+			}
+
+			ctx.StopExecution()
+			handler(ctx, event)
 		}()
 
 		ctx.Next()
 	}
 }
 
-// logPanic 记录 panic 信息
-func logPanic(ctx iris.Context, err interface{}) {
-	// 构建错误信息
-	errorInfo := map[string]interface{}{
-		"error":      fmt.Sprintf("%v", err),
-		"stack":      string(debug.Stack()),
-		"method":     ctx.Method(),
-		"path":       ctx.Path(),
-		"query":      ctx.URLParams(),
-		"headers":    ctx.Request().Header,
-		"remote_addr": ctx.RemoteAddr(),
-		"user_agent": ctx.GetHeader("User-Agent"),
-	}
+// newPanicEvent 根据 panic 与请求信息创建上报事件
+func newPanicEvent(ctx iris.Context, recovered interface{}, body *bodyCapture) *reporting.Event {
+	event := reporting.NewEvent(recovered, debug.Stack(), 1)
+	redactor := logging.Redact()
+
+	event.RequestID = ctx.Values().GetString("request_id")
+	event.TraceID = tracing.TraceID(ctx.Request().Context())
+	event.Method = ctx.Method()
+	event.Path = ctx.Path()
+	event.Route = routeTemplate(ctx)
+	event.Query = redactor.Query(ctx.Request().URL.RawQuery)
+	event.ClientIP = utils.ClientIP(ctx)
+	event.UserAgent = ctx.GetHeader("User-Agent")
 
-	// 添加用户信息（如果有认证）
 	if userID := ctx.Values().Get("user_id"); userID != nil {
-		errorInfo["user_id"] = userID
+		event.UserID = fmt.Sprint(userID)
 	}
-	if username := ctx.Values().Get("username"); username != nil {
-		errorInfo["username"] = username
+
+	if body != nil && !ctx.Values().GetBoolDefault(omitRequestBodyKey, false) {
+		body.fill()
+		if requestBody := body.Bytes(); len(requestBody) > 0 {
+			event.Body = redactor.Body(ctx.GetHeader("Content-Type"), requestBody)
+		}
 	}
 
-	// 输出错误日志（这里简化处理）
-	println("[PANIC] 服务器内部错误:")
-	println("错误信息:", fmt.Sprintf("%v", err))
-	println("请求方法:", ctx.Method())
-	println("请求路径:", ctx.Path())
-	println("客户端IP:", ctx.RemoteAddr())
-	println("堆栈信息:", string(debug.Stack()))
+	return event
 }
 
-// handlePanic 处理 panic 响应
-func handlePanic(ctx iris.Context, err interface{}) {
+// hasRequestBody 判断请求方法是否通常带有请求体
+func hasRequestBody(method string) bool {
+	return method == "POST" || method == "PUT" || method == "PATCH" || method == "DELETE"
+}
+
+// handlePanic 处理 panic 响应：生产环境只返回通用错误，调试模式附带 panic 信息与调用栈
+func handlePanic(ctx iris.Context, event *reporting.Event, debugMode bool) {
+	// 丢弃 panic 前已写入的部分响应
+	if recorder, ok := ctx.IsRecording(); ok {
+		recorder.ResetBody()
+	}
+	ctx.StatusCode(iris.StatusInternalServerError)
+
 	// 根据请求类型返回不同的响应格式
 	accept := ctx.GetHeader("Accept")
-	
+
 	// 如果是 API 请求或接受 JSON
 	if isAPIRequest(ctx.Path()) || containsJSON(accept) {
-		ctx.JSON(iris.Map{
+		response := iris.Map{
 			"code":    500,
 			"message": "服务器内部错误，请稍后重试",
 			"error":   "internal_server_error",
-		})
-	} else {
-		// 返回 HTML 错误页面
-		ctx.HTML(`
+		}
+		if event.RequestID != "" {
+			response["request_id"] = event.RequestID
+		}
+		if debugMode {
+			response["event_id"] = event.ID
+			response["panic"] = event.Message
+			response["stack"] = event.Stack
+		}
+		ctx.JSON(response)
+		return
+	}
+
+	detail := ""
+	if debugMode {
+		detail = `<pre class="stack">` + html.EscapeString(event.Message+"\n\n"+event.Stack) + `</pre>`
+	}
+
+	// 返回 HTML 错误页面（内联样式需要带上安全响应头中间件生成的 CSP nonce）
+	ctx.HTML(`
 			<!DOCTYPE html>
 			<html>
 			<head>
 				<title>服务器错误</title>
 				<meta charset="UTF-8">
-				<style>
+				<style nonce="` + html.EscapeString(CSPNonce(ctx)) + `">
 					body { font-family: Arial, sans-serif; text-align: center; padding: 50px; }
 					.error { color: #e74c3c; font-size: 48px; margin-bottom: 20px; }
 					.message { color: #333; font-size: 18px; }
+					.stack { text-align: left; font-size: 12px; background: #f7f7f7; padding: 16px; overflow: auto; }
 				</style>
 			</head>
 			<body>
 				<div class="error">500</div>
 				<div class="message">服务器内部错误，请稍后重试</div>
+				` + detail + `
 			</body>
 			</html>
 		`)
-	}
-
-	ctx.StatusCode(iris.StatusInternalServerError)
 }
 
 // isAPIRequest 判断是否为 API 请求
@@ -99,51 +178,8 @@ func isAPIRequest(path string) bool {
 
 // containsJSON 检查是否包含 JSON
 func containsJSON(accept string) bool {
-	return len(accept) >= 4 && 
-		   (accept[:4] == "appl" || 
-		    accept[:4] == "text" ||
-		    accept[:4] == "*/")
-}
-
-// CustomRecovery 自定义恢复中间件
-func CustomRecovery(customHandler func(ctx iris.Context, err interface{})) iris.Handler {
-	return func(ctx iris.Context) {
-		defer func() {
-			if err := recover(); err != nil {
-				// 记录 panic 信息
-				logPanic(ctx, err)
-				
-				// 使用自定义处理器
-				if customHandler != nil {
-					customHandler(ctx, err)
-				} else {
-					// 使用默认处理器
-					handlePanic(ctx, err)
-				}
-			}
-		}()
-
-		ctx.Next()
-	}
+	return len(accept) >= 4 &&
+		(accept[:4] == "appl" ||
+			accept[:4] == "text" ||
+			accept[:4] == "*/")
 }
-
-// RecoveryWithLogger 带自定义日志记录器的恢复中间件
-func RecoveryWithLogger(logger func(ctx iris.Context, err interface{})) iris.Handler {
-	return func(ctx iris.Context) {
-		defer func() {
-			if err := recover(); err != nil {
-				// 使用自定义日志记录器
-				if logger != nil {
-					logger(ctx, err)
-				} else {
-					logPanic(ctx, err)
-				}
-				
-				// 处理响应
-				handlePanic(ctx, err)
-			}
-		}()
-
-		ctx.Next()
-	}
-}
\ No newline at end of file
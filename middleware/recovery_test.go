@@ -0,0 +1,158 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"iris-cn-sample-project/config"
+	"iris-cn-sample-project/reporting"
+
+	"github.com/kataras/iris/v12"
+)
+
+// memoryReporter 将事件保存在内存中的上报器
+type memoryReporter struct {
+	mu     sync.Mutex
+	events []*reporting.Event
+}
+
+func (r *memoryReporter) Report(_ context.Context, event *reporting.Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+	return nil
+}
+
+// newPanicApp 创建挂载恢复中间件的测试应用
+func newPanicApp(t *testing.T, reporter reporting.Reporter, debugMode bool) *iris.Application {
+	t.Helper()
+
+	app := iris.New()
+	app.Use(RequestID())
+	app.Use(RecoveryWithConfig(config.ErrorReportConfig{BodyMaxBytes: 1024}, reporter, func(ctx iris.Context, event *reporting.Event) {
+		handlePanic(ctx, event, debugMode)
+	}))
+	app.Post("/api/orders/{id}", func(ctx iris.Context) {
+		ctx.Values().Set("user_id", uint(7))
+		var req map[string]interface{}
+		ctx.ReadJSON(&req)
+		var items []string
+		_ = items[len(req)] // 下标越界
+	})
+	if err := app.Build(); err != nil {
+		t.Fatalf("构建应用失败: %v", err)
+	}
+	return app
+}
+
+// doPanic 发送会触发 panic 的请求
+func doPanic(app *iris.Application) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/api/orders/42?token=abc", strings.NewReader(`{"item":"book","password":"`+secretPassword+`"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Request-ID", "req-123")
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, req)
+	return rec
+}
+
+// TestRecoveryReports 验证上报内容与生产环境的通用响应
+func TestRecoveryReports(t *testing.T) {
+	reporter := &memoryReporter{}
+	app := newPanicApp(t, reporter, false)
+
+	rec := doPanic(app)
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("状态码 = %d，期望 500", rec.Code)
+	}
+
+	var resp map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("响应不是 JSON: %s", rec.Body.String())
+	}
+	if resp["request_id"] != "req-123" {
+		t.Errorf("request_id = %v，期望 req-123", resp["request_id"])
+	}
+	if strings.Contains(rec.Body.String(), "index out of range") || resp["stack"] != nil {
+		t.Errorf("生产环境响应不应包含 panic 细节: %s", rec.Body.String())
+	}
+
+	if len(reporter.events) != 1 {
+		t.Fatalf("上报次数 = %d，期望 1", len(reporter.events))
+	}
+	event := reporter.events[0]
+	if event.RequestID != "req-123" || event.Route != "/api/orders/{id}" || event.UserID != "7" {
+		t.Errorf("上报的请求信息不完整: %+v", event)
+	}
+	if !strings.Contains(event.Message, "index out of range") || event.Stack == "" {
+		t.Errorf("上报缺少 panic 信息: %q", event.Message)
+	}
+	if len(event.Frames) == 0 || !strings.Contains(event.Frames[0].Function, "newPanicApp") {
+		t.Errorf("第一帧应为 panic 发生的位置: %+v", event.Frames)
+	}
+	if !strings.Contains(event.Body, "book") || strings.Contains(event.Body, secretPassword) || strings.Contains(event.Query, "abc") {
+		t.Errorf("请求体或查询参数未脱敏: body=%s query=%s", event.Body, event.Query)
+	}
+}
+
+// TestRecoveryOmitsBinaryBodies 验证无法按字段脱敏的请求体（tus 分块、图片、xlsx 等）不会随 panic 上报
+func TestRecoveryOmitsBinaryBodies(t *testing.T) {
+	for _, contentType := range []string{
+		"application/offset+octet-stream",
+		"image/png",
+		"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+		"",
+	} {
+		reporter := &memoryReporter{}
+		app := newPanicApp(t, reporter, false)
+		req := httptest.NewRequest(http.MethodPost, "/api/orders/42", strings.NewReader("\x89PNG password="+secretPassword))
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		app.ServeHTTP(httptest.NewRecorder(), req)
+
+		if len(reporter.events) != 1 {
+			t.Fatalf("%q: 上报次数 = %d，期望 1", contentType, len(reporter.events))
+		}
+		if body := reporter.events[0].Body; body != "" {
+			t.Errorf("%q: 不应上报请求体: %s", contentType, body)
+		}
+	}
+}
+
+// TestRecoveryDebug 验证调试模式下响应附带调用栈
+func TestRecoveryDebug(t *testing.T) {
+	app := newPanicApp(t, &memoryReporter{}, true)
+
+	rec := doPanic(app)
+	var resp map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("响应不是 JSON: %s", rec.Body.String())
+	}
+	if stack, _ := resp["stack"].(string); !strings.Contains(stack, "goroutine") {
+		t.Errorf("调试模式响应应包含调用栈: %s", rec.Body.String())
+	}
+	if panicMsg, _ := resp["panic"].(string); !strings.Contains(panicMsg, "index out of range") {
+		t.Errorf("调试模式响应应包含 panic 信息: %v", resp["panic"])
+	}
+}
+
+// TestRecoveryDedup 验证相同 panic 只上报一次
+func TestRecoveryDedup(t *testing.T) {
+	reporter := &memoryReporter{}
+	app := newPanicApp(t, reporting.Dedup(reporter, time.Minute), false)
+
+	for i := 0; i < 3; i++ {
+		if rec := doPanic(app); rec.Code != http.StatusInternalServerError {
+			t.Fatalf("状态码 = %d，期望 500", rec.Code)
+		}
+	}
+	if len(reporter.events) != 1 {
+		t.Errorf("上报次数 = %d，期望 1", len(reporter.events))
+	}
+}
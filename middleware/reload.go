@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"reflect"
+	"sync/atomic"
+
+	"iris-cn-sample-project/config"
+	"iris-cn-sample-project/logging"
+
+	"github.com/kataras/iris/v12"
+)
+
+// reloadable 随配置热更新的中间件
+//
+// section 取出中间件关心的配置，热更新后该部分发生变化时用 build 重新构建处理器并原子替换；
+// 已经进入旧处理器的请求不受影响。
+func reloadable[T any](name string, section func(*config.Config) T, build func(T) iris.Handler) iris.Handler {
+	var handler atomic.Pointer[iris.Handler]
+	h := build(section(config.GetConfig()))
+	handler.Store(&h)
+
+	config.Subscribe(name, func(old, cur *config.Config) {
+		if reflect.DeepEqual(section(old), section(cur)) {
+			return
+		}
+		h := build(section(cur))
+		handler.Store(&h)
+		logging.L().Info("中间件配置已热更新", "middleware", name)
+	})
+
+	return func(ctx iris.Context) {
+		(*handler.Load())(ctx)
+	}
+}
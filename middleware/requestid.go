@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"iris-cn-sample-project/config"
+	"iris-cn-sample-project/logging"
+	"iris-cn-sample-project/requestid"
+
+	"github.com/kataras/iris/v12"
+)
+
+// RequestID 请求ID中间件（使用全局请求ID配置）
+func RequestID() iris.Handler {
+	return RequestIDWithConfig(config.GetConfig().RequestID)
+}
+
+// RequestIDWithConfig 使用指定配置的请求ID中间件
+//
+// 客户端传入的请求ID只有在 TrustIncoming 开启且通过格式与长度校验时才会沿用，否则重新生成。
+// 请求ID会写入响应头、ctx.Values() 的 request_id 以及请求的 context.Context，
+// 后续的日志、出站 HTTP 请求（requestid.Transport）与 SQL 注释都从 context 中读取。
+func RequestIDWithConfig(cfg config.RequestIDConfig) iris.Handler {
+	header := cfg.Header
+	if header == "" {
+		header = requestid.Header
+	}
+
+	return func(ctx iris.Context) {
+		id := ctx.GetHeader(header)
+		if id != "" && (!cfg.TrustIncoming || !requestid.Valid(id, cfg.MaxLength)) {
+			if cfg.TrustIncoming {
+				logging.L().DebugContext(ctx.Request().Context(), "忽略无效的请求ID", "length", len(id))
+			}
+			id = ""
+		}
+		if id == "" {
+			id = requestid.New(cfg.Format)
+		}
+
+		// 设置请求ID到响应头和上下文
+		ctx.Header(header, id)
+		ctx.Values().Set("request_id", id)
+		ctx.ResetRequest(ctx.Request().WithContext(requestid.NewContext(ctx.Request().Context(), id)))
+
+		ctx.Next()
+	}
+}
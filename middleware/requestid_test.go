@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"iris-cn-sample-project/config"
+	"iris-cn-sample-project/logging"
+	"iris-cn-sample-project/requestid"
+
+	"github.com/kataras/iris/v12"
+)
+
+// TestRequestID 验证客户端请求ID的校验、生成，以及向 context 与日志的传递
+func TestRequestID(t *testing.T) {
+	var sink bytes.Buffer
+	previous := logging.L()
+	logging.SetLogger(slog.New(slog.NewJSONHandler(&sink, nil)))
+	t.Cleanup(func() { logging.SetLogger(previous) })
+
+	cfg := config.RequestIDConfig{Header: "X-Request-ID", Format: requestid.FormatUUIDv7, TrustIncoming: true, MaxLength: 32}
+
+	var fromContext string
+	app := iris.New()
+	app.Use(RequestIDWithConfig(cfg))
+	app.Get("/", func(ctx iris.Context) {
+		fromContext = requestid.FromContext(ctx.Request().Context())
+		logging.L().InfoContext(ctx.Request().Context(), "处理请求")
+	})
+	if err := app.Build(); err != nil {
+		t.Fatalf("构建应用失败: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		incoming string
+		reuse    bool
+	}{
+		{"没有请求ID时生成", "", false},
+		{"沿用合法的请求ID", "client-abc_123", true},
+		{"拒绝超长的请求ID", strings.Repeat("a", 33), false},
+		{"拒绝包含非法字符的请求ID", "abc\" injected=\"1", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink.Reset()
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.incoming != "" {
+				req.Header.Set("X-Request-ID", tt.incoming)
+			}
+			rec := httptest.NewRecorder()
+			app.ServeHTTP(rec, req)
+
+			id := rec.Header().Get("X-Request-ID")
+			if tt.reuse && id != tt.incoming {
+				t.Errorf("请求ID = %q，期望沿用 %q", id, tt.incoming)
+			}
+			if !tt.reuse && (id == tt.incoming || len(id) != 36) {
+				t.Errorf("请求ID = %q，期望新生成的 UUIDv7", id)
+			}
+			if fromContext != id {
+				t.Errorf("context 中的请求ID = %q，期望 %q", fromContext, id)
+			}
+			if !strings.Contains(sink.String(), `"request_id":"`+id+`"`) {
+				t.Errorf("日志缺少请求ID: %s", sink.String())
+			}
+		})
+	}
+}
+
+// TestRequestIDUntrusted 验证不信任客户端请求ID时总是重新生成
+func TestRequestIDUntrusted(t *testing.T) {
+	app := iris.New()
+	app.Use(RequestIDWithConfig(config.RequestIDConfig{Format: requestid.FormatULID}))
+	app.Get("/", func(ctx iris.Context) {})
+	if err := app.Build(); err != nil {
+		t.Fatalf("构建应用失败: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Request-ID", "client-abc")
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, req)
+
+	if id := rec.Header().Get("X-Request-ID"); id == "client-abc" || len(id) != 26 {
+		t.Errorf("请求ID = %q，期望新生成的 ULID", id)
+	}
+}
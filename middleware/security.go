@@ -0,0 +1,156 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"sort"
+	"strconv"
+	"strings"
+
+	"iris-cn-sample-project/config"
+	"iris-cn-sample-project/utils"
+
+	"github.com/kataras/iris/v12"
+)
+
+// cspNonceKey CSP nonce 在 ctx.Values() 与模板数据中的键
+const cspNonceKey = "cspNonce"
+
+// cspReportGroup Reporting-Endpoints 中 CSP 上报端点的名称
+const cspReportGroup = "csp-endpoint"
+
+// SecurityHeaders 安全响应头中间件（使用全局安全配置）
+//
+// 建议通过 UseRouter 注册，使未匹配路由的 404 等响应同样带有安全响应头。
+func SecurityHeaders() iris.Handler {
+	return SecurityHeadersWithConfig(config.GetConfig().Security)
+}
+
+// SecurityHeadersWithConfig 使用指定配置的安全响应头中间件
+//
+// 请求路径匹配到 Overrides 中的路径前缀时使用对应策略（最长前缀优先），否则使用默认策略。
+// CSP 中的 {nonce} 会替换为每个请求新生成的随机值，并以 cspNonce 提供给模板（<script nonce="{{.cspNonce}}">）。
+// HSTS 只在 HTTPS 请求（含可信代理声明的 HTTPS）中发送。
+func SecurityHeadersWithConfig(cfg config.SecurityConfig) iris.Handler {
+	if !cfg.Enabled {
+		return func(ctx iris.Context) {
+			ctx.Next()
+		}
+	}
+
+	defaultPolicy := newSecurityPolicy(cfg, cfg.SecurityHeadersPolicy)
+
+	overrides := make([]securityOverride, 0, len(cfg.Overrides))
+	for _, o := range cfg.Overrides {
+		overrides = append(overrides, securityOverride{
+			prefix: strings.TrimRight(o.PathPrefix, "/"),
+			policy: newSecurityPolicy(cfg, o.SecurityHeadersPolicy),
+		})
+	}
+	sort.SliceStable(overrides, func(i, j int) bool {
+		return len(overrides[i].prefix) > len(overrides[j].prefix)
+	})
+
+	hsts := ""
+	if cfg.HSTSMaxAge > 0 {
+		hsts = "max-age=" + strconv.Itoa(cfg.HSTSMaxAge)
+		if cfg.HSTSIncludeSubdomains {
+			hsts += "; includeSubDomains"
+		}
+		if cfg.HSTSPreload {
+			hsts += "; preload"
+		}
+	}
+
+	cspHeader := "Content-Security-Policy"
+	if cfg.CSPReportOnly {
+		cspHeader = "Content-Security-Policy-Report-Only"
+	}
+
+	return func(ctx iris.Context) {
+		policy := defaultPolicy
+		path := ctx.Path()
+		for _, o := range overrides {
+			if path == o.prefix || strings.HasPrefix(path, o.prefix+"/") {
+				policy = o.policy
+				break
+			}
+		}
+
+		header := ctx.ResponseWriter().Header()
+		header.Set("X-Content-Type-Options", "nosniff")
+		setHeader(header.Set, "X-Frame-Options", policy.frameOptions)
+		setHeader(header.Set, "Referrer-Policy", policy.referrerPolicy)
+		setHeader(header.Set, "Permissions-Policy", policy.permissionsPolicy)
+
+		if hsts != "" && utils.IsHTTPS(ctx.Request()) {
+			header.Set("Strict-Transport-Security", hsts)
+		}
+
+		if policy.csp != "" {
+			csp := policy.csp
+			if policy.needsNonce {
+				nonce := newCSPNonce()
+				ctx.Values().Set(cspNonceKey, nonce)
+				ctx.ViewData(cspNonceKey, nonce)
+				csp = strings.ReplaceAll(csp, "{nonce}", nonce)
+			}
+			header.Set(cspHeader, csp)
+			if cfg.CSPReportURI != "" {
+				header.Set("Reporting-Endpoints", cspReportGroup+`="`+cfg.CSPReportURI+`"`)
+			}
+		}
+
+		ctx.Next()
+	}
+}
+
+// CSPNonce 获取当前请求的 CSP nonce（策略中没有 {nonce} 时为空）
+func CSPNonce(ctx iris.Context) string {
+	return ctx.Values().GetString(cspNonceKey)
+}
+
+// securityPolicy 预处理后的安全响应头策略
+type securityPolicy struct {
+	csp               string
+	needsNonce        bool
+	frameOptions      string
+	referrerPolicy    string
+	permissionsPolicy string
+}
+
+// securityOverride 按路径前缀覆盖的策略
+type securityOverride struct {
+	prefix string
+	policy *securityPolicy
+}
+
+// newSecurityPolicy 预处理策略：配置了上报地址时在 CSP 后追加 report-uri 与 report-to
+func newSecurityPolicy(cfg config.SecurityConfig, p config.SecurityHeadersPolicy) *securityPolicy {
+	csp := strings.TrimRight(strings.TrimSpace(p.CSP), ";")
+	if csp != "" && cfg.CSPReportURI != "" && !strings.Contains(csp, "report-uri") {
+		csp += "; report-uri " + cfg.CSPReportURI + "; report-to " + cspReportGroup
+	}
+
+	return &securityPolicy{
+		csp:               csp,
+		needsNonce:        strings.Contains(csp, "{nonce}"),
+		frameOptions:      p.FrameOptions,
+		referrerPolicy:    p.ReferrerPolicy,
+		permissionsPolicy: p.PermissionsPolicy,
+	}
+}
+
+// setHeader 值不为空时设置响应头
+func setHeader(set func(key, value string), key, value string) {
+	if value != "" {
+		set(key, value)
+	}
+}
+
+// newCSPNonce 生成 128 位随机 nonce（URL 安全的 base64，模板输出到属性中时无需转义）
+func newCSPNonce() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,176 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"iris-cn-sample-project/config"
+	"iris-cn-sample-project/utils"
+
+	"github.com/kataras/iris/v12"
+)
+
+// testSecurityConfig 测试用的安全响应头配置：页面策略使用 nonce，/api 使用严格策略
+func testSecurityConfig() config.SecurityConfig {
+	return config.SecurityConfig{
+		Enabled:               true,
+		HSTSMaxAge:            3600,
+		HSTSIncludeSubdomains: true,
+		CSPReportURI:          "/csp-report",
+		SecurityHeadersPolicy: config.SecurityHeadersPolicy{
+			CSP:          "default-src 'self'; script-src 'self' 'nonce-{nonce}'",
+			FrameOptions: "SAMEORIGIN",
+		},
+		Overrides: []config.SecurityHeadersOverride{
+			{
+				PathPrefix: "/api",
+				SecurityHeadersPolicy: config.SecurityHeadersPolicy{
+					CSP:            "default-src 'none'; frame-ancestors 'none'",
+					FrameOptions:   "DENY",
+					ReferrerPolicy: "no-referrer",
+				},
+			},
+		},
+	}
+}
+
+// newSecurityTestApp 创建注册了安全响应头中间件的测试应用，页面处理器输出模板可用的 nonce
+func newSecurityTestApp(t *testing.T, cfg config.SecurityConfig) *iris.Application {
+	t.Helper()
+
+	app := iris.New()
+	app.UseRouter(SecurityHeadersWithConfig(cfg))
+	app.Get("/page", func(ctx iris.Context) {
+		ctx.WriteString(CSPNonce(ctx))
+	})
+	app.Get("/api/data", func(ctx iris.Context) {
+		ctx.JSON(iris.Map{"code": 200})
+	})
+	app.Get("/apidocs", func(ctx iris.Context) {})
+	if err := app.Build(); err != nil {
+		t.Fatalf("构建应用失败: %v", err)
+	}
+	return app
+}
+
+// TestSecurityHeadersPolicies 验证页面与 API 使用不同的策略，且页面的 nonce 每个请求不同并与模板一致
+func TestSecurityHeadersPolicies(t *testing.T) {
+	app := newSecurityTestApp(t, testSecurityConfig())
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	first, second := serve("/page"), serve("/page")
+	for _, rec := range []*httptest.ResponseRecorder{first, second} {
+		nonce := rec.Body.String()
+		csp := rec.Header().Get("Content-Security-Policy")
+		if nonce == "" || !strings.Contains(csp, "'nonce-"+nonce+"'") {
+			t.Errorf("CSP 应包含模板中的 nonce %q: %s", nonce, csp)
+		}
+		if !strings.Contains(csp, "report-uri /csp-report; report-to csp-endpoint") {
+			t.Errorf("CSP 缺少上报地址: %s", csp)
+		}
+		if got := rec.Header().Get("X-Frame-Options"); got != "SAMEORIGIN" {
+			t.Errorf("页面的 X-Frame-Options = %q，期望 SAMEORIGIN", got)
+		}
+		if got := rec.Header().Get("X-Content-Type-Options"); got != "nosniff" {
+			t.Errorf("X-Content-Type-Options = %q，期望 nosniff", got)
+		}
+	}
+	if first.Body.String() == second.Body.String() {
+		t.Error("每个请求应生成不同的 nonce")
+	}
+
+	api := serve("/api/data")
+	if csp := api.Header().Get("Content-Security-Policy"); !strings.HasPrefix(csp, "default-src 'none'; frame-ancestors 'none'") {
+		t.Errorf("API 的 CSP 应使用严格策略: %s", csp)
+	}
+	if got := api.Header().Get("X-Frame-Options"); got != "DENY" {
+		t.Errorf("API 的 X-Frame-Options = %q，期望 DENY", got)
+	}
+	if got := api.Header().Get("Referrer-Policy"); got != "no-referrer" {
+		t.Errorf("API 的 Referrer-Policy = %q，期望 no-referrer", got)
+	}
+
+	// 路径前缀按路径段匹配，/apidocs 不属于 /api
+	if got := serve("/apidocs").Header().Get("X-Frame-Options"); got != "SAMEORIGIN" {
+		t.Errorf("/apidocs 的 X-Frame-Options = %q，期望使用页面策略", got)
+	}
+
+	// 未匹配路由的 404 同样带有安全响应头
+	if got := serve("/missing").Header().Get("X-Content-Type-Options"); got != "nosniff" {
+		t.Errorf("404 响应缺少 X-Content-Type-Options")
+	}
+}
+
+// TestSecurityHeadersReportOnly 验证只上报模式使用 Content-Security-Policy-Report-Only
+func TestSecurityHeadersReportOnly(t *testing.T) {
+	cfg := testSecurityConfig()
+	cfg.CSPReportOnly = true
+	app := newSecurityTestApp(t, cfg)
+
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/page", nil))
+
+	if rec.Header().Get("Content-Security-Policy") != "" {
+		t.Error("只上报模式不应发送 Content-Security-Policy")
+	}
+	if rec.Header().Get("Content-Security-Policy-Report-Only") == "" {
+		t.Error("只上报模式缺少 Content-Security-Policy-Report-Only")
+	}
+	if got := rec.Header().Get("Reporting-Endpoints"); got != `csp-endpoint="/csp-report"` {
+		t.Errorf("Reporting-Endpoints = %q", got)
+	}
+}
+
+// TestSecurityHeadersHSTS 验证 HSTS 只在 HTTPS 请求中发送，且只信任可信代理声明的协议
+func TestSecurityHeadersHSTS(t *testing.T) {
+	if err := utils.SetTrustedProxies([]string{"10.0.0.1"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { utils.SetTrustedProxies(nil, nil) })
+
+	app := newSecurityTestApp(t, testSecurityConfig())
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		proto      string
+		https      bool
+		want       bool
+	}{
+		{"HTTP 请求", "192.0.2.1:1234", "", false, false},
+		{"TLS 连接", "192.0.2.1:1234", "", true, true},
+		{"可信代理声明 HTTPS", "10.0.0.1:1234", "https", false, true},
+		{"不可信来源伪造协议头", "192.0.2.1:1234", "https", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := "http://example.com/page"
+			if tt.https {
+				target = "https://example.com/page"
+			}
+			req := httptest.NewRequest(http.MethodGet, target, nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.proto != "" {
+				req.Header.Set("X-Forwarded-Proto", tt.proto)
+			}
+			rec := httptest.NewRecorder()
+			app.ServeHTTP(rec, req)
+
+			got := rec.Header().Get("Strict-Transport-Security")
+			if tt.want && got != "max-age=3600; includeSubDomains" {
+				t.Errorf("Strict-Transport-Security = %q，期望 max-age=3600; includeSubDomains", got)
+			}
+			if !tt.want && got != "" {
+				t.Errorf("非 HTTPS 请求不应发送 HSTS: %q", got)
+			}
+		})
+	}
+}
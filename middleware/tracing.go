@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"net/http"
+
+	"iris-cn-sample-project/tracing"
+	"iris-cn-sample-project/utils"
+
+	"github.com/kataras/iris/v12"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracing 链路追踪中间件：解析上游的 W3C traceparent，为每个请求创建服务端 span
+//
+// span 上下文会写回请求的 context，服务层与 GORM 通过 ctx.Request().Context() 创建子 span；
+// 需要放在 RequestID 之后，以便将请求ID记录到 span 上，trace_id 也会写入 ctx.Values() 与响应头。
+func Tracing() iris.Handler {
+	return func(ctx iris.Context) {
+		r := ctx.Request()
+		route := routeTemplate(ctx)
+
+		parent := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		spanCtx, span := tracing.Tracer().Start(parent, r.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", r.Method),
+				attribute.String("http.route", route),
+				attribute.String("url.path", r.URL.Path),
+				attribute.String("client.address", utils.ClientIP(ctx)),
+				attribute.String("user_agent.original", r.UserAgent()),
+			),
+		)
+		defer span.End()
+
+		if requestID := ctx.Values().GetString("request_id"); requestID != "" {
+			span.SetAttributes(attribute.String("request.id", requestID))
+		}
+		if sc := span.SpanContext(); sc.HasTraceID() {
+			ctx.Values().Set("trace_id", sc.TraceID().String())
+			ctx.Header("X-Trace-ID", sc.TraceID().String())
+		}
+
+		ctx.ResetRequest(r.WithContext(spanCtx))
+
+		ctx.Next()
+
+		status := ctx.GetStatusCode()
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+		if userID := ctx.Values().Get("user_id"); userID != nil {
+			if id, ok := userID.(uint); ok {
+				span.SetAttributes(attribute.Int64("enduser.id", int64(id)))
+			}
+		}
+		// 按 OpenTelemetry 语义约定，服务端只把 5xx 标记为失败
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"iris-cn-sample-project/config"
+	"iris-cn-sample-project/logging"
+	"iris-cn-sample-project/tracing"
+
+	"github.com/kataras/iris/v12"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// 上游网关传入的 traceparent
+const (
+	upstreamTraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	upstreamParent  = "00-" + upstreamTraceID + "-00f067aa0ba902b7-01"
+	testRequestID   = "req-tracing-test"
+)
+
+// TestTracingPropagation 验证 traceparent 传播、服务层与 GORM 子 span 以及与请求ID、日志的关联
+func TestTracingPropagation(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tracing.InitWithExporter(config.TracingConfig{ServiceName: "tracing-test", SampleRatio: 1}, exporter)
+	t.Cleanup(func() { tracing.Shutdown(context.Background()) })
+
+	var sink bytes.Buffer
+	previous := logging.L()
+	logging.SetLogger(slog.New(slog.NewJSONHandler(&sink, nil)))
+	t.Cleanup(func() { logging.SetLogger(previous) })
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("打开数据库失败: %v", err)
+	}
+	if err := db.Use(tracing.NewGormPlugin()); err != nil {
+		t.Fatalf("注册链路追踪插件失败: %v", err)
+	}
+
+	app := iris.New()
+	app.Use(RequestID())
+	app.Use(Tracing())
+	app.Use(LoggerWithConfig(config.LogConfig{}))
+	app.Get("/api/users/{id:int}", func(ctx iris.Context) {
+		spanCtx, span := tracing.Start(ctx.Request().Context(), "services.GetUserByID")
+		var n int
+		err := db.WithContext(spanCtx).Raw("SELECT 1").Scan(&n).Error
+		tracing.End(span, err)
+		ctx.JSON(iris.Map{"code": 200, "data": n})
+	})
+	if err := app.Build(); err != nil {
+		t.Fatalf("构建应用失败: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/users/1", nil)
+	req.Header.Set("traceparent", upstreamParent)
+	req.Header.Set("X-Request-ID", testRequestID)
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("状态码 = %d，期望 200", rec.Code)
+	}
+	if got := rec.Header().Get("X-Trace-ID"); got != upstreamTraceID {
+		t.Errorf("X-Trace-ID = %q，期望沿用上游 trace ID %q", got, upstreamTraceID)
+	}
+
+	spans := exporter.GetSpans()
+	byName := make(map[string]tracetest.SpanStub, len(spans))
+	for _, span := range spans {
+		if span.SpanContext.TraceID().String() != upstreamTraceID {
+			t.Errorf("span %q 的 trace ID = %s，期望 %s", span.Name, span.SpanContext.TraceID(), upstreamTraceID)
+		}
+		byName[span.Name] = span
+	}
+
+	server, ok := byName["GET /api/users/{id:int}"]
+	if !ok {
+		t.Fatalf("缺少请求 span，实际: %v", spanNames(spans))
+	}
+	if server.Parent.SpanID().String() != "00f067aa0ba902b7" {
+		t.Errorf("请求 span 的父 span = %s，期望上游 span", server.Parent.SpanID())
+	}
+	attrs := make(map[string]string)
+	for _, kv := range server.Attributes {
+		attrs[string(kv.Key)] = kv.Value.Emit()
+	}
+	if attrs["request.id"] != testRequestID {
+		t.Errorf("request.id = %q，期望 %q", attrs["request.id"], testRequestID)
+	}
+	if attrs["http.response.status_code"] != "200" {
+		t.Errorf("http.response.status_code = %q，期望 200", attrs["http.response.status_code"])
+	}
+
+	service, ok := byName["services.GetUserByID"]
+	if !ok || service.Parent.SpanID() != server.SpanContext.SpanID() {
+		t.Errorf("服务层 span 应为请求 span 的子 span，实际: %v", spanNames(spans))
+	}
+	query, ok := byName["gorm.row"]
+	if !ok || query.Parent.SpanID() != service.SpanContext.SpanID() {
+		t.Errorf("GORM span 应为服务层 span 的子 span，实际: %v", spanNames(spans))
+	}
+
+	logged := sink.String()
+	if !strings.Contains(logged, `"trace_id":"`+upstreamTraceID+`"`) || !strings.Contains(logged, testRequestID) {
+		t.Errorf("请求日志应同时包含 trace_id 与请求ID: %s", logged)
+	}
+}
+
+// spanNames 获取 span 名称列表，用于错误信息
+func spanNames(spans tracetest.SpanStubs) []string {
+	names := make([]string, 0, len(spans))
+	for _, span := range spans {
+		names = append(names, span.Name)
+	}
+	return names
+}
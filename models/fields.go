@@ -0,0 +1,96 @@
+package models
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// UserInfoFields UserInfo 中可以通过 fields 参数选择的字段
+var UserInfoFields = NewFieldSet(UserInfo{})
+
+// FieldSet 根据结构体的 json 标签确定可以通过 fields 参数选择的字段（稀疏字段集）
+//
+// 只有带 json 标签的导出字段可以选择，敏感字段（如 User.Password）不在响应结构体中，因此永远无法被请求。
+// select 标签为该字段需要查询的数据库列（逗号分隔），默认与 json 名称相同，用于只查询所选字段对应的列。
+type FieldSet struct {
+	names   []string
+	index   map[string]int
+	columns map[string][]string
+}
+
+// NewFieldSet 从结构体类型创建字段集
+func NewFieldSet(v interface{}) *FieldSet {
+	t := reflect.TypeOf(v)
+	s := &FieldSet{index: make(map[string]int), columns: make(map[string][]string)}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if !f.IsExported() || name == "" || name == "-" {
+			continue
+		}
+		columns := []string{name}
+		if sel, ok := f.Tag.Lookup("select"); ok {
+			columns = strings.Split(sel, ",")
+		}
+		s.names = append(s.names, name)
+		s.index[name] = i
+		s.columns[name] = columns
+	}
+	return s
+}
+
+// Names 可以选择的字段，按结构体中的顺序
+func (s *FieldSet) Names() []string {
+	return append([]string(nil), s.names...)
+}
+
+// Parse 解析逗号分隔的字段列表，去掉重复的字段；有不支持的字段时返回错误，value 为空时返回 nil（全部字段）
+func (s *FieldSet) Parse(value string) ([]string, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+	var fields []string
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if _, ok := s.index[name]; !ok {
+			return nil, fmt.Errorf("不支持的字段 %q，可选字段: %s", name, strings.Join(s.names, ", "))
+		}
+		if !seen[name] {
+			seen[name] = true
+			fields = append(fields, name)
+		}
+	}
+	return fields, nil
+}
+
+// Columns 所选字段需要查询的数据库列（去重），fields 为空时返回 nil，表示查询全部列
+func (s *FieldSet) Columns(fields []string) []string {
+	var columns []string
+	seen := make(map[string]bool)
+	for _, name := range fields {
+		for _, column := range s.columns[name] {
+			if !seen[column] {
+				seen[column] = true
+				columns = append(columns, column)
+			}
+		}
+	}
+	return columns
+}
+
+// Project 取出结构体（或其指针）中所选的字段，fields 为空时取全部字段
+func (s *FieldSet) Project(v interface{}, fields []string) map[string]interface{} {
+	if len(fields) == 0 {
+		fields = s.names
+	}
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	m := make(map[string]interface{}, len(fields))
+	for _, name := range fields {
+		if i, ok := s.index[name]; ok {
+			m[name] = rv.Field(i).Interface()
+		}
+	}
+	return m
+}
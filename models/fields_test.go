@@ -0,0 +1,40 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUserInfoFields(t *testing.T) {
+	fields, err := UserInfoFields.Parse(" id,username , avatar,id")
+	if err != nil {
+		t.Fatalf("Parse 失败: %v", err)
+	}
+	if want := []string{"id", "username", "avatar"}; !reflect.DeepEqual(fields, want) {
+		t.Errorf("Parse = %v, want %v", fields, want)
+	}
+	if want := []string{"id", "username", "avatar"}; !reflect.DeepEqual(UserInfoFields.Columns(fields), want) {
+		t.Errorf("Columns = %v, want %v", UserInfoFields.Columns(fields), want)
+	}
+	if columns := UserInfoFields.Columns(nil); columns != nil {
+		t.Errorf("Columns(nil) = %v, want nil", columns)
+	}
+
+	for _, value := range []string{"password", "id,password", "avatar_version", "id,,username", "ID"} {
+		if _, err := UserInfoFields.Parse(value); err == nil {
+			t.Errorf("Parse(%q) 应返回错误", value)
+		}
+	}
+	if fields, err := UserInfoFields.Parse(""); err != nil || fields != nil {
+		t.Errorf("Parse(\"\") = %v, %v, want nil, nil", fields, err)
+	}
+
+	info := &UserInfo{ID: 7, Username: "alice", Email: "alice@example.com", Avatar: "/api/avatars/7"}
+	got := UserInfoFields.Project(info, []string{"username", "avatar"})
+	if want := map[string]interface{}{"username": "alice", "avatar": "/api/avatars/7"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Project = %v, want %v", got, want)
+	}
+	if all := UserInfoFields.Project(*info, nil); len(all) != len(UserInfoFields.Names()) || all["email"] != "alice@example.com" {
+		t.Errorf("Project(nil) = %v", all)
+	}
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// File 已上传文件的元数据，文件内容保存在存储后端中
+type File struct {
+	ID           uint           `json:"id" gorm:"primaryKey"`
+	Key          string         `json:"key" gorm:"uniqueIndex;not null;size:255"` // 存储后端中的对象 key
+	Storage      string         `json:"storage" gorm:"not null;size:20"`          // 存储后端：local、s3
+	OriginalName string         `json:"original_name" gorm:"size:255"`            // 客户端提供的文件名，仅用于展示
+	ContentType  string         `json:"content_type" gorm:"size:100"`             // 按文件内容识别出的 MIME 类型
+	Purpose      string         `json:"purpose" gorm:"size:20;default:attachment"`
+	Status       string         `json:"status" gorm:"size:20;default:active;index"`
+	ScanResult   string         `json:"scan_result,omitempty" gorm:"size:255"` // 恶意软件扫描命中的特征
+	Size         int64          `json:"size"`
+	SHA256       string         `json:"sha256" gorm:"index;size:64"`
+	UserID       *uint          `json:"user_id" gorm:"index"`   // 上传者，匿名上传为空
+	UploaderIP   string         `json:"-" gorm:"index;size:45"` // 匿名上传者的客户端IP，用于匿名上传的配额
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// 文件状态
+const (
+	FileStatusActive      = "active"      // 正常
+	FileStatusQuarantined = "quarantined" // 未通过恶意软件扫描，已移入隔离区
+	FileStatusMissing     = "missing"     // 核对时发现存储后端中的对象已丢失，不计入配额
+)
+
+// TableName 指定表名
+func (File) TableName() string {
+	return "files"
+}
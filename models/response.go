@@ -27,6 +27,21 @@ type PageInfo struct {
     TotalPage int   `json:"total_page"` // 总页数
 }
 
+// CursorResponse 游标分页响应结构体
+type CursorResponse struct {
+    Code    int         `json:"code"`    // 响应状态码
+    Message string      `json:"message"` // 响应消息
+    Data    interface{} `json:"data"`    // 响应数据
+    Cursor  CursorInfo  `json:"cursor"`  // 游标信息
+}
+
+// CursorInfo 游标分页信息
+type CursorInfo struct {
+    Limit int    `json:"limit"`          // 每页大小
+    Next  string `json:"next,omitempty"` // 下一页的游标，没有下一页时为空
+    Prev  string `json:"prev,omitempty"` // 上一页的游标，没有上一页时为空
+}
+
 // LoginRequest 登录请求结构体
 type LoginRequest struct {
     Username string `json:"username" validate:"required"`
@@ -49,25 +64,24 @@ type RegisterRequest struct {
     LastName  string `json:"last_name" validate:"max=50"`
 }
 
-// UserInfo 用户信息结构体（不包含敏感信息）
+// UserInfo 用户信息结构体（不包含敏感信息），json 标签决定了 fields 参数可以选择的字段（见 UserInfoFields）
 type UserInfo struct {
     ID        uint      `json:"id"`
     Username  string    `json:"username"`
     Email     string    `json:"email"`
     FirstName string    `json:"first_name"`
     LastName  string    `json:"last_name"`
-    Avatar    string    `json:"avatar"`
+    Avatar    string    `json:"avatar" select:"id,avatar"` // 头像地址由用户ID与头像版本生成
     Role      string    `json:"role"`
     Status    string    `json:"status"`
     CreatedAt time.Time `json:"created_at"`
     UpdatedAt time.Time `json:"updated_at"`
 }
 
-// UpdateUserRequest 更新用户请求结构体
+// UpdateUserRequest 更新用户请求结构体（头像通过 PUT /api/protected/avatar 上传，不能直接设置）
 type UpdateUserRequest struct {
     FirstName string `json:"first_name" validate:"max=50"`
     LastName  string `json:"last_name" validate:"max=50"`
-    Avatar    string `json:"avatar"`
     Role      string `json:"role" validate:"omitempty,oneof=admin user"`
     Status    string `json:"status" validate:"omitempty,oneof=active inactive"`
 }
@@ -87,6 +101,27 @@ type FileUploadResponse struct {
     Message  string `json:"message"`
 }
 
+// ShareFileRequest 生成文件分享链接请求结构体，ExpiresIn 为链接有效期（秒），为 0 时使用默认值
+type ShareFileRequest struct {
+    ExpiresIn int `json:"expires_in"`
+}
+
+// UpdateQuotaRequest 设置用户存储配额请求结构体，字段为 null 时恢复为按角色或全局配置，0 表示不限制（与全局、角色配置一致）
+type UpdateQuotaRequest struct {
+    QuotaBytes *int64 `json:"quota_bytes"`
+    QuotaFiles *int64 `json:"quota_files"`
+}
+
+// ImportUserRequest 批量导入的一行用户数据，校验规则与注册相同；密码为空时生成临时密码并通过邀请邮件发送
+type ImportUserRequest struct {
+    Username  string `json:"username" validate:"required,min=3,max=50"`
+    Email     string `json:"email" validate:"required,email"`
+    Password  string `json:"password" validate:"omitempty,min=6"`
+    FirstName string `json:"first_name" validate:"max=50"`
+    LastName  string `json:"last_name" validate:"max=50"`
+    Role      string `json:"role" validate:"omitempty,oneof=admin user"` // 为空时为 user
+}
+
 // ErrorResponse 错误响应结构体
 type ErrorResponse struct {
     Code      int                    `json:"code"`
@@ -115,9 +150,9 @@ func NewResponse(code int, message string, data interface{}) *Response {
 
 // NewPageResponse 创建分页响应
 func NewPageResponse(code int, message string, data interface{}, current, pageSize int, total int64) *PageResponse {
-    totalPage := int(total) / pageSize
-    if int(total)%pageSize > 0 {
-        totalPage++
+    totalPage := 0
+    if pageSize > 0 {
+        totalPage = int((total + int64(pageSize) - 1) / int64(pageSize))
     }
 
     return &PageResponse{
@@ -133,6 +168,20 @@ func NewPageResponse(code int, message string, data interface{}, current, pageSi
     }
 }
 
+// NewCursorResponse 创建游标分页响应
+func NewCursorResponse(code int, message string, data interface{}, limit int, next, prev string) *CursorResponse {
+    return &CursorResponse{
+        Code:    code,
+        Message: message,
+        Data:    data,
+        Cursor: CursorInfo{
+            Limit: limit,
+            Next:  next,
+            Prev:  prev,
+        },
+    }
+}
+
 // NewErrorResponse 创建错误响应
 func NewErrorResponse(code int, message string, errors map[string]interface{}, path string) *ErrorResponse {
     return &ErrorResponse{
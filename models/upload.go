@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// Upload 可续传上传（tus 协议）的进度
+//
+// 每次 PATCH 收到的内容作为一个分段保存在存储后端中（key 为 tus/<id>/<序号>），
+// 全部收到后按顺序拼接并登记为 File，随后删除分段。
+type Upload struct {
+	ID        string    `json:"id" gorm:"primaryKey;size:36"`
+	UserID    uint      `json:"user_id" gorm:"index;not null"`
+	Length    int64     `json:"length" gorm:"column:upload_length;not null"`
+	Offset    int64     `json:"offset" gorm:"column:upload_offset;not null;default:0"`
+	PartCount int       `json:"part_count" gorm:"not null;default:0"`
+	Filename  string    `json:"filename" gorm:"size:255"`
+	Metadata  string    `json:"metadata" gorm:"size:2048"` // 客户端提供的 Upload-Metadata 原文
+	FileID    *uint     `json:"file_id"`                   // 完成后登记的文件
+	ExpiresAt time.Time `json:"expires_at" gorm:"index"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (Upload) TableName() string {
+	return "uploads"
+}
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"fmt"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -8,21 +10,26 @@ import (
 
 // User 用户模型
 type User struct {
-	ID        uint           `json:"id" gorm:"primaryKey"`
-	Username  string         `json:"username" gorm:"uniqueIndex;not null;size:50" validate:"required,min=3,max=50"`
-	Email     string         `json:"email" gorm:"uniqueIndex;not null;size:100" validate:"required,email"`
-	Password  string         `json:"-" gorm:"not null;size:255"`
-	FirstName string         `json:"first_name" gorm:"size:50"`
-	LastName  string         `json:"last_name" gorm:"size:50"`
-	Avatar    string         `json:"avatar" gorm:"size:255"`
-	Role      string         `json:"role" gorm:"default:user;size:20"`
-	Status    string         `json:"status" gorm:"default:active;size:20"`
-	LastLogin *time.Time     `json:"last_login"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+	ID         uint           `json:"id" gorm:"primaryKey"`
+	Username   string         `json:"username" gorm:"uniqueIndex;not null;size:50" validate:"required,min=3,max=50"`
+	Email      string         `json:"email" gorm:"uniqueIndex;not null;size:100" validate:"required,email"`
+	Password   string         `json:"-" gorm:"not null;size:255"`
+	FirstName  string         `json:"first_name" gorm:"size:50"`
+	LastName   string         `json:"last_name" gorm:"size:50"`
+	Avatar     string         `json:"avatar" gorm:"size:255"` // 托管头像的引用（见 AvatarRefPrefix），对外使用 AvatarURL
+	Role       string         `json:"role" gorm:"default:user;size:20"`
+	Status     string         `json:"status" gorm:"default:active;size:20"`
+	QuotaBytes *int64         `json:"quota_bytes"` // 存储空间配额（字节），为空时使用角色或全局配置，0 表示不限制
+	QuotaFiles *int64         `json:"quota_files"` // 文件数配额，规则同上
+	LastLogin  *time.Time     `json:"last_login"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	DeletedAt  gorm.DeletedAt `json:"-" gorm:"index"`
 }
 
+// AvatarRefPrefix 托管头像引用的前缀：User.Avatar 保存为 avatars/<版本ID>，各尺寸的缩略图存放在该前缀下
+const AvatarRefPrefix = "avatars/"
+
 // TableName 指定表名
 func (User) TableName() string {
 	return "users"
@@ -62,4 +69,21 @@ func (u *User) IsActive() bool {
 // IsAdmin 检查用户是否为管理员
 func (u *User) IsAdmin() bool {
 	return u.Role == "admin"
+}
+
+// AvatarVersion 托管头像的版本ID，没有上传头像时为空
+func (u *User) AvatarVersion() string {
+	if version, ok := strings.CutPrefix(u.Avatar, AvatarRefPrefix); ok {
+		return version
+	}
+	return ""
+}
+
+// AvatarURL 头像地址：托管头像带上版本号以便长期缓存，没有上传头像时同一地址返回生成的默认头像
+func (u *User) AvatarURL() string {
+	url := fmt.Sprintf("/api/avatars/%d", u.ID)
+	if version := u.AvatarVersion(); version != "" {
+		url += "?v=" + version
+	}
+	return url
 }
\ No newline at end of file
@@ -0,0 +1,75 @@
+package pagination
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCodec(t *testing.T) {
+	codec := NewCodec("secret")
+	cur := Cursor{CreatedAt: time.Date(2024, 5, 1, 8, 30, 0, 123456789, time.Local), ID: 42, Backward: true}
+	token := codec.Encode(cur, "/api/users?role=admin")
+
+	got, err := codec.Decode(token, "/api/users?role=admin")
+	if err != nil {
+		t.Fatalf("Decode 失败: %v", err)
+	}
+	if !got.CreatedAt.Equal(cur.CreatedAt) || got.ID != cur.ID || got.Backward != cur.Backward {
+		t.Errorf("Decode = %+v, want %+v", got, cur)
+	}
+
+	encoded, sig, _ := strings.Cut(token, ".")
+	invalid := map[string]struct {
+		token, scope string
+		codec        *Codec
+	}{
+		"查询条件不同": {token, "/api/users?role=user", codec},
+		"密钥不同":   {token, "/api/users?role=admin", NewCodec("other")},
+		"篡改内容":   {encoded + "x." + sig, "/api/users?role=admin", codec},
+		"缺少签名":   {encoded, "/api/users?role=admin", codec},
+		"不是游标":   {"abc", "/api/users?role=admin", codec},
+	}
+	for name, tt := range invalid {
+		if _, err := tt.codec.Decode(tt.token, tt.scope); err != ErrInvalidCursor {
+			t.Errorf("%s: err = %v, want ErrInvalidCursor", name, err)
+		}
+	}
+}
+
+func TestTrim(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	key := func(id uint) (time.Time, uint) { return base.Add(time.Duration(id) * time.Second), id }
+	at := func(id uint, backward bool) *Cursor {
+		createdAt, _ := key(id)
+		return &Cursor{CreatedAt: createdAt, ID: id, Backward: backward}
+	}
+
+	tests := []struct {
+		name       string
+		items      []uint
+		cur        *Cursor
+		want       []uint
+		next, prev *Cursor
+	}{
+		{"第一页", []uint{9, 8, 7}, nil, []uint{9, 8}, at(8, false), nil},
+		{"只有一页", []uint{9, 8}, nil, []uint{9, 8}, nil, nil},
+		{"中间页", []uint{7, 6, 5}, at(8, false), []uint{7, 6}, at(6, false), at(7, true)},
+		{"最后一页", []uint{5}, at(6, false), []uint{5}, nil, at(5, true)},
+		{"向前翻页", []uint{7, 8, 9}, at(6, true), []uint{8, 7}, at(7, false), at(8, true)},
+		{"向前翻到第一页", []uint{8, 9}, at(7, true), []uint{9, 8}, at(8, false), nil},
+		{"空页", nil, at(1, false), nil, nil, nil},
+	}
+	for _, tt := range tests {
+		got, next, prev := Trim(tt.items, tt.cur, 2, key)
+		if len(got) != 0 || len(tt.want) != 0 {
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s: items = %v, want %v", tt.name, got, tt.want)
+			}
+		}
+		if !reflect.DeepEqual(next, tt.next) || !reflect.DeepEqual(prev, tt.prev) {
+			t.Errorf("%s: next = %+v, prev = %+v, want %+v, %+v", tt.name, next, prev, tt.next, tt.prev)
+		}
+	}
+}
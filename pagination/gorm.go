@@ -0,0 +1,55 @@
+package pagination
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Keyset 为查询添加游标条件、按 (created_at, id) 排序并多取一条记录，用于判断是否还有下一页
+//
+// table 为排序列所在的表；desc 为列表的排列顺序，向前翻页时反向扫描，结果由 Trim 恢复顺序。
+func Keyset(db *gorm.DB, table string, cur *Cursor, desc bool, limit int) *gorm.DB {
+	scanDesc := desc
+	if cur != nil && cur.Backward {
+		scanDesc = !desc
+	}
+	op, dir := ">", ""
+	if scanDesc {
+		op, dir = "<", " DESC"
+	}
+	if cur != nil {
+		db = db.Where(fmt.Sprintf("(%[1]s.created_at %[2]s ? OR (%[1]s.created_at = ? AND %[1]s.id %[2]s ?))", table, op),
+			cur.CreatedAt, cur.CreatedAt, cur.ID)
+	}
+	return db.Order(table + ".created_at" + dir).Order(table + ".id" + dir).Limit(limit + 1)
+}
+
+// Trim 去掉 Keyset 多取的记录并恢复列表顺序，返回下一页与上一页的游标（没有时为 nil）
+func Trim[T any](items []T, cur *Cursor, limit int, key func(T) (time.Time, uint)) (_ []T, next, prev *Cursor) {
+	more := len(items) > limit
+	if more {
+		items = items[:limit]
+	}
+	backward := cur != nil && cur.Backward
+	if backward {
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+	}
+	if len(items) == 0 {
+		return items, nil, nil
+	}
+
+	// 向后翻页时还有更多记录才有下一页；向前翻页时总是可以回到出发的那一页
+	if more || backward {
+		createdAt, id := key(items[len(items)-1])
+		next = &Cursor{CreatedAt: createdAt, ID: id}
+	}
+	if (more && backward) || (cur != nil && !backward) {
+		createdAt, id := key(items[0])
+		prev = &Cursor{CreatedAt: createdAt, ID: id, Backward: true}
+	}
+	return items, next, prev
+}
@@ -0,0 +1,150 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepInterval 清理过期 key 的间隔
+const sweepInterval = time.Minute
+
+// MemoryStore 进程内限流存储
+type MemoryStore struct {
+	mu        sync.Mutex
+	entries   map[string]*entry
+	lastSweep time.Time
+}
+
+// entry 单个 key 的限流状态
+type entry struct {
+	// 令牌桶
+	tokens float64
+	last   time.Time
+
+	// 滑动窗口
+	windowStart time.Time
+	prevCount   int
+	currCount   int
+
+	// expires 状态可以丢弃的时间（配额已完全恢复）
+	expires time.Time
+}
+
+// NewMemoryStore 创建进程内限流存储
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]*entry)}
+}
+
+// Take 尝试为 key 消耗一次配额
+func (s *MemoryStore) Take(_ context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	if err := limit.Validate(); err != nil {
+		return Result{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep(now)
+
+	e, ok := s.entries[key]
+	if !ok {
+		e = &entry{}
+		s.entries[key] = e
+	}
+
+	if limit.Algorithm == TokenBucket {
+		return e.takeToken(limit, now), nil
+	}
+	return e.takeWindow(limit, now), nil
+}
+
+// Len 当前保存的 key 数量
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
+
+// sweep 定期清理配额已完全恢复的 key，避免内存随客户端数量无限增长
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+
+	for key, e := range s.entries {
+		if !now.Before(e.expires) {
+			delete(s.entries, key)
+		}
+	}
+}
+
+// takeToken 令牌桶算法
+func (e *entry) takeToken(limit Limit, now time.Time) Result {
+	capacity := float64(limit.Capacity())
+	rate := float64(limit.Requests) / limit.Window.Seconds() // 每秒补充的令牌数
+
+	if e.last.IsZero() {
+		e.tokens = capacity
+	} else if elapsed := now.Sub(e.last).Seconds(); elapsed > 0 {
+		e.tokens = math.Min(capacity, e.tokens+elapsed*rate)
+	}
+	e.last = now
+
+	result := Result{Limit: int(capacity)}
+	if e.tokens >= 1 {
+		e.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = seconds((1 - e.tokens) / rate)
+	}
+
+	result.Remaining = int(math.Floor(e.tokens))
+	result.Reset = seconds((capacity - e.tokens) / rate)
+	e.expires = now.Add(result.Reset)
+	return result
+}
+
+// takeWindow 滑动窗口计数算法
+func (e *entry) takeWindow(limit Limit, now time.Time) Result {
+	window := limit.Window
+	start := now.Truncate(window)
+
+	// 进入新窗口时，上一个窗口的计数作为加权的基础
+	switch {
+	case e.windowStart.IsZero() || start.Sub(e.windowStart) >= 2*window:
+		e.prevCount, e.currCount = 0, 0
+	case start.After(e.windowStart):
+		e.prevCount, e.currCount = e.currCount, 0
+	}
+	e.windowStart = start
+
+	elapsed := float64(now.Sub(start)) / float64(window)
+	estimated := float64(e.prevCount)*(1-elapsed) + float64(e.currCount)
+	untilNext := start.Add(window).Sub(now)
+
+	result := Result{Limit: limit.Requests, Reset: untilNext}
+	if estimated+1 <= float64(limit.Requests) {
+		e.currCount++
+		estimated++
+		result.Allowed = true
+	} else if e.currCount+1 <= limit.Requests && e.prevCount > 0 {
+		// 等到上一个窗口的权重下降到足以容纳本次请求
+		need := 1 - float64(limit.Requests-e.currCount-1)/float64(e.prevCount)
+		result.RetryAfter = time.Duration((need - elapsed) * float64(window))
+	} else {
+		result.RetryAfter = untilNext
+	}
+
+	result.Remaining = int(math.Max(0, math.Floor(float64(limit.Requests)-estimated)))
+	// 当前窗口的请求在下一个窗口结束后才完全不再计入
+	e.expires = start.Add(2 * window)
+	return result
+}
+
+// seconds 将秒数转换为时长
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,109 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestTokenBucket 验证令牌桶的突发容量、拒绝后的等待时间与令牌补充
+func TestTokenBucket(t *testing.T) {
+	store := NewMemoryStore()
+	limit := Limit{Algorithm: TokenBucket, Requests: 60, Window: time.Minute, Burst: 3}
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 3; i++ {
+		res, err := store.Take(context.Background(), "k", limit, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !res.Allowed || res.Remaining != 2-i || res.Limit != 3 {
+			t.Fatalf("第 %d 次请求: %+v", i+1, res)
+		}
+	}
+
+	res, _ := store.Take(context.Background(), "k", limit, now)
+	if res.Allowed {
+		t.Fatal("突发容量用尽后应被拒绝")
+	}
+	if res.RetryAfter != time.Second {
+		t.Errorf("RetryAfter = %v，期望 1s（每秒补充 1 个令牌）", res.RetryAfter)
+	}
+
+	res, _ = store.Take(context.Background(), "k", limit, now.Add(time.Second))
+	if !res.Allowed {
+		t.Error("补充令牌后应允许请求")
+	}
+
+	res, _ = store.Take(context.Background(), "other", limit, now)
+	if !res.Allowed {
+		t.Error("不同 key 的配额应相互独立")
+	}
+}
+
+// TestSlidingWindow 验证滑动窗口按上一个窗口的计数加权
+func TestSlidingWindow(t *testing.T) {
+	store := NewMemoryStore()
+	limit := Limit{Algorithm: SlidingWindow, Requests: 4, Window: time.Minute}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 4; i++ {
+		if res, _ := store.Take(context.Background(), "k", limit, start.Add(time.Duration(i)*time.Second)); !res.Allowed {
+			t.Fatalf("第 %d 次请求应被允许", i+1)
+		}
+	}
+
+	res, _ := store.Take(context.Background(), "k", limit, start.Add(30*time.Second))
+	if res.Allowed {
+		t.Fatal("窗口内超出配额应被拒绝")
+	}
+	if res.RetryAfter != 30*time.Second {
+		t.Errorf("RetryAfter = %v，期望等到下一个窗口（30s）", res.RetryAfter)
+	}
+
+	// 下一个窗口过去 1/4 时，上一个窗口的 4 次请求按 3/4 计入，只剩 1 次配额
+	next := start.Add(75 * time.Second)
+	if res, _ := store.Take(context.Background(), "k", limit, next); !res.Allowed || res.Remaining != 0 {
+		t.Fatalf("加权后应剩余 1 次配额: %+v", res)
+	}
+	res, _ = store.Take(context.Background(), "k", limit, next)
+	if res.Allowed {
+		t.Fatal("加权后的配额用尽应被拒绝")
+	}
+	// 需要上一个窗口的权重降到 2/4，即窗口过去一半
+	if res.RetryAfter != 15*time.Second {
+		t.Errorf("RetryAfter = %v，期望 15s", res.RetryAfter)
+	}
+}
+
+// TestMemoryStoreSweep 验证配额完全恢复的 key 会被清理
+func TestMemoryStoreSweep(t *testing.T) {
+	store := NewMemoryStore()
+	limit := Limit{Algorithm: TokenBucket, Requests: 10, Window: time.Second}
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	store.Take(context.Background(), "a", limit, now)
+	store.Take(context.Background(), "b", limit, now)
+	if store.Len() != 2 {
+		t.Fatalf("Len = %d，期望 2", store.Len())
+	}
+
+	store.Take(context.Background(), "c", limit, now.Add(2*sweepInterval))
+	if store.Len() != 1 {
+		t.Errorf("清理后 Len = %d，期望 1", store.Len())
+	}
+}
+
+// TestInvalidLimit 验证无效的限流参数
+func TestInvalidLimit(t *testing.T) {
+	store := NewMemoryStore()
+	for _, limit := range []Limit{
+		{Algorithm: "fixed", Requests: 1, Window: time.Second},
+		{Algorithm: TokenBucket, Requests: 0, Window: time.Second},
+		{Algorithm: SlidingWindow, Requests: 1},
+	} {
+		if _, err := store.Take(context.Background(), "k", limit, time.Now()); err == nil {
+			t.Errorf("%+v 应返回错误", limit)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Algorithm 限流算法
+type Algorithm string
+
+const (
+	// TokenBucket 令牌桶：按固定速率补充令牌，允许不超过桶容量的突发请求
+	TokenBucket Algorithm = "token_bucket"
+	// SlidingWindow 滑动窗口计数：按上一个窗口的计数加权估算当前窗口内的请求数
+	SlidingWindow Algorithm = "sliding_window"
+)
+
+// Limit 限流参数
+type Limit struct {
+	Algorithm Algorithm
+	Requests  int           // 每个窗口允许的请求数
+	Window    time.Duration // 窗口长度
+	Burst     int           // 令牌桶容量，<= 0 时与 Requests 相同
+}
+
+// Capacity 单个 key 在不等待的情况下最多可以发出的请求数
+func (l Limit) Capacity() int {
+	if l.Algorithm == TokenBucket && l.Burst > 0 {
+		return l.Burst
+	}
+	return l.Requests
+}
+
+// Validate 校验限流参数
+func (l Limit) Validate() error {
+	switch l.Algorithm {
+	case TokenBucket, SlidingWindow:
+	default:
+		return fmt.Errorf("不支持的限流算法: %s", l.Algorithm)
+	}
+	if l.Requests <= 0 {
+		return fmt.Errorf("限流请求数必须大于 0")
+	}
+	if l.Window <= 0 {
+		return fmt.Errorf("限流窗口必须大于 0")
+	}
+	return nil
+}
+
+// Result 一次限流判断的结果
+type Result struct {
+	Allowed    bool
+	Limit      int           // 配额上限
+	Remaining  int           // 剩余配额
+	Reset      time.Duration // 配额完全恢复所需时间
+	RetryAfter time.Duration // 被拒绝时需要等待的时间
+}
+
+// Store 限流状态存储
+//
+// 内存存储只在单个实例内生效；多实例部署时可以基于 Redis 等共享存储实现该接口，
+// 实现需要保证同一个 key 上的 Take 是原子的。
+type Store interface {
+	// Take 尝试为 key 消耗一次配额
+	Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error)
+}
@@ -0,0 +1,49 @@
+package reporting
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// FileReporter 将事件逐个写入目录的上报器（便于离线收集或由其他进程转发）
+type FileReporter struct {
+	dir string
+}
+
+// NewFileReporter 创建文件上报器，目录不存在时自动创建
+func NewFileReporter(dir string) (*FileReporter, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("错误上报目录不能为空")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("创建错误上报目录失败: %v", err)
+	}
+	return &FileReporter{dir: dir}, nil
+}
+
+// Report 将事件写入 <时间>-<事件ID>.json（先写临时文件再重命名，读取方不会看到写了一半的文件）
+func (r *FileReporter) Report(_ context.Context, event *Event) error {
+	data, err := json.MarshalIndent(event, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	name := event.Timestamp.UTC().Format("20060102T150405") + "-" + event.ID + ".json"
+	tmp, err := os.CreateTemp(r.dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(r.dir, name))
+}
@@ -0,0 +1,48 @@
+package reporting
+
+import (
+	"context"
+	"log/slog"
+
+	"iris-cn-sample-project/logging"
+)
+
+// LogReporter 写入应用日志的上报器
+type LogReporter struct{}
+
+// NewLogReporter 创建日志上报器
+func NewLogReporter() *LogReporter {
+	return &LogReporter{}
+}
+
+// Report 以 error 级别记录事件
+func (LogReporter) Report(ctx context.Context, event *Event) error {
+	attrs := []slog.Attr{
+		slog.String("event_id", event.ID),
+		slog.String("fingerprint", event.Fingerprint),
+		slog.String("error", event.Message),
+		slog.String("type", event.Type),
+		slog.String("stack", event.Stack),
+		slog.String("request_id", event.RequestID),
+		slog.String("method", event.Method),
+		slog.String("path", event.Path),
+		slog.String("route", event.Route),
+		slog.String("client_ip", event.ClientIP),
+		slog.String("user_agent", event.UserAgent),
+	}
+	if event.Query != "" {
+		attrs = append(attrs, slog.String("query", event.Query))
+	}
+	if event.UserID != "" {
+		attrs = append(attrs, slog.String("user_id", event.UserID))
+	}
+	if event.Body != "" {
+		attrs = append(attrs, slog.String("request_body", event.Body))
+	}
+	if event.Suppressed > 0 {
+		attrs = append(attrs, slog.Int("suppressed", event.Suppressed))
+	}
+
+	logging.L().LogAttrs(ctx, slog.LevelError, "服务器内部错误（panic）", attrs...)
+	return nil
+}
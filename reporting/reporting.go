@@ -0,0 +1,204 @@
+package reporting
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"iris-cn-sample-project/config"
+)
+
+// Event 一次 panic 的上报内容
+type Event struct {
+	ID          string    `json:"id"`
+	Timestamp   time.Time `json:"timestamp"`
+	Fingerprint string    `json:"fingerprint"`
+	Type        string    `json:"type"` // panic 值的类型
+	Message     string    `json:"message"`
+	Stack       string    `json:"stack"`
+	Frames      []Frame   `json:"frames"` // 调用栈（从 panic 位置开始）
+
+	RequestID string `json:"request_id,omitempty"`
+	TraceID   string `json:"trace_id,omitempty"`
+	Method    string `json:"method,omitempty"`
+	Path      string `json:"path,omitempty"`
+	Route     string `json:"route,omitempty"`
+	Query     string `json:"query,omitempty"` // 已脱敏
+	UserID    string `json:"user_id,omitempty"`
+	ClientIP  string `json:"client_ip,omitempty"`
+	UserAgent string `json:"user_agent,omitempty"`
+	Body      string `json:"body,omitempty"` // 已脱敏并截断
+
+	// Suppressed 上一次上报之后因去重而未上报的相同 panic 次数
+	Suppressed int `json:"suppressed,omitempty"`
+}
+
+// Frame 调用栈中的一帧
+type Frame struct {
+	Function string `json:"function"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+}
+
+// Reporter 错误上报器
+type Reporter interface {
+	Report(ctx context.Context, event *Event) error
+}
+
+// New 根据配置创建上报器（已按 DedupWindow 去重）
+func New(cfg config.ErrorReportConfig) (Reporter, error) {
+	var reporter Reporter
+	switch cfg.Reporter {
+	case "log", "":
+		reporter = NewLogReporter()
+	case "file":
+		r, err := NewFileReporter(cfg.SpoolDir)
+		if err != nil {
+			return nil, err
+		}
+		reporter = r
+	case "sentry":
+		r, err := NewSentryReporter(cfg.DSN, time.Duration(cfg.Timeout)*time.Second)
+		if err != nil {
+			return nil, err
+		}
+		reporter = r
+	default:
+		return nil, fmt.Errorf("不支持的错误上报方式: %s", cfg.Reporter)
+	}
+
+	if cfg.DedupWindow > 0 {
+		reporter = Dedup(reporter, time.Duration(cfg.DedupWindow)*time.Second)
+	}
+	return reporter, nil
+}
+
+// NewEvent 根据 panic 值创建事件，skip 为 runtime.Callers 需要跳过的栈帧数
+//
+// 应在 recover 所在的 defer 函数中调用，此时调用栈仍包含 panic 发生的位置。
+func NewEvent(recovered interface{}, stack []byte, skip int) *Event {
+	event := &Event{
+		ID:        newEventID(),
+		Timestamp: time.Now(),
+		Type:      fmt.Sprintf("%T", recovered),
+		Message:   fmt.Sprint(recovered),
+		Stack:     string(stack),
+		Frames:    callers(skip + 1),
+	}
+	if err, ok := recovered.(error); ok {
+		event.Message = err.Error()
+	}
+	event.Fingerprint = fingerprint(event)
+	return event
+}
+
+// callers 获取 panic 位置开始的调用栈（跳过 runtime 中 panic 相关的帧）
+func callers(skip int) []Frame {
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(skip+1, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	var result []Frame
+	panicking := false
+	for {
+		frame, more := frames.Next()
+		if frame.Function == "runtime.gopanic" {
+			// 丢弃 defer 与 recover 相关的帧，从 panic 位置开始记录
+			result = result[:0]
+			panicking = true
+		} else if !panicking || !strings.HasPrefix(frame.Function, "runtime.") || len(result) > 0 {
+			result = append(result, Frame{Function: frame.Function, File: frame.File, Line: frame.Line})
+		}
+		if !more {
+			break
+		}
+	}
+	return result
+}
+
+// fingerprint 计算去重指纹：panic 值的类型与 panic 发生的位置
+//
+// 不包含消息内容，下标越界等消息中带有变量值的 panic 在同一位置也视为相同。
+func fingerprint(event *Event) string {
+	h := sha256.New()
+	h.Write([]byte(event.Type))
+	if len(event.Frames) > 0 {
+		top := event.Frames[0]
+		fmt.Fprintf(h, "|%s|%s:%d", top.Function, top.File, top.Line)
+	} else {
+		h.Write([]byte("|" + event.Message))
+	}
+	return hex.EncodeToString(h.Sum(nil)[:8])
+}
+
+// newEventID 生成 32 位十六进制的事件ID（与 Sentry 的 event_id 格式一致）
+func newEventID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// dedupReporter 对相同指纹的 panic 去重的上报器
+type dedupReporter struct {
+	next   Reporter
+	window time.Duration
+
+	mu        sync.Mutex
+	seen      map[string]*dedupEntry
+	lastSweep time.Time
+}
+
+// dedupEntry 单个指纹的去重状态
+type dedupEntry struct {
+	reported   time.Time
+	suppressed int
+}
+
+// Dedup 包装上报器：同一指纹在 window 内只上报一次，被抑制的次数随下一次上报一起发送
+func Dedup(next Reporter, window time.Duration) Reporter {
+	return &dedupReporter{next: next, window: window, seen: make(map[string]*dedupEntry)}
+}
+
+// Report 上报事件（窗口内重复的事件直接丢弃）
+func (d *dedupReporter) Report(ctx context.Context, event *Event) error {
+	now := event.Timestamp
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	d.mu.Lock()
+	d.sweep(now)
+	entry, ok := d.seen[event.Fingerprint]
+	if ok && now.Sub(entry.reported) < d.window {
+		entry.suppressed++
+		d.mu.Unlock()
+		return nil
+	}
+	if ok {
+		event.Suppressed = entry.suppressed
+	}
+	d.seen[event.Fingerprint] = &dedupEntry{reported: now}
+	d.mu.Unlock()
+
+	return d.next.Report(ctx, event)
+}
+
+// sweep 定期清理窗口已过且没有被抑制事件的指纹
+func (d *dedupReporter) sweep(now time.Time) {
+	if now.Sub(d.lastSweep) < d.window {
+		return
+	}
+	d.lastSweep = now
+
+	for key, entry := range d.seen {
+		if entry.suppressed == 0 && now.Sub(entry.reported) >= d.window {
+			delete(d.seen, key)
+		}
+	}
+}
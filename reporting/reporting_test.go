@@ -0,0 +1,167 @@
+package reporting
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"iris-cn-sample-project/config"
+)
+
+// recordingReporter 记录收到的事件
+type recordingReporter struct {
+	events []*Event
+}
+
+func (r *recordingReporter) Report(_ context.Context, event *Event) error {
+	r.events = append(r.events, event)
+	return nil
+}
+
+// capturePanic 触发 panic 并在 recover 后创建事件
+func capturePanic(f func()) (event *Event) {
+	defer func() {
+		event = NewEvent(recover(), nil, 0)
+	}()
+	f()
+	return nil
+}
+
+// TestNewEvent 验证调用栈从 panic 位置开始，且同一位置的 panic 指纹相同
+func TestNewEvent(t *testing.T) {
+	panicAt := func(i int) {
+		var items []int
+		_ = items[i]
+	}
+
+	a := capturePanic(func() { panicAt(1) })
+	b := capturePanic(func() { panicAt(2) })
+
+	if len(a.Frames) == 0 || !strings.Contains(a.Frames[0].Function, "TestNewEvent.func1") {
+		t.Fatalf("第一帧应为 panic 发生的位置: %+v", a.Frames)
+	}
+	if len(a.ID) != 32 || a.ID == b.ID {
+		t.Errorf("事件ID = %q / %q，期望不同的 32 位十六进制字符串", a.ID, b.ID)
+	}
+	if a.Message == b.Message || a.Fingerprint != b.Fingerprint {
+		t.Errorf("同一位置的 panic 指纹应相同: %s / %s", a.Fingerprint, b.Fingerprint)
+	}
+
+	c := capturePanic(func() { panic(errors.New("boom")) })
+	if c.Message != "boom" || c.Fingerprint == a.Fingerprint {
+		t.Errorf("不同位置的 panic 指纹应不同: %+v", c)
+	}
+}
+
+// TestDedup 验证窗口内的相同事件只上报一次，并在下一次上报时附带被抑制的次数
+func TestDedup(t *testing.T) {
+	next := &recordingReporter{}
+	reporter := Dedup(next, time.Minute)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	report := func(fingerprint string, at time.Time) {
+		reporter.Report(context.Background(), &Event{Fingerprint: fingerprint, Timestamp: at})
+	}
+
+	report("a", now)
+	report("a", now.Add(10*time.Second))
+	report("a", now.Add(20*time.Second))
+	report("b", now.Add(30*time.Second))
+	if len(next.events) != 2 {
+		t.Fatalf("上报次数 = %d，期望 2", len(next.events))
+	}
+
+	report("a", now.Add(time.Minute))
+	if len(next.events) != 3 || next.events[2].Suppressed != 2 {
+		t.Errorf("窗口过后应重新上报并附带被抑制的次数: %+v", next.events[len(next.events)-1])
+	}
+}
+
+// TestFileReporter 验证事件写入目录
+func TestFileReporter(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "errors")
+	reporter, err := New(config.ErrorReportConfig{Reporter: "file", SpoolDir: dir})
+	if err != nil {
+		t.Fatalf("创建上报器失败: %v", err)
+	}
+
+	event := &Event{ID: "0123456789abcdef0123456789abcdef", Timestamp: time.Now(), Message: "boom"}
+	if err := reporter.Report(context.Background(), event); err != nil {
+		t.Fatalf("上报失败: %v", err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(files) != 1 || !strings.HasSuffix(files[0], event.ID+".json") {
+		t.Fatalf("目录中的文件 = %v", files)
+	}
+	data, _ := os.ReadFile(files[0])
+	var saved Event
+	if err := json.Unmarshal(data, &saved); err != nil || saved.Message != "boom" {
+		t.Errorf("文件内容 = %s", data)
+	}
+}
+
+// TestSentryReporter 验证 DSN 解析、认证头与事件格式
+func TestSentryReporter(t *testing.T) {
+	var (
+		gotPath string
+		gotAuth string
+		got     map[string]interface{}
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotAuth = r.URL.Path, r.Header.Get("X-Sentry-Auth")
+		json.NewDecoder(r.Body).Decode(&got)
+	}))
+	defer server.Close()
+
+	dsn := strings.Replace(server.URL, "://", "://publickey@", 1) + "/sentry/42"
+	reporter, err := NewSentryReporter(dsn, time.Second)
+	if err != nil {
+		t.Fatalf("创建上报器失败: %v", err)
+	}
+
+	event := &Event{
+		ID:          "0123456789abcdef0123456789abcdef",
+		Timestamp:   time.Now(),
+		Fingerprint: "fp",
+		Type:        "runtime.boundsError",
+		Message:     "index out of range",
+		Frames: []Frame{
+			{Function: "iris-cn-sample-project/controllers.GetUser", File: "user_controller.go", Line: 10},
+			{Function: "github.com/kataras/iris/v12/context.(*Context).Next", File: "context.go", Line: 20},
+		},
+		RequestID: "req-1",
+		UserID:    "7",
+	}
+	if err := reporter.Report(context.Background(), event); err != nil {
+		t.Fatalf("上报失败: %v", err)
+	}
+
+	if gotPath != "/sentry/api/42/store/" {
+		t.Errorf("上报路径 = %q", gotPath)
+	}
+	if !strings.Contains(gotAuth, "sentry_key=publickey") || !strings.Contains(gotAuth, "sentry_version=7") {
+		t.Errorf("X-Sentry-Auth = %q", gotAuth)
+	}
+	if got["event_id"] != event.ID || got["tags"].(map[string]interface{})["request_id"] != "req-1" {
+		t.Errorf("事件内容 = %v", got)
+	}
+
+	// 栈帧按调用顺序排列，panic 位置在最后
+	frames := got["exception"].(map[string]interface{})["values"].([]interface{})[0].(map[string]interface{})["stacktrace"].(map[string]interface{})["frames"].([]interface{})
+	last := frames[len(frames)-1].(map[string]interface{})
+	if last["function"] != event.Frames[0].Function || last["in_app"] != true {
+		t.Errorf("最后一帧 = %v", last)
+	}
+
+	if _, err := NewSentryReporter("http://host/42", time.Second); err == nil {
+		t.Error("缺少公钥的 DSN 应返回错误")
+	}
+}
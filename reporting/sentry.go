@@ -0,0 +1,142 @@
+package reporting
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"iris-cn-sample-project/requestid"
+	"iris-cn-sample-project/version"
+)
+
+// modulePrefix 本项目的包路径前缀，用于标记应用内的栈帧
+const modulePrefix = "iris-cn-sample-project/"
+
+// SentryReporter 上报到 Sentry 兼容服务（store 接口）的上报器
+type SentryReporter struct {
+	endpoint string
+	auth     string
+	client   *http.Client
+}
+
+// NewSentryReporter 根据 DSN 创建上报器，DSN 格式为 {scheme}://{key}[:{secret}]@{host}[/{path}]/{project}
+func NewSentryReporter(dsn string, timeout time.Duration) (*SentryReporter, error) {
+	u, err := url.Parse(dsn)
+	if err != nil || dsn == "" {
+		return nil, fmt.Errorf("Sentry DSN 无效: %q", dsn)
+	}
+	if u.User == nil || u.User.Username() == "" {
+		return nil, fmt.Errorf("Sentry DSN 缺少公钥")
+	}
+
+	path := strings.TrimSuffix(u.Path, "/")
+	slash := strings.LastIndex(path, "/")
+	project := path[slash+1:]
+	if project == "" {
+		return nil, fmt.Errorf("Sentry DSN 缺少项目ID")
+	}
+
+	auth := fmt.Sprintf("Sentry sentry_version=7, sentry_client=iris-cn-sample-project/%s, sentry_key=%s", version.Version, u.User.Username())
+	if secret, ok := u.User.Password(); ok {
+		auth += ", sentry_secret=" + secret
+	}
+
+	if timeout <= 0 {
+		timeout = 3 * time.Second
+	}
+
+	return &SentryReporter{
+		endpoint: fmt.Sprintf("%s://%s%s/api/%s/store/", u.Scheme, u.Host, path[:slash], project),
+		auth:     auth,
+		client:   &http.Client{Timeout: timeout, Transport: requestid.NewTransport(nil)},
+	}, nil
+}
+
+// Report 发送事件
+func (r *SentryReporter) Report(ctx context.Context, event *Event) error {
+	payload, err := json.Marshal(sentryEvent(event))
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Sentry-Auth", r.auth)
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("发送错误上报失败: %v", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("错误上报服务返回状态码 %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// sentryEvent 转换为 Sentry 事件格式
+func sentryEvent(event *Event) map[string]interface{} {
+	// Sentry 的栈帧按调用顺序排列，最后一帧是 panic 发生的位置
+	frames := make([]map[string]interface{}, 0, len(event.Frames))
+	for i := len(event.Frames) - 1; i >= 0; i-- {
+		f := event.Frames[i]
+		frames = append(frames, map[string]interface{}{
+			"function": f.Function,
+			"abs_path": f.File,
+			"lineno":   f.Line,
+			"in_app":   strings.HasPrefix(f.Function, modulePrefix) || strings.HasPrefix(f.Function, "main."),
+		})
+	}
+
+	tags := map[string]string{"route": event.Route}
+	if event.RequestID != "" {
+		tags["request_id"] = event.RequestID
+	}
+	if event.TraceID != "" {
+		tags["trace_id"] = event.TraceID
+	}
+
+	payload := map[string]interface{}{
+		"event_id":    event.ID,
+		"timestamp":   event.Timestamp.UTC().Format(time.RFC3339Nano),
+		"platform":    "go",
+		"level":       "error",
+		"logger":      "panic",
+		"release":     version.Version,
+		"fingerprint": []string{event.Fingerprint},
+		"exception": map[string]interface{}{
+			"values": []map[string]interface{}{{
+				"type":       event.Type,
+				"value":      event.Message,
+				"stacktrace": map[string]interface{}{"frames": frames},
+			}},
+		},
+		"request": map[string]interface{}{
+			"method":       event.Method,
+			"url":          event.Path,
+			"query_string": event.Query,
+			"data":         event.Body,
+			"headers":      map[string]string{"User-Agent": event.UserAgent},
+		},
+		"user": map[string]string{
+			"id":         event.UserID,
+			"ip_address": event.ClientIP,
+		},
+		"tags": tags,
+	}
+	if event.Suppressed > 0 {
+		payload["extra"] = map[string]interface{}{"suppressed": event.Suppressed}
+	}
+	return payload
+}
@@ -0,0 +1,71 @@
+package requestid
+
+import (
+	"errors"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GormPlugin 为 SQL 添加 /* request_id=... */ 注释的 GORM 插件，便于从慢查询日志、数据库审计中追溯到请求
+//
+// 只有语句的上下文中存在请求ID（服务层通过 db.WithContext(ctx) 传入请求上下文）时才会添加。
+type GormPlugin struct{}
+
+// NewGormPlugin 创建 GORM 请求ID注释插件
+func NewGormPlugin() *GormPlugin {
+	return &GormPlugin{}
+}
+
+// Name 插件名称
+func (p *GormPlugin) Name() string {
+	return "requestid"
+}
+
+// Initialize 在构建 SQL 的回调之前注册注释的添加
+func (p *GormPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+
+	return errors.Join(
+		cb.Create().Before("gorm:create").Register("requestid:create", comment("INSERT", "VALUES")),
+		cb.Query().Before("gorm:query").Register("requestid:query", comment("SELECT")),
+		cb.Update().Before("gorm:update").Register("requestid:update", comment("UPDATE")),
+		cb.Delete().Before("gorm:delete").Register("requestid:delete", comment("DELETE")),
+		cb.Row().Before("gorm:row").Register("requestid:row", comment("SELECT")),
+		cb.Raw().Before("gorm:raw").Register("requestid:raw", comment()),
+	)
+}
+
+// comment 为语句添加注释：SQL 已构建（Raw）时直接加在 SQL 前，否则挂在候选子句中第一个可用的子句之前
+//
+// 方言自定义了构建方式的子句（例如 SQLite 的 INSERT）会忽略 BeforeExpression，此时退而使用下一个候选子句。
+func comment(clauseNames ...string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		id := FromContext(db.Statement.Context)
+		if id == "" {
+			return
+		}
+		text := "/* request_id=" + strings.ReplaceAll(id, "*/", "") + " */"
+
+		stmt := db.Statement
+		if stmt.SQL.Len() > 0 {
+			if strings.HasPrefix(stmt.SQL.String(), "/*") {
+				return
+			}
+			sql := text + " " + stmt.SQL.String()
+			stmt.SQL.Reset()
+			stmt.SQL.WriteString(sql)
+			return
+		}
+		for _, name := range clauseNames {
+			if _, ok := db.ClauseBuilders[name]; ok {
+				continue
+			}
+			c := stmt.Clauses[name]
+			c.BeforeExpression = clause.Expr{SQL: text}
+			stmt.Clauses[name] = c
+			return
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"net/http"
+	"time"
+)
+
+// Header 传递请求ID的默认请求头
+const Header = "X-Request-ID"
+
+// DefaultMaxLength 客户端传入的请求ID的默认长度上限
+const DefaultMaxLength = 64
+
+// 请求ID格式
+const (
+	FormatUUIDv7 = "uuidv7"
+	FormatULID   = "ulid"
+)
+
+// contextKey 请求ID在 context.Context 中的键
+type contextKey struct{}
+
+// NewContext 返回携带请求ID的上下文
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext 获取上下文中的请求ID，不存在时返回空字符串
+func FromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// New 按格式生成请求ID，未知格式使用 UUIDv7
+func New(format string) string {
+	if format == FormatULID {
+		return NewULID()
+	}
+	return NewUUIDv7()
+}
+
+// NewUUIDv7 生成 UUIDv7（RFC 9562）：48 位毫秒时间戳 + 74 位随机数，按时间有序
+func NewUUIDv7() string {
+	var b [16]byte
+	fillTimeAndRandom(&b)
+	b[6] = b[6]&0x0f | 0x70 // 版本 7
+	b[8] = b[8]&0x3f | 0x80 // RFC 9562 变体
+
+	var s [36]byte
+	hex.Encode(s[0:8], b[0:4])
+	s[8] = '-'
+	hex.Encode(s[9:13], b[4:6])
+	s[13] = '-'
+	hex.Encode(s[14:18], b[6:8])
+	s[18] = '-'
+	hex.Encode(s[19:23], b[8:10])
+	s[23] = '-'
+	hex.Encode(s[24:], b[10:])
+	return string(s[:])
+}
+
+// crockford ULID 使用的 Crockford Base32 字母表
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// NewULID 生成 ULID：48 位毫秒时间戳 + 80 位随机数，编码为 26 个字符
+func NewULID() string {
+	var b [16]byte
+	fillTimeAndRandom(&b)
+
+	// 128 位按 5 位一组编码，首字符只占 3 位
+	hi := binary.BigEndian.Uint64(b[0:8])
+	lo := binary.BigEndian.Uint64(b[8:16])
+	var s [26]byte
+	for i := 25; i >= 0; i-- {
+		s[i] = crockford[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(s[:])
+}
+
+// fillTimeAndRandom 前 6 字节写入毫秒时间戳，其余字节填充随机数
+func fillTimeAndRandom(b *[16]byte) {
+	ms := uint64(time.Now().UnixMilli())
+	b[0] = byte(ms >> 40)
+	b[1] = byte(ms >> 32)
+	b[2] = byte(ms >> 24)
+	b[3] = byte(ms >> 16)
+	b[4] = byte(ms >> 8)
+	b[5] = byte(ms)
+	rand.Read(b[6:])
+}
+
+// Valid 校验客户端传入的请求ID：长度不超过 maxLength，只允许字母、数字与 - _ . : 字符
+//
+// 限制字符集可以避免请求ID被用于日志注入，或在写入 SQL 注释、响应头时破坏格式。
+func Valid(id string, maxLength int) bool {
+	if maxLength <= 0 {
+		maxLength = DefaultMaxLength
+	}
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// Transport 为出站 HTTP 请求附加上下文中请求ID的 RoundTripper
+type Transport struct {
+	Base http.RoundTripper
+}
+
+// NewTransport 创建 Transport，base 为 nil 时使用 http.DefaultTransport
+func NewTransport(base http.RoundTripper) *Transport {
+	return &Transport{Base: base}
+}
+
+// RoundTrip 发送请求（请求已带有请求ID时不覆盖）
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	if id := FromContext(req.Context()); id != "" && req.Header.Get(Header) == "" {
+		// RoundTripper 不应修改传入的请求
+		req = req.Clone(req.Context())
+		req.Header.Set(Header, id)
+	}
+	return base.RoundTrip(req)
+}
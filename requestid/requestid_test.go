@@ -0,0 +1,127 @@
+package requestid
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// TestNewUUIDv7 验证 UUIDv7 的格式、版本位与唯一性
+func TestNewUUIDv7(t *testing.T) {
+	pattern := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+	seen := make(map[string]bool)
+	for i := 0; i < 1000; i++ {
+		id := NewUUIDv7()
+		if !pattern.MatchString(id) {
+			t.Fatalf("UUIDv7 格式错误: %s", id)
+		}
+		if seen[id] {
+			t.Fatalf("生成了重复的请求ID: %s", id)
+		}
+		seen[id] = true
+	}
+}
+
+// TestNewULID 验证 ULID 的格式，且按时间戳前缀排序
+func TestNewULID(t *testing.T) {
+	pattern := regexp.MustCompile(`^[0-7][0-9A-HJKMNP-TV-Z]{25}$`)
+
+	a, b := NewULID(), New(FormatULID)
+	if !pattern.MatchString(a) || !pattern.MatchString(b) {
+		t.Fatalf("ULID 格式错误: %s / %s", a, b)
+	}
+	if a == b || a[:10] > b[:10] {
+		t.Errorf("ULID 应唯一且时间戳前缀递增: %s / %s", a, b)
+	}
+}
+
+// TestValid 验证客户端传入请求ID的校验
+func TestValid(t *testing.T) {
+	tests := []struct {
+		id   string
+		want bool
+	}{
+		{"0190b5b4-6c1e-7a3b-8f00-0123456789ab", true},
+		{"01J2TKD5GQ8Z6V4X3W2Y1A0B9C", true},
+		{"trace.abc_123:4", true},
+		{"", false},
+		{strings.Repeat("a", DefaultMaxLength), true},
+		{strings.Repeat("a", DefaultMaxLength+1), false},
+		{"abc\n{\"level\":\"ERROR\"}", false},
+		{"abc */ DROP TABLE users", false},
+		{"<script>", false},
+	}
+
+	for _, tt := range tests {
+		if got := Valid(tt.id, 0); got != tt.want {
+			t.Errorf("Valid(%q) = %v，期望 %v", tt.id, got, tt.want)
+		}
+	}
+}
+
+// TestTransport 验证出站请求携带上下文中的请求ID
+func TestTransport(t *testing.T) {
+	var got string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get(Header)
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: NewTransport(nil)}
+	req, _ := http.NewRequestWithContext(NewContext(context.Background(), "req-1"), http.MethodGet, server.URL, nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if got != "req-1" {
+		t.Errorf("出站请求的 %s = %q，期望 req-1", Header, got)
+	}
+	if req.Header.Get(Header) != "" {
+		t.Error("Transport 不应修改调用方的请求")
+	}
+}
+
+// TestGormPlugin 验证 SQL 前添加请求ID注释
+func TestGormPlugin(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("打开数据库失败: %v", err)
+	}
+	if err := db.Use(NewGormPlugin()); err != nil {
+		t.Fatalf("注册插件失败: %v", err)
+	}
+
+	type item struct {
+		ID   uint
+		Name string
+	}
+	dry := db.Session(&gorm.Session{DryRun: true})
+	ctx := NewContext(context.Background(), "req-1")
+
+	statements := map[string]string{
+		"query":  dry.WithContext(ctx).Where("name = ?", "a").Find(&[]item{}).Statement.SQL.String(),
+		"create": dry.WithContext(ctx).Create(&item{Name: "a"}).Statement.SQL.String(),
+		"update": dry.WithContext(ctx).Model(&item{ID: 1}).Update("name", "b").Statement.SQL.String(),
+		"delete": dry.WithContext(ctx).Delete(&item{ID: 1}).Statement.SQL.String(),
+		"raw":    dry.WithContext(ctx).Raw("SELECT 1").Scan(&[]int{}).Statement.SQL.String(),
+	}
+	for op, sql := range statements {
+		if !strings.Contains(sql, "/* request_id=req-1 */ ") {
+			t.Errorf("%s 语句缺少请求ID注释: %s", op, sql)
+		}
+	}
+
+	if sql := dry.Find(&[]item{}).Statement.SQL.String(); strings.Contains(sql, "request_id") {
+		t.Errorf("上下文中没有请求ID时不应添加注释: %s", sql)
+	}
+}
@@ -0,0 +1,29 @@
+package server
+
+import (
+	"net"
+	"net/http"
+)
+
+// RedirectHandler 将 HTTP 请求重定向到 HTTPS
+//
+// 目标主机固定为配置的 host，不使用请求的 Host：否则任意 Host 都会被重定向过去（开放重定向），
+// 缓存了重定向响应的代理也可能被投毒。httpsPort 不是 443 时附加到目标地址中。
+// GET/HEAD 使用 301，其余方法使用 308 以保留请求方法与请求体。
+// 重定向响应不携带 HSTS：按规范 HSTS 只能通过 HTTPS 响应下发，浏览器跟随重定向后由 HTTPS 响应设置。
+func RedirectHandler(httpsPort, host string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		target := host
+		if httpsPort != "" && httpsPort != "443" {
+			target = net.JoinHostPort(target, httpsPort)
+		} else if ip := net.ParseIP(target); ip != nil && ip.To4() == nil {
+			target = "[" + target + "]"
+		}
+
+		code := http.StatusPermanentRedirect
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			code = http.StatusMovedPermanently
+		}
+		http.Redirect(w, r, "https://"+target+r.URL.RequestURI(), code)
+	})
+}
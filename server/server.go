@@ -0,0 +1,214 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"iris-cn-sample-project/config"
+	"iris-cn-sample-project/health"
+	"iris-cn-sample-project/logging"
+)
+
+// hookTimeout 单个停机钩子的超时时间
+const hookTimeout = 10 * time.Second
+
+// Server 支持优雅停机的 HTTP 服务器
+//
+// 收到停止信号后依次：标记为未就绪（/readyz 返回 503）、等待 DrainDelay 让负载均衡摘除实例、
+// 停止接受新连接并在 ShutdownTimeout 内等待进行中的请求完成（超时则强制关闭连接），最后按注册的逆序执行停机钩子。
+type Server struct {
+	srv             *http.Server
+	shutdownTimeout time.Duration
+	drainDelay      time.Duration
+
+	certs          *CertReloader
+	reloadInterval time.Duration
+	redirect       *http.Server
+	redirectLn     net.Listener
+
+	mu    sync.Mutex
+	hooks []hook
+}
+
+// hook 停机钩子
+type hook struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+// New 根据服务器配置创建 HTTP 服务器
+func New(cfg config.ServerConfig, handler http.Handler) *Server {
+	return &Server{
+		srv: &http.Server{
+			Addr:              ":" + cfg.Port,
+			Handler:           handler,
+			ReadTimeout:       seconds(cfg.ReadTimeout),
+			ReadHeaderTimeout: seconds(cfg.ReadHeaderTimeout),
+			WriteTimeout:      seconds(cfg.WriteTimeout),
+			IdleTimeout:       seconds(cfg.IdleTimeout),
+			MaxHeaderBytes:    cfg.MaxHeaderBytes,
+			ErrorLog:          slog.NewLogLogger(logging.L().Handler(), slog.LevelWarn),
+		},
+		shutdownTimeout: seconds(cfg.ShutdownTimeout),
+		drainDelay:      seconds(cfg.DrainDelay),
+	}
+}
+
+// ConfigureTLS 启用 HTTPS；配置了 RedirectPort 时额外监听该端口，将 HTTP 请求重定向到 HTTPS
+func (s *Server) ConfigureTLS(cfg config.TLSConfig) error {
+	tlsCfg, certs, err := NewTLSConfig(cfg)
+	if err != nil {
+		return err
+	}
+	s.srv.TLSConfig = tlsCfg
+	s.certs = certs
+	s.reloadInterval = seconds(cfg.ReloadInterval)
+
+	if cfg.RedirectPort != "" {
+		if cfg.RedirectHost == "" {
+			return errors.New("配置了重定向端口时必须设置重定向目标主机名（redirect_host）")
+		}
+		_, port, _ := net.SplitHostPort(s.srv.Addr)
+		s.redirect = &http.Server{
+			Addr:              ":" + cfg.RedirectPort,
+			Handler:           RedirectHandler(port, cfg.RedirectHost),
+			ReadTimeout:       s.srv.ReadTimeout,
+			ReadHeaderTimeout: s.srv.ReadHeaderTimeout,
+			WriteTimeout:      s.srv.WriteTimeout,
+			IdleTimeout:       s.srv.IdleTimeout,
+			MaxHeaderBytes:    s.srv.MaxHeaderBytes,
+			ErrorLog:          s.srv.ErrorLog,
+		}
+	}
+	return nil
+}
+
+// OnShutdown 注册停机钩子，钩子在所有请求结束后按注册的逆序执行（先注册的资源最后释放）
+func (s *Server) OnShutdown(name string, fn func(ctx context.Context) error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.hooks = append(s.hooks, hook{name: name, fn: fn})
+}
+
+// Run 监听配置的地址并提供服务，直到 ctx 被取消（收到停止信号）后优雅停机
+func (s *Server) Run(ctx context.Context) error {
+	ln, err := net.Listen("tcp", s.srv.Addr)
+	if err != nil {
+		return errors.Join(err, s.runHooks())
+	}
+	if s.redirect != nil {
+		if s.redirectLn, err = net.Listen("tcp", s.redirect.Addr); err != nil {
+			ln.Close()
+			return errors.Join(err, s.runHooks())
+		}
+	}
+	return s.Serve(ctx, ln)
+}
+
+// Serve 在指定的监听器上提供服务，直到 ctx 被取消后优雅停机
+//
+// 正常停机时返回 nil；服务器异常退出、停机超时或钩子失败时返回对应的错误。
+func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
+	serveErr := make(chan error, 1)
+	if s.srv.TLSConfig != nil {
+		go func() {
+			serveErr <- s.srv.ServeTLS(ln, "", "")
+		}()
+		logging.L().Info("HTTPS 服务器已启动", "addr", ln.Addr().String())
+
+		watchCtx, stopWatch := context.WithCancel(ctx)
+		defer stopWatch()
+		go s.certs.Watch(watchCtx, s.reloadInterval)
+	} else {
+		go func() {
+			serveErr <- s.srv.Serve(ln)
+		}()
+		logging.L().Info("HTTP 服务器已启动", "addr", ln.Addr().String())
+	}
+
+	if s.redirectLn != nil {
+		go func() {
+			// 重定向监听失败不影响 HTTPS 服务
+			if err := s.redirect.Serve(s.redirectLn); !errors.Is(err, http.ErrServerClosed) {
+				logging.L().Error("HTTP 重定向服务器异常退出", "error", err)
+			}
+		}()
+		logging.L().Info("HTTP 重定向服务器已启动", "addr", s.redirectLn.Addr().String())
+	}
+
+	select {
+	case err := <-serveErr:
+		// 未收到停止信号服务器就退出了，仍需释放资源
+		if s.redirect != nil {
+			s.redirect.Close()
+		}
+		return errors.Join(err, s.runHooks())
+	case <-ctx.Done():
+	}
+
+	return errors.Join(s.shutdown(serveErr), s.runHooks())
+}
+
+// shutdown 摘除流量并等待进行中的请求完成
+func (s *Server) shutdown(serveErr <-chan error) error {
+	health.SetReady(false)
+	logging.L().Info("收到停止信号，开始优雅停机", "drain_delay", s.drainDelay.String(), "timeout", s.shutdownTimeout.String())
+
+	if s.drainDelay > 0 {
+		// 摘流期间关闭 keep-alive，促使客户端在新连接上切换到其他实例
+		s.srv.SetKeepAlivesEnabled(false)
+		time.Sleep(s.drainDelay)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
+	defer cancel()
+
+	if s.redirect != nil {
+		s.redirect.Shutdown(ctx)
+	}
+
+	err := s.srv.Shutdown(ctx)
+	if errors.Is(err, context.DeadlineExceeded) {
+		logging.L().Warn("等待进行中的请求超时，强制关闭连接", "timeout", s.shutdownTimeout.String())
+		s.srv.Close()
+	}
+	if serr := <-serveErr; !errors.Is(serr, http.ErrServerClosed) {
+		err = errors.Join(err, serr)
+	}
+
+	if err == nil {
+		logging.L().Info("进行中的请求已全部完成")
+	}
+	return err
+}
+
+// runHooks 按注册的逆序执行停机钩子，单个钩子失败不影响其余钩子
+func (s *Server) runHooks() error {
+	s.mu.Lock()
+	hooks := s.hooks
+	s.hooks = nil
+	s.mu.Unlock()
+
+	var errs []error
+	for i := len(hooks) - 1; i >= 0; i-- {
+		h := hooks[i]
+		ctx, cancel := context.WithTimeout(context.Background(), hookTimeout)
+		if err := h.fn(ctx); err != nil {
+			logging.L().Warn("停机钩子执行失败", "hook", h.name, "error", err)
+			errs = append(errs, err)
+		}
+		cancel()
+	}
+	return errors.Join(errs...)
+}
+
+// seconds 将以秒为单位的配置转换为 time.Duration
+func seconds(n int) time.Duration {
+	return time.Duration(n) * time.Second
+}
@@ -0,0 +1,148 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"iris-cn-sample-project/config"
+	"iris-cn-sample-project/health"
+)
+
+// startServer 在随机端口启动服务器，返回访问地址与 Serve 的结果通道
+func startServer(t *testing.T, s *Server, ctx context.Context) (string, <-chan error) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Serve(ctx, ln)
+	}()
+	t.Cleanup(func() { health.SetReady(true) })
+	return "http://" + ln.Addr().String(), done
+}
+
+// TestGracefulShutdown 验证停机时标记为未就绪、等待进行中的请求完成、拒绝新连接，并按逆序执行钩子
+func TestGracefulShutdown(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		io.WriteString(w, "done")
+	})
+
+	s := New(config.ServerConfig{ShutdownTimeout: 5}, handler)
+	var order []string
+	s.OnShutdown("first", func(ctx context.Context) error {
+		order = append(order, "first")
+		return nil
+	})
+	s.OnShutdown("second", func(ctx context.Context) error {
+		order = append(order, "second")
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	url, done := startServer(t, s, ctx)
+
+	type result struct {
+		body string
+		err  error
+	}
+	inFlight := make(chan result, 1)
+	go func() {
+		resp, err := http.Get(url)
+		if err != nil {
+			inFlight <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		inFlight <- result{body: string(body), err: err}
+	}()
+	<-started
+
+	cancel()
+	deadline := time.Now().Add(2 * time.Second)
+	for health.IsReady() && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if health.IsReady() {
+		t.Fatal("停机期间应标记为未就绪")
+	}
+
+	close(release)
+	if r := <-inFlight; r.err != nil || r.body != "done" {
+		t.Errorf("进行中的请求应正常完成: body=%q err=%v", r.body, r.err)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("正常停机不应返回错误: %v", err)
+	}
+	if strings.Join(order, ",") != "second,first" {
+		t.Errorf("钩子执行顺序 = %v，期望按注册的逆序执行", order)
+	}
+	if _, err := http.Get(url); err == nil {
+		t.Error("停机后不应再接受新连接")
+	}
+}
+
+// TestShutdownTimeout 验证请求超过停机时限时强制关闭，钩子仍会执行且错误被返回
+func TestShutdownTimeout(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	})
+
+	// ShutdownTimeout 为 0 时立即超时
+	s := New(config.ServerConfig{}, handler)
+	hookErr := errors.New("关闭失败")
+	ran := false
+	s.OnShutdown("db", func(ctx context.Context) error {
+		ran = true
+		return hookErr
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	url, done := startServer(t, s, ctx)
+	go http.Get(url)
+	<-started
+	cancel()
+
+	err := <-done
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("停机超时应返回 DeadlineExceeded: %v", err)
+	}
+	if !ran || !errors.Is(err, hookErr) {
+		t.Errorf("超时后仍应执行钩子并返回钩子错误: ran=%v err=%v", ran, err)
+	}
+}
+
+// TestNewTimeouts 验证服务器超时配置的应用
+func TestNewTimeouts(t *testing.T) {
+	s := New(config.ServerConfig{
+		Port:              "9090",
+		ReadTimeout:       30,
+		ReadHeaderTimeout: 5,
+		WriteTimeout:      60,
+		IdleTimeout:       120,
+		MaxHeaderBytes:    8192,
+	}, http.NotFoundHandler())
+
+	srv := s.srv
+	if srv.Addr != ":9090" || srv.ReadTimeout != 30*time.Second || srv.ReadHeaderTimeout != 5*time.Second ||
+		srv.WriteTimeout != time.Minute || srv.IdleTimeout != 2*time.Minute || srv.MaxHeaderBytes != 8192 {
+		t.Errorf("服务器配置未正确应用: %+v", srv)
+	}
+}
@@ -0,0 +1,184 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"iris-cn-sample-project/config"
+	"iris-cn-sample-project/logging"
+)
+
+// tlsVersions 支持配置的最低协议版本
+var tlsVersions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// NewTLSConfig 根据 TLS 配置创建 tls.Config，证书通过返回的 CertReloader 提供以支持热更新
+func NewTLSConfig(cfg config.TLSConfig) (*tls.Config, *CertReloader, error) {
+	reloader, err := NewCertReloader(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tlsCfg := &tls.Config{
+		GetCertificate: reloader.GetCertificate,
+		MinVersion:     tls.VersionTLS12,
+	}
+
+	if cfg.MinVersion != "" {
+		version, ok := tlsVersions[cfg.MinVersion]
+		if !ok {
+			return nil, nil, fmt.Errorf("不支持的 TLS 最低版本: %s（可选 1.2、1.3）", cfg.MinVersion)
+		}
+		tlsCfg.MinVersion = version
+	}
+
+	if len(cfg.CipherSuites) > 0 {
+		suites, err := cipherSuites(cfg.CipherSuites)
+		if err != nil {
+			return nil, nil, err
+		}
+		tlsCfg.CipherSuites = suites
+	}
+
+	if cfg.ClientCAFile != "" {
+		pem, err := os.ReadFile(cfg.ClientCAFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("读取客户端 CA 证书失败: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, nil, fmt.Errorf("客户端 CA 证书中没有有效的 PEM 证书: %s", cfg.ClientCAFile)
+		}
+		tlsCfg.ClientCAs = pool
+
+		switch cfg.ClientAuth {
+		case "", "require":
+			tlsCfg.ClientAuth = tls.RequireAndVerifyClientCert
+		case "optional":
+			tlsCfg.ClientAuth = tls.VerifyClientCertIfGiven
+		default:
+			return nil, nil, fmt.Errorf("不支持的客户端证书要求: %s（可选 require、optional）", cfg.ClientAuth)
+		}
+	}
+
+	return tlsCfg, reloader, nil
+}
+
+// cipherSuites 将加密套件名称转换为 ID，只接受 Go 认为安全的套件
+func cipherSuites(names []string) ([]uint16, error) {
+	known := make(map[string]uint16)
+	for _, suite := range tls.CipherSuites() {
+		known[suite.Name] = suite.ID
+	}
+
+	ids := make([]uint16, 0, len(names))
+	for _, name := range names {
+		id, ok := known[strings.TrimSpace(name)]
+		if !ok {
+			return nil, fmt.Errorf("不支持或不安全的加密套件: %s", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// CertReloader 可热更新的服务器证书
+//
+// 证书文件被替换（包括 Kubernetes Secret 挂载的符号链接切换）后由 Watch 重新加载，新的 TLS 握手立即使用新证书，
+// 已建立的连接不受影响；加载失败时继续使用旧证书。
+type CertReloader struct {
+	certFile string
+	keyFile  string
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	version string
+}
+
+// NewCertReloader 加载证书并创建证书热更新器
+func NewCertReloader(certFile, keyFile string) (*CertReloader, error) {
+	r := &CertReloader{certFile: certFile, keyFile: keyFile}
+	if _, err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetCertificate 返回当前的证书，用作 tls.Config.GetCertificate
+func (r *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.cert, nil
+}
+
+// Watch 每隔 interval 检查证书文件是否变化并重新加载，直到 ctx 被取消
+func (r *CertReloader) Watch(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reloaded, err := r.reload()
+			if err != nil {
+				logging.L().Warn("重新加载 TLS 证书失败，继续使用旧证书", "cert_file", r.certFile, "error", err)
+			} else if reloaded {
+				logging.L().Info("TLS 证书已重新加载", "cert_file", r.certFile)
+			}
+		}
+	}
+}
+
+// reload 证书或私钥文件的修改时间、大小发生变化时重新加载，返回是否加载了新证书
+func (r *CertReloader) reload() (bool, error) {
+	version, err := fileVersion(r.certFile, r.keyFile)
+	if err != nil {
+		return false, err
+	}
+
+	r.mu.RLock()
+	unchanged := r.cert != nil && version == r.version
+	r.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return false, fmt.Errorf("加载 TLS 证书失败: %v", err)
+	}
+
+	r.mu.Lock()
+	r.cert = &cert
+	r.version = version
+	r.mu.Unlock()
+	return true, nil
+}
+
+// fileVersion 以修改时间与大小标识文件的版本
+func fileVersion(files ...string) (string, error) {
+	var b strings.Builder
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return "", fmt.Errorf("读取证书文件失败: %v", err)
+		}
+		fmt.Fprintf(&b, "%s:%d:%d;", file, info.ModTime().UnixNano(), info.Size())
+	}
+	return b.String(), nil
+}
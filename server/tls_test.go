@@ -0,0 +1,270 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"iris-cn-sample-project/config"
+)
+
+// testCert 测试用证书
+type testCert struct {
+	cert     *x509.Certificate
+	key      *ecdsa.PrivateKey
+	certFile string
+	keyFile  string
+}
+
+// newTestCert 生成证书并写入 dir；parent 为空时生成自签名证书
+func newTestCert(t *testing.T, dir, name string, isCA bool, parent *testCert) *testCert {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+	}
+
+	signer, signerKey := template, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+
+	c := &testCert{
+		cert:     cert,
+		key:      key,
+		certFile: filepath.Join(dir, name+".crt"),
+		keyFile:  filepath.Join(dir, name+".key"),
+	}
+	writePEM(t, c.certFile, "CERTIFICATE", der)
+	writePEM(t, c.keyFile, "EC PRIVATE KEY", keyDER)
+	return c
+}
+
+// writePEM 写入 PEM 文件
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// tlsClient 创建信任指定证书、不复用连接的客户端
+func tlsClient(roots []*x509.Certificate, clientCert *testCert) *http.Client {
+	pool := x509.NewCertPool()
+	for _, root := range roots {
+		pool.AddCert(root)
+	}
+	tlsCfg := &tls.Config{RootCAs: pool}
+	if clientCert != nil {
+		pair, _ := tls.LoadX509KeyPair(clientCert.certFile, clientCert.keyFile)
+		tlsCfg.Certificates = []tls.Certificate{pair}
+	}
+	return &http.Client{Transport: &http.Transport{TLSClientConfig: tlsCfg, DisableKeepAlives: true}}
+}
+
+// serveTLS 使用 TLS 配置启动测试服务器
+func serveTLS(t *testing.T, cfg config.TLSConfig) (*Server, string) {
+	t.Helper()
+
+	s := New(config.ServerConfig{}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil {
+			t.Error("请求应通过 TLS 到达")
+		}
+	}))
+	if err := s.ConfigureTLS(cfg); err != nil {
+		t.Fatalf("配置 TLS 失败: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	url, done := startServer(t, s, ctx)
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return s, "https" + url[len("http"):]
+}
+
+// TestTLSCertReload 验证 HTTPS 服务与证书文件替换后的热更新
+func TestTLSCertReload(t *testing.T) {
+	dir := t.TempDir()
+	first := newTestCert(t, dir, "first", false, nil)
+	s, url := serveTLS(t, config.TLSConfig{CertFile: first.certFile, KeyFile: first.keyFile, MinVersion: "1.2"})
+
+	second := newTestCert(t, dir, "second", false, nil)
+	client := tlsClient([]*x509.Certificate{first.cert, second.cert}, nil)
+
+	peerName := func() string {
+		t.Helper()
+		resp, err := client.Get(url)
+		if err != nil {
+			t.Fatalf("HTTPS 请求失败: %v", err)
+		}
+		resp.Body.Close()
+		return resp.TLS.PeerCertificates[0].Subject.CommonName
+	}
+
+	if got := peerName(); got != "first" {
+		t.Fatalf("服务器证书 = %s，期望 first", got)
+	}
+
+	// 替换证书文件（修改时间推后，避免与原文件落在同一时间精度内）
+	for src, dst := range map[string]string{second.certFile: first.certFile, second.keyFile: first.keyFile} {
+		data, _ := os.ReadFile(src)
+		os.WriteFile(dst, data, 0600)
+		later := time.Now().Add(time.Minute)
+		os.Chtimes(dst, later, later)
+	}
+	if reloaded, err := s.certs.reload(); err != nil || !reloaded {
+		t.Fatalf("证书应被重新加载: reloaded=%v err=%v", reloaded, err)
+	}
+	if got := peerName(); got != "second" {
+		t.Errorf("热更新后服务器证书 = %s，期望 second", got)
+	}
+
+	// 文件未变化时不重复加载；加载失败时保留旧证书
+	if reloaded, _ := s.certs.reload(); reloaded {
+		t.Error("证书文件未变化时不应重新加载")
+	}
+	os.WriteFile(first.keyFile, []byte("broken"), 0600)
+	if _, err := s.certs.reload(); err == nil {
+		t.Error("私钥无效时应返回错误")
+	}
+	if got := peerName(); got != "second" {
+		t.Errorf("加载失败后应继续使用旧证书，实际为 %s", got)
+	}
+}
+
+// TestMutualTLS 验证配置客户端 CA 后要求客户端证书
+func TestMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	serverCert := newTestCert(t, dir, "server", false, nil)
+	ca := newTestCert(t, dir, "ca", true, nil)
+	clientCert := newTestCert(t, dir, "client", false, ca)
+	untrusted := newTestCert(t, dir, "untrusted", false, nil)
+
+	_, url := serveTLS(t, config.TLSConfig{
+		CertFile:     serverCert.certFile,
+		KeyFile:      serverCert.keyFile,
+		ClientCAFile: ca.certFile,
+	})
+	roots := []*x509.Certificate{serverCert.cert}
+
+	if resp, err := tlsClient(roots, clientCert).Get(url); err != nil {
+		t.Errorf("携带受信任的客户端证书应成功: %v", err)
+	} else {
+		resp.Body.Close()
+	}
+	for name, cert := range map[string]*testCert{"未提供证书": nil, "不受信任的证书": untrusted} {
+		if resp, err := tlsClient(roots, cert).Get(url); err == nil {
+			resp.Body.Close()
+			t.Errorf("%s时应拒绝连接", name)
+		}
+	}
+}
+
+// TestNewTLSConfigInvalid 验证无效的 TLS 配置
+func TestNewTLSConfigInvalid(t *testing.T) {
+	dir := t.TempDir()
+	cert := newTestCert(t, dir, "server", false, nil)
+	base := config.TLSConfig{CertFile: cert.certFile, KeyFile: cert.keyFile}
+
+	tests := map[string]func(*config.TLSConfig){
+		"证书文件不存在":  func(c *config.TLSConfig) { c.CertFile = filepath.Join(dir, "missing.crt") },
+		"不支持的最低版本": func(c *config.TLSConfig) { c.MinVersion = "1.0" },
+		"不安全的加密套件": func(c *config.TLSConfig) { c.CipherSuites = []string{"TLS_RSA_WITH_RC4_128_SHA"} },
+		"无效的客户端证书要求": func(c *config.TLSConfig) {
+			c.ClientCAFile = cert.certFile
+			c.ClientAuth = "sometimes"
+		},
+	}
+	for name, mutate := range tests {
+		cfg := base
+		mutate(&cfg)
+		if _, _, err := NewTLSConfig(cfg); err == nil {
+			t.Errorf("%s: 应返回错误", name)
+		}
+	}
+
+	cfg := base
+	cfg.MinVersion = "1.3"
+	cfg.CipherSuites = []string{"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256"}
+	tlsCfg, _, err := NewTLSConfig(cfg)
+	if err != nil || tlsCfg.MinVersion != tls.VersionTLS13 || len(tlsCfg.CipherSuites) != 1 {
+		t.Errorf("有效配置应被正确应用: %+v, %v", tlsCfg, err)
+	}
+}
+
+// TestRedirectHandler 验证 HTTP 到 HTTPS 的重定向
+func TestRedirectHandler(t *testing.T) {
+	tests := []struct {
+		name     string
+		port     string
+		host     string
+		method   string
+		target   string
+		code     int
+		location string
+	}{
+		{"默认端口", "443", "example.com", http.MethodGet, "http://example.com/a?b=1", http.StatusMovedPermanently, "https://example.com/a?b=1"},
+		{"附加 HTTPS 端口", "8443", "example.com", http.MethodGet, "http://example.com:8080/a", http.StatusMovedPermanently, "https://example.com:8443/a"},
+		{"POST 使用 308", "443", "example.com", http.MethodPost, "http://example.com/form", http.StatusPermanentRedirect, "https://example.com/form"},
+		{"不使用请求的 Host", "443", "www.example.com", http.MethodGet, "http://evil.test/", http.StatusMovedPermanently, "https://www.example.com/"},
+		{"不使用请求的 Host 与端口", "8443", "www.example.com", http.MethodGet, "http://evil.test:8080/x", http.StatusMovedPermanently, "https://www.example.com:8443/x"},
+		{"IPv6", "8443", "::1", http.MethodGet, "http://[::1]:8080/", http.StatusMovedPermanently, "https://[::1]:8443/"},
+		{"IPv6 默认端口", "443", "::1", http.MethodGet, "http://[::1]/", http.StatusMovedPermanently, "https://[::1]/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			RedirectHandler(tt.port, tt.host).ServeHTTP(rec, httptest.NewRequest(tt.method, tt.target, nil))
+
+			if rec.Code != tt.code || rec.Header().Get("Location") != tt.location {
+				t.Errorf("重定向 = %d %s，期望 %d %s", rec.Code, rec.Header().Get("Location"), tt.code, tt.location)
+			}
+			if rec.Header().Get("Strict-Transport-Security") != "" {
+				t.Error("HTTP 重定向响应不应携带 HSTS")
+			}
+		})
+	}
+
+	// 监听重定向端口时必须配置目标主机名
+	cert := newTestCert(t, t.TempDir(), "redirect", false, nil)
+	s := New(config.ServerConfig{}, http.NotFoundHandler())
+	if err := s.ConfigureTLS(config.TLSConfig{CertFile: cert.certFile, KeyFile: cert.keyFile, RedirectPort: "8080"}); err == nil {
+		t.Error("未配置 redirect_host 时 ConfigureTLS 应失败")
+	}
+}
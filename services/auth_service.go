@@ -1,12 +1,14 @@
 package services
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"time"
 
 	"iris-cn-sample-project/config"
 	"iris-cn-sample-project/models"
+	"iris-cn-sample-project/tracing"
 	"iris-cn-sample-project/utils"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -53,7 +55,10 @@ func GenerateTokenPair(user *models.User) (accessToken, refreshToken string, exp
 }
 
 // ValidateToken 验证令牌并返回用户信息
-func ValidateToken(tokenString string) (*models.User, error) {
+func ValidateToken(ctx context.Context, tokenString string) (_ *models.User, err error) {
+	ctx, span := tracing.Start(ctx, "services.ValidateToken")
+	defer func() { tracing.End(span, err) }()
+
 	// 验证 JWT 令牌
 	claims, err := utils.ValidateJWT(tokenString)
 	if err != nil {
@@ -61,7 +66,7 @@ func ValidateToken(tokenString string) (*models.User, error) {
 	}
 
 	// 根据令牌中的用户ID获取用户信息
-	user, err := GetUserByID(claims.UserID)
+	user, err := GetUserByID(ctx, claims.UserID)
 	if err != nil {
 		return nil, fmt.Errorf("获取用户信息失败: %v", err)
 	}
@@ -84,7 +89,10 @@ func ValidateToken(tokenString string) (*models.User, error) {
 }
 
 // RefreshAccessToken 使用刷新令牌获取新的访问令牌
-func RefreshAccessToken(refreshTokenString string) (string, time.Time, error) {
+func RefreshAccessToken(ctx context.Context, refreshTokenString string) (_ string, _ time.Time, err error) {
+	ctx, span := tracing.Start(ctx, "services.RefreshAccessToken")
+	defer func() { tracing.End(span, err) }()
+
 	// 解析刷新令牌（不验证过期时间）
 	refreshToken, err := jwt.ParseWithClaims(refreshTokenString, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
 		// 验证签名方法
@@ -101,7 +109,7 @@ func RefreshAccessToken(refreshTokenString string) (string, time.Time, error) {
 	// 检查令牌是否有效
 	if claims, ok := refreshToken.Claims.(*JWTClaims); ok && refreshToken.Valid {
 		// 验证用户是否仍然存在且有效
-		user, err := GetUserByID(claims.UserID)
+		user, err := GetUserByID(ctx, claims.UserID)
 		if err != nil {
 			return "", time.Time{}, fmt.Errorf("用户不存在或已被禁用: %v", err)
 		}
@@ -230,9 +238,9 @@ func CreateSession(user *models.User) (map[string]interface{}, error) {
 }
 
 // RefreshSession 刷新用户会话
-func RefreshSession(refreshTokenString string) (map[string]interface{}, error) {
+func RefreshSession(ctx context.Context, refreshTokenString string) (map[string]interface{}, error) {
 	// 使用刷新令牌获取新的访问令牌
-	newAccessToken, expiresAt, err := RefreshAccessToken(refreshTokenString)
+	newAccessToken, expiresAt, err := RefreshAccessToken(ctx, refreshTokenString)
 	if err != nil {
 		return nil, fmt.Errorf("刷新会话失败: %v", err)
 	}
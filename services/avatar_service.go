@@ -0,0 +1,251 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"strconv"
+	"strings"
+
+	"iris-cn-sample-project/avatar"
+	"iris-cn-sample-project/database"
+	"iris-cn-sample-project/logging"
+	"iris-cn-sample-project/metrics"
+	"iris-cn-sample-project/models"
+	"iris-cn-sample-project/requestid"
+	"iris-cn-sample-project/storage"
+	"iris-cn-sample-project/tracing"
+	"iris-cn-sample-project/upload"
+
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+)
+
+// ErrUserNotFound 用户不存在或未激活
+var ErrUserNotFound = errors.New("用户不存在")
+
+// avatarUpdateRetries 并发更新头像时比较并交换的重试次数
+const avatarUpdateRetries = 3
+
+// AvatarImage 头像图片内容
+type AvatarImage struct {
+	Body        io.ReadCloser
+	Size        int64
+	ContentType string
+	ETag        string
+	Version     string // 托管头像的版本ID，默认头像为空
+}
+
+// avatarKey 头像缩略图的对象 key：avatars/<版本ID>/<边长>.<格式>
+func avatarKey(ref string, size int, format string) string {
+	return fmt.Sprintf("%s/%d.%s", ref, size, format)
+}
+
+// SetAvatar 保存用户上传的头像
+//
+// 内容按头像策略识别类型、限制大小并做恶意软件扫描（与普通上传相同，未通过扫描的原图移入隔离区），
+// 然后解码、按 EXIF 方向摆正、居中裁剪，为 avatar.Sizes 中的每个尺寸生成 WebP 与 PNG 写入存储。
+// 原图不保存，重新编码后的图片不包含 EXIF 等元数据。User.Avatar 更新为新的引用后删除旧头像的缩略图。
+func SetAvatar(ctx context.Context, userID uint, r io.Reader, originalName string) (_ *models.User, err error) {
+	ctx, span := tracing.Start(ctx, "services.SetAvatar", attribute.Int64("user.id", int64(userID)))
+	defer func() { tracing.End(span, err) }()
+	defer func() {
+		if reason := upload.Reason(err); reason != "" {
+			metrics.UploadsRejectedTotal.WithLabelValues(reason).Inc()
+		}
+	}()
+
+	store := storage.Default()
+	if store == nil {
+		return nil, fmt.Errorf("存储后端尚未初始化")
+	}
+	user, err := findActiveUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	policy, _ := upload.Lookup(upload.PurposeAvatar)
+	body, inspection, err := policy.Inspect(r, originalName)
+	if err != nil {
+		return nil, err
+	}
+	// 头像大小上限只有几 MB，直接读入内存
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("读取上传文件失败: %w", err)
+	}
+
+	result, err := scanFile(ctx, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if result.Infected {
+		sum := sha256.Sum256(data)
+		file := models.File{
+			OriginalName: SanitizeFilename(originalName),
+			ContentType:  inspection.ContentType,
+			Purpose:      policy.Name,
+			Size:         int64(len(data)),
+			SHA256:       hex.EncodeToString(sum[:]),
+			UserID:       &userID,
+		}
+		return nil, quarantineFile(ctx, bytes.NewReader(data), &file, inspection.Ext, result.Signature)
+	}
+
+	img, err := avatar.Decode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	ref := models.AvatarRefPrefix + requestid.NewUUIDv7()
+	span.SetAttributes(attribute.String("avatar.ref", ref))
+	if err := putAvatarVariants(ctx, store, ref, img); err != nil {
+		deleteAvatarVariants(context.WithoutCancel(ctx), ref)
+		return nil, err
+	}
+
+	old, err := replaceAvatar(ctx, user, ref)
+	if err != nil {
+		deleteAvatarVariants(context.WithoutCancel(ctx), ref)
+		return nil, err
+	}
+	if old != "" {
+		deleteAvatarVariants(context.WithoutCancel(ctx), old)
+	}
+	logging.L().InfoContext(ctx, "用户更新头像", "user_id", userID, "avatar", ref)
+	return user, nil
+}
+
+// DeleteAvatar 删除用户的头像，之后显示默认头像
+func DeleteAvatar(ctx context.Context, userID uint) (err error) {
+	ctx, span := tracing.Start(ctx, "services.DeleteAvatar", attribute.Int64("user.id", int64(userID)))
+	defer func() { tracing.End(span, err) }()
+
+	user, err := findActiveUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	old, err := replaceAvatar(ctx, user, "")
+	if err != nil {
+		return err
+	}
+	if old != "" {
+		deleteAvatarVariants(context.WithoutCancel(ctx), old)
+	}
+	return nil
+}
+
+// OpenAvatar 打开用户头像的指定尺寸与格式；没有上传头像（或缩略图丢失）时返回生成的 identicon
+func OpenAvatar(ctx context.Context, userID uint, size int, format string) (_ *AvatarImage, err error) {
+	ctx, span := tracing.Start(ctx, "services.OpenAvatar", attribute.Int64("user.id", int64(userID)))
+	defer func() { tracing.End(span, err) }()
+
+	user, err := findActiveUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if version := user.AvatarVersion(); version != "" {
+		rc, info, err := storage.Default().Get(ctx, avatarKey(user.Avatar, size, format))
+		if err == nil {
+			return &AvatarImage{
+				Body:        rc,
+				Size:        info.Size,
+				ContentType: avatar.ContentType(format),
+				ETag:        fmt.Sprintf(`"%s-%d.%s"`, version, size, format),
+				Version:     version,
+			}, nil
+		}
+		if !errors.Is(err, storage.ErrNotFound) {
+			return nil, fmt.Errorf("读取头像失败: %v", err)
+		}
+		logging.L().WarnContext(ctx, "头像缩略图不存在，使用默认头像", "user_id", userID, "avatar", user.Avatar)
+	}
+
+	var buf bytes.Buffer
+	if err := avatar.Encode(&buf, avatar.Identicon(strconv.FormatUint(uint64(user.ID), 10), size), format); err != nil {
+		return nil, fmt.Errorf("生成默认头像失败: %v", err)
+	}
+	return &AvatarImage{
+		Body:        io.NopCloser(&buf),
+		Size:        int64(buf.Len()),
+		ContentType: avatar.ContentType(format),
+		ETag:        fmt.Sprintf(`"identicon-%d-%d.%s"`, user.ID, size, format),
+	}, nil
+}
+
+// findActiveUser 查询激活状态的用户
+func findActiveUser(ctx context.Context, userID uint) (*models.User, error) {
+	var user models.User
+	if err := database.GetDB().WithContext(ctx).Where("id = ? AND status = ?", userID, "active").First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("查询用户失败: %v", err)
+	}
+	return &user, nil
+}
+
+// putAvatarVariants 生成并保存各尺寸、各格式的缩略图
+func putAvatarVariants(ctx context.Context, store storage.Storage, ref string, img image.Image) error {
+	for _, size := range avatar.Sizes {
+		thumb := avatar.Thumbnail(img, size)
+		for _, format := range avatar.Formats {
+			var buf bytes.Buffer
+			if err := avatar.Encode(&buf, thumb, format); err != nil {
+				return fmt.Errorf("生成头像失败: %v", err)
+			}
+			key := avatarKey(ref, size, format)
+			if _, err := store.Put(ctx, key, &buf, storage.PutOptions{Size: int64(buf.Len()), ContentType: avatar.ContentType(format)}); err != nil {
+				return fmt.Errorf("保存头像失败: %v", err)
+			}
+		}
+	}
+	return nil
+}
+
+// replaceAvatar 以比较并交换的方式更新 User.Avatar，返回被替换的托管头像引用（原来没有托管头像时为空）
+//
+// 并发修改时只有一个请求能替换某个旧值，保证旧头像的缩略图只被删除一次，新头像的缩略图也不会无人引用。
+func replaceAvatar(ctx context.Context, user *models.User, ref string) (string, error) {
+	db := database.GetDB().WithContext(ctx)
+	for i := 0; i < avatarUpdateRetries; i++ {
+		old := user.Avatar
+		result := db.Model(&models.User{}).Where("id = ? AND avatar = ?", user.ID, old).Update("avatar", ref)
+		if result.Error != nil {
+			return "", fmt.Errorf("更新头像失败: %v", result.Error)
+		}
+		if result.RowsAffected == 1 {
+			user.Avatar = ref
+			if strings.HasPrefix(old, models.AvatarRefPrefix) {
+				return old, nil
+			}
+			return "", nil
+		}
+
+		var current models.User
+		if err := db.Select("avatar").Where("id = ?", user.ID).First(&current).Error; err != nil {
+			return "", fmt.Errorf("更新头像失败: %v", err)
+		}
+		user.Avatar = current.Avatar
+	}
+	return "", errors.New("头像正在被其他请求修改，请稍后重试")
+}
+
+// deleteAvatarVariants 删除头像的全部缩略图，失败时只记录日志
+func deleteAvatarVariants(ctx context.Context, ref string) {
+	store := storage.Default()
+	for _, size := range avatar.Sizes {
+		for _, format := range avatar.Formats {
+			key := avatarKey(ref, size, format)
+			if err := store.Delete(ctx, key); err != nil {
+				logging.L().WarnContext(ctx, "删除头像缩略图失败", "key", key, "error", err)
+			}
+		}
+	}
+}
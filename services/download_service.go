@@ -0,0 +1,97 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"time"
+
+	"iris-cn-sample-project/config"
+	"iris-cn-sample-project/database"
+	"iris-cn-sample-project/models"
+	"iris-cn-sample-project/storage"
+	"iris-cn-sample-project/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+)
+
+// ErrFileNotFound 文件不存在、已删除或已被隔离
+var ErrFileNotFound = errors.New("文件不存在")
+
+// FindFile 查询可以下载的文件（已隔离的文件不能下载）
+func FindFile(ctx context.Context, id uint) (*models.File, error) {
+	var file models.File
+	err := database.GetDB().WithContext(ctx).
+		Where("id = ? AND status = ?", id, models.FileStatusActive).
+		First(&file).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrFileNotFound
+		}
+		return nil, fmt.Errorf("查询文件失败: %v", err)
+	}
+	return &file, nil
+}
+
+// CanAccessFile 判断用户能否访问文件：上传者本人或管理员；匿名上传的文件只有管理员与签名地址可以访问
+func CanAccessFile(file *models.File, userID uint, role string) bool {
+	if role == "admin" {
+		return true
+	}
+	return userID != 0 && file.UserID != nil && *file.UserID == userID
+}
+
+// FileDownloadURL 生成在 expires 内有效的签名下载地址，持有该地址的任何人都可以下载文件
+func FileDownloadURL(file *models.File, expires time.Duration) (string, time.Time) {
+	expiresAt := time.Now().Add(expires)
+	exp, sig := downloadSigner().Sign(downloadSubject(file), expiresAt)
+	query := url.Values{"expires": {exp}, "signature": {sig}}
+	return fmt.Sprintf("/api/files/%d/download?%s", file.ID, query.Encode()), expiresAt
+}
+
+// VerifyFileDownload 校验签名下载地址中的 expires 与 signature
+func VerifyFileDownload(file *models.File, expires, signature string) bool {
+	return downloadSigner().Verify(downloadSubject(file), expires, signature, time.Now())
+}
+
+// OpenFile 打开文件内容，支持定位以响应 Range 请求；对象在存储中不存在时返回 ErrFileNotFound
+func OpenFile(ctx context.Context, file *models.File) (_ io.ReadSeekCloser, err error) {
+	ctx, span := tracing.Start(ctx, "services.OpenFile",
+		attribute.Int64("file.id", int64(file.ID)),
+		attribute.String("storage.key", file.Key),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	store := storage.Default()
+	if store == nil {
+		return nil, fmt.Errorf("存储后端尚未初始化")
+	}
+	body, _, err := storage.Open(ctx, store, file.Key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, ErrFileNotFound
+		}
+		return nil, fmt.Errorf("读取文件失败: %v", err)
+	}
+	return body, nil
+}
+
+// downloadSigner 下载地址的签名器，与存储后端使用相同的密钥（未配置时由 JWT 密钥派生）
+func downloadSigner() *storage.Signer {
+	cfg := config.GetConfig()
+	key := cfg.Storage.SigningKey
+	if key == "" {
+		key = cfg.JWT.Secret
+	}
+	return storage.NewSigner(key)
+}
+
+// downloadSubject 签名的内容：绑定对象 key 而不只是文件ID，文件删除后ID被复用时旧地址也不会指向新文件
+//
+// 对象 key 中不会出现冒号，与存储后端自身签名的 key 不会混淆。
+func downloadSubject(file *models.File) string {
+	return "download:" + file.Key
+}
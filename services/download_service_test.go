@@ -0,0 +1,68 @@
+package services
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"iris-cn-sample-project/models"
+)
+
+// signedQuery 解析签名下载地址中的 expires 与 signature
+func signedQuery(t *testing.T, file *models.File, expires time.Duration) (string, string) {
+	t.Helper()
+	link, _ := FileDownloadURL(file, expires)
+	u, err := url.Parse(link)
+	if err != nil {
+		t.Fatalf("解析下载地址失败: %v", err)
+	}
+	return u.Query().Get("expires"), u.Query().Get("signature")
+}
+
+func TestVerifyFileDownload(t *testing.T) {
+	setupTestEnv(t)
+
+	file := &models.File{ID: 7, Key: "2024/05/01/a.png"}
+	exp, sig := signedQuery(t, file, time.Minute)
+	if !VerifyFileDownload(file, exp, sig) {
+		t.Fatal("有效的签名地址校验失败")
+	}
+
+	tampered := []byte(sig)
+	tampered[0] ^= 1
+	expired, expiredSig := signedQuery(t, file, -time.Second)
+	tests := []struct {
+		name      string
+		file      *models.File
+		expires   string
+		signature string
+	}{
+		{"签名被篡改", file, exp, string(tampered)},
+		{"有效期被修改", file, exp + "0", sig},
+		{"已过期", file, expired, expiredSig},
+		{"缺少签名", file, exp, ""},
+		// 文件删除后ID被复用：新文件的 key 不同，旧地址不能下载新文件
+		{"ID 相同的其他文件", &models.File{ID: 7, Key: "2024/05/02/b.png"}, exp, sig},
+	}
+	for _, tt := range tests {
+		if VerifyFileDownload(tt.file, tt.expires, tt.signature) {
+			t.Errorf("%s: 不应通过校验", tt.name)
+		}
+	}
+
+	// 存储后端对同一 key 签发的地址（如本地存储的静态文件地址）不能用于下载接口
+	storageExp, storageSig := downloadSigner().Sign(file.Key, time.Now().Add(time.Minute))
+	if VerifyFileDownload(file, storageExp, storageSig) {
+		t.Error("存储后端的签名不应通过下载地址校验")
+	}
+}
+
+func TestDownloadSubject(t *testing.T) {
+	a := downloadSubject(&models.File{ID: 1, Key: "2024/05/01/a.png"})
+	if a != "download:2024/05/01/a.png" {
+		t.Errorf("downloadSubject = %q", a)
+	}
+	if b := downloadSubject(&models.File{ID: 1, Key: "2024/05/01/b.png"}); a == b {
+		t.Error("不同 key 的签名内容应不同")
+	}
+}
@@ -0,0 +1,274 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"iris-cn-sample-project/config"
+	"iris-cn-sample-project/database"
+	"iris-cn-sample-project/logging"
+	"iris-cn-sample-project/metrics"
+	"iris-cn-sample-project/models"
+	"iris-cn-sample-project/pagination"
+	"iris-cn-sample-project/storage"
+	"iris-cn-sample-project/tracing"
+	"iris-cn-sample-project/upload"
+
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+)
+
+// maxOriginalNameLength 保存的原始文件名最大长度（字节）
+const maxOriginalNameLength = 255
+
+// SaveFile 校验、扫描并保存上传的文件，记录元数据
+//
+// 文件类型按内容识别并按用途策略校验、读取时即时限制大小（见 upload.Policy.Inspect），
+// 内容先写入临时文件并计算 SHA-256，再交给恶意软件扫描器：未通过扫描的文件移入隔离区、记录为 quarantined
+// 并返回 upload.ErrInfected；通过扫描后写入存储后端。对象 key 由服务端生成，客户端文件名只作为元数据保存；
+// 元数据写入失败时删除已上传的对象，避免存储中留下无主文件。文件计入上传者的存储配额（userID 为 0 时为匿名上传，
+// 按 clientIP 计入匿名上传的配额），超出时返回 upload.ErrQuotaExceeded。
+func SaveFile(ctx context.Context, r io.Reader, originalName string, policy *upload.Policy, userID uint, clientIP string) (*models.File, error) {
+	return saveFile(ctx, r, originalName, policy, userID, clientIP, Usage{})
+}
+
+// saveFile 保存文件，reserved 为配额中已为该文件预留的用量（见 checkQuota）
+func saveFile(ctx context.Context, r io.Reader, originalName string, policy *upload.Policy, userID uint, clientIP string, reserved Usage) (_ *models.File, err error) {
+	ctx, span := tracing.Start(ctx, "services.SaveFile")
+	defer func() { tracing.End(span, err) }()
+	defer func() {
+		if reason := upload.Reason(err); reason != "" {
+			metrics.UploadsRejectedTotal.WithLabelValues(reason).Inc()
+		}
+	}()
+
+	store := storage.Default()
+	if store == nil {
+		return nil, fmt.Errorf("存储后端尚未初始化")
+	}
+
+	body, inspection, err := policy.Inspect(r, originalName)
+	if err != nil {
+		return nil, err
+	}
+
+	// 先写入临时文件：扫描需要完整的内容，写入存储后端时也能给出确定的大小
+	tmp, err := os.CreateTemp("", "upload-*")
+	if err != nil {
+		return nil, fmt.Errorf("创建临时文件失败: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hash), body)
+	if err != nil {
+		return nil, fmt.Errorf("读取上传文件失败: %w", err)
+	}
+
+	file := models.File{
+		Storage:      config.GetConfig().Storage.Driver,
+		OriginalName: SanitizeFilename(originalName),
+		ContentType:  inspection.ContentType,
+		Purpose:      policy.Name,
+		Status:       models.FileStatusActive,
+		Size:         size,
+		SHA256:       hex.EncodeToString(hash.Sum(nil)),
+	}
+	if userID != 0 {
+		file.UserID = &userID
+	} else {
+		file.UploaderIP = clientIP
+	}
+	span.SetAttributes(
+		attribute.String("file.purpose", file.Purpose),
+		attribute.String("file.content_type", file.ContentType),
+		attribute.Int64("file.size", size),
+	)
+
+	if err := checkQuota(ctx, userID, clientIP, size, reserved); err != nil {
+		return nil, err
+	}
+
+	result, err := scanFile(ctx, tmp)
+	if err != nil {
+		return nil, err
+	}
+	if result.Infected {
+		return nil, quarantineFile(ctx, tmp, &file, inspection.Ext, result.Signature)
+	}
+
+	file.Key = storage.NewKey(inspection.Ext)
+	span.SetAttributes(attribute.String("storage.key", file.Key))
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	if _, err := store.Put(ctx, file.Key, tmp, storage.PutOptions{Size: size, ContentType: file.ContentType}); err != nil {
+		return nil, fmt.Errorf("保存文件失败: %v", err)
+	}
+
+	if err := database.GetDB().WithContext(ctx).Create(&file).Error; err != nil {
+		if derr := store.Delete(context.WithoutCancel(ctx), file.Key); derr != nil {
+			logging.L().ErrorContext(ctx, "删除无主文件失败", "key", file.Key, "error", derr)
+		}
+		return nil, fmt.Errorf("保存文件信息失败: %v", err)
+	}
+	return &file, nil
+}
+
+// ListFiles 分页查询文件（按上传时间倒序），userID 为 0 时查询所有用户的文件；不包含已隔离的文件
+func ListFiles(ctx context.Context, userID uint, page, pageSize int) (_ []models.File, _ int64, err error) {
+	ctx, span := tracing.Start(ctx, "services.ListFiles")
+	defer func() { tracing.End(span, err) }()
+
+	query := database.GetDB().WithContext(ctx).Model(&models.File{}).
+		Where("status IN ?", []string{models.FileStatusActive, models.FileStatusMissing})
+	if userID != 0 {
+		query = query.Where("user_id = ?", userID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("获取文件总数失败: %v", err)
+	}
+	var files []models.File
+	if err := query.Order("created_at DESC, id DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&files).Error; err != nil {
+		return nil, 0, fmt.Errorf("获取文件列表失败: %v", err)
+	}
+	return files, total, nil
+}
+
+// ListFilesByCursor 游标分页查询文件（按上传时间倒序），返回下一页与上一页的游标（没有时为 nil）
+func ListFilesByCursor(ctx context.Context, userID uint, cur *pagination.Cursor, limit int) (_ []models.File, next, prev *pagination.Cursor, err error) {
+	ctx, span := tracing.Start(ctx, "services.ListFilesByCursor", attribute.Bool("page.cursor", cur != nil))
+	defer func() { tracing.End(span, err) }()
+
+	query := database.GetDB().WithContext(ctx).Model(&models.File{}).
+		Where("status IN ?", []string{models.FileStatusActive, models.FileStatusMissing})
+	if userID != 0 {
+		query = query.Where("user_id = ?", userID)
+	}
+
+	var files []models.File
+	if err := pagination.Keyset(query, "files", cur, true, limit).Find(&files).Error; err != nil {
+		return nil, nil, nil, fmt.Errorf("获取文件列表失败: %v", err)
+	}
+	files, next, prev = pagination.Trim(files, cur, limit, func(f models.File) (time.Time, uint) {
+		return f.CreatedAt, f.ID
+	})
+	return files, next, prev, nil
+}
+
+// DeleteFile 删除文件：上传者本人或管理员；先删除记录再删除对象，对象删除失败时由核对任务清理
+func DeleteFile(ctx context.Context, id, userID uint, role string) (err error) {
+	ctx, span := tracing.Start(ctx, "services.DeleteFile", attribute.Int64("file.id", int64(id)))
+	defer func() { tracing.End(span, err) }()
+
+	db := database.GetDB().WithContext(ctx)
+	var file models.File
+	if err := db.Where("id = ? AND status IN ?", id, []string{models.FileStatusActive, models.FileStatusMissing}).
+		First(&file).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrFileNotFound
+		}
+		return fmt.Errorf("查询文件失败: %v", err)
+	}
+	// 不暴露其他用户的文件是否存在
+	if !CanAccessFile(&file, userID, role) {
+		return ErrFileNotFound
+	}
+
+	if err := db.Delete(&file).Error; err != nil {
+		return fmt.Errorf("删除文件失败: %v", err)
+	}
+	if err := storage.Default().Delete(context.WithoutCancel(ctx), file.Key); err != nil {
+		logging.L().WarnContext(ctx, "删除文件对象失败，等待核对任务清理", "key", file.Key, "error", err)
+	}
+	logging.L().InfoContext(ctx, "删除文件", "file_id", file.ID, "user_id", userID, "key", file.Key)
+	return nil
+}
+
+// scanFile 从头扫描内容；扫描服务不可用时按配置放行或返回 upload.ErrScanUnavailable
+func scanFile(ctx context.Context, f io.ReadSeeker) (upload.ScanResult, error) {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return upload.ScanResult{}, err
+	}
+
+	scanner, failOpen := upload.DefaultScanner()
+	result, err := scanner.Scan(ctx, f)
+	if err != nil {
+		if failOpen {
+			logging.L().WarnContext(ctx, "文件安全扫描失败，按配置放行", "error", err)
+			return upload.ScanResult{}, nil
+		}
+		logging.L().ErrorContext(ctx, "文件安全扫描失败", "error", err)
+		return upload.ScanResult{}, fmt.Errorf("%w: %v", upload.ErrScanUnavailable, err)
+	}
+	return result, nil
+}
+
+// quarantineFile 将未通过扫描的文件移入隔离区并记录，总是返回 upload.ErrInfected
+//
+// 隔离失败只记录日志，不影响拒绝上传。
+func quarantineFile(ctx context.Context, f io.ReadSeeker, file *models.File, ext, signature string) error {
+	rejected := fmt.Errorf("%w: %s", upload.ErrInfected, signature)
+
+	file.Key = storage.NewKey(ext)
+	file.Storage = upload.QuarantineStorage
+	file.Status = models.FileStatusQuarantined
+	file.ScanResult = signature
+	logging.L().WarnContext(ctx, "上传的文件未通过安全扫描，已隔离",
+		"signature", signature, "key", file.Key, "sha256", file.SHA256, "original_name", file.OriginalName)
+
+	q := upload.Quarantine()
+	if q == nil {
+		logging.L().ErrorContext(ctx, "隔离区尚未初始化，未保存被拒绝的文件", "sha256", file.SHA256)
+		return rejected
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		logging.L().ErrorContext(ctx, "隔离文件失败", "error", err)
+		return rejected
+	}
+	if _, err := q.Put(ctx, file.Key, f, storage.PutOptions{Size: file.Size, ContentType: file.ContentType}); err != nil {
+		logging.L().ErrorContext(ctx, "隔离文件失败", "error", err)
+		return rejected
+	}
+	if err := database.GetDB().WithContext(ctx).Create(file).Error; err != nil {
+		logging.L().ErrorContext(ctx, "记录隔离文件失败", "key", file.Key, "error", err)
+	}
+	return rejected
+}
+
+// SanitizeFilename 清理客户端提供的文件名：去掉目录部分与控制字符，并限制长度
+func SanitizeFilename(name string) string {
+	name = strings.ReplaceAll(name, `\`, "/")
+	name = path.Base(name)
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || r == utf8.RuneError {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+	if name == "." || name == "/" || name == ".." {
+		name = ""
+	}
+	for len(name) > maxOriginalNameLength {
+		_, n := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-n]
+	}
+	return name
+}
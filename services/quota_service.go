@@ -0,0 +1,152 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"iris-cn-sample-project/config"
+	"iris-cn-sample-project/database"
+	"iris-cn-sample-project/models"
+	"iris-cn-sample-project/upload"
+
+	"gorm.io/gorm"
+)
+
+// Quota 存储配额，0 表示不限制
+type Quota struct {
+	MaxBytes int64 `json:"max_bytes"`
+	MaxFiles int64 `json:"max_files"`
+}
+
+// Usage 存储用量
+type Usage struct {
+	Bytes int64 `json:"bytes"`
+	Files int64 `json:"files"`
+}
+
+// StorageUsage 统计用户占用的存储空间与文件数：已保存的文件加上未完成的上传（按声明的长度预留）
+//
+// 头像由服务端生成且大小固定，不计入用量。
+func StorageUsage(ctx context.Context, userID uint) (Usage, error) {
+	db := database.GetDB().WithContext(ctx)
+
+	var files, pending Usage
+	if err := db.Model(&models.File{}).
+		Where("user_id = ? AND status = ?", userID, models.FileStatusActive).
+		Select("COALESCE(SUM(size), 0) AS bytes, COUNT(*) AS files").Scan(&files).Error; err != nil {
+		return Usage{}, fmt.Errorf("统计存储空间失败: %v", err)
+	}
+	if err := db.Model(&models.Upload{}).
+		Where("user_id = ? AND file_id IS NULL AND expires_at > ?", userID, time.Now()).
+		Select("COALESCE(SUM(upload_length), 0) AS bytes, COUNT(*) AS files").Scan(&pending).Error; err != nil {
+		return Usage{}, fmt.Errorf("统计存储空间失败: %v", err)
+	}
+	return Usage{Bytes: files.Bytes + pending.Bytes, Files: files.Files + pending.Files}, nil
+}
+
+// anonymousUsage 统计同一客户端IP匿名上传的文件占用的存储空间与文件数
+func anonymousUsage(ctx context.Context, clientIP string) (Usage, error) {
+	var usage Usage
+	if err := database.GetDB().WithContext(ctx).Model(&models.File{}).
+		Where("user_id IS NULL AND uploader_ip = ? AND status = ?", clientIP, models.FileStatusActive).
+		Select("COALESCE(SUM(size), 0) AS bytes, COUNT(*) AS files").Scan(&usage).Error; err != nil {
+		return Usage{}, fmt.Errorf("统计存储空间失败: %v", err)
+	}
+	return usage, nil
+}
+
+// AnonymousQuota 同一客户端IP匿名上传的存储配额
+func AnonymousQuota() Quota {
+	cfg := config.GetConfig().Upload
+	return Quota{MaxBytes: int64(cfg.AnonymousQuotaMB) << 20, MaxFiles: int64(cfg.AnonymousMaxFiles)}
+}
+
+// UserQuota 获取用户的存储配额
+func UserQuota(ctx context.Context, userID uint) (Quota, error) {
+	var user models.User
+	if err := database.GetDB().WithContext(ctx).Select("id", "role", "quota_bytes", "quota_files").
+		First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return Quota{}, ErrUserNotFound
+		}
+		return Quota{}, fmt.Errorf("查询用户配额失败: %v", err)
+	}
+	return QuotaFor(&user), nil
+}
+
+// QuotaFor 计算用户的存储配额：用户上单独设置的配额优先，其次是 upload.role_quotas 中该角色的配额，最后是全局配置
+func QuotaFor(user *models.User) Quota {
+	cfg := config.GetConfig().Upload
+	quota := Quota{MaxBytes: int64(cfg.UserQuotaMB) << 20, MaxFiles: int64(cfg.UserMaxFiles)}
+	if role, ok := cfg.RoleQuotas[user.Role]; ok {
+		quota = Quota{MaxBytes: int64(role.QuotaMB) << 20, MaxFiles: int64(role.MaxFiles)}
+	}
+	if user.QuotaBytes != nil {
+		quota.MaxBytes = *user.QuotaBytes
+	}
+	if user.QuotaFiles != nil {
+		quota.MaxFiles = *user.QuotaFiles
+	}
+	return quota
+}
+
+// SetUserQuota 设置用户的存储配额，参数为 nil 时恢复为按角色或全局配置，0 表示不限制
+func SetUserQuota(ctx context.Context, userID uint, maxBytes, maxFiles *int64) (*models.User, error) {
+	db := database.GetDB().WithContext(ctx)
+	var user models.User
+	if err := db.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("查询用户失败: %v", err)
+	}
+	if err := db.Model(&user).Select("quota_bytes", "quota_files").
+		Updates(models.User{QuotaBytes: maxBytes, QuotaFiles: maxFiles}).Error; err != nil {
+		return nil, fmt.Errorf("更新存储配额失败: %v", err)
+	}
+	user.QuotaBytes, user.QuotaFiles = maxBytes, maxFiles
+	return &user, nil
+}
+
+// checkQuota 检查用户再保存一个 size 字节的文件后是否超出配额，超出时返回 upload.ErrQuotaExceeded
+//
+// userID 为 0 时是匿名上传，按 clientIP 统计用量并使用 AnonymousQuota。
+// reserved 为已计入用量、将被这个文件取代的预留（完成可续传上传时为该上传声明的长度）。
+// 并发上传时检查与写入之间没有加锁，可能短暂超出配额少许，可以接受。
+func checkQuota(ctx context.Context, userID uint, clientIP string, size int64, reserved Usage) error {
+	quota, hint := AnonymousQuota(), "请登录后上传"
+	if userID != 0 {
+		var err error
+		if quota, err = UserQuota(ctx, userID); err != nil {
+			return err
+		}
+		hint = "请删除不需要的文件后重试"
+	}
+	if quota.MaxBytes == 0 && quota.MaxFiles == 0 {
+		return nil
+	}
+
+	var used Usage
+	var err error
+	if userID != 0 {
+		used, err = StorageUsage(ctx, userID)
+	} else {
+		used, err = anonymousUsage(ctx, clientIP)
+	}
+	if err != nil {
+		return err
+	}
+	used.Bytes -= reserved.Bytes
+	used.Files -= reserved.Files
+
+	if quota.MaxFiles > 0 && used.Files+1 > quota.MaxFiles {
+		return fmt.Errorf("%w: 文件数已达上限 %d 个，%s", upload.ErrQuotaExceeded, quota.MaxFiles, hint)
+	}
+	if quota.MaxBytes > 0 && used.Bytes+size > quota.MaxBytes {
+		return fmt.Errorf("%w: 已使用 %s，本次上传 %s，配额 %s，%s",
+			upload.ErrQuotaExceeded, upload.FormatSize(used.Bytes), upload.FormatSize(size), upload.FormatSize(quota.MaxBytes), hint)
+	}
+	return nil
+}
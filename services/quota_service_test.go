@@ -0,0 +1,114 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"iris-cn-sample-project/config"
+	"iris-cn-sample-project/database"
+	"iris-cn-sample-project/models"
+	"iris-cn-sample-project/upload"
+)
+
+func TestQuotaFor(t *testing.T) {
+	cfg := config.Defaults()
+	cfg.Upload.UserQuotaMB = 100
+	cfg.Upload.UserMaxFiles = 10
+	cfg.Upload.RoleQuotas = map[string]config.QuotaConfig{"vip": {QuotaMB: 1024}}
+	previous := config.GetConfig()
+	config.SetConfig(cfg)
+	t.Cleanup(func() { config.SetConfig(previous) })
+
+	bytes, files, unlimited := int64(5<<20), int64(3), int64(0)
+	tests := []struct {
+		name string
+		user models.User
+		want Quota
+	}{
+		{"全局配置", models.User{Role: "user"}, Quota{MaxBytes: 100 << 20, MaxFiles: 10}},
+		{"角色配置", models.User{Role: "vip"}, Quota{MaxBytes: 1024 << 20}},
+		{"用户单独设置", models.User{Role: "vip", QuotaBytes: &bytes, QuotaFiles: &files}, Quota{MaxBytes: 5 << 20, MaxFiles: 3}},
+		{"用户单独设置为不限制", models.User{Role: "user", QuotaBytes: &unlimited}, Quota{MaxFiles: 10}},
+	}
+	for _, tt := range tests {
+		if got := QuotaFor(&tt.user); got != tt.want {
+			t.Errorf("%s: QuotaFor = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCheckQuota(t *testing.T) {
+	setupTestEnv(t)
+	ctx := context.Background()
+
+	maxBytes, maxFiles := int64(1000), int64(2)
+	if _, err := SetUserQuota(ctx, testUserID, &maxBytes, &maxFiles); err != nil {
+		t.Fatalf("SetUserQuota 失败: %v", err)
+	}
+	userID := uint(testUserID)
+	if err := database.GetDB().Create(&models.File{Key: "2024/05/01/a.txt", Storage: "local", Size: 300, UserID: &userID}).Error; err != nil {
+		t.Fatalf("创建文件记录失败: %v", err)
+	}
+	// 未完成的上传按声明的长度预留
+	u, err := CreateUpload(ctx, testUserID, 600, "b.txt", "")
+	if err != nil {
+		t.Fatalf("CreateUpload 失败: %v", err)
+	}
+
+	if err := checkQuota(ctx, testUserID, "", 100, Usage{}); !errors.Is(err, upload.ErrQuotaExceeded) {
+		t.Errorf("文件数已达上限: err = %v, want ErrQuotaExceeded", err)
+	}
+	// 完成该上传时，它的预留被这个文件取代
+	if err := checkQuota(ctx, testUserID, "", u.Length, Usage{Bytes: u.Length, Files: 1}); err != nil {
+		t.Errorf("扣除预留后未超出配额: %v", err)
+	}
+	if err := checkQuota(ctx, testUserID, "", u.Length+101, Usage{Bytes: u.Length, Files: 1}); !errors.Is(err, upload.ErrQuotaExceeded) {
+		t.Errorf("扣除预留后超出字节配额: err = %v, want ErrQuotaExceeded", err)
+	}
+	if _, err := CreateUpload(ctx, testUserID, 50, "c.txt", ""); !errors.Is(err, upload.ErrQuotaExceeded) {
+		t.Errorf("创建上传时应检查配额: err = %v", err)
+	}
+
+}
+
+func TestCheckAnonymousQuota(t *testing.T) {
+	cfg := setupTestEnv(t)
+	cfg.Upload.AnonymousQuotaMB = 1
+	cfg.Upload.AnonymousMaxFiles = 2
+	ctx := context.Background()
+
+	// 同一IP已匿名上传了 1 个文件；登录用户的文件与其他IP的文件不计入
+	userID := uint(testUserID)
+	for _, f := range []models.File{
+		{Key: "2024/05/01/a.txt", Size: 600 << 10, UploaderIP: "203.0.113.7"},
+		{Key: "2024/05/01/b.txt", Size: 900 << 10, UploaderIP: "198.51.100.1"},
+		{Key: "2024/05/01/c.txt", Size: 900 << 10, UserID: &userID},
+	} {
+		f.Storage, f.Status = "local", models.FileStatusActive
+		if err := database.GetDB().Create(&f).Error; err != nil {
+			t.Fatalf("创建文件记录失败: %v", err)
+		}
+	}
+
+	if err := checkQuota(ctx, 0, "203.0.113.7", 400<<10, Usage{}); err != nil {
+		t.Errorf("未超出匿名配额: %v", err)
+	}
+	if err := checkQuota(ctx, 0, "203.0.113.7", 500<<10, Usage{}); !errors.Is(err, upload.ErrQuotaExceeded) {
+		t.Errorf("超出匿名字节配额: err = %v, want ErrQuotaExceeded", err)
+	}
+	if err := checkQuota(ctx, 0, "192.0.2.1", 1<<20, Usage{}); err != nil {
+		t.Errorf("其他IP不受影响: %v", err)
+	}
+	if err := database.GetDB().Create(&models.File{Key: "2024/05/01/d.txt", Storage: "local", Size: 1, UploaderIP: "203.0.113.7"}).Error; err != nil {
+		t.Fatalf("创建文件记录失败: %v", err)
+	}
+	if err := checkQuota(ctx, 0, "203.0.113.7", 1, Usage{}); !errors.Is(err, upload.ErrQuotaExceeded) {
+		t.Errorf("超出匿名文件数配额: err = %v, want ErrQuotaExceeded", err)
+	}
+
+	cfg.Upload.AnonymousQuotaMB, cfg.Upload.AnonymousMaxFiles = 0, 0
+	if err := checkQuota(ctx, 0, "203.0.113.7", 1<<40, Usage{}); err != nil {
+		t.Errorf("匿名配额为 0 时不限制: %v", err)
+	}
+}
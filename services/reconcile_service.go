@@ -0,0 +1,164 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"iris-cn-sample-project/config"
+	"iris-cn-sample-project/database"
+	"iris-cn-sample-project/logging"
+	"iris-cn-sample-project/metrics"
+	"iris-cn-sample-project/models"
+	"iris-cn-sample-project/storage"
+	"iris-cn-sample-project/tracing"
+)
+
+// orphanGracePeriod 新写入的对象在这段时间内不视为无主：保存文件与头像时先写入对象、再写入数据库
+const orphanGracePeriod = time.Hour
+
+// ReconcileResult 存储核对结果
+type ReconcileResult struct {
+	Objects  int `json:"objects"`  // 存储中的对象数
+	Orphans  int `json:"orphans"`  // 删除的无主对象数
+	Missing  int `json:"missing"`  // 对象丢失、标记为 missing 的文件数
+	Restored int `json:"restored"` // 对象重新出现、恢复为 active 的文件数
+}
+
+// ReconcileStorage 核对存储后端与数据库
+//
+// 存储中没有被 files 表、用户头像或未完成的可续传上传引用的对象视为无主对象并删除（写入不到 orphanGracePeriod 的除外）；
+// files 表中对象已丢失的文件标记为 missing，不再计入配额，对象重新出现时恢复为 active。
+func ReconcileStorage(ctx context.Context) (_ ReconcileResult, err error) {
+	ctx, span := tracing.Start(ctx, "services.ReconcileStorage")
+	defer func() { tracing.End(span, err) }()
+
+	var result ReconcileResult
+	store := storage.Default()
+	lister, ok := store.(storage.Lister)
+	if !ok {
+		return result, errors.New("存储后端不支持列出对象")
+	}
+	driver := config.GetConfig().Storage.Driver
+	db := database.GetDB().WithContext(ctx)
+
+	// 先读取引用，再列出对象：之后新写入的对象都在宽限期内，不会被误删
+	var files []models.File
+	if err := db.Select("id", "key", "status").
+		Where("storage = ? AND status IN ?", driver, []string{models.FileStatusActive, models.FileStatusMissing}).
+		Find(&files).Error; err != nil {
+		return result, fmt.Errorf("查询文件失败: %v", err)
+	}
+	fileKeys := make(map[string]bool, len(files))
+	for _, f := range files {
+		fileKeys[f.Key] = true
+	}
+
+	var avatars []string
+	if err := db.Model(&models.User{}).Where("avatar LIKE ?", models.AvatarRefPrefix+"%").
+		Pluck("avatar", &avatars).Error; err != nil {
+		return result, fmt.Errorf("查询头像失败: %v", err)
+	}
+	avatarRefs := make(map[string]bool, len(avatars))
+	for _, ref := range avatars {
+		avatarRefs[ref] = true
+	}
+
+	var uploadIDs []string
+	if err := db.Model(&models.Upload{}).Pluck("id", &uploadIDs).Error; err != nil {
+		return result, fmt.Errorf("查询可续传上传失败: %v", err)
+	}
+	uploads := make(map[string]bool, len(uploadIDs))
+	for _, id := range uploadIDs {
+		uploads[id] = true
+	}
+
+	seen := make(map[string]bool, len(files))
+	cutoff := time.Now().Add(-orphanGracePeriod)
+	err = lister.List(ctx, "", func(obj storage.ObjectInfo) error {
+		result.Objects++
+		seen[obj.Key] = true
+		if obj.LastModified.After(cutoff) || objectReferenced(obj.Key, fileKeys, avatarRefs, uploads) {
+			return nil
+		}
+		if err := store.Delete(ctx, obj.Key); err != nil {
+			logging.L().WarnContext(ctx, "删除无主对象失败", "key", obj.Key, "error", err)
+			return nil
+		}
+		logging.L().InfoContext(ctx, "删除无主对象", "key", obj.Key, "size", obj.Size)
+		metrics.StorageOrphansDeletedTotal.Inc()
+		result.Orphans++
+		return nil
+	})
+	if err != nil {
+		return result, fmt.Errorf("列出存储对象失败: %v", err)
+	}
+
+	var missing, restored []uint
+	for _, f := range files {
+		switch {
+		case f.Status == models.FileStatusActive && !seen[f.Key]:
+			missing = append(missing, f.ID)
+		case f.Status == models.FileStatusMissing && seen[f.Key]:
+			restored = append(restored, f.ID)
+		}
+	}
+	if len(missing) > 0 {
+		logging.L().WarnContext(ctx, "文件对象已丢失", "file_ids", missing)
+		if err := db.Model(&models.File{}).Where("id IN ? AND status = ?", missing, models.FileStatusActive).
+			Update("status", models.FileStatusMissing).Error; err != nil {
+			return result, fmt.Errorf("更新文件状态失败: %v", err)
+		}
+	}
+	if len(restored) > 0 {
+		if err := db.Model(&models.File{}).Where("id IN ? AND status = ?", restored, models.FileStatusMissing).
+			Update("status", models.FileStatusActive).Error; err != nil {
+			return result, fmt.Errorf("更新文件状态失败: %v", err)
+		}
+	}
+	result.Missing, result.Restored = len(missing), len(restored)
+	return result, nil
+}
+
+// objectReferenced 判断对象是否仍被引用：files 表中的文件、用户当前头像的缩略图（avatars/<版本ID>/...）
+// 或未完成的可续传上传的分段（tus/<上传ID>/...）
+func objectReferenced(key string, fileKeys, avatarRefs, uploads map[string]bool) bool {
+	if fileKeys[key] {
+		return true
+	}
+	parts := strings.SplitN(key, "/", 3)
+	if len(parts) < 3 {
+		return false
+	}
+	switch parts[0] + "/" {
+	case models.AvatarRefPrefix:
+		return avatarRefs[parts[0]+"/"+parts[1]]
+	case tusPartPrefix:
+		return uploads[parts[1]]
+	}
+	return false
+}
+
+// RunStorageReconcile 定期核对存储后端，直到 ctx 被取消
+func RunStorageReconcile(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		result, err := ReconcileStorage(ctx)
+		if err != nil {
+			logging.L().Error("核对存储失败", "error", err)
+			continue
+		}
+		logging.L().Info("存储核对完成", "objects", result.Objects, "orphans", result.Orphans,
+			"missing", result.Missing, "restored", result.Restored)
+	}
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"iris-cn-sample-project/database"
+	"iris-cn-sample-project/models"
+	"iris-cn-sample-project/storage"
+)
+
+// putTestObject 写入对象并把修改时间设为 age 之前
+func putTestObject(t *testing.T, ctx context.Context, root, key string, age time.Duration) {
+	t.Helper()
+	if _, err := storage.Default().Put(ctx, key, strings.NewReader("content"), storage.PutOptions{Size: -1}); err != nil {
+		t.Fatalf("写入对象 %s 失败: %v", key, err)
+	}
+	modified := time.Now().Add(-age)
+	if err := os.Chtimes(filepath.Join(root, filepath.FromSlash(key)), modified, modified); err != nil {
+		t.Fatalf("修改对象时间失败: %v", err)
+	}
+}
+
+// objectExists 判断对象是否还在存储中
+func objectExists(ctx context.Context, key string) bool {
+	_, err := storage.Default().Stat(ctx, key)
+	return err == nil
+}
+
+func TestReconcileStorage(t *testing.T) {
+	cfg := setupTestEnv(t)
+	ctx := context.Background()
+	db := database.GetDB()
+	old := 2 * orphanGracePeriod
+
+	userID := uint(testUserID)
+	active := models.File{Key: "2024/05/01/active.txt", Storage: "local", Status: models.FileStatusActive, Size: 7, UserID: &userID}
+	lost := models.File{Key: "2024/05/01/lost.txt", Storage: "local", Status: models.FileStatusActive, Size: 7, UserID: &userID}
+	back := models.File{Key: "2024/05/01/back.txt", Storage: "local", Status: models.FileStatusMissing, Size: 7, UserID: &userID}
+	for _, f := range []*models.File{&active, &lost, &back} {
+		if err := db.Create(f).Error; err != nil {
+			t.Fatalf("创建文件记录失败: %v", err)
+		}
+	}
+	if err := db.Model(&models.User{}).Where("id = ?", testUserID).Update("avatar", models.AvatarRefPrefix+"current").Error; err != nil {
+		t.Fatalf("设置头像失败: %v", err)
+	}
+	u, err := CreateUpload(ctx, testUserID, 100, "big.txt", "")
+	if err != nil {
+		t.Fatalf("CreateUpload 失败: %v", err)
+	}
+
+	kept := []string{
+		active.Key,
+		back.Key,
+		"avatars/current/64.webp",
+		"avatars/current/256.webp",
+		uploadPartKey(u.ID, 0),
+	}
+	orphans := []string{
+		"2024/05/01/orphan.txt",
+		"avatars/previous/64.webp",
+		uploadPartKey("01900000-0000-7000-8000-000000000000", 0),
+	}
+	for _, key := range append(kept, orphans...) {
+		putTestObject(t, ctx, cfg.Upload.Dir, key, old)
+	}
+	// 宽限期内的对象即使没有被引用也保留
+	fresh := "2024/05/01/fresh.txt"
+	putTestObject(t, ctx, cfg.Upload.Dir, fresh, time.Minute)
+
+	result, err := ReconcileStorage(ctx)
+	if err != nil {
+		t.Fatalf("ReconcileStorage 失败: %v", err)
+	}
+	want := ReconcileResult{Objects: len(kept) + len(orphans) + 1, Orphans: len(orphans), Missing: 1, Restored: 1}
+	if result != want {
+		t.Errorf("result = %+v, want %+v", result, want)
+	}
+	for _, key := range append(kept, fresh) {
+		if !objectExists(ctx, key) {
+			t.Errorf("对象 %s 不应被删除", key)
+		}
+	}
+	for _, key := range orphans {
+		if objectExists(ctx, key) {
+			t.Errorf("无主对象 %s 应被删除", key)
+		}
+	}
+
+	status := func(f models.File) string {
+		var got models.File
+		db.First(&got, f.ID)
+		return got.Status
+	}
+	if got := status(lost); got != models.FileStatusMissing {
+		t.Errorf("对象丢失的文件状态 = %s, want missing", got)
+	}
+	if got := status(back); got != models.FileStatusActive {
+		t.Errorf("对象重新出现的文件状态 = %s, want active", got)
+	}
+	if got := status(active); got != models.FileStatusActive {
+		t.Errorf("正常文件的状态 = %s, want active", got)
+	}
+
+	// 丢失的文件不计入用量：两个正常文件加上未完成上传的预留
+	usage, err := StorageUsage(ctx, testUserID)
+	if err != nil {
+		t.Fatalf("StorageUsage 失败: %v", err)
+	}
+	if want := (Usage{Bytes: 7*2 + 100, Files: 3}); usage != want {
+		t.Errorf("用量 = %+v, want %+v", usage, want)
+	}
+}
+
+func TestObjectReferenced(t *testing.T) {
+	fileKeys := map[string]bool{"2024/05/01/a.txt": true}
+	avatars := map[string]bool{"avatars/v2": true}
+	uploads := map[string]bool{"u1": true}
+
+	tests := map[string]bool{
+		"2024/05/01/a.txt":   true,
+		"2024/05/01/b.txt":   false,
+		"avatars/v2/64.webp": true,
+		"avatars/v1/64.webp": false,
+		"avatars/v2":         false,
+		"tus/u1/000000":      true,
+		"tus/u2/000000":      false,
+		"tus/u1":             false,
+	}
+	for key, want := range tests {
+		if got := objectReferenced(key, fileKeys, avatars, uploads); got != want {
+			t.Errorf("objectReferenced(%q) = %v, want %v", key, got, want)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package services
+
+import (
+	"path/filepath"
+	"testing"
+
+	"iris-cn-sample-project/config"
+	"iris-cn-sample-project/database"
+	"iris-cn-sample-project/storage"
+	"iris-cn-sample-project/upload"
+)
+
+// setupTestEnv 使用临时目录中的 SQLite 数据库与本地存储初始化服务依赖，返回当前配置
+//
+// 数据库中有初始化的 admin（ID 1）与 user（ID 2）两个用户。测试结束后关闭数据库并恢复原来的配置与存储后端。
+func setupTestEnv(t *testing.T) *config.Config {
+	t.Helper()
+
+	dir := t.TempDir()
+	cfg := config.Defaults()
+	cfg.Database.Database = filepath.Join(dir, "test.db")
+	cfg.Upload.Dir = filepath.Join(dir, "uploads")
+	cfg.Upload.QuarantineDir = filepath.Join(dir, "quarantine")
+	cfg.Storage.Driver = "local"
+	cfg.Storage.SigningKey = "test-signing-key"
+
+	previous, previousStore := config.GetConfig(), storage.Default()
+	config.SetConfig(cfg)
+	t.Cleanup(func() {
+		database.CloseDB()
+		storage.SetDefault(previousStore)
+		config.SetConfig(previous)
+	})
+
+	if err := database.InitDB(); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+	if err := storage.Init(cfg.Storage, cfg.Upload.Dir); err != nil {
+		t.Fatalf("初始化存储失败: %v", err)
+	}
+	if err := upload.Init(cfg.Upload); err != nil {
+		t.Fatalf("初始化上传策略失败: %v", err)
+	}
+	return cfg
+}
+
+// testUserID 初始化数据中普通用户的ID
+const testUserID = 2
@@ -0,0 +1,53 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"iris-cn-sample-project/database"
+	"iris-cn-sample-project/logging"
+	"iris-cn-sample-project/models"
+)
+
+// SQL 日志只记录带占位符的语句，INSERT/UPDATE 绑定的值（邮箱、密码哈希等）不能出现在日志中
+func TestSQLLogOmitsBoundValues(t *testing.T) {
+	setupTestEnv(t)
+	ctx := context.Background()
+
+	previous, previousLevel := logging.L(), logging.Level()
+	t.Cleanup(func() {
+		logging.SetLogger(previous)
+		logging.SetLevel(previousLevel.String())
+	})
+	var buf bytes.Buffer
+	logging.SetLogger(slog.New(slog.NewJSONHandler(&buf, logging.NewHandlerOptions())))
+	if err := logging.SetLevel("debug"); err != nil {
+		t.Fatal(err)
+	}
+
+	user, err := CreateUser(ctx, &models.RegisterRequest{Username: "sqllog", Email: "sql-log-secret@example.com", Password: "secret123"})
+	if err != nil {
+		t.Fatalf("CreateUser 失败: %v", err)
+	}
+	if err := database.GetDB().Model(user).Update("password", "$2a$10$updatedhashvalue").Error; err != nil {
+		t.Fatalf("更新密码失败: %v", err)
+	}
+	// 出错的语句同样记录 SQL
+	duplicate := models.User{Username: "sqllog", Email: "sql-log-duplicate@example.com", Password: "x"}
+	if err := database.GetDB().Create(&duplicate).Error; err == nil {
+		t.Fatal("重复的用户名应插入失败")
+	}
+
+	out := buf.String()
+	if !strings.Contains(out, "INSERT INTO") || !strings.Contains(out, "SQL 执行失败") {
+		t.Fatalf("应记录 SQL 语句，实际输出: %s", out)
+	}
+	for _, value := range []string{"sql-log-secret@example.com", "sql-log-duplicate@example.com", user.Password, "$2a$10$updatedhashvalue"} {
+		if strings.Contains(out, value) {
+			t.Errorf("日志中出现了绑定的值 %q", value)
+		}
+	}
+}
@@ -0,0 +1,433 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"sync"
+	"time"
+
+	"iris-cn-sample-project/config"
+	"iris-cn-sample-project/database"
+	"iris-cn-sample-project/logging"
+	"iris-cn-sample-project/models"
+	"iris-cn-sample-project/requestid"
+	"iris-cn-sample-project/storage"
+	"iris-cn-sample-project/tracing"
+	"iris-cn-sample-project/upload"
+
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+)
+
+// 可续传上传的错误
+var (
+	ErrUploadNotFound         = errors.New("上传不存在")
+	ErrUploadExpired          = errors.New("上传已过期")
+	ErrUploadOffsetMismatch   = errors.New("Upload-Offset 与已接收的长度不一致")
+	ErrUploadChecksumMismatch = errors.New("分段内容与 Upload-Checksum 不一致")
+	ErrUploadLocked           = errors.New("该上传正在被另一个请求写入")
+)
+
+// UploadChecksum 分段的校验和（tus checksum 扩展）
+type UploadChecksum struct {
+	Hash     hash.Hash
+	Expected []byte
+}
+
+// uploadLocks 正在写入的上传（同一上传同时只允许一个 PATCH）
+var uploadLocks sync.Map
+
+// lockUpload 获取上传的写锁，已被占用时返回 false
+func lockUpload(id string) (func(), bool) {
+	v, _ := uploadLocks.LoadOrStore(id, &sync.Mutex{})
+	mu := v.(*sync.Mutex)
+	if !mu.TryLock() {
+		return nil, false
+	}
+	return mu.Unlock, true
+}
+
+// uploadExpiration 未完成的上传在最后一次写入后保留的时间
+func uploadExpiration() time.Duration {
+	return time.Duration(config.GetConfig().Upload.Tus.Expiration) * time.Second
+}
+
+// tusPartPrefix 可续传上传的分段在存储后端中的前缀
+const tusPartPrefix = "tus/"
+
+// uploadPartKey 分段在存储后端中的 key
+func uploadPartKey(id string, index int) string {
+	return fmt.Sprintf("%s%s/%06d", tusPartPrefix, id, index)
+}
+
+// CreateUpload 创建可续传上传，按声明的长度检查大小上限与用户配额
+func CreateUpload(ctx context.Context, userID uint, length int64, filename, metadata string) (_ *models.Upload, err error) {
+	ctx, span := tracing.Start(ctx, "services.CreateUpload")
+	defer func() { tracing.End(span, err) }()
+
+	policy := upload.Resumable()
+	if length == 0 {
+		return nil, upload.ErrEmpty
+	}
+	if length > policy.MaxSize {
+		return nil, fmt.Errorf("%w: 不能超过 %d 字节", upload.ErrTooLarge, policy.MaxSize)
+	}
+
+	if err := checkQuota(ctx, userID, "", length, Usage{}); err != nil {
+		return nil, err
+	}
+
+	u := models.Upload{
+		ID:        requestid.NewUUIDv7(),
+		UserID:    userID,
+		Length:    length,
+		Filename:  SanitizeFilename(filename),
+		Metadata:  metadata,
+		ExpiresAt: time.Now().Add(uploadExpiration()),
+	}
+	if err := database.GetDB().WithContext(ctx).Create(&u).Error; err != nil {
+		return nil, fmt.Errorf("创建上传失败: %v", err)
+	}
+	span.SetAttributes(attribute.String("upload.id", u.ID), attribute.Int64("upload.length", length))
+	return &u, nil
+}
+
+// GetUpload 获取用户自己的上传；不存在或属于其他用户时返回 ErrUploadNotFound，未完成且已过期时返回 ErrUploadExpired
+func GetUpload(ctx context.Context, id string, userID uint) (*models.Upload, error) {
+	u, err := findUpload(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if u.FileID == nil && time.Now().After(u.ExpiresAt) {
+		return nil, ErrUploadExpired
+	}
+	return u, nil
+}
+
+// findUpload 查询用户自己的上传（包括已过期但尚未清理的）
+func findUpload(ctx context.Context, id string, userID uint) (*models.Upload, error) {
+	var u models.Upload
+	err := database.GetDB().WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).First(&u).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrUploadNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询上传失败: %v", err)
+	}
+	return &u, nil
+}
+
+// AppendUpload 在 offset 处追加一个分段（tus PATCH）
+//
+// 没有校验和时，客户端中途断开前收到的内容会被保存，客户端可以通过 HEAD 获取偏移量后继续上传；
+// 带校验和时只有完整且校验通过的分段才会被保存。全部内容收到后按上传策略校验、扫描并登记为文件，
+// 返回登记的文件；登记失败（如类型不允许）时删除该上传。
+func AppendUpload(ctx context.Context, id string, userID uint, offset int64, r io.Reader, checksum *UploadChecksum) (_ *models.Upload, _ *models.File, err error) {
+	ctx, span := tracing.Start(ctx, "services.AppendUpload")
+	defer func() { tracing.End(span, err) }()
+	span.SetAttributes(attribute.String("upload.id", id), attribute.Int64("upload.offset", offset))
+
+	unlock, ok := lockUpload(id)
+	if !ok {
+		return nil, nil, ErrUploadLocked
+	}
+	defer unlock()
+
+	u, err := GetUpload(ctx, id, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if offset != u.Offset {
+		return nil, nil, fmt.Errorf("%w: 已接收 %d 字节", ErrUploadOffsetMismatch, u.Offset)
+	}
+	if u.FileID != nil {
+		return u, nil, nil
+	}
+
+	if u.Offset < u.Length {
+		if err := appendPart(ctx, u, r, checksum); err != nil {
+			return nil, nil, err
+		}
+	}
+	if u.Offset < u.Length {
+		return u, nil, nil
+	}
+
+	file, err := finishUpload(ctx, u)
+	if err != nil {
+		return nil, nil, err
+	}
+	return u, file, nil
+}
+
+// appendPart 保存一个分段并更新偏移量
+func appendPart(ctx context.Context, u *models.Upload, r io.Reader, checksum *UploadChecksum) error {
+	store := storage.Default()
+	chunk := &chunkReader{r: r, remaining: u.Length - u.Offset}
+	if checksum != nil {
+		chunk.hash = checksum.Hash
+	}
+
+	// 客户端断开时请求的 ctx 会被取消，写入分段不跟随它，以便保存已收到的内容
+	key := uploadPartKey(u.ID, u.PartCount)
+	if _, err := store.Put(context.WithoutCancel(ctx), key, chunk, storage.PutOptions{Size: -1, ContentType: "application/octet-stream"}); err != nil {
+		// 存储只保留错误信息，读取分段时的错误原样返回以便区分客户端错误
+		if chunk.err != nil {
+			return chunk.err
+		}
+		return fmt.Errorf("保存分段失败: %w", err)
+	}
+
+	discard := func() {
+		if err := store.Delete(context.WithoutCancel(ctx), key); err != nil {
+			logging.L().ErrorContext(ctx, "删除分段失败", "key", key, "error", err)
+		}
+	}
+	if chunk.read == 0 {
+		discard()
+		return nil
+	}
+	if checksum != nil && !bytes.Equal(checksum.Hash.Sum(nil), checksum.Expected) {
+		discard()
+		return ErrUploadChecksumMismatch
+	}
+	if chunk.interrupted != nil {
+		logging.L().InfoContext(ctx, "上传中断，已保存收到的内容", "upload_id", u.ID, "received", chunk.read, "error", chunk.interrupted)
+	}
+
+	// 只在偏移量未被其他实例修改时更新（同一实例内已由 uploadLocks 串行化）
+	expiresAt := time.Now().Add(uploadExpiration())
+	result := database.GetDB().WithContext(context.WithoutCancel(ctx)).Model(&models.Upload{}).
+		Where("id = ? AND upload_offset = ? AND part_count = ?", u.ID, u.Offset, u.PartCount).
+		Updates(map[string]interface{}{
+			"upload_offset": u.Offset + chunk.read,
+			"part_count":    u.PartCount + 1,
+			"expires_at":    expiresAt,
+		})
+	if result.Error != nil || result.RowsAffected == 0 {
+		discard()
+		if result.Error != nil {
+			return fmt.Errorf("更新上传进度失败: %v", result.Error)
+		}
+		return ErrUploadOffsetMismatch
+	}
+
+	u.Offset += chunk.read
+	u.PartCount++
+	u.ExpiresAt = expiresAt
+	return nil
+}
+
+// finishUpload 拼接所有分段并登记为文件；分段读取失败时保留上传，客户端可以用空的 PATCH 重试
+func finishUpload(ctx context.Context, u *models.Upload) (*models.File, error) {
+	store := storage.Default()
+	parts := &partsReader{ctx: ctx, store: store, id: u.ID, count: u.PartCount}
+	defer parts.Close()
+
+	// 该上传创建时已按声明的长度预留了配额
+	file, err := saveFile(ctx, parts, u.Filename, upload.Resumable(), u.UserID, "", Usage{Bytes: u.Length, Files: 1})
+	if err != nil {
+		if parts.err != nil || upload.Reason(err) == "" {
+			return nil, err
+		}
+		// 内容不符合上传策略：上传已经无法完成，删除分段与记录
+		deleteUpload(context.WithoutCancel(ctx), u)
+		return nil, err
+	}
+
+	deleteUploadParts(context.WithoutCancel(ctx), u)
+	expiresAt := time.Now().Add(uploadExpiration())
+	if err := database.GetDB().WithContext(context.WithoutCancel(ctx)).Model(u).
+		Updates(map[string]interface{}{"file_id": file.ID, "part_count": 0, "expires_at": expiresAt}).Error; err != nil {
+		logging.L().ErrorContext(ctx, "更新上传状态失败", "upload_id", u.ID, "error", err)
+	}
+	u.FileID = &file.ID
+	u.PartCount = 0
+	u.ExpiresAt = expiresAt
+	return file, nil
+}
+
+// TerminateUpload 删除上传及已收到的分段（tus termination 扩展）
+func TerminateUpload(ctx context.Context, id string, userID uint) error {
+	unlock, ok := lockUpload(id)
+	if !ok {
+		return ErrUploadLocked
+	}
+	defer unlock()
+
+	u, err := findUpload(ctx, id, userID)
+	if err != nil {
+		return err
+	}
+	return deleteUpload(ctx, u)
+}
+
+// deleteUpload 删除分段与上传记录
+func deleteUpload(ctx context.Context, u *models.Upload) error {
+	deleteUploadParts(ctx, u)
+	if err := database.GetDB().WithContext(ctx).Delete(u).Error; err != nil {
+		return fmt.Errorf("删除上传失败: %v", err)
+	}
+	uploadLocks.Delete(u.ID)
+	return nil
+}
+
+// deleteUploadParts 删除已收到的分段，失败时只记录日志（过期清理会再次尝试）
+func deleteUploadParts(ctx context.Context, u *models.Upload) {
+	store := storage.Default()
+	for i := 0; i < u.PartCount; i++ {
+		if err := store.Delete(ctx, uploadPartKey(u.ID, i)); err != nil {
+			logging.L().WarnContext(ctx, "删除分段失败", "upload_id", u.ID, "part", i, "error", err)
+		}
+	}
+}
+
+// uploadCleanupBatch 每次清理的最大上传数
+const uploadCleanupBatch = 100
+
+// CleanupUploads 删除已过期的上传：未完成的同时删除已收到的分段，已完成的只删除记录
+func CleanupUploads(ctx context.Context) (int, error) {
+	var expired []models.Upload
+	if err := database.GetDB().WithContext(ctx).
+		Where("expires_at < ?", time.Now()).
+		Limit(uploadCleanupBatch).
+		Find(&expired).Error; err != nil {
+		return 0, fmt.Errorf("查询过期上传失败: %v", err)
+	}
+
+	removed := 0
+	for i := range expired {
+		u := &expired[i]
+		unlock, ok := lockUpload(u.ID)
+		if !ok {
+			continue
+		}
+		err := deleteUpload(ctx, u)
+		unlock()
+		if err != nil {
+			return removed, err
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// RunUploadCleanup 定期清理过期的上传，直到 ctx 被取消
+func RunUploadCleanup(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for {
+			n, err := CleanupUploads(ctx)
+			if err != nil {
+				logging.L().Error("清理过期上传失败", "error", err)
+				break
+			}
+			if n > 0 {
+				logging.L().Info("已清理过期上传", "count", n)
+			}
+			if n < uploadCleanupBatch {
+				break
+			}
+		}
+	}
+}
+
+// chunkReader 读取一个分段：不超过剩余长度，计算校验和，并记录客户端连接中断
+type chunkReader struct {
+	r           io.Reader
+	remaining   int64
+	read        int64
+	hash        hash.Hash
+	interrupted error
+	err         error // 返回给调用方的错误
+}
+
+func (c *chunkReader) Read(p []byte) (int, error) {
+	// 多读一个字节，用于发现超出 Upload-Length 的内容
+	if int64(len(p)) > c.remaining+1 {
+		p = p[:c.remaining+1]
+	}
+	n, err := c.r.Read(p)
+	if int64(n) > c.remaining {
+		c.err = fmt.Errorf("%w: 内容超出了 Upload-Length", upload.ErrTooLarge)
+		return 0, c.err
+	}
+	c.remaining -= int64(n)
+	c.read += int64(n)
+	if c.hash != nil {
+		c.hash.Write(p[:n])
+	}
+
+	if err != nil && err != io.EOF {
+		c.interrupted = err
+		if c.hash == nil {
+			// 没有校验和时保留已收到的内容
+			err = io.EOF
+		} else {
+			c.err = err
+		}
+	}
+	return n, err
+}
+
+// partsReader 按顺序读取所有分段
+type partsReader struct {
+	ctx   context.Context
+	store storage.Storage
+	id    string
+	count int
+	next  int
+	cur   io.ReadCloser
+	err   error // 读取分段失败（区别于内容校验失败）
+}
+
+func (p *partsReader) Read(b []byte) (int, error) {
+	for {
+		if p.cur == nil {
+			if p.next >= p.count {
+				return 0, io.EOF
+			}
+			rc, _, err := p.store.Get(p.ctx, uploadPartKey(p.id, p.next))
+			if err != nil {
+				p.err = fmt.Errorf("读取分段 %d 失败: %w", p.next, err)
+				return 0, p.err
+			}
+			p.cur = rc
+			p.next++
+		}
+
+		n, err := p.cur.Read(b)
+		if err == io.EOF {
+			p.cur.Close()
+			p.cur = nil
+			if n > 0 {
+				return n, nil
+			}
+			continue
+		}
+		if err != nil {
+			p.err = err
+		}
+		return n, err
+	}
+}
+
+// Close 关闭正在读取的分段
+func (p *partsReader) Close() error {
+	if p.cur != nil {
+		return p.cur.Close()
+	}
+	return nil
+}
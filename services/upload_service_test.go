@@ -0,0 +1,237 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"iris-cn-sample-project/database"
+	"iris-cn-sample-project/models"
+	"iris-cn-sample-project/storage"
+)
+
+// testUploadContent 可续传上传测试使用的文本内容
+var testUploadContent = []byte(strings.Repeat("可续传上传的测试内容。\n", 64))
+
+// createTestUpload 以测试用户创建一个上传
+func createTestUpload(t *testing.T, ctx context.Context) *models.Upload {
+	t.Helper()
+	u, err := CreateUpload(ctx, testUserID, int64(len(testUploadContent)), "notes.txt", "")
+	if err != nil {
+		t.Fatalf("CreateUpload 失败: %v", err)
+	}
+	return u
+}
+
+// partExists 判断上传的第 index 个分段是否还在存储中
+func partExists(t *testing.T, ctx context.Context, id string, index int) bool {
+	t.Helper()
+	_, err := storage.Default().Stat(ctx, uploadPartKey(id, index))
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("查询分段失败: %v", err)
+	}
+	return err == nil
+}
+
+func TestAppendUpload(t *testing.T) {
+	setupTestEnv(t)
+	ctx := context.Background()
+	u := createTestUpload(t, ctx)
+
+	usage, err := StorageUsage(ctx, testUserID)
+	if err != nil {
+		t.Fatalf("StorageUsage 失败: %v", err)
+	}
+	if want := (Usage{Bytes: u.Length, Files: 1}); usage != want {
+		t.Errorf("创建后用量 = %+v, want %+v（按声明的长度预留）", usage, want)
+	}
+
+	half := int64(len(testUploadContent) / 2)
+	got, file, err := AppendUpload(ctx, u.ID, testUserID, 0, bytes.NewReader(testUploadContent[:half]), nil)
+	if err != nil || file != nil || got.Offset != half {
+		t.Fatalf("第一个分段: offset = %v, file = %v, err = %v", got, file, err)
+	}
+
+	// 偏移量与已接收的长度不一致
+	if _, _, err := AppendUpload(ctx, u.ID, testUserID, 0, bytes.NewReader(testUploadContent), nil); !errors.Is(err, ErrUploadOffsetMismatch) {
+		t.Errorf("偏移量不一致: err = %v, want ErrUploadOffsetMismatch", err)
+	}
+	// 其他用户的上传视为不存在
+	if _, _, err := AppendUpload(ctx, u.ID, 1, half, bytes.NewReader(testUploadContent[half:]), nil); !errors.Is(err, ErrUploadNotFound) {
+		t.Errorf("其他用户: err = %v, want ErrUploadNotFound", err)
+	}
+
+	got, file, err = AppendUpload(ctx, u.ID, testUserID, half, bytes.NewReader(testUploadContent[half:]), nil)
+	if err != nil {
+		t.Fatalf("最后一个分段失败: %v", err)
+	}
+	if file == nil || got.FileID == nil || *got.FileID != file.ID {
+		t.Fatalf("完成后应登记文件: upload = %+v, file = %+v", got, file)
+	}
+	if file.Size != u.Length || file.OriginalName != "notes.txt" || file.Status != models.FileStatusActive {
+		t.Errorf("登记的文件 = %+v", file)
+	}
+
+	body, err := OpenFile(ctx, file)
+	if err != nil {
+		t.Fatalf("OpenFile 失败: %v", err)
+	}
+	data, _ := io.ReadAll(body)
+	body.Close()
+	if !bytes.Equal(data, testUploadContent) {
+		t.Errorf("拼接后的内容不一致: %d 字节，期望 %d 字节", len(data), len(testUploadContent))
+	}
+	for i := 0; i < 2; i++ {
+		if partExists(t, ctx, u.ID, i) {
+			t.Errorf("完成后分段 %d 应被删除", i)
+		}
+	}
+
+	// 登记为文件后释放预留：用量只包含文件本身
+	usage, err = StorageUsage(ctx, testUserID)
+	if err != nil {
+		t.Fatalf("StorageUsage 失败: %v", err)
+	}
+	if want := (Usage{Bytes: file.Size, Files: 1}); usage != want {
+		t.Errorf("完成后用量 = %+v, want %+v", usage, want)
+	}
+
+	// 已完成的上传再次 PATCH 只返回进度
+	got, file, err = AppendUpload(ctx, u.ID, testUserID, u.Length, bytes.NewReader(nil), nil)
+	if err != nil || file != nil || got.FileID == nil {
+		t.Errorf("已完成的上传: upload = %+v, file = %v, err = %v", got, file, err)
+	}
+}
+
+func TestAppendUploadLocked(t *testing.T) {
+	setupTestEnv(t)
+	ctx := context.Background()
+	u := createTestUpload(t, ctx)
+
+	unlock, ok := lockUpload(u.ID)
+	if !ok {
+		t.Fatal("获取上传锁失败")
+	}
+	if _, _, err := AppendUpload(ctx, u.ID, testUserID, 0, bytes.NewReader(testUploadContent), nil); !errors.Is(err, ErrUploadLocked) {
+		t.Errorf("并发写入: err = %v, want ErrUploadLocked", err)
+	}
+	if err := TerminateUpload(ctx, u.ID, testUserID); !errors.Is(err, ErrUploadLocked) {
+		t.Errorf("并发删除: err = %v, want ErrUploadLocked", err)
+	}
+	unlock()
+
+	if _, _, err := AppendUpload(ctx, u.ID, testUserID, 0, bytes.NewReader(testUploadContent[:10]), nil); err != nil {
+		t.Errorf("释放锁后写入失败: %v", err)
+	}
+}
+
+func TestAppendUploadChecksum(t *testing.T) {
+	setupTestEnv(t)
+	ctx := context.Background()
+	u := createTestUpload(t, ctx)
+
+	chunk := testUploadContent[:100]
+	wrong := sha1.Sum([]byte("其他内容"))
+	_, _, err := AppendUpload(ctx, u.ID, testUserID, 0, bytes.NewReader(chunk), &UploadChecksum{Hash: sha1.New(), Expected: wrong[:]})
+	if !errors.Is(err, ErrUploadChecksumMismatch) {
+		t.Fatalf("校验和不一致: err = %v, want ErrUploadChecksumMismatch", err)
+	}
+	if got, _ := GetUpload(ctx, u.ID, testUserID); got.Offset != 0 || got.PartCount != 0 {
+		t.Errorf("校验失败的分段不应保存: offset = %d, parts = %d", got.Offset, got.PartCount)
+	}
+	if partExists(t, ctx, u.ID, 0) {
+		t.Error("校验失败的分段应从存储中删除")
+	}
+
+	sum := sha1.Sum(chunk)
+	got, _, err := AppendUpload(ctx, u.ID, testUserID, 0, bytes.NewReader(chunk), &UploadChecksum{Hash: sha1.New(), Expected: sum[:]})
+	if err != nil || got.Offset != int64(len(chunk)) {
+		t.Errorf("校验通过: upload = %+v, err = %v", got, err)
+	}
+}
+
+// interruptedReader 返回 data 后以 err 结束，模拟客户端中途断开
+type interruptedReader struct {
+	data []byte
+	err  error
+}
+
+func (r *interruptedReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		return 0, r.err
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+func TestAppendUploadInterrupted(t *testing.T) {
+	setupTestEnv(t)
+	ctx := context.Background()
+	u := createTestUpload(t, ctx)
+	disconnected := errors.New("连接已断开")
+
+	// 没有校验和：保留断开前收到的内容，客户端从新的偏移量继续
+	got, file, err := AppendUpload(ctx, u.ID, testUserID, 0, &interruptedReader{data: testUploadContent[:300], err: disconnected}, nil)
+	if err != nil || file != nil || got.Offset != 300 {
+		t.Fatalf("中断的 PATCH: upload = %+v, err = %v", got, err)
+	}
+	if !partExists(t, ctx, u.ID, 0) {
+		t.Error("中断前收到的内容应保存为分段")
+	}
+
+	// 带校验和：不完整的分段无法校验，不保存
+	sum := sha1.Sum(testUploadContent[300:])
+	_, _, err = AppendUpload(ctx, u.ID, testUserID, 300,
+		&interruptedReader{data: testUploadContent[300:400], err: disconnected}, &UploadChecksum{Hash: sha1.New(), Expected: sum[:]})
+	if !errors.Is(err, disconnected) {
+		t.Errorf("带校验和的中断: err = %v, want %v", err, disconnected)
+	}
+	if got, _ := GetUpload(ctx, u.ID, testUserID); got.Offset != 300 || got.PartCount != 1 {
+		t.Errorf("带校验和的中断不应更新进度: offset = %d, parts = %d", got.Offset, got.PartCount)
+	}
+
+	_, file, err = AppendUpload(ctx, u.ID, testUserID, 300, bytes.NewReader(testUploadContent[300:]), nil)
+	if err != nil || file == nil || file.Size != u.Length {
+		t.Errorf("续传完成: file = %+v, err = %v", file, err)
+	}
+}
+
+func TestCleanupUploads(t *testing.T) {
+	setupTestEnv(t)
+	ctx := context.Background()
+
+	expired := createTestUpload(t, ctx)
+	if _, _, err := AppendUpload(ctx, expired.ID, testUserID, 0, bytes.NewReader(testUploadContent[:100]), nil); err != nil {
+		t.Fatalf("AppendUpload 失败: %v", err)
+	}
+	active := createTestUpload(t, ctx)
+
+	db := database.GetDB()
+	if err := db.Model(&models.Upload{}).Where("id = ?", expired.ID).
+		Update("expires_at", time.Now().Add(-time.Minute)).Error; err != nil {
+		t.Fatalf("更新过期时间失败: %v", err)
+	}
+	if _, err := GetUpload(ctx, expired.ID, testUserID); !errors.Is(err, ErrUploadExpired) {
+		t.Errorf("过期的上传: err = %v, want ErrUploadExpired", err)
+	}
+
+	removed, err := CleanupUploads(ctx)
+	if err != nil || removed != 1 {
+		t.Fatalf("CleanupUploads = %d, %v, want 1", removed, err)
+	}
+	if _, err := findUpload(ctx, expired.ID, testUserID); !errors.Is(err, ErrUploadNotFound) {
+		t.Errorf("过期的上传应被删除: err = %v", err)
+	}
+	if partExists(t, ctx, expired.ID, 0) {
+		t.Error("过期上传的分段应被删除")
+	}
+	if _, err := GetUpload(ctx, active.ID, testUserID); err != nil {
+		t.Errorf("未过期的上传不应被删除: %v", err)
+	}
+}
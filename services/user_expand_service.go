@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"iris-cn-sample-project/database"
+	"iris-cn-sample-project/models"
+	"iris-cn-sample-project/tracing"
+)
+
+// ErrExpandForbidden 当前用户无权展开请求的关联数据
+var ErrExpandForbidden = errors.New("无权展开该关联数据")
+
+// userExpander 可以通过 expand 参数附加到用户信息中的关联数据
+type userExpander struct {
+	adminOnly bool
+	// load 批量加载一组用户的关联数据，避免逐个用户查询；结果中没有的用户使用零值
+	load func(ctx context.Context, ids []uint) (map[uint]interface{}, error)
+}
+
+// userExpanders 支持的关联数据，新增关联数据（如会话数、角色详情）时在这里注册
+var userExpanders = map[string]userExpander{
+	"storage": {adminOnly: true, load: loadStorageUsage},
+}
+
+// UserExpansions 支持展开的关联数据
+func UserExpansions() []string {
+	names := make([]string, 0, len(userExpanders))
+	for name := range userExpanders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseUserExpand 解析逗号分隔的 expand 参数并检查权限：不支持的名称返回错误，无权展开时返回 ErrExpandForbidden
+func ParseUserExpand(value, role string) ([]string, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+	var expand []string
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		expander, ok := userExpanders[name]
+		if !ok {
+			return nil, fmt.Errorf("不支持展开 %q，可选: %s", name, strings.Join(UserExpansions(), ", "))
+		}
+		if expander.adminOnly && role != "admin" {
+			return nil, fmt.Errorf("%w: %s", ErrExpandForbidden, name)
+		}
+		if !seen[name] {
+			seen[name] = true
+			expand = append(expand, name)
+		}
+	}
+	return expand, nil
+}
+
+// ExpandUsers 批量加载用户的关联数据，返回 名称 → 用户ID → 数据
+func ExpandUsers(ctx context.Context, ids []uint, expand []string) (_ map[string]map[uint]interface{}, err error) {
+	ctx, span := tracing.Start(ctx, "services.ExpandUsers")
+	defer func() { tracing.End(span, err) }()
+
+	result := make(map[string]map[uint]interface{}, len(expand))
+	for _, name := range expand {
+		expander, ok := userExpanders[name]
+		if !ok {
+			return nil, fmt.Errorf("不支持展开 %q", name)
+		}
+		data, err := expander.load(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+		result[name] = data
+	}
+	return result, nil
+}
+
+// loadStorageUsage 用户的存储用量，口径与 StorageUsage 相同（已保存的文件加上未完成的上传）
+func loadStorageUsage(ctx context.Context, ids []uint) (map[uint]interface{}, error) {
+	db := database.GetDB().WithContext(ctx)
+
+	type row struct {
+		UserID uint
+		Bytes  int64
+		Files  int64
+	}
+	var files, pending []row
+	if err := db.Model(&models.File{}).
+		Where("user_id IN ? AND status = ?", ids, models.FileStatusActive).
+		Select("user_id, COALESCE(SUM(size), 0) AS bytes, COUNT(*) AS files").
+		Group("user_id").Scan(&files).Error; err != nil {
+		return nil, fmt.Errorf("统计存储空间失败: %v", err)
+	}
+	if err := db.Model(&models.Upload{}).
+		Where("user_id IN ? AND file_id IS NULL AND expires_at > ?", ids, time.Now()).
+		Select("user_id, COALESCE(SUM(upload_length), 0) AS bytes, COUNT(*) AS files").
+		Group("user_id").Scan(&pending).Error; err != nil {
+		return nil, fmt.Errorf("统计存储空间失败: %v", err)
+	}
+
+	usage := make(map[uint]Usage, len(ids))
+	for _, r := range append(files, pending...) {
+		u := usage[r.UserID]
+		u.Bytes += r.Bytes
+		u.Files += r.Files
+		usage[r.UserID] = u
+	}
+	result := make(map[uint]interface{}, len(ids))
+	for _, id := range ids {
+		result[id] = usage[id]
+	}
+	return result, nil
+}
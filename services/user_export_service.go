@@ -0,0 +1,45 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"iris-cn-sample-project/models"
+	"iris-cn-sample-project/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ExportUsers 按条件逐个读取用户交给 fn，排序与 SearchUsers 相同
+//
+// 通过数据库游标逐行读取，不会把全部用户加载到内存；fn 返回错误时停止读取并返回该错误。
+func ExportUsers(ctx context.Context, filter UserFilter, fn func(*models.UserInfo) error) (err error) {
+	ctx, span := tracing.Start(ctx, "services.ExportUsers",
+		attribute.Bool("search.keyword", filter.Keyword != ""),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	query, rank := userQuery(ctx, filter)
+	rows, err := query.Select(userColumns("users", filter.Columns)).Clauses(userOrderBy(filter.Sort, rank)).Rows()
+	if err != nil {
+		return fmt.Errorf("查询用户失败: %v", err)
+	}
+	defer rows.Close()
+
+	count := 0
+	for rows.Next() {
+		var user models.User
+		if err := query.ScanRows(rows, &user); err != nil {
+			return fmt.Errorf("读取用户失败: %v", err)
+		}
+		if err := fn(toUserInfo(&user)); err != nil {
+			return err
+		}
+		count++
+	}
+	span.SetAttributes(attribute.Int("export.rows", count))
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("读取用户失败: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,433 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"iris-cn-sample-project/config"
+	"iris-cn-sample-project/database"
+	"iris-cn-sample-project/logging"
+	"iris-cn-sample-project/mailer"
+	"iris-cn-sample-project/models"
+	"iris-cn-sample-project/tracing"
+	"iris-cn-sample-project/utils"
+
+	"go.opentelemetry.io/otel/attribute"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// 批量导入模式
+const (
+	ImportAtomic     = "atomic"      // 任一行失败时不创建任何用户
+	ImportBestEffort = "best_effort" // 跳过失败的行，创建其余用户
+)
+
+// 批量导入的文件格式
+const (
+	ImportCSV  = "csv"  // 首行为表头，列名与 JSON 字段相同
+	ImportJSON = "json" // 对象数组
+)
+
+// MaxImportRows 一次最多导入的用户数
+const MaxImportRows = 5000
+
+// importColumns 导入数据中可以出现的字段
+var importColumns = models.NewFieldSet(models.ImportUserRequest{})
+
+// ErrInvalidImport 导入文件无法解析（格式错误、表头不正确、没有数据或超过行数上限）
+var ErrInvalidImport = errors.New("导入数据无效")
+
+// errImportRollback 回滚导入事务（试运行，或 atomic 模式下有失败的行）
+var errImportRollback = errors.New("回滚导入")
+
+// ImportOptions 批量导入选项
+type ImportOptions struct {
+	Mode   string // ImportAtomic（默认）或 ImportBestEffort
+	DryRun bool   // 试运行：完整执行校验与写入后回滚，不发送邮件
+	Invite bool   // 向创建的用户发送邀请邮件，此时密码可以为空（生成临时密码）
+}
+
+// ImportResult 批量导入结果
+type ImportResult struct {
+	Mode         string           `json:"mode"`
+	DryRun       bool             `json:"dry_run"`
+	Total        int              `json:"total"`
+	Created      int              `json:"created"` // 创建的用户数，试运行时为可以创建的用户数
+	Failed       int              `json:"failed"`  // 有错误的行数
+	Invited      int              `json:"invited"` // 已发送邀请邮件的用户数
+	Errors       []ImportRowError `json:"errors"`
+	InviteErrors []ImportRowError `json:"invite_errors,omitempty"`
+}
+
+// ImportRowError 一行数据的错误，Row 为数据的序号（从 1 开始，不含 CSV 表头）
+type ImportRowError struct {
+	Row      int               `json:"row"`
+	Username string            `json:"username,omitempty"`
+	Message  string            `json:"message"`
+	Fields   map[string]string `json:"fields,omitempty"` // 字段 → 错误提示
+}
+
+// ParseUserImport 解析导入文件，格式为 ImportCSV 或 ImportJSON；字段两端的空白会被去掉
+func ParseUserImport(r io.Reader, format string) ([]models.ImportUserRequest, error) {
+	var rows []models.ImportUserRequest
+	var err error
+	switch format {
+	case ImportCSV:
+		rows, err = parseImportCSV(r)
+	case ImportJSON:
+		rows, err = parseImportJSON(r)
+	default:
+		return nil, fmt.Errorf("%w: 不支持的格式 %q，可选: %s, %s", ErrInvalidImport, format, ImportCSV, ImportJSON)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidImport, err)
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("%w: 文件中没有数据", ErrInvalidImport)
+	}
+	return rows, nil
+}
+
+// parseImportCSV 解析 CSV：首行为表头（可以带 UTF-8 BOM），username 与 email 列必须存在，不认识的列返回错误
+func parseImportCSV(r io.Reader) ([]models.ImportUserRequest, error) {
+	cr := csv.NewReader(r)
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, errors.New("文件为空")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("CSV 格式错误: %w", err)
+	}
+
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.TrimSpace(strings.TrimPrefix(name, "\uFEFF"))
+		if name == "" {
+			return nil, fmt.Errorf("CSV 表头中第 %d 列没有列名", i+1)
+		}
+		if _, err := importColumns.Parse(name); err != nil {
+			return nil, fmt.Errorf("CSV 表头中%v", err)
+		}
+		if _, ok := index[name]; ok {
+			return nil, fmt.Errorf("CSV 表头中的列 %s 重复", name)
+		}
+		index[name] = i
+	}
+	for _, name := range []string{"username", "email"} {
+		if _, ok := index[name]; !ok {
+			return nil, fmt.Errorf("CSV 表头中缺少 %s 列", name)
+		}
+	}
+
+	var rows []models.ImportUserRequest
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("CSV 格式错误: %w", err)
+		}
+		if len(rows) == MaxImportRows {
+			return nil, fmt.Errorf("一次最多导入 %d 个用户", MaxImportRows)
+		}
+		value := func(name string) string {
+			if i, ok := index[name]; ok {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		rows = append(rows, models.ImportUserRequest{
+			Username:  value("username"),
+			Email:     value("email"),
+			Password:  value("password"),
+			FirstName: value("first_name"),
+			LastName:  value("last_name"),
+			Role:      value("role"),
+		})
+	}
+}
+
+// parseImportJSON 解析 JSON 对象数组，逐个解码，不认识的字段返回错误
+func parseImportJSON(r io.Reader) ([]models.ImportUserRequest, error) {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+		return nil, errors.New("JSON 格式错误: 导入数据必须是对象数组")
+	}
+
+	var rows []models.ImportUserRequest
+	for dec.More() {
+		if len(rows) == MaxImportRows {
+			return nil, fmt.Errorf("一次最多导入 %d 个用户", MaxImportRows)
+		}
+		var row models.ImportUserRequest
+		if err := dec.Decode(&row); err != nil {
+			return nil, fmt.Errorf("JSON 格式错误（第 %d 条）: %w", len(rows)+1, err)
+		}
+		row.Username = strings.TrimSpace(row.Username)
+		row.Email = strings.TrimSpace(row.Email)
+		row.FirstName = strings.TrimSpace(row.FirstName)
+		row.LastName = strings.TrimSpace(row.LastName)
+		row.Role = strings.TrimSpace(row.Role)
+		rows = append(rows, row)
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, fmt.Errorf("JSON 格式错误: %w", err)
+	}
+	return rows, nil
+}
+
+// importUser 通过校验、等待写入的一行
+type importUser struct {
+	row      int
+	user     models.User
+	password string // 生成的临时密码，用户提供了密码时为空
+	created  bool
+}
+
+// ImportUsers 批量创建用户
+//
+// 每行先按注册规则校验，并检查文件内与数据库中的重复用户名、邮箱；之后在一个事务中逐行写入（每行使用保存点，
+// 失败的行不影响其他行），试运行或 atomic 模式下有失败的行时回滚整个事务。密码在事务开始前完成加密，
+// 避免长时间占用数据库写锁。需要发送邀请时，未配置邮件服务器返回 mailer.ErrNotConfigured（试运行也会检查）；
+// 邀请邮件在提交后逐个发送，发送失败记录在 InviteErrors 中，不影响已创建的用户。
+func ImportUsers(ctx context.Context, rows []models.ImportUserRequest, opts ImportOptions) (_ *ImportResult, err error) {
+	if opts.Mode == "" {
+		opts.Mode = ImportAtomic
+	}
+	ctx, span := tracing.Start(ctx, "services.ImportUsers",
+		attribute.String("import.mode", opts.Mode),
+		attribute.Bool("import.dry_run", opts.DryRun),
+		attribute.Int("import.rows", len(rows)),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	if opts.Mode != ImportAtomic && opts.Mode != ImportBestEffort {
+		return nil, fmt.Errorf("不支持的导入模式 %q，可选: %s, %s", opts.Mode, ImportAtomic, ImportBestEffort)
+	}
+	var m *mailer.Mailer
+	if opts.Invite {
+		if m, err = mailer.New(config.GetConfig().Mail); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &ImportResult{Mode: opts.Mode, DryRun: opts.DryRun, Total: len(rows), Errors: []ImportRowError{}}
+	rowErrors, valid := validateImportRows(rows, opts)
+	result.Errors = append(result.Errors, rowErrors...)
+
+	db := database.GetDB().WithContext(ctx)
+	existing, err := existingUsers(db, rows, valid)
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []int
+	for _, i := range valid {
+		if fields := existing[i]; fields != nil {
+			result.Errors = append(result.Errors, ImportRowError{Row: i + 1, Username: rows[i].Username, Message: "用户已存在", Fields: fields})
+			continue
+		}
+		candidates = append(candidates, i)
+	}
+
+	// atomic 模式下已有失败的行时不会创建任何用户，除试运行外不必再加密密码与尝试写入
+	var pending []*importUser
+	if opts.Mode == ImportBestEffort || opts.DryRun || len(result.Errors) == 0 {
+		for _, i := range candidates {
+			p, err := newImportUser(i+1, rows[i], opts.DryRun)
+			if err != nil {
+				return nil, err
+			}
+			pending = append(pending, p)
+		}
+
+		err = db.Transaction(func(tx *gorm.DB) error {
+			for _, p := range pending {
+				tx.SavePoint("import_row")
+				if err := tx.Create(&p.user).Error; err != nil {
+					tx.RollbackTo("import_row")
+					result.Errors = append(result.Errors, ImportRowError{Row: p.row, Username: p.user.Username, Message: "创建用户失败: " + err.Error()})
+					continue
+				}
+				p.created = true
+			}
+			if opts.DryRun || (opts.Mode == ImportAtomic && len(result.Errors) > 0) {
+				return errImportRollback
+			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, errImportRollback) {
+			return nil, fmt.Errorf("导入用户失败: %v", err)
+		}
+	}
+
+	sort.Slice(result.Errors, func(i, j int) bool { return result.Errors[i].Row < result.Errors[j].Row })
+	result.Failed = len(result.Errors)
+	if opts.Mode == ImportBestEffort || result.Failed == 0 {
+		for _, p := range pending {
+			if p.created {
+				result.Created++
+			}
+		}
+	}
+
+	logging.L().InfoContext(ctx, "批量导入用户", "mode", opts.Mode, "dry_run", opts.DryRun,
+		"total", result.Total, "created", result.Created, "failed", result.Failed)
+
+	if m != nil && !opts.DryRun && result.Created > 0 {
+		for _, p := range pending {
+			if !p.created {
+				continue
+			}
+			if err := m.Send(ctx, inviteMessage(&p.user, p.password)); err != nil {
+				logging.L().WarnContext(ctx, "发送邀请邮件失败", "user_id", p.user.ID, "error", err)
+				result.InviteErrors = append(result.InviteErrors, ImportRowError{Row: p.row, Username: p.user.Username, Message: err.Error()})
+				continue
+			}
+			result.Invited++
+		}
+	}
+	return result, nil
+}
+
+// validateImportRows 按注册规则校验每一行并检查文件内重复的用户名与邮箱（邮箱不区分大小写），返回错误与通过校验的行的下标
+func validateImportRows(rows []models.ImportUserRequest, opts ImportOptions) ([]ImportRowError, []int) {
+	var rowErrors []ImportRowError
+	var valid []int
+	usernames := make(map[string]int)
+	emails := make(map[string]int)
+	for i, row := range rows {
+		fields := utils.ValidateStructFields(&row)
+		if fields == nil {
+			fields = make(map[string]string)
+		}
+		if row.Password == "" && !opts.Invite {
+			fields["password"] = "不发送邀请邮件时密码不能为空"
+		}
+		if first, ok := usernames[row.Username]; ok && row.Username != "" {
+			fields["username"] = fmt.Sprintf("与第 %d 行重复", first)
+		} else {
+			usernames[row.Username] = i + 1
+		}
+		email := strings.ToLower(row.Email)
+		if first, ok := emails[email]; ok && email != "" {
+			fields["email"] = fmt.Sprintf("与第 %d 行重复", first)
+		} else {
+			emails[email] = i + 1
+		}
+
+		if len(fields) > 0 {
+			rowErrors = append(rowErrors, ImportRowError{Row: i + 1, Username: row.Username, Message: "数据校验失败", Fields: fields})
+			continue
+		}
+		valid = append(valid, i)
+	}
+	return rowErrors, valid
+}
+
+// existingUsers 查询用户名或邮箱已被占用的行（包括已删除的用户，唯一索引仍然包含它们），返回 行下标 → 字段错误
+func existingUsers(db *gorm.DB, rows []models.ImportUserRequest, valid []int) (map[int]map[string]string, error) {
+	const batchSize = 500
+	columns := []struct {
+		name, message string
+		value         func(models.ImportUserRequest) string
+	}{
+		{"username", "用户名已存在", func(r models.ImportUserRequest) string { return r.Username }},
+		{"email", "邮箱已存在", func(r models.ImportUserRequest) string { return r.Email }},
+	}
+
+	taken := make(map[int]map[string]string)
+	for _, column := range columns {
+		for start := 0; start < len(valid); start += batchSize {
+			batch := valid[start:min(start+batchSize, len(valid))]
+			values := make([]string, len(batch))
+			for j, i := range batch {
+				values[j] = column.value(rows[i])
+			}
+
+			var found []string
+			if err := db.Unscoped().Model(&models.User{}).Where(column.name+" IN ?", values).Pluck(column.name, &found).Error; err != nil {
+				return nil, fmt.Errorf("查询已有用户失败: %v", err)
+			}
+			exists := make(map[string]bool, len(found))
+			for _, value := range found {
+				exists[value] = true
+			}
+			for _, i := range batch {
+				if exists[column.value(rows[i])] {
+					if taken[i] == nil {
+						taken[i] = make(map[string]string)
+					}
+					taken[i][column.name] = column.message
+				}
+			}
+		}
+	}
+	return taken, nil
+}
+
+// newImportUser 生成待写入的用户：没有密码时生成临时密码；试运行时不加密密码
+func newImportUser(row int, req models.ImportUserRequest, dryRun bool) (*importUser, error) {
+	p := &importUser{row: row}
+	password := req.Password
+	if password == "" {
+		generated, err := temporaryPassword()
+		if err != nil {
+			return nil, err
+		}
+		p.password, password = generated, generated
+	}
+	role := req.Role
+	if role == "" {
+		role = "user"
+	}
+	p.user = models.User{
+		Username:  req.Username,
+		Email:     req.Email,
+		FirstName: req.FirstName,
+		LastName:  req.LastName,
+		Role:      role,
+		Status:    "active",
+	}
+	if dryRun {
+		return p, nil
+	}
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("密码加密失败: %v", err)
+	}
+	p.user.Password = string(hashed)
+	return p, nil
+}
+
+// temporaryPassword 生成临时密码（16 个字符，96 位随机数）
+func temporaryPassword() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("生成临时密码失败: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// inviteMessage 邀请邮件，password 为生成的临时密码（用户提供了密码时为空，由管理员另行告知）
+func inviteMessage(user *models.User, password string) mailer.Message {
+	var body strings.Builder
+	fmt.Fprintf(&body, "%s，您好：\n\n管理员已为您创建账号。\n\n用户名：%s\n", user.Username, user.Username)
+	if password != "" {
+		fmt.Fprintf(&body, "临时密码：%s\n\n请登录后尽快修改密码。\n", password)
+	} else {
+		body.WriteString("密码：由管理员另行告知\n")
+	}
+	return mailer.Message{To: user.Email, Subject: "您的账号已创建", Body: body.String()}
+}
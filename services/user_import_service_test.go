@@ -0,0 +1,110 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"iris-cn-sample-project/models"
+)
+
+func TestParseUserImport(t *testing.T) {
+	want := []models.ImportUserRequest{
+		{Username: "alice", Email: "alice@example.com", Password: "secret1", Role: "admin"},
+		{Username: "bob", Email: "bob@example.com", FirstName: "三", LastName: "张"},
+	}
+
+	csv := "\uFEFFusername, email ,password,role,first_name,last_name\n" +
+		"alice,alice@example.com,secret1,admin,,\n" +
+		"\n" +
+		" bob ,bob@example.com,,,三,张\n"
+	rows, err := ParseUserImport(strings.NewReader(csv), ImportCSV)
+	if err != nil {
+		t.Fatalf("解析 CSV 失败: %v", err)
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("CSV = %+v, want %+v", rows, want)
+	}
+
+	data := `[{"username":"alice","email":"alice@example.com","password":"secret1","role":"admin"},
+		{"username":" bob","email":"bob@example.com","first_name":"三","last_name":"张"}]`
+	rows, err = ParseUserImport(strings.NewReader(data), ImportJSON)
+	if err != nil {
+		t.Fatalf("解析 JSON 失败: %v", err)
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("JSON = %+v, want %+v", rows, want)
+	}
+
+	invalid := map[string]struct{ data, format string }{
+		"空文件":        {"", ImportCSV},
+		"只有表头":       {"username,email\n", ImportCSV},
+		"缺少 email 列": {"username\nalice\n", ImportCSV},
+		"不认识的列":      {"username,email,password_hash\nalice,a@example.com,x\n", ImportCSV},
+		"重复的列":       {"username,email,email\nalice,a@example.com,b@example.com\n", ImportCSV},
+		"列数不一致":      {"username,email\nalice\n", ImportCSV},
+		"不是数组":       {`{"username":"alice"}`, ImportJSON},
+		"不认识的字段":     {`[{"username":"alice","is_admin":true}]`, ImportJSON},
+		"空数组":        {`[]`, ImportJSON},
+		"不支持的格式":     {"username,email\n", "xlsx"},
+	}
+	for name, tt := range invalid {
+		if _, err := ParseUserImport(strings.NewReader(tt.data), tt.format); !errors.Is(err, ErrInvalidImport) {
+			t.Errorf("%s: err = %v, want ErrInvalidImport", name, err)
+		}
+	}
+}
+
+func TestValidateImportRows(t *testing.T) {
+	rows := []models.ImportUserRequest{
+		{Username: "alice", Email: "alice@example.com", Password: "secret1"},
+		{Username: "al", Email: "not-an-email", Password: "secret1"},
+		{Username: "alice", Email: "ALICE@example.com", Password: "secret1"},
+		{Username: "carol", Email: "carol@example.com"},
+		{Username: "dave", Email: "dave@example.com", Password: "secret1", Role: "root"},
+	}
+	rowErrors, valid := validateImportRows(rows, ImportOptions{})
+	if !reflect.DeepEqual(valid, []int{0}) {
+		t.Errorf("valid = %v, want [0]", valid)
+	}
+	wantFields := map[int][]string{
+		2: {"email", "username"},
+		3: {"email", "username"},
+		4: {"password"},
+		5: {"role"},
+	}
+	if len(rowErrors) != len(wantFields) {
+		t.Fatalf("errors = %+v", rowErrors)
+	}
+	for _, e := range rowErrors {
+		var fields []string
+		for field := range e.Fields {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		if !reflect.DeepEqual(fields, wantFields[e.Row]) {
+			t.Errorf("第 %d 行错误字段 = %v, want %v", e.Row, fields, wantFields[e.Row])
+		}
+	}
+	if msg := rowErrors[1].Fields["username"]; msg != "与第 1 行重复" {
+		t.Errorf("重复用户名提示 = %q", msg)
+	}
+
+	// 发送邀请时可以不提供密码
+	if _, valid := validateImportRows(rows[3:4], ImportOptions{Invite: true}); len(valid) != 1 {
+		t.Error("发送邀请时没有密码的行应通过校验")
+	}
+}
+
+func TestTemporaryPassword(t *testing.T) {
+	a, err := temporaryPassword()
+	if err != nil {
+		t.Fatalf("temporaryPassword 失败: %v", err)
+	}
+	b, _ := temporaryPassword()
+	if len(a) != 16 || a == b {
+		t.Errorf("temporaryPassword = %q, %q", a, b)
+	}
+}
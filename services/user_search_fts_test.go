@@ -0,0 +1,110 @@
+//go:build sqlite_fts5
+
+package services
+
+import (
+	"context"
+	"testing"
+
+	"iris-cn-sample-project/database"
+	"iris-cn-sample-project/models"
+)
+
+// ftsMatch 直接在 users_fts 中匹配关键字，返回命中的用户ID
+func ftsMatch(t *testing.T, keyword string) []uint {
+	t.Helper()
+	var ids []uint
+	if err := database.GetDB().Raw("SELECT rowid FROM users_fts WHERE users_fts MATCH ? ORDER BY rowid", `"`+keyword+`"`).
+		Scan(&ids).Error; err != nil {
+		t.Fatalf("查询全文索引失败: %v", err)
+	}
+	return ids
+}
+
+// checkFTSIntegrity 外部内容表的索引与 users 表不一致时 integrity-check 返回错误
+func checkFTSIntegrity(t *testing.T) {
+	t.Helper()
+	if err := database.GetDB().Exec("INSERT INTO users_fts(users_fts, rank) VALUES ('integrity-check', 1)").Error; err != nil {
+		t.Fatalf("全文索引与 users 表不一致: %v", err)
+	}
+}
+
+func TestUserSearchFTSTriggers(t *testing.T) {
+	setupTestEnv(t)
+	ctx := context.Background()
+	db := database.GetDB()
+	if !database.UserSearchFTS() {
+		t.Fatal("sqlite_fts5 构建标签下应启用全文索引")
+	}
+
+	user, err := CreateUser(ctx, &models.RegisterRequest{Username: "zhangwei", Email: "zhangwei@example.com", Password: "secret123", FirstName: "张伟"})
+	if err != nil {
+		t.Fatalf("CreateUser 失败: %v", err)
+	}
+	if got := ftsMatch(t, "zhangwei"); len(got) != 1 || got[0] != user.ID {
+		t.Errorf("新增用户: 命中 %v, want [%d]", got, user.ID)
+	}
+	users, total, err := SearchUsers(ctx, UserFilter{Keyword: "张伟"}, 1, 10)
+	if err != nil || total != 1 || users[0].ID != user.ID {
+		t.Errorf("SearchUsers(张伟) = %v, %d, %v", users, total, err)
+	}
+
+	if err := db.Model(user).Update("username", "lina_renamed").Error; err != nil {
+		t.Fatalf("修改用户名失败: %v", err)
+	}
+	if got := ftsMatch(t, "zhangwei"); len(got) != 1 {
+		// 邮箱仍然包含旧用户名
+		t.Errorf("修改用户名后按邮箱: 命中 %v", got)
+	}
+	if got := ftsMatch(t, "lina_renamed"); len(got) != 1 || got[0] != user.ID {
+		t.Errorf("修改用户名后按新用户名: 命中 %v, want [%d]", got, user.ID)
+	}
+	if err := db.Model(user).Update("email", "lina@example.com").Error; err != nil {
+		t.Fatalf("修改邮箱失败: %v", err)
+	}
+	if got := ftsMatch(t, "zhangwei"); len(got) != 0 {
+		t.Errorf("旧用户名与旧邮箱不应再命中: %v", got)
+	}
+	checkFTSIntegrity(t)
+
+	if err := db.Unscoped().Delete(&models.User{}, user.ID).Error; err != nil {
+		t.Fatalf("删除用户失败: %v", err)
+	}
+	if got := ftsMatch(t, "lina"); len(got) != 0 {
+		t.Errorf("删除后不应命中: %v", got)
+	}
+	checkFTSIntegrity(t)
+}
+
+// 触发器缺失（如迁移重建了 users 表）期间的变更在下次启动时通过 rebuild 补齐
+func TestUserSearchFTSRebuild(t *testing.T) {
+	setupTestEnv(t)
+	db := database.GetDB()
+
+	if err := db.Exec("DROP TRIGGER users_fts_au").Error; err != nil {
+		t.Fatalf("删除触发器失败: %v", err)
+	}
+	if err := db.Model(&models.User{}).Where("id = ?", testUserID).Update("first_name", "诸葛孔明").Error; err != nil {
+		t.Fatalf("修改姓名失败: %v", err)
+	}
+	if got := ftsMatch(t, "诸葛孔"); len(got) != 0 {
+		t.Fatalf("没有触发器时索引不应更新: %v", got)
+	}
+
+	if err := database.CloseDB(); err != nil {
+		t.Fatalf("关闭数据库失败: %v", err)
+	}
+	if err := database.InitDB(); err != nil {
+		t.Fatalf("重新初始化数据库失败: %v", err)
+	}
+	if got := ftsMatch(t, "诸葛孔"); len(got) != 1 || got[0] != testUserID {
+		t.Errorf("重建后: 命中 %v, want [%d]", got, testUserID)
+	}
+	checkFTSIntegrity(t)
+
+	var triggers int64
+	database.GetDB().Raw("SELECT COUNT(*) FROM sqlite_master WHERE type = 'trigger' AND name LIKE 'users_fts_%'").Scan(&triggers)
+	if triggers != 3 {
+		t.Errorf("触发器数量 = %d, want 3", triggers)
+	}
+}
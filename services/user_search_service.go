@@ -0,0 +1,295 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"iris-cn-sample-project/database"
+	"iris-cn-sample-project/models"
+	"iris-cn-sample-project/pagination"
+	"iris-cn-sample-project/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// UserSortRelevance 按搜索相关度排序，只能与关键字一起使用
+const UserSortRelevance = "relevance"
+
+// maxUserSortFields 排序字段的最大个数
+const maxUserSortFields = 3
+
+// userSortColumns 允许排序的字段及对应的列，排序只能使用这些字段
+var userSortColumns = map[string]string{
+	"id":         "users.id",
+	"username":   "users.username",
+	"email":      "users.email",
+	"role":       "users.role",
+	"status":     "users.status",
+	"created_at": "users.created_at",
+	"updated_at": "users.updated_at",
+	"last_login": "users.last_login",
+}
+
+// UserFilter 用户列表的搜索、过滤与排序条件，零值表示不限制
+type UserFilter struct {
+	Keyword     string     // 在用户名、邮箱、姓名中搜索，多个词之间为“且”
+	Role        string     // 角色
+	Status      string     // 状态
+	CreatedFrom *time.Time // 注册时间下限（含）
+	CreatedTo   *time.Time // 注册时间上限（含）
+	Sort        []UserSort // 为空时有关键字按相关度排序，否则按注册时间倒序
+	Columns     []string   // 只查询这些列（见 models.UserInfoFields），为空时查询全部列
+}
+
+// UserSort 排序字段
+type UserSort struct {
+	Field string
+	Desc  bool
+}
+
+// ParseUserSort 解析排序参数，如 "role,-created_at"：逗号分隔，字段前加 "-" 表示倒序
+func ParseUserSort(value string) ([]UserSort, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+	var sorts []UserSort
+	seen := make(map[string]bool)
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		desc := strings.HasPrefix(field, "-")
+		field = strings.TrimPrefix(field, "-")
+
+		if _, ok := userSortColumns[field]; !ok && field != UserSortRelevance {
+			return nil, fmt.Errorf("不支持按 %q 排序，可选字段: %s", field, strings.Join(UserSortFields(), ", "))
+		}
+		if field == UserSortRelevance && desc {
+			return nil, fmt.Errorf("%s 不支持倒序", UserSortRelevance)
+		}
+		if seen[field] {
+			return nil, fmt.Errorf("排序字段 %s 重复", field)
+		}
+		seen[field] = true
+		sorts = append(sorts, UserSort{Field: field, Desc: desc})
+	}
+	if len(sorts) > maxUserSortFields {
+		return nil, fmt.Errorf("最多按 %d 个字段排序", maxUserSortFields)
+	}
+	return sorts, nil
+}
+
+// UserSortFields 允许排序的字段
+func UserSortFields() []string {
+	fields := []string{UserSortRelevance}
+	for field := range userSortColumns {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields[1:])
+	return fields
+}
+
+// SearchUsers 按条件搜索用户（分页）
+//
+// SQLite 启用 FTS5 时关键字通过 users_fts 全文索引匹配并按 bm25 排序（用户名权重最高）；
+// 否则（未启用 FTS5、其他数据库，或关键字中有少于 3 个字符的词，trigram 索引无法匹配）使用 LIKE 查询，
+// 相关度按完全匹配、用户名前缀、其他字段前缀的顺序近似。
+func SearchUsers(ctx context.Context, filter UserFilter, page, pageSize int) (_ []*models.UserInfo, _ int64, err error) {
+	ctx, span := tracing.Start(ctx, "services.SearchUsers",
+		attribute.Bool("search.keyword", filter.Keyword != ""),
+		attribute.Bool("search.fts", database.UserSearchFTS()),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	query, rank := userQuery(ctx, filter)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("获取搜索结果总数失败: %v", err)
+	}
+
+	var users []models.User
+	if err := query.Select(userColumns("users", filter.Columns)).
+		Clauses(userOrderBy(filter.Sort, rank)).
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&users).Error; err != nil {
+		return nil, 0, fmt.Errorf("搜索用户失败: %v", err)
+	}
+	return toUserInfos(users), total, nil
+}
+
+// SearchUsersByCursor 按条件搜索用户（游标分页），按注册时间排序：filter.Sort 为空或 -created_at 时倒序，created_at 时正序
+//
+// 返回下一页与上一页的游标，没有时为 nil。
+func SearchUsersByCursor(ctx context.Context, filter UserFilter, cur *pagination.Cursor, limit int) (_ []*models.UserInfo, next, prev *pagination.Cursor, err error) {
+	ctx, span := tracing.Start(ctx, "services.SearchUsersByCursor",
+		attribute.Bool("search.keyword", filter.Keyword != ""),
+		attribute.Bool("page.cursor", cur != nil),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	if !CursorSortable(filter.Sort) {
+		return nil, nil, nil, fmt.Errorf("游标分页只支持按 created_at 排序")
+	}
+	desc := len(filter.Sort) == 0 || filter.Sort[0].Desc
+
+	query, _ := userQuery(ctx, filter)
+	var users []models.User
+	if err := pagination.Keyset(query.Select(userColumns("users", filter.Columns)), "users", cur, desc, limit).
+		Find(&users).Error; err != nil {
+		return nil, nil, nil, fmt.Errorf("搜索用户失败: %v", err)
+	}
+	users, next, prev = pagination.Trim(users, cur, limit, func(u models.User) (time.Time, uint) {
+		return u.CreatedAt, u.ID
+	})
+	return toUserInfos(users), next, prev, nil
+}
+
+// CursorSortable 排序条件能否使用游标分页：只能按注册时间排序
+func CursorSortable(sorts []UserSort) bool {
+	return len(sorts) == 0 || (len(sorts) == 1 && sorts[0].Field == "created_at")
+}
+
+// userQuery 按过滤条件构造查询，返回关键字的相关度表达式（没有关键字时为 nil）
+func userQuery(ctx context.Context, filter UserFilter) (*gorm.DB, *clause.Expr) {
+	query := database.GetDB().WithContext(ctx).Model(&models.User{})
+	if filter.Role != "" {
+		query = query.Where("users.role = ?", filter.Role)
+	}
+	if filter.Status != "" {
+		query = query.Where("users.status = ?", filter.Status)
+	}
+	if filter.CreatedFrom != nil {
+		query = query.Where("users.created_at >= ?", *filter.CreatedFrom)
+	}
+	if filter.CreatedTo != nil {
+		query = query.Where("users.created_at <= ?", *filter.CreatedTo)
+	}
+	var rank *clause.Expr
+	if terms := strings.Fields(filter.Keyword); len(terms) > 0 {
+		query, rank = matchUsers(query, terms)
+	}
+	return query.Session(&gorm.Session{}), rank
+}
+
+// userColumns 要查询的列：columns 为空时为全部列；否则总是包含分页使用的 id 与 created_at
+func userColumns(table string, columns []string) []string {
+	if len(columns) == 0 {
+		return []string{table + ".*"}
+	}
+	selected := []string{table + ".id", table + ".created_at"}
+	for _, column := range columns {
+		if column != "id" && column != "created_at" {
+			selected = append(selected, table+"."+column)
+		}
+	}
+	return selected
+}
+
+// toUserInfos 转换为用户信息列表（不包含敏感信息）
+func toUserInfos(users []models.User) []*models.UserInfo {
+	userInfos := make([]*models.UserInfo, len(users))
+	for i := range users {
+		userInfos[i] = toUserInfo(&users[i])
+	}
+	return userInfos
+}
+
+// toUserInfo 转换为用户信息（不包含敏感信息）
+func toUserInfo(user *models.User) *models.UserInfo {
+	return &models.UserInfo{
+		ID:        user.ID,
+		Username:  user.Username,
+		Email:     user.Email,
+		FirstName: user.FirstName,
+		LastName:  user.LastName,
+		Avatar:    user.AvatarURL(),
+		Role:      user.Role,
+		Status:    user.Status,
+		CreatedAt: user.CreatedAt,
+		UpdatedAt: user.UpdatedAt,
+	}
+}
+
+// matchUsers 添加关键字条件，返回相关度表达式（值越小越相关）
+func matchUsers(query *gorm.DB, terms []string) (*gorm.DB, *clause.Expr) {
+	if database.UserSearchFTS() && ftsSearchable(terms) {
+		quoted := make([]string, len(terms))
+		for i, term := range terms {
+			// 每个词作为短语查询，避免 FTS5 查询语法（AND、*、列过滤等）被注入
+			quoted[i] = `"` + strings.ReplaceAll(term, `"`, `""`) + `"`
+		}
+		query = query.Joins("JOIN users_fts ON users_fts.rowid = users.id").
+			Where("users_fts MATCH ?", strings.Join(quoted, " "))
+		return query, &clause.Expr{SQL: "bm25(users_fts, 10.0, 5.0, 2.0, 2.0)"}
+	}
+
+	for _, term := range terms {
+		pattern := "%" + escapeLike(term) + "%"
+		query = query.Where(`(users.username LIKE ? ESCAPE '\' OR users.email LIKE ? ESCAPE '\' OR `+
+			`users.first_name LIKE ? ESCAPE '\' OR users.last_name LIKE ? ESCAPE '\')`,
+			pattern, pattern, pattern, pattern)
+	}
+	exact, prefix := escapeLike(terms[0]), escapeLike(terms[0])+"%"
+	return query, &clause.Expr{
+		SQL: `CASE WHEN users.username LIKE ? ESCAPE '\' OR users.email LIKE ? ESCAPE '\' THEN 0 ` +
+			`WHEN users.username LIKE ? ESCAPE '\' THEN 1 ` +
+			`WHEN users.email LIKE ? ESCAPE '\' OR users.first_name LIKE ? ESCAPE '\' OR users.last_name LIKE ? ESCAPE '\' THEN 2 ` +
+			`ELSE 3 END`,
+		Vars: []interface{}{exact, exact, prefix, prefix, prefix, prefix},
+	}
+}
+
+// ftsSearchable trigram 分词的索引只能匹配不少于 3 个字符的词
+func ftsSearchable(terms []string) bool {
+	for _, term := range terms {
+		if utf8.RuneCountInString(term) < 3 {
+			return false
+		}
+	}
+	return true
+}
+
+// escapeLike 转义 LIKE 模式中的通配符，关键字按字面匹配
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// userOrderBy 生成排序子句，最后按 ID 倒序保证分页顺序稳定
+func userOrderBy(sorts []UserSort, rank *clause.Expr) clause.OrderBy {
+	if len(sorts) == 0 {
+		if rank != nil {
+			sorts = []UserSort{{Field: UserSortRelevance}}
+		} else {
+			sorts = []UserSort{{Field: "created_at", Desc: true}}
+		}
+	}
+
+	var parts []string
+	var vars []interface{}
+	sortedByID := false
+	for _, s := range sorts {
+		if s.Field == UserSortRelevance {
+			if rank != nil {
+				parts = append(parts, rank.SQL)
+				vars = append(vars, rank.Vars...)
+			}
+			continue
+		}
+		part := userSortColumns[s.Field]
+		if s.Desc {
+			part += " DESC"
+		}
+		parts = append(parts, part)
+		sortedByID = sortedByID || s.Field == "id"
+	}
+	if !sortedByID {
+		parts = append(parts, "users.id DESC")
+	}
+	return clause.OrderBy{Expression: clause.Expr{SQL: strings.Join(parts, ", "), Vars: vars}}
+}
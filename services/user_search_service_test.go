@@ -0,0 +1,57 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseUserSort(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    []UserSort
+		wantErr bool
+	}{
+		{value: "", want: nil},
+		{value: "-created_at", want: []UserSort{{Field: "created_at", Desc: true}}},
+		{value: "role, username", want: []UserSort{{Field: "role"}, {Field: "username"}}},
+		{value: "relevance,-id", want: []UserSort{{Field: "relevance"}, {Field: "id", Desc: true}}},
+		{value: "password", wantErr: true},
+		{value: "users.id", wantErr: true},
+		{value: "-relevance", wantErr: true},
+		{value: "id,-id", wantErr: true},
+		{value: "id,role,status,email", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseUserSort(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseUserSort(%q) err = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseUserSort(%q) = %+v, want %+v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestEscapeLike(t *testing.T) {
+	if got, want := escapeLike(`50%_off\`), `50\%\_off\\`; got != want {
+		t.Errorf("escapeLike = %q, want %q", got, want)
+	}
+}
+
+func TestParseUserExpand(t *testing.T) {
+	expand, err := ParseUserExpand("storage, storage", "admin")
+	if err != nil || !reflect.DeepEqual(expand, []string{"storage"}) {
+		t.Errorf("ParseUserExpand = %v, %v, want [storage]", expand, err)
+	}
+	if _, err := ParseUserExpand("storage", "user"); !errors.Is(err, ErrExpandForbidden) {
+		t.Errorf("普通用户展开 storage: err = %v, want ErrExpandForbidden", err)
+	}
+	if _, err := ParseUserExpand("password", "admin"); err == nil || errors.Is(err, ErrExpandForbidden) {
+		t.Errorf("展开 password: err = %v, want 不支持", err)
+	}
+	if expand, err := ParseUserExpand("", "user"); err != nil || expand != nil {
+		t.Errorf("ParseUserExpand(\"\") = %v, %v", expand, err)
+	}
+}
@@ -1,21 +1,29 @@
 package services
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"time"
 
 	"iris-cn-sample-project/database"
+	"iris-cn-sample-project/logging"
+	"iris-cn-sample-project/metrics"
 	"iris-cn-sample-project/models"
+	"iris-cn-sample-project/tracing"
 	"iris-cn-sample-project/utils"
 
+	"go.opentelemetry.io/otel/attribute"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
 // CreateUser 创建用户服务
-func CreateUser(req *models.RegisterRequest) (*models.User, error) {
-	db := database.GetDB()
+func CreateUser(ctx context.Context, req *models.RegisterRequest) (_ *models.User, err error) {
+	ctx, span := tracing.Start(ctx, "services.CreateUser")
+	defer func() { tracing.End(span, err) }()
+
+	db := database.GetDB().WithContext(ctx)
 
 	// 检查用户名是否已存在
 	var existingUser models.User
@@ -49,41 +57,62 @@ func CreateUser(req *models.RegisterRequest) (*models.User, error) {
 		return nil, fmt.Errorf("创建用户失败: %v", err)
 	}
 
+	metrics.RegistrationsTotal.Inc()
+	logging.L().InfoContext(ctx, "新用户注册", "user_id", user.ID, "username", user.Username)
+
 	return &user, nil
 }
 
 // LoginUser 用户登录服务
-func LoginUser(username, password string) (*models.User, error) {
-	db := database.GetDB()
+func LoginUser(ctx context.Context, username, password string) (_ *models.User, err error) {
+	ctx, span := tracing.Start(ctx, "services.LoginUser")
+	defer func() { tracing.End(span, err) }()
+
+	db := database.GetDB().WithContext(ctx)
 
 	// 查找用户（支持用户名或邮箱登录）
 	var user models.User
 	if err := db.Where("username = ? OR email = ?", username, username).First(&user).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
+			metrics.LoginFailuresTotal.WithLabelValues(metrics.LoginFailureUserNotFound).Inc()
+			logging.L().WarnContext(ctx, "登录失败", "username", username, "reason", metrics.LoginFailureUserNotFound)
 			return nil, errors.New("用户不存在")
 		}
+		metrics.LoginFailuresTotal.WithLabelValues(metrics.LoginFailureInternalError).Inc()
+		logging.L().ErrorContext(ctx, "登录查询用户失败", "username", username, "error", err)
 		return nil, fmt.Errorf("查询用户失败: %v", err)
 	}
 
 	// 检查用户状态
 	if !user.IsActive() {
+		metrics.LoginFailuresTotal.WithLabelValues(metrics.LoginFailureUserInactive).Inc()
+		logging.L().WarnContext(ctx, "登录失败", "user_id", user.ID, "reason", metrics.LoginFailureUserInactive)
 		return nil, errors.New("用户账户已被禁用")
 	}
 
 	// 验证密码
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+		metrics.LoginFailuresTotal.WithLabelValues(metrics.LoginFailureBadPassword).Inc()
+		logging.L().WarnContext(ctx, "登录失败", "user_id", user.ID, "reason", metrics.LoginFailureBadPassword)
 		return nil, errors.New("密码错误")
 	}
 
+	metrics.LoginsTotal.Inc()
+	logging.L().InfoContext(ctx, "用户登录", "user_id", user.ID)
+
 	return &user, nil
 }
 
-// GetUserByID 根据ID获取用户
-func GetUserByID(userID uint) (*models.UserInfo, error) {
-	db := database.GetDB()
+// GetUserByID 根据ID获取用户，columns 不为空时只查询这些列（见 models.UserInfoFields）
+func GetUserByID(ctx context.Context, userID uint, columns ...string) (_ *models.UserInfo, err error) {
+	ctx, span := tracing.Start(ctx, "services.GetUserByID", attribute.Int64("user.id", int64(userID)))
+	defer func() { tracing.End(span, err) }()
+
+	db := database.GetDB().WithContext(ctx)
 
 	var user models.User
-	if err := db.Where("id = ? AND status = ?", userID, "active").First(&user).Error; err != nil {
+	if err := db.Select(userColumns("users", columns)).
+		Where("id = ? AND status = ?", userID, "active").First(&user).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("用户不存在")
 		}
@@ -97,7 +126,7 @@ func GetUserByID(userID uint) (*models.UserInfo, error) {
 		Email:     user.Email,
 		FirstName: user.FirstName,
 		LastName:  user.LastName,
-		Avatar:    user.Avatar,
+		Avatar:    user.AvatarURL(),
 		Role:      user.Role,
 		Status:    user.Status,
 		CreatedAt: user.CreatedAt,
@@ -108,8 +137,11 @@ func GetUserByID(userID uint) (*models.UserInfo, error) {
 }
 
 // GetUsers 获取用户列表（分页）
-func GetUsers(page, pageSize int) ([]*models.UserInfo, int64, error) {
-	db := database.GetDB()
+func GetUsers(ctx context.Context, page, pageSize int) (_ []*models.UserInfo, _ int64, err error) {
+	ctx, span := tracing.Start(ctx, "services.GetUsers")
+	defer func() { tracing.End(span, err) }()
+
+	db := database.GetDB().WithContext(ctx)
 
 	var users []models.User
 	var total int64
@@ -140,7 +172,7 @@ func GetUsers(page, pageSize int) ([]*models.UserInfo, int64, error) {
 			Email:     user.Email,
 			FirstName: user.FirstName,
 			LastName:  user.LastName,
-			Avatar:    user.Avatar,
+			Avatar:    user.AvatarURL(),
 			Role:      user.Role,
 			Status:    user.Status,
 			CreatedAt: user.CreatedAt,
@@ -152,8 +184,11 @@ func GetUsers(page, pageSize int) ([]*models.UserInfo, int64, error) {
 }
 
 // UpdateUser 更新用户信息
-func UpdateUser(userID uint, req *models.UpdateUserRequest) (*models.UserInfo, error) {
-	db := database.GetDB()
+func UpdateUser(ctx context.Context, userID uint, req *models.UpdateUserRequest) (_ *models.UserInfo, err error) {
+	ctx, span := tracing.Start(ctx, "services.UpdateUser", attribute.Int64("user.id", int64(userID)))
+	defer func() { tracing.End(span, err) }()
+
+	db := database.GetDB().WithContext(ctx)
 
 	// 查找用户
 	var user models.User
@@ -171,9 +206,6 @@ func UpdateUser(userID uint, req *models.UpdateUserRequest) (*models.UserInfo, e
 	if req.LastName != "" {
 		user.LastName = req.LastName
 	}
-	if req.Avatar != "" {
-		user.Avatar = req.Avatar
-	}
 	if req.Role != "" {
 		user.Role = req.Role
 	}
@@ -193,7 +225,7 @@ func UpdateUser(userID uint, req *models.UpdateUserRequest) (*models.UserInfo, e
 		Email:     user.Email,
 		FirstName: user.FirstName,
 		LastName:  user.LastName,
-		Avatar:    user.Avatar,
+		Avatar:    user.AvatarURL(),
 		Role:      user.Role,
 		Status:    user.Status,
 		CreatedAt: user.CreatedAt,
@@ -204,23 +236,32 @@ func UpdateUser(userID uint, req *models.UpdateUserRequest) (*models.UserInfo, e
 }
 
 // DeleteUser 删除用户（软删除）
-func DeleteUser(userID uint) error {
-	db := database.GetDB()
+func DeleteUser(ctx context.Context, userID uint) (err error) {
+	ctx, span := tracing.Start(ctx, "services.DeleteUser", attribute.Int64("user.id", int64(userID)))
+	defer func() { tracing.End(span, err) }()
+
+	db := database.GetDB().WithContext(ctx)
 
 	// 软删除用户
 	if err := db.Delete(&models.User{}, userID).Error; err != nil {
 		return fmt.Errorf("删除用户失败: %v", err)
 	}
 
+	logging.L().InfoContext(ctx, "用户已删除", "user_id", userID)
+
 	return nil
 }
 
 // UpdateUserLastLogin 更新用户最后登录时间
-func UpdateUserLastLogin(userID uint) error {
-	db := database.GetDB()
+func UpdateUserLastLogin(ctx context.Context, userID uint) (err error) {
+	ctx, span := tracing.Start(ctx, "services.UpdateUserLastLogin", attribute.Int64("user.id", int64(userID)))
+	defer func() { tracing.End(span, err) }()
+
+	db := database.GetDB().WithContext(ctx)
 	now := time.Now()
 
 	if err := db.Model(&models.User{}).Where("id = ?", userID).Update("last_login", &now).Error; err != nil {
+		logging.L().ErrorContext(ctx, "更新最后登录时间失败", "user_id", userID, "error", err)
 		return fmt.Errorf("更新最后登录时间失败: %v", err)
 	}
 
@@ -228,8 +269,11 @@ func UpdateUserLastLogin(userID uint) error {
 }
 
 // ChangeUserPassword 修改用户密码
-func ChangeUserPassword(userID uint, oldPassword, newPassword string) error {
-	db := database.GetDB()
+func ChangeUserPassword(ctx context.Context, userID uint, oldPassword, newPassword string) (err error) {
+	ctx, span := tracing.Start(ctx, "services.ChangeUserPassword", attribute.Int64("user.id", int64(userID)))
+	defer func() { tracing.End(span, err) }()
+
+	db := database.GetDB().WithContext(ctx)
 
 	// 查找用户
 	var user models.User
@@ -253,12 +297,17 @@ func ChangeUserPassword(userID uint, oldPassword, newPassword string) error {
 		return fmt.Errorf("更新密码失败: %v", err)
 	}
 
+	logging.L().InfoContext(ctx, "用户修改密码", "user_id", userID)
+
 	return nil
 }
 
 // GetUserByUsername 根据用户名获取用户
-func GetUserByUsername(username string) (*models.User, error) {
-	db := database.GetDB()
+func GetUserByUsername(ctx context.Context, username string) (_ *models.User, err error) {
+	ctx, span := tracing.Start(ctx, "services.GetUserByUsername")
+	defer func() { tracing.End(span, err) }()
+
+	db := database.GetDB().WithContext(ctx)
 
 	var user models.User
 	if err := db.Where("username = ?", username).First(&user).Error; err != nil {
@@ -272,8 +321,11 @@ func GetUserByUsername(username string) (*models.User, error) {
 }
 
 // GetUserByEmail 根据邮箱获取用户
-func GetUserByEmail(email string) (*models.User, error) {
-	db := database.GetDB()
+func GetUserByEmail(ctx context.Context, email string) (_ *models.User, err error) {
+	ctx, span := tracing.Start(ctx, "services.GetUserByEmail")
+	defer func() { tracing.End(span, err) }()
+
+	db := database.GetDB().WithContext(ctx)
 
 	var user models.User
 	if err := db.Where("email = ?", email).First(&user).Error; err != nil {
@@ -286,65 +338,17 @@ func GetUserByEmail(email string) (*models.User, error) {
 	return &user, nil
 }
 
-// SearchUsers 搜索用户
-func SearchUsers(keyword string, page, pageSize int) ([]*models.UserInfo, int64, error) {
-	db := database.GetDB()
-
-	var users []models.User
-	var total int64
-
-	// 构建搜索条件
-	searchPattern := "%" + keyword + "%"
-
-	// 获取总数
-	if err := db.Model(&models.User{}).
-		Where("(username LIKE ? OR email LIKE ? OR first_name LIKE ? OR last_name LIKE ?) AND status = ?",
-			searchPattern, searchPattern, searchPattern, searchPattern, "active").
-		Count(&total).Error; err != nil {
-		return nil, 0, fmt.Errorf("获取搜索结果总数失败: %v", err)
-	}
-
-	// 计算偏移量
-	offset := (page - 1) * pageSize
-
-	// 搜索用户
-	if err := db.Where("(username LIKE ? OR email LIKE ? OR first_name LIKE ? OR last_name LIKE ?) AND status = ?",
-		searchPattern, searchPattern, searchPattern, searchPattern, "active").
-		Offset(offset).
-		Limit(pageSize).
-		Order("created_at DESC").
-		Find(&users).Error; err != nil {
-		return nil, 0, fmt.Errorf("搜索用户失败: %v", err)
-	}
-
-	// 转换为用户信息列表
-	userInfos := make([]*models.UserInfo, len(users))
-	for i, user := range users {
-		userInfos[i] = &models.UserInfo{
-			ID:        user.ID,
-			Username:  user.Username,
-			Email:     user.Email,
-			FirstName: user.FirstName,
-			LastName:  user.LastName,
-			Avatar:    user.Avatar,
-			Role:      user.Role,
-			Status:    user.Status,
-			CreatedAt: user.CreatedAt,
-			UpdatedAt: user.UpdatedAt,
-		}
-	}
-
-	return userInfos, total, nil
-}
-
 // ValidateUserCredentials 验证用户凭据
-func ValidateUserCredentials(username, password string) (*models.User, error) {
-	return LoginUser(username, password)
+func ValidateUserCredentials(ctx context.Context, username, password string) (*models.User, error) {
+	return LoginUser(ctx, username, password)
 }
 
 // IsUserExists 检查用户是否存在
-func IsUserExists(username, email string) (bool, error) {
-	db := database.GetDB()
+func IsUserExists(ctx context.Context, username, email string) (_ bool, err error) {
+	ctx, span := tracing.Start(ctx, "services.IsUserExists")
+	defer func() { tracing.End(span, err) }()
+
+	db := database.GetDB().WithContext(ctx)
 
 	var count int64
 	if err := db.Model(&models.User{}).
@@ -357,8 +361,11 @@ func IsUserExists(username, email string) (bool, error) {
 }
 
 // GetUserStats 获取用户统计信息
-func GetUserStats() (map[string]interface{}, error) {
-	db := database.GetDB()
+func GetUserStats(ctx context.Context) (_ map[string]interface{}, err error) {
+	ctx, span := tracing.Start(ctx, "services.GetUserStats")
+	defer func() { tracing.End(span, err) }()
+
+	db := database.GetDB().WithContext(ctx)
 
 	stats := make(map[string]interface{})
 
@@ -0,0 +1,204 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Local 本地文件系统存储
+type Local struct {
+	root    string
+	baseURL string
+	signer  *Signer
+}
+
+// NewLocal 创建本地文件系统存储，文件保存在 root 目录下，通过 baseURL 对外访问；signer 为 nil 时不提供下载地址
+func NewLocal(root, baseURL string, signer *Signer) (*Local, error) {
+	if root == "" {
+		return nil, errors.New("本地存储目录不能为空")
+	}
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, fmt.Errorf("创建本地存储目录失败: %v", err)
+	}
+	return &Local{root: root, baseURL: strings.TrimRight(baseURL, "/"), signer: signer}, nil
+}
+
+// path 返回 key 对应的文件路径
+func (l *Local) path(key string) (string, error) {
+	if !ValidKey(key) {
+		return "", ErrInvalidKey
+	}
+	return filepath.Join(l.root, filepath.FromSlash(key)), nil
+}
+
+// Put 先写入同目录下的临时文件再重命名，读取失败或中途取消时不会留下不完整的文件
+func (l *Local) Put(ctx context.Context, key string, r io.Reader, opts PutOptions) (ObjectInfo, error) {
+	dst, err := l.path(key)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return ObjectInfo{}, fmt.Errorf("创建目录失败: %v", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(dst), ".upload-*")
+	if err != nil {
+		return ObjectInfo{}, fmt.Errorf("创建临时文件失败: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	size, err := io.Copy(tmp, contextReader{ctx: ctx, r: r})
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return ObjectInfo{}, fmt.Errorf("写入文件失败: %v", err)
+	}
+	if opts.Size >= 0 && size != opts.Size {
+		return ObjectInfo{}, fmt.Errorf("写入的大小 %d 与声明的大小 %d 不一致", size, opts.Size)
+	}
+
+	if err := os.Rename(tmp.Name(), dst); err != nil {
+		return ObjectInfo{}, fmt.Errorf("保存文件失败: %v", err)
+	}
+	return l.Stat(ctx, key)
+}
+
+// Get 打开文件
+func (l *Local) Get(ctx context.Context, key string) (io.ReadCloser, ObjectInfo, error) {
+	p, err := l.path(key)
+	if err != nil {
+		return nil, ObjectInfo{}, err
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, ObjectInfo{}, notFound(err)
+	}
+	info, err := l.Stat(ctx, key)
+	if err != nil {
+		f.Close()
+		return nil, ObjectInfo{}, err
+	}
+	return f, info, nil
+}
+
+// Delete 删除文件，并删除因此变空的上级目录
+func (l *Local) Delete(ctx context.Context, key string) error {
+	p, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("删除文件失败: %v", err)
+	}
+	// 目录非空时 os.Remove 会失败，此时停止
+	for dir := filepath.Dir(p); dir != filepath.Clean(l.root); dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil {
+			break
+		}
+	}
+	return nil
+}
+
+// Stat 获取文件信息，MIME 类型由扩展名推断
+func (l *Local) Stat(ctx context.Context, key string) (ObjectInfo, error) {
+	p, err := l.path(key)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	fi, err := os.Stat(p)
+	if err != nil {
+		return ObjectInfo{}, notFound(err)
+	}
+	if fi.IsDir() {
+		return ObjectInfo{}, ErrNotFound
+	}
+	return ObjectInfo{
+		Key:          key,
+		Size:         fi.Size(),
+		ContentType:  mime.TypeByExtension(path.Ext(key)),
+		LastModified: fi.ModTime(),
+	}, nil
+}
+
+// List 遍历目录列出对象，跳过写入中的临时文件与不是合法 key 的文件
+func (l *Local) List(ctx context.Context, prefix string, fn func(ObjectInfo) error) error {
+	root := filepath.Clean(l.root)
+	return filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if d.IsDir() || !d.Type().IsRegular() || strings.HasPrefix(d.Name(), ".upload-") {
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) || !ValidKey(key) {
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil {
+			// 遍历期间被删除
+			return nil
+		}
+		return fn(ObjectInfo{
+			Key:          key,
+			Size:         fi.Size(),
+			ContentType:  mime.TypeByExtension(path.Ext(key)),
+			LastModified: fi.ModTime(),
+		})
+	})
+}
+
+// SignedURL 生成带 HMAC 签名与过期时间的下载地址
+func (l *Local) SignedURL(ctx context.Context, key string, expires time.Duration) (string, error) {
+	if !ValidKey(key) {
+		return "", ErrInvalidKey
+	}
+	if l.signer == nil {
+		return "", errors.New("该存储未配置签名密钥，不提供下载地址")
+	}
+	exp, sig := l.signer.Sign(key, time.Now().Add(expires))
+	query := url.Values{"expires": {exp}, "signature": {sig}}
+	return l.baseURL + "/" + key + "?" + query.Encode(), nil
+}
+
+// notFound 将文件不存在的错误转换为 ErrNotFound
+func notFound(err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// contextReader 在 ctx 取消后停止读取
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
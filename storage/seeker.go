@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// RangeReader 支持按范围读取对象的存储后端
+type RangeReader interface {
+	// GetRange 读取对象从 offset 开始的 length 个字节；对象不存在时返回 ErrNotFound
+	GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error)
+}
+
+// Open 打开对象用于随机读取（如响应 Range 请求）
+//
+// 后端返回的内容本身可以定位时（本地文件）直接使用；否则在定位后按需通过 RangeReader 重新发起范围读取，
+// 顺序读取整个对象时只有一次请求。
+func Open(ctx context.Context, s Storage, key string) (io.ReadSeekCloser, ObjectInfo, error) {
+	rc, info, err := s.Get(ctx, key)
+	if err != nil {
+		return nil, ObjectInfo{}, err
+	}
+	if rs, ok := rc.(io.ReadSeekCloser); ok {
+		return rs, info, nil
+	}
+	rr, ok := s.(RangeReader)
+	if !ok || info.Size < 0 {
+		rc.Close()
+		return nil, ObjectInfo{}, errors.New("存储后端不支持按范围读取")
+	}
+	return &rangeSeeker{ctx: ctx, s: rr, key: key, size: info.Size, body: rc}, info, nil
+}
+
+// rangeSeeker 基于范围读取实现的 io.ReadSeekCloser
+type rangeSeeker struct {
+	ctx  context.Context
+	s    RangeReader
+	key  string
+	size int64
+	pos  int64
+	body io.ReadCloser // 从 pos 开始的内容，定位后关闭，下次读取时重新打开
+}
+
+func (r *rangeSeeker) Read(p []byte) (int, error) {
+	if r.pos >= r.size {
+		return 0, io.EOF
+	}
+	if r.body == nil {
+		body, err := r.s.GetRange(r.ctx, r.key, r.pos, r.size-r.pos)
+		if err != nil {
+			return 0, err
+		}
+		r.body = body
+	}
+	n, err := r.body.Read(p)
+	r.pos += int64(n)
+	return n, err
+}
+
+func (r *rangeSeeker) Seek(offset int64, whence int) (int64, error) {
+	var pos int64
+	switch whence {
+	case io.SeekStart:
+		pos = offset
+	case io.SeekCurrent:
+		pos = r.pos + offset
+	case io.SeekEnd:
+		pos = r.size + offset
+	default:
+		return 0, fmt.Errorf("无效的 whence: %d", whence)
+	}
+	if pos < 0 {
+		return 0, errors.New("定位到负数位置")
+	}
+	if pos != r.pos {
+		r.Close()
+		r.pos = pos
+	}
+	return pos, nil
+}
+
+func (r *rangeSeeker) Close() error {
+	if r.body == nil {
+		return nil
+	}
+	err := r.body.Close()
+	r.body = nil
+	return err
+}
@@ -0,0 +1,170 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"iris-cn-sample-project/config"
+	"iris-cn-sample-project/requestid"
+)
+
+// ErrNotFound 对象不存在
+var ErrNotFound = errors.New("对象不存在")
+
+// ErrInvalidKey 对象 key 不合法
+var ErrInvalidKey = errors.New("对象 key 不合法")
+
+// maxKeyLength 对象 key 的最大长度
+const maxKeyLength = 512
+
+// Storage 文件存储后端
+//
+// key 由 NewKey 生成（或经 ValidKey 校验），只包含字母、数字与 ._-/，不会包含客户端提供的文件名。
+type Storage interface {
+	// Put 写入对象，size 未知时传 -1；同名对象会被覆盖
+	Put(ctx context.Context, key string, r io.Reader, opts PutOptions) (ObjectInfo, error)
+	// Get 读取对象，调用方负责关闭返回的 ReadCloser；对象不存在时返回 ErrNotFound
+	Get(ctx context.Context, key string) (io.ReadCloser, ObjectInfo, error)
+	// Delete 删除对象，对象不存在时不返回错误
+	Delete(ctx context.Context, key string) error
+	// Stat 获取对象信息，对象不存在时返回 ErrNotFound
+	Stat(ctx context.Context, key string) (ObjectInfo, error)
+	// SignedURL 生成在 expires 内有效的下载地址
+	SignedURL(ctx context.Context, key string, expires time.Duration) (string, error)
+}
+
+// Lister 支持列出对象的存储后端
+type Lister interface {
+	// List 列出 key 以 prefix 开头的所有对象，fn 返回错误时停止并返回该错误
+	List(ctx context.Context, prefix string, fn func(ObjectInfo) error) error
+}
+
+// PutOptions 写入选项
+type PutOptions struct {
+	Size        int64  // 对象大小，未知时为 -1
+	ContentType string // MIME 类型
+}
+
+// ObjectInfo 对象信息
+type ObjectInfo struct {
+	Key          string
+	Size         int64
+	ContentType  string
+	ETag         string
+	LastModified time.Time
+}
+
+// New 根据存储配置创建存储后端，localDir 为本地存储的根目录
+func New(cfg config.StorageConfig, localDir string) (Storage, error) {
+	switch cfg.Driver {
+	case "local", "":
+		return NewLocal(localDir, cfg.BaseURL, NewSigner(cfg.SigningKey))
+	case "s3":
+		return NewS3(cfg.S3)
+	default:
+		return nil, fmt.Errorf("不支持的存储后端: %s", cfg.Driver)
+	}
+}
+
+// current 全局存储后端
+var current Storage
+
+// Init 根据存储配置初始化全局存储后端
+func Init(cfg config.StorageConfig, localDir string) error {
+	s, err := New(cfg, localDir)
+	if err != nil {
+		return err
+	}
+	current = s
+	return nil
+}
+
+// Default 获取全局存储后端
+func Default() Storage {
+	return current
+}
+
+// SetDefault 替换全局存储后端（主要用于测试）
+func SetDefault(s Storage) {
+	current = s
+}
+
+// NewKey 生成新的对象 key：按日期分目录，文件名为 UUIDv7（按时间有序）加扩展名
+//
+// ext 应来自允许的扩展名列表，不合法的扩展名会被丢弃。
+func NewKey(ext string) string {
+	ext = strings.ToLower(ext)
+	if ext != "" && (!strings.HasPrefix(ext, ".") || len(ext) > 10 || !isKeyName(ext[1:])) {
+		ext = ""
+	}
+	return time.Now().UTC().Format("2006/01/02") + "/" + requestid.NewUUIDv7() + ext
+}
+
+// ValidKey 校验对象 key：相对路径、不包含 . 或 .. 段，只包含字母、数字与 ._-/
+func ValidKey(key string) bool {
+	if key == "" || len(key) > maxKeyLength || path.Clean(key) != key || strings.HasPrefix(key, "/") {
+		return false
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "." || segment == ".." || !isKeyName(segment) {
+			return false
+		}
+	}
+	return true
+}
+
+// isKeyName 判断 key 的一段是否只包含安全字符
+func isKeyName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '.' || c == '_' || c == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// Signer 为下载地址签名（HMAC-SHA256），用于本地存储等不支持预签名的后端
+type Signer struct {
+	key []byte
+}
+
+// NewSigner 创建签名器，签名密钥经过派生，与同一密钥的其他用途（如 JWT）相互隔离
+func NewSigner(secret string) *Signer {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("storage-signed-url"))
+	return &Signer{key: mac.Sum(nil)}
+}
+
+// Sign 对 key 与过期时间签名，返回 expires 与 signature 查询参数
+func (s *Signer) Sign(key string, expiresAt time.Time) (expires, signature string) {
+	expires = strconv.FormatInt(expiresAt.Unix(), 10)
+	return expires, s.signature(key, expires)
+}
+
+// Verify 校验签名与过期时间
+func (s *Signer) Verify(key, expires, signature string, now time.Time) bool {
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || now.Unix() > unix {
+		return false
+	}
+	return hmac.Equal([]byte(s.signature(key, expires)), []byte(signature))
+}
+
+// signature 计算签名
+func (s *Signer) signature(key, expires string) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(key + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
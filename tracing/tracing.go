@@ -0,0 +1,192 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"iris-cn-sample-project/config"
+	"iris-cn-sample-project/version"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName 追踪器名称
+const instrumentationName = "iris-cn-sample-project"
+
+// provider 当前安装的 TracerProvider（未启用追踪时为 nil，使用 otel 默认的空实现）
+var (
+	providerMu sync.Mutex
+	provider   *sdktrace.TracerProvider
+)
+
+func init() {
+	// 即使未启用追踪也解析上游的 traceparent，使日志中的 trace_id 与网关保持一致
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+}
+
+// Init 根据链路追踪配置初始化全局 TracerProvider
+func Init(cfg config.TracingConfig) error {
+	if !cfg.Enabled {
+		return nil
+	}
+
+	exporter, err := newExporter(cfg)
+	if err != nil {
+		return err
+	}
+	if exporter == nil {
+		return nil
+	}
+
+	// OTLP 批量导出以减少网络请求，标准输出同步导出便于调试
+	if _, ok := exporter.(*stdouttrace.Exporter); ok {
+		install(cfg, sdktrace.NewSimpleSpanProcessor(exporter))
+	} else {
+		install(cfg, sdktrace.NewBatchSpanProcessor(exporter))
+	}
+	return nil
+}
+
+// InitWithExporter 使用指定的导出器初始化全局 TracerProvider，span 结束时同步导出
+//
+// 测试中可以传入 tracetest.NewInMemoryExporter()，便于对生成的 span 进行断言。
+func InitWithExporter(cfg config.TracingConfig, exporter sdktrace.SpanExporter) {
+	install(cfg, sdktrace.NewSimpleSpanProcessor(exporter))
+}
+
+// install 创建并安装全局 TracerProvider，替换之前安装的实例
+func install(cfg config.TracingConfig, processor sdktrace.SpanProcessor) {
+	serviceName := cfg.ServiceName
+	if serviceName == "" {
+		serviceName = instrumentationName
+	}
+
+	res := resource.NewSchemaless(
+		attribute.String("service.name", serviceName),
+		attribute.String("service.version", version.Version),
+	)
+
+	ratio := cfg.SampleRatio
+	if ratio <= 0 || ratio > 1 {
+		ratio = 1
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+		sdktrace.WithSpanProcessor(processor),
+	)
+
+	providerMu.Lock()
+	previous := provider
+	provider = tp
+	providerMu.Unlock()
+
+	otel.SetTracerProvider(tp)
+
+	if previous != nil {
+		previous.Shutdown(context.Background())
+	}
+}
+
+// Shutdown 导出剩余的 span 并关闭 TracerProvider
+func Shutdown(ctx context.Context) error {
+	providerMu.Lock()
+	tp := provider
+	provider = nil
+	providerMu.Unlock()
+
+	if tp == nil {
+		return nil
+	}
+	return tp.Shutdown(ctx)
+}
+
+// Tracer 获取应用的追踪器
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Start 创建子 span，调用方负责结束 span
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return Tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End 结束 span，err 不为 nil 时记录错误并将 span 标记为失败
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// TraceID 获取上下文中的 trace ID，不存在时返回空字符串
+func TraceID(ctx context.Context) string {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.HasTraceID() {
+		return ""
+	}
+	return sc.TraceID().String()
+}
+
+// SpanID 获取上下文中的 span ID，不存在时返回空字符串
+func SpanID(ctx context.Context) string {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.HasSpanID() {
+		return ""
+	}
+	return sc.SpanID().String()
+}
+
+// newExporter 根据配置创建导出器，none 返回 nil
+func newExporter(cfg config.TracingConfig) (sdktrace.SpanExporter, error) {
+	switch strings.ToLower(cfg.Exporter) {
+	case "otlp", "":
+		opts := []otlptracehttp.Option{
+			otlptracehttp.WithEndpoint(cfg.Endpoint),
+		}
+		if cfg.URLPath != "" {
+			opts = append(opts, otlptracehttp.WithURLPath(cfg.URLPath))
+		}
+		if cfg.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		if len(cfg.Headers) > 0 {
+			opts = append(opts, otlptracehttp.WithHeaders(cfg.Headers))
+		}
+		// 创建时不会连接接收端，导出失败只会在后台记录日志
+		exporter, err := otlptracehttp.New(context.Background(), opts...)
+		if err != nil {
+			return nil, fmt.Errorf("创建 OTLP 导出器失败: %v", err)
+		}
+		return exporter, nil
+	case "stdout":
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+		if err != nil {
+			return nil, fmt.Errorf("创建标准输出导出器失败: %v", err)
+		}
+		return exporter, nil
+	case "none":
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("不支持的链路追踪导出器: %s", cfg.Exporter)
+	}
+}
@@ -0,0 +1,182 @@
+package upload
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/gabriel-vasile/mimetype"
+)
+
+// sniffLength 用于识别文件类型的头部长度（与 mimetype 的默认读取长度一致）
+const sniffLength = 3072
+
+// markupMarkers 图片中出现即视为多义文件的标记（小写）：浏览器或其他解析器可能把这类文件当作 HTML、脚本或 PDF 处理
+var markupMarkers = [][]byte{
+	[]byte("<script"),
+	[]byte("<html"),
+	[]byte("<body"),
+	[]byte("<iframe"),
+	[]byte("<object"),
+	[]byte("<embed"),
+	[]byte("<svg"),
+	[]byte("<?php"),
+	[]byte("<!doctype"),
+	[]byte("javascript:"),
+	[]byte("%pdf-"),
+}
+
+// pixelMarkers 图片的像素数据中检查的标记：像素数据的位置与长度由文件自己声明，不能整段跳过，
+// 但其中是近似随机的字节，只检查足够长的完整标记（不含 <svg、<body），大文件中偶然命中的概率可以忽略
+var pixelMarkers = [][]byte{
+	[]byte("<script"),
+	[]byte("<html"),
+	[]byte("<iframe"),
+	[]byte("<object"),
+	[]byte("<embed"),
+	[]byte("<?php"),
+	[]byte("<!doctype"),
+	[]byte("javascript:"),
+	[]byte("%pdf-"),
+}
+
+// Inspection 内容检测结果
+type Inspection struct {
+	ContentType string // 按文件内容识别出的 MIME 类型
+	Ext         string // 保存时使用的扩展名
+}
+
+// Inspect 读取内容头部识别真实类型并按策略校验
+//
+// 文件类型只根据内容（magic bytes）判断，客户端提供的文件名与 Content-Type 不参与判断。
+// 返回的 Reader 从头输出完整内容，并在读取过程中继续限制大小、检查图片中的可疑标记，
+// 超出限制或发现可疑内容时读取立即返回 ErrTooLarge 或 ErrSuspicious。
+// 图片的头部、末尾、元数据与文本块以及图片结束后附加的内容检查全部标记，压缩的像素数据只检查 pixelMarkers（见 regions）。
+func (p *Policy) Inspect(r io.Reader, filename string) (io.Reader, Inspection, error) {
+	g := &guard{r: r, limit: p.MaxSize}
+
+	head := make([]byte, sniffLength)
+	n, err := io.ReadFull(g, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, Inspection{}, err
+	}
+	head = head[:n]
+	if n == 0 {
+		return nil, Inspection{}, ErrEmpty
+	}
+
+	contentType := mediaType(mimetype.Detect(head).String())
+	if _, ok := p.Types[contentType]; !ok {
+		return nil, Inspection{}, fmt.Errorf("%w: %s", ErrTypeNotAllowed, contentType)
+	}
+
+	if strings.HasPrefix(contentType, "image/") {
+		g.markers = markupMarkers
+		if err := g.scan(head, g.markers); err != nil {
+			return nil, Inspection{}, err
+		}
+		g.remember(head)
+		// 头部已整体检查过，这里只推进图片结构的解析
+		g.regions = newRegions(contentType)
+		if g.regions != nil {
+			g.regions.split(head, func([]byte, bool) {})
+		}
+	}
+
+	return io.MultiReader(bytes.NewReader(head), g), Inspection{
+		ContentType: contentType,
+		Ext:         p.allowedExt(contentType, filename),
+	}, nil
+}
+
+// mediaType 去掉 MIME 类型中的参数（如 text/plain; charset=utf-8）
+func mediaType(s string) string {
+	s, _, _ = strings.Cut(s, ";")
+	return strings.TrimSpace(s)
+}
+
+// guard 在读取过程中限制大小，并检查内容中是否出现可疑标记（跨读取块匹配）
+type guard struct {
+	r       io.Reader
+	limit   int64
+	read    int64
+	markers [][]byte
+	regions *regions // 图片结构，nil 时头部与末尾之外的内容都按像素数据检查
+	tail    []byte   // 上一块末尾的内容（小写），用于匹配跨块的标记
+	last    []byte   // 最后读取的内容，读完后检查末尾 sniffLength 字节
+}
+
+func (g *guard) Read(p []byte) (int, error) {
+	n, err := g.r.Read(p)
+	g.read += int64(n)
+	if g.read > g.limit {
+		return 0, fmt.Errorf("%w: 不能超过 %d 字节", ErrTooLarge, g.limit)
+	}
+	if len(g.markers) > 0 {
+		if serr := g.inspect(p[:n]); serr != nil {
+			return 0, serr
+		}
+		if err == io.EOF {
+			// 附加在文件末尾的内容：即使图片结构无法识别也要检查全部标记
+			g.tail = g.tail[:0]
+			if serr := g.scan(g.last[max(0, len(g.last)-sniffLength):], g.markers); serr != nil {
+				return 0, serr
+			}
+		}
+	}
+	return n, err
+}
+
+// inspect 按图片结构检查一块内容：元数据与文本块检查全部标记，像素数据只检查 pixelMarkers
+func (g *guard) inspect(chunk []byte) error {
+	g.remember(chunk)
+	if g.regions == nil {
+		return g.scan(chunk, pixelMarkers)
+	}
+	var err error
+	g.regions.split(chunk, func(part []byte, scan bool) {
+		switch {
+		case err != nil:
+		case scan:
+			err = g.scan(part, g.markers)
+		default:
+			err = g.scan(part, pixelMarkers)
+		}
+	})
+	return err
+}
+
+// remember 记录最后读取的内容
+func (g *guard) remember(chunk []byte) {
+	g.last = append(g.last, chunk...)
+	if len(g.last) > 2*sniffLength {
+		g.last = append(g.last[:0], g.last[len(g.last)-sniffLength:]...)
+	}
+}
+
+// scan 检查一块内容中是否出现 markers 中的标记
+func (g *guard) scan(chunk []byte, markers [][]byte) error {
+	window := append(g.tail, bytes.ToLower(chunk)...)
+	for _, marker := range markers {
+		if bytes.Contains(window, marker) {
+			return fmt.Errorf("%w: 图片中包含 %q", ErrSuspicious, marker)
+		}
+	}
+
+	keep := maxMarkerLength - 1
+	if len(window) < keep {
+		keep = len(window)
+	}
+	g.tail = append(g.tail[:0], window[len(window)-keep:]...)
+	return nil
+}
+
+// maxMarkerLength 最长标记的长度
+var maxMarkerLength = func() int {
+	n := 0
+	for _, m := range markupMarkers {
+		n = max(n, len(m))
+	}
+	return n
+}()
@@ -0,0 +1,189 @@
+package upload
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"iris-cn-sample-project/config"
+	"iris-cn-sample-project/metrics"
+	"iris-cn-sample-project/storage"
+)
+
+// 上传用途
+const (
+	PurposeAttachment = "attachment" // 普通附件
+	PurposeAvatar     = "avatar"     // 用户头像
+)
+
+// QuarantineStorage 隔离区在 files.storage 中的名称
+const QuarantineStorage = "quarantine"
+
+// 上传被拒绝的原因
+var (
+	ErrEmpty           = errors.New("文件内容为空")
+	ErrTooLarge        = errors.New("文件大小超过限制")
+	ErrTypeNotAllowed  = errors.New("不支持的文件类型")
+	ErrSuspicious      = errors.New("文件包含可疑内容")
+	ErrInfected        = errors.New("文件未通过安全扫描")
+	ErrScanUnavailable = errors.New("文件安全扫描暂不可用")
+	ErrQuotaExceeded   = errors.New("超出存储配额")
+)
+
+// Policy 某一用途的上传策略
+type Policy struct {
+	Name    string
+	MaxSize int64 // 字节
+
+	// Types 允许的 MIME 类型（按文件内容识别）及其对应的扩展名，第一个扩展名为默认扩展名
+	Types map[string][]string
+}
+
+// policies 内置的上传策略
+var policies = map[string]*Policy{
+	PurposeAttachment: {
+		Name:    PurposeAttachment,
+		MaxSize: 10 << 20,
+		Types: map[string][]string{
+			"image/jpeg":                {".jpg", ".jpeg"},
+			"image/png":                 {".png"},
+			"image/gif":                 {".gif"},
+			"application/pdf":           {".pdf"},
+			"application/msword":        {".doc"},
+			"application/x-ole-storage": {".doc"},
+			"application/vnd.openxmlformats-officedocument.wordprocessingml.document": {".docx"},
+			"text/plain": {".txt"},
+		},
+	},
+	PurposeAvatar: {
+		Name:    PurposeAvatar,
+		MaxSize: 2 << 20,
+		Types: map[string][]string{
+			"image/jpeg": {".jpg", ".jpeg"},
+			"image/png":  {".png"},
+			"image/gif":  {".gif"},
+			"image/webp": {".webp"},
+		},
+	},
+}
+
+// resumable 可续传上传使用的策略：与附件允许的类型相同，大小上限单独配置
+var resumable = &Policy{
+	Name:    PurposeAttachment,
+	MaxSize: 1 << 30,
+	Types:   policies[PurposeAttachment].Types,
+}
+
+// Resumable 获取可续传上传（tus）使用的策略
+func Resumable() *Policy {
+	return resumable
+}
+
+// Lookup 获取上传用途对应的策略
+func Lookup(purpose string) (*Policy, bool) {
+	p, ok := policies[purpose]
+	return p, ok
+}
+
+// 全局扫描器与隔离区
+var (
+	scanner    Scanner = nopScanner{}
+	failOpen   bool
+	quarantine storage.Storage
+)
+
+// Init 根据上传配置设置大小上限、恶意软件扫描器与隔离区
+func Init(cfg config.UploadConfig) error {
+	if cfg.MaxSizeMB > 0 {
+		policies[PurposeAttachment].MaxSize = int64(cfg.MaxSizeMB) << 20
+	}
+	if cfg.Tus.MaxSizeMB > 0 {
+		resumable.MaxSize = int64(cfg.Tus.MaxSizeMB) << 20
+	}
+
+	s, err := NewScanner(cfg.Scanner)
+	if err != nil {
+		return err
+	}
+
+	// 隔离区不在静态文件目录下，也不提供下载地址
+	q, err := storage.NewLocal(cfg.QuarantineDir, "", nil)
+	if err != nil {
+		return fmt.Errorf("初始化隔离区失败: %v", err)
+	}
+
+	scanner, failOpen, quarantine = s, cfg.Scanner.FailOpen, q
+	return nil
+}
+
+// DefaultScanner 获取全局扫描器与扫描服务不可用时是否放行
+func DefaultScanner() (Scanner, bool) {
+	return scanner, failOpen
+}
+
+// SetScanner 替换全局扫描器（主要用于测试）
+func SetScanner(s Scanner, open bool) {
+	scanner, failOpen = s, open
+}
+
+// Quarantine 获取隔离区存储，未初始化时为 nil
+func Quarantine() storage.Storage {
+	return quarantine
+}
+
+// Reason 返回上传被拒绝的原因标签（用于指标），不是上传校验错误时返回空字符串
+func Reason(err error) string {
+	switch {
+	case errors.Is(err, ErrEmpty):
+		return metrics.UploadRejectedEmpty
+	case errors.Is(err, ErrTooLarge):
+		return metrics.UploadRejectedTooLarge
+	case errors.Is(err, ErrTypeNotAllowed):
+		return metrics.UploadRejectedTypeNotAllowed
+	case errors.Is(err, ErrSuspicious):
+		return metrics.UploadRejectedSuspicious
+	case errors.Is(err, ErrInfected):
+		return metrics.UploadRejectedInfected
+	case errors.Is(err, ErrScanUnavailable):
+		return metrics.UploadRejectedScanUnavailable
+	case errors.Is(err, ErrQuotaExceeded):
+		return metrics.UploadRejectedQuotaExceeded
+	default:
+		return ""
+	}
+}
+
+// allowedExt 根据识别出的类型确定扩展名：客户端扩展名与类型一致时沿用，否则使用该类型的默认扩展名
+func (p *Policy) allowedExt(contentType, filename string) string {
+	exts := p.Types[contentType]
+	ext := strings.ToLower(filepath.Ext(filename))
+	for _, e := range exts {
+		if e == ext {
+			return ext
+		}
+	}
+	return exts[0]
+}
+
+// MaxSizeText 大小上限的可读形式
+func (p *Policy) MaxSizeText() string {
+	if p.MaxSize%(1<<20) == 0 {
+		return fmt.Sprintf("%dMB", p.MaxSize>>20)
+	}
+	return fmt.Sprintf("%dKB", p.MaxSize>>10)
+}
+
+// FormatSize 将字节数格式化为便于阅读的文本（保留一位小数），如 1.5GB、320.0KB
+func FormatSize(n int64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.1fGB", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1fMB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1fKB", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%dB", n)
+	}
+}
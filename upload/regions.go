@@ -0,0 +1,343 @@
+package upload
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// 图片的元数据、文本块与图片结束标记之后附加的内容检查全部标记；像素数据是压缩后近似随机的字节，
+// 在其中搜索 <svg 这样的短标记大约每 5×10⁸ 字节就会误中一次，大图片会被误判为可疑文件，
+// 因此只检查 pixelMarkers 中的长标记（各段的长度来自上传的文件本身，不能据此整段跳过）。
+// 结构无法解析时退回按全部标记检查剩余的内容。
+
+// segment 图片内容中的一段
+type segment struct {
+	length  int64 // 长度，-1 表示直到内容结束（或由 terminator 确定）
+	scan    bool  // 是否检查可疑标记
+	collect bool  // 是否收集内容交给下一次 next 解析（用于长度、类型等字段）
+}
+
+// imageFormat 按图片格式的结构逐段解析内容
+type imageFormat interface {
+	// next 在上一段结束后调用，field 为上一段收集的内容，返回下一段
+	next(field []byte) segment
+}
+
+// terminator 长度未知的段（如 JPEG 的熵编码数据）由内容中的结束标记确定
+type terminator interface {
+	// end 返回 part 中结束标记之后的位置，没有结束标记时返回 -1
+	end(part []byte) int
+}
+
+// rest 剩余的全部内容都检查
+var rest = segment{length: -1, scan: true}
+
+// regions 把读取到的内容按图片结构切分为需要检查与可以跳过的部分
+type regions struct {
+	format imageFormat
+	cur    segment
+	field  []byte
+}
+
+// newRegions 创建图片格式对应的解析器，不认识的格式返回 nil（只检查头部与末尾）
+func newRegions(contentType string) *regions {
+	var f imageFormat
+	switch contentType {
+	case "image/png":
+		f = &pngFormat{}
+	case "image/jpeg":
+		f = &jpegFormat{}
+	case "image/gif":
+		f = &gifFormat{}
+	case "image/webp":
+		f = &webpFormat{}
+	default:
+		return nil
+	}
+	r := &regions{format: f}
+	r.cur = f.next(nil)
+	return r
+}
+
+// split 按顺序把 chunk 切分为若干段交给 fn，scan 表示该段是否需要检查
+func (r *regions) split(chunk []byte, fn func(part []byte, scan bool)) {
+	for len(chunk) > 0 {
+		if r.cur.length == 0 {
+			r.cur = r.format.next(r.field)
+			r.field = r.field[:0]
+			continue
+		}
+
+		k := len(chunk)
+		if r.cur.length > 0 && int64(k) > r.cur.length {
+			k = int(r.cur.length)
+		}
+		ended := false
+		if t, ok := r.format.(terminator); ok && r.cur.length < 0 {
+			if i := t.end(chunk[:k]); i >= 0 {
+				k, ended = i, true
+			}
+		}
+
+		part := chunk[:k]
+		if r.cur.collect {
+			r.field = append(r.field, part...)
+		}
+		fn(part, r.cur.scan)
+		chunk = chunk[k:]
+		switch {
+		case ended:
+			r.cur.length = 0
+		case r.cur.length > 0:
+			r.cur.length -= int64(k)
+		}
+	}
+}
+
+// pngFormat PNG：跳过 IDAT、fdAT 块的数据，其余块（tEXt、iTXt、zTXt、eXIf 等）与 IEND 之后的内容都检查
+type pngFormat struct {
+	state int
+	iend  bool
+}
+
+const (
+	pngStart = iota
+	pngSignature
+	pngChunkHeader
+	pngChunkData
+	pngCRC
+	pngTrailing
+)
+
+func (p *pngFormat) next(field []byte) segment {
+	switch p.state {
+	case pngStart:
+		p.state = pngSignature
+		return segment{length: 8}
+	case pngSignature, pngCRC:
+		if p.iend {
+			p.state = pngTrailing
+			return rest
+		}
+		p.state = pngChunkHeader
+		return segment{length: 8, collect: true}
+	case pngChunkHeader:
+		length := int64(binary.BigEndian.Uint32(field[:4]))
+		typ := string(field[4:8])
+		p.iend = typ == "IEND"
+		p.state = pngChunkData
+		return segment{length: length, scan: typ != "IDAT" && typ != "fdAT"}
+	case pngChunkData:
+		p.state = pngCRC
+		return segment{length: 4}
+	default:
+		return rest
+	}
+}
+
+// jpegFormat JPEG：检查第一个 SOS 之前的各个段（APPn 中的 EXIF、XMP，COM 注释等）与 EOI 之后的内容，跳过熵编码数据
+type jpegFormat struct {
+	state  int
+	code   byte
+	prevFF bool // 熵编码数据中上一个读取块以 0xFF 结尾
+}
+
+const (
+	jpegStart = iota
+	jpegMarker
+	jpegLength
+	jpegSegment
+	jpegScanHeader
+	jpegEntropy
+	jpegTrailing
+)
+
+func (j *jpegFormat) next(field []byte) segment {
+	switch j.state {
+	case jpegStart, jpegSegment:
+		j.state = jpegMarker
+		return segment{length: 2, collect: true}
+	case jpegMarker:
+		if field[0] != 0xFF {
+			j.state = jpegTrailing
+			return rest
+		}
+		j.code = field[1]
+		switch {
+		case j.code == 0xD9:
+			j.state = jpegTrailing
+			return rest
+		case j.code == 0xD8 || j.code == 0x01 || (j.code >= 0xD0 && j.code <= 0xD7):
+			// 没有长度字段的标记
+			return segment{length: 2, collect: true}
+		}
+		j.state = jpegLength
+		return segment{length: 2, collect: true}
+	case jpegLength:
+		length := int64(binary.BigEndian.Uint16(field))
+		if length < 2 {
+			j.state = jpegTrailing
+			return rest
+		}
+		if j.code == 0xDA {
+			j.state = jpegScanHeader
+			return segment{length: length - 2}
+		}
+		j.state = jpegSegment
+		return segment{length: length - 2, scan: true}
+	case jpegScanHeader:
+		// SOS 段头之后是熵编码数据，渐进式 JPEG 后续的 DHT、SOS 等段也一并跳过，直到 EOI
+		j.state = jpegEntropy
+		return segment{length: -1}
+	default:
+		return rest
+	}
+}
+
+// end 查找 EOI（FF D9）：熵编码数据中的 0xFF 后面只会是 0x00、RSTn 或其他段的标记
+func (j *jpegFormat) end(part []byte) int {
+	if j.state != jpegEntropy || len(part) == 0 {
+		return -1
+	}
+	i := -1
+	if j.prevFF && part[0] == 0xD9 {
+		i = 1
+	} else if k := bytes.Index(part, []byte{0xFF, 0xD9}); k >= 0 {
+		i = k + 2
+	}
+	if i >= 0 {
+		j.state = jpegTrailing
+		return i
+	}
+	j.prevFF = part[len(part)-1] == 0xFF
+	return -1
+}
+
+// gifFormat GIF：检查扩展块（注释、应用扩展中的 XMP 等）与结束符之后的内容，跳过颜色表与图像数据
+type gifFormat struct {
+	state    int
+	scanData bool // 当前子块序列是否检查（扩展块检查，图像数据不检查）
+}
+
+const (
+	gifStart = iota
+	gifHeader
+	gifColorTable
+	gifIntroducer
+	gifLabel
+	gifDescriptor
+	gifImageHeader
+	gifBlockSize
+	gifBlockData
+	gifTrailing
+)
+
+// gifTableSize 标志字节中的颜色表长度，没有颜色表时为 0
+func gifTableSize(flags byte) int64 {
+	if flags&0x80 == 0 {
+		return 0
+	}
+	return 3 << ((flags & 0x07) + 1)
+}
+
+func (g *gifFormat) next(field []byte) segment {
+	switch g.state {
+	case gifStart:
+		g.state = gifHeader
+		return segment{length: 13, collect: true}
+	case gifHeader:
+		g.state = gifColorTable
+		return segment{length: gifTableSize(field[10])}
+	case gifColorTable, gifImageHeader:
+		// 读取下一个块的引导符或图像数据的第一个子块
+		if g.state == gifImageHeader {
+			g.state = gifBlockSize
+		} else {
+			g.state = gifIntroducer
+		}
+		return segment{length: 1, collect: true}
+	case gifIntroducer:
+		switch field[0] {
+		case 0x21:
+			g.state = gifLabel
+			return segment{length: 1}
+		case 0x2C:
+			g.state = gifDescriptor
+			return segment{length: 9, collect: true}
+		default:
+			// 0x3B 结束符或无法解析的内容
+			g.state = gifTrailing
+			return rest
+		}
+	case gifLabel:
+		g.scanData = true
+		g.state = gifBlockSize
+		return segment{length: 1, collect: true}
+	case gifDescriptor:
+		// 局部颜色表与 LZW 最小码长
+		g.scanData = false
+		g.state = gifImageHeader
+		return segment{length: gifTableSize(field[8]) + 1}
+	case gifBlockSize:
+		if field[0] == 0 {
+			g.state = gifIntroducer
+			return segment{length: 1, collect: true}
+		}
+		g.state = gifBlockData
+		return segment{length: int64(field[0]), scan: g.scanData}
+	case gifBlockData:
+		g.state = gifBlockSize
+		return segment{length: 1, collect: true}
+	default:
+		return rest
+	}
+}
+
+// webpFormat WebP（RIFF）：跳过图像数据块，其余块（EXIF、XMP 等）与 RIFF 之后的内容都检查
+type webpFormat struct {
+	state int
+	pos   int64 // 已解析的字节数
+	total int64 // RIFF 声明的总长度
+}
+
+const (
+	webpStart = iota
+	webpHeader
+	webpChunkHeader
+	webpChunkData
+	webpTrailing
+)
+
+// webpImageChunks 图像数据块
+var webpImageChunks = map[string]bool{"VP8 ": true, "VP8L": true, "ALPH": true, "ANMF": true}
+
+func (w *webpFormat) next(field []byte) segment {
+	s := w.segment(field)
+	if s.length > 0 {
+		w.pos += s.length
+	}
+	return s
+}
+
+func (w *webpFormat) segment(field []byte) segment {
+	switch w.state {
+	case webpStart:
+		w.state = webpHeader
+		return segment{length: 12, collect: true}
+	case webpHeader:
+		w.total = int64(binary.LittleEndian.Uint32(field[4:8])) + 8
+	case webpChunkHeader:
+		size := int64(binary.LittleEndian.Uint32(field[4:8]))
+		w.state = webpChunkData
+		return segment{length: size + size&1, scan: !webpImageChunks[string(field[:4])]}
+	case webpTrailing:
+		return rest
+	}
+	// 文件头或一个块结束
+	if w.pos >= w.total {
+		w.state = webpTrailing
+		return rest
+	}
+	w.state = webpChunkHeader
+	return segment{length: 8, collect: true}
+}
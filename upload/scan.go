@@ -0,0 +1,150 @@
+package upload
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"iris-cn-sample-project/config"
+)
+
+// ScanResult 扫描结果
+type ScanResult struct {
+	Infected  bool
+	Signature string // 命中的特征名称
+}
+
+// Scanner 恶意软件扫描器
+type Scanner interface {
+	// Scan 扫描内容；扫描服务出错时返回 error，发现恶意内容时返回 Infected 的结果
+	Scan(ctx context.Context, r io.Reader) (ScanResult, error)
+}
+
+// NewScanner 根据扫描配置创建扫描器
+func NewScanner(cfg config.ScannerConfig) (Scanner, error) {
+	switch cfg.Driver {
+	case "none", "":
+		return nopScanner{}, nil
+	case "clamav":
+		return NewClamAV(cfg.Address, time.Duration(cfg.Timeout)*time.Second)
+	default:
+		return nil, fmt.Errorf("不支持的扫描器: %s", cfg.Driver)
+	}
+}
+
+// nopScanner 不做扫描
+type nopScanner struct{}
+
+func (nopScanner) Scan(ctx context.Context, r io.Reader) (ScanResult, error) {
+	return ScanResult{}, nil
+}
+
+// clamChunkSize INSTREAM 每个数据块的大小
+const clamChunkSize = 32 << 10
+
+// ClamAV 通过 clamd 的 INSTREAM 命令扫描内容
+type ClamAV struct {
+	network string
+	address string
+	timeout time.Duration
+}
+
+// NewClamAV 创建 ClamAV 扫描器，address 形如 unix:///var/run/clamav/clamd.ctl 或 tcp://127.0.0.1:3310
+func NewClamAV(address string, timeout time.Duration) (*ClamAV, error) {
+	u, err := url.Parse(address)
+	if err != nil {
+		return nil, fmt.Errorf("clamd 地址 %q 无效: %v", address, err)
+	}
+	c := &ClamAV{network: u.Scheme, timeout: timeout}
+	switch u.Scheme {
+	case "unix":
+		c.address = u.Path
+	case "tcp":
+		c.address = u.Host
+	default:
+		return nil, fmt.Errorf("clamd 地址 %q 无效: 只支持 unix:// 与 tcp://", address)
+	}
+	if c.timeout <= 0 {
+		c.timeout = 30 * time.Second
+	}
+	return c, nil
+}
+
+// Scan 以 INSTREAM 协议发送内容：每块为 4 字节大端长度加数据，以长度 0 结束；clamd 返回 "stream: OK" 或 "stream: <特征> FOUND"
+func (c *ClamAV) Scan(ctx context.Context, r io.Reader) (ScanResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, c.network, c.address)
+	if err != nil {
+		return ScanResult{}, fmt.Errorf("连接 clamd 失败: %v", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return ScanResult{}, fmt.Errorf("发送扫描命令失败: %v", err)
+	}
+
+	buf := make([]byte, 4+clamChunkSize)
+	for {
+		n, rerr := io.ReadFull(r, buf[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(buf[:4], uint32(n))
+			if _, err := conn.Write(buf[:4+n]); err != nil {
+				// clamd 在超过 StreamMaxLength 时会提前回复错误并关闭连接
+				if reply, rerr := readClamReply(conn); rerr == nil {
+					return parseClamReply(reply)
+				}
+				return ScanResult{}, fmt.Errorf("发送扫描内容失败: %v", err)
+			}
+		}
+		if rerr == io.EOF || rerr == io.ErrUnexpectedEOF {
+			break
+		}
+		if rerr != nil {
+			return ScanResult{}, rerr
+		}
+	}
+	if _, err := conn.Write([]byte{0, 0, 0, 0}); err != nil {
+		return ScanResult{}, fmt.Errorf("发送扫描内容失败: %v", err)
+	}
+
+	reply, err := readClamReply(conn)
+	if err != nil {
+		return ScanResult{}, fmt.Errorf("读取扫描结果失败: %v", err)
+	}
+	return parseClamReply(reply)
+}
+
+// readClamReply 读取以 \0 结尾的回复
+func readClamReply(conn net.Conn) (string, error) {
+	reply, err := bufio.NewReader(conn).ReadBytes(0)
+	if err != nil && (err != io.EOF || len(reply) == 0) {
+		return "", err
+	}
+	return string(bytes.TrimRight(reply, "\x00\n")), nil
+}
+
+// parseClamReply 解析扫描结果
+func parseClamReply(reply string) (ScanResult, error) {
+	result := strings.TrimPrefix(reply, "stream: ")
+	switch {
+	case result == "OK":
+		return ScanResult{}, nil
+	case strings.HasSuffix(result, " FOUND"):
+		return ScanResult{Infected: true, Signature: strings.TrimSuffix(result, " FOUND")}, nil
+	default:
+		return ScanResult{}, fmt.Errorf("clamd 返回错误: %s", reply)
+	}
+}
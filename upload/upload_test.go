@@ -0,0 +1,361 @@
+package upload
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"iris-cn-sample-project/config"
+)
+
+var (
+	pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x00\x01\x00\x00\x00\x01\x08\x06\x00\x00\x00")
+	jpegHead  = []byte("\xff\xd8\xff\xe0\x00\x10JFIF\x00")
+)
+
+// eicar EICAR 标准测试文件（无害，但所有杀毒软件都会报毒）
+const eicar = `X5O!P%@AP[4\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*`
+
+func TestInspect(t *testing.T) {
+	attachment, _ := Lookup(PurposeAttachment)
+	avatar, _ := Lookup(PurposeAvatar)
+
+	tests := []struct {
+		name     string
+		policy   *Policy
+		content  []byte
+		filename string
+		wantType string
+		wantExt  string
+		wantErr  error
+	}{
+		{"png", avatar, pngHeader, "a.png", "image/png", ".png", nil},
+		{"jpeg 扩展名沿用", avatar, jpegHead, "a.JPEG", "image/jpeg", ".jpeg", nil},
+		{"扩展名与内容不符", avatar, pngHeader, "a.jpg", "image/png", ".png", nil},
+		{"伪装成图片的 HTML", avatar, []byte("<!DOCTYPE html><html><script>alert(1)</script>"), "a.png", "", "", ErrTypeNotAllowed},
+		{"伪装成图片的 SVG", avatar, []byte(`<svg xmlns="http://www.w3.org/2000/svg"></svg>`), "a.png", "", "", ErrTypeNotAllowed},
+		{"头像不接受文本", avatar, []byte("hello"), "a.txt", "", "", ErrTypeNotAllowed},
+		{"附件文本", attachment, []byte("hello <script> in text"), "notes.TXT", "text/plain", ".txt", nil},
+		{"附件 PDF", attachment, []byte("%PDF-1.7\n%\xe2\xe3\xcf\xd3\n"), "doc.exe", "application/pdf", ".pdf", nil},
+		{"可执行文件", attachment, []byte("MZ\x90\x00\x03\x00\x00\x00\x04\x00\x00\x00\xff\xff"), "a.pdf", "", "", ErrTypeNotAllowed},
+		{"空文件", attachment, nil, "a.txt", "", "", ErrEmpty},
+		{"头部中的脚本", avatar, append(append([]byte{}, pngHeader...), "<script>"...), "a.png", "", "", ErrSuspicious},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, got, err := tt.policy.Inspect(bytes.NewReader(tt.content), tt.filename)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Inspect 失败: %v", err)
+			}
+			if got.ContentType != tt.wantType || got.Ext != tt.wantExt {
+				t.Errorf("Inspect = %+v, want %s %s", got, tt.wantType, tt.wantExt)
+			}
+			data, err := io.ReadAll(r)
+			if err != nil || !bytes.Equal(data, tt.content) {
+				t.Errorf("返回的内容不完整: %v", err)
+			}
+		})
+	}
+}
+
+// countingReader 无限长的内容，记录已读取的字节数
+type countingReader struct {
+	head []byte
+	read int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n := copy(p, c.head)
+	c.head = c.head[n:]
+	for i := n; i < len(p); i++ {
+		p[i] = 0
+	}
+	c.read += int64(len(p))
+	return len(p), nil
+}
+
+func TestInspectStreamingLimit(t *testing.T) {
+	policy := &Policy{Name: "test", MaxSize: 1 << 20, Types: map[string][]string{"image/png": {".png"}}}
+	src := &countingReader{head: pngHeader}
+
+	r, _, err := policy.Inspect(src, "big.png")
+	if err != nil {
+		t.Fatalf("Inspect 失败: %v", err)
+	}
+	n, err := io.Copy(io.Discard, r)
+	if !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("err = %v, want ErrTooLarge", err)
+	}
+	if n > policy.MaxSize {
+		t.Errorf("输出了 %d 字节，超过上限 %d", n, policy.MaxSize)
+	}
+	// 超出上限后立即停止读取，而不是读完整个请求体
+	if src.read > policy.MaxSize+64<<10 {
+		t.Errorf("读取了 %d 字节，超出上限后没有及时停止", src.read)
+	}
+
+	exact := append(append([]byte{}, pngHeader...), make([]byte, policy.MaxSize-int64(len(pngHeader)))...)
+	r, _, err = policy.Inspect(bytes.NewReader(exact), "exact.png")
+	if err != nil {
+		t.Fatalf("Inspect 失败: %v", err)
+	}
+	if n, err := io.Copy(io.Discard, r); err != nil || n != policy.MaxSize {
+		t.Errorf("恰好等于上限的文件应通过: %d, %v", n, err)
+	}
+}
+
+func TestInspectPolyglot(t *testing.T) {
+	avatar, _ := Lookup(PurposeAvatar)
+
+	for _, marker := range []string{"<ScRiPt src=x>", "<html>", "%PDF-1.4"} {
+		content := append(append([]byte{}, pngHeader...), make([]byte, 10000)...)
+		content = append(content, marker...)
+
+		// 逐字节读取，标记跨越多个读取块
+		r, _, err := avatar.Inspect(iotest.OneByteReader(bytes.NewReader(content)), "a.png")
+		if err != nil {
+			t.Fatalf("Inspect 失败: %v", err)
+		}
+		if _, err := io.Copy(io.Discard, r); !errors.Is(err, ErrSuspicious) {
+			t.Errorf("%q: err = %v, want ErrSuspicious", marker, err)
+		}
+	}
+
+	clean := append(append([]byte{}, pngHeader...), bytes.Repeat([]byte("<scrip"), 1000)...)
+	r, _, err := avatar.Inspect(iotest.HalfReader(bytes.NewReader(clean)), "a.png")
+	if err != nil {
+		t.Fatalf("Inspect 失败: %v", err)
+	}
+	if _, err := io.Copy(io.Discard, r); err != nil {
+		t.Errorf("不完整的标记不应被拒绝: %v", err)
+	}
+}
+
+// noise 长度为 n、不含任何标记的填充内容，marker 非空时放在正中间
+func noise(n int, marker string) []byte {
+	b := bytes.Repeat([]byte{0x5a, 0xa5, 0x3c}, n/3+1)[:n]
+	copy(b[n/2:], marker)
+	return b
+}
+
+// pngChunk 构造 PNG 块（CRC 不参与检查，填 0）
+func pngChunk(typ string, data []byte) []byte {
+	b := binary.BigEndian.AppendUint32(nil, uint32(len(data)))
+	b = append(b, typ...)
+	b = append(b, data...)
+	return append(b, 0, 0, 0, 0)
+}
+
+func testPNG(idat []byte, extra ...[]byte) []byte {
+	b := []byte("\x89PNG\r\n\x1a\n")
+	b = append(b, pngChunk("IHDR", []byte("\x00\x00\x00\x01\x00\x00\x00\x01\x08\x06\x00\x00\x00"))...)
+	b = append(b, pngChunk("IDAT", idat)...)
+	for _, chunk := range extra {
+		b = append(b, chunk...)
+	}
+	return append(b, pngChunk("IEND", nil)...)
+}
+
+// jpegMarkerSegment 构造带长度字段的 JPEG 段
+func jpegMarkerSegment(code byte, data []byte) []byte {
+	b := binary.BigEndian.AppendUint16([]byte{0xFF, code}, uint16(len(data)+2))
+	return append(b, data...)
+}
+
+func testJPEG(app1, entropy []byte) []byte {
+	b := append([]byte{0xFF, 0xD8}, jpegMarkerSegment(0xE0, []byte("JFIF\x00\x01\x01\x00\x00\x01\x00\x01\x00\x00"))...)
+	b = append(b, jpegMarkerSegment(0xE1, app1)...)
+	b = append(b, jpegMarkerSegment(0xDA, []byte{0x01, 0x01, 0x00, 0x00, 0x3F, 0x00})...)
+	b = append(b, entropy...)
+	return append(b, 0xFF, 0xD9)
+}
+
+// gifBlocks 把数据拆分为 GIF 子块
+func gifBlocks(data []byte) []byte {
+	var b []byte
+	for len(data) > 0 {
+		n := min(len(data), 255)
+		b = append(b, byte(n))
+		b = append(b, data[:n]...)
+		data = data[n:]
+	}
+	return append(b, 0)
+}
+
+func testGIF(comment, pixels []byte) []byte {
+	b := []byte("GIF89a\x01\x00\x01\x00\x80\x00\x00")
+	b = append(b, make([]byte, 6)...) // 两种颜色的全局颜色表
+	b = append(b, 0x21, 0xFE)
+	b = append(b, gifBlocks(comment)...)
+	b = append(b, 0x2C, 0, 0, 0, 0, 1, 0, 1, 0, 0, 0x02)
+	b = append(b, gifBlocks(pixels)...)
+	return append(b, 0x3B)
+}
+
+// webpChunk 构造 RIFF 块（奇数长度补齐一个字节）
+func webpChunk(fourcc string, data []byte) []byte {
+	b := binary.LittleEndian.AppendUint32([]byte(fourcc), uint32(len(data)))
+	b = append(b, data...)
+	if len(data)%2 == 1 {
+		b = append(b, 0)
+	}
+	return b
+}
+
+func testWebP(chunks ...[]byte) []byte {
+	body := []byte("WEBP")
+	for _, chunk := range chunks {
+		body = append(body, chunk...)
+	}
+	b := binary.LittleEndian.AppendUint32([]byte("RIFF"), uint32(len(body)))
+	return append(b, body...)
+}
+
+// 压缩的像素数据近似随机，其中偶然出现的标记不应导致正常图片被拒绝；元数据、文本块与图片结束后附加的内容仍要检查
+func TestInspectImageRegions(t *testing.T) {
+	avatar, _ := Lookup(PurposeAvatar)
+	padding := noise(10000, "")
+
+	tests := []struct {
+		name    string
+		content []byte
+		want    error
+	}{
+		{"PNG 像素数据", testPNG(noise(20000, "<svg")), nil},
+		{"PNG 文本块", testPNG(padding, pngChunk("tEXt", noise(10000, "Comment\x00<script>"))), ErrSuspicious},
+		{"PNG 结束后附加", append(testPNG(padding), noise(10000, "<html>")...), ErrSuspicious},
+		{"JPEG 熵编码数据", testJPEG(noise(100, ""), noise(20000, "<svg\xff\x00")), nil},
+		{"JPEG EXIF", testJPEG(noise(10000, "<?php"), padding), ErrSuspicious},
+		{"JPEG 结束后附加", append(testJPEG(nil, padding), noise(10000, "%PDF-1.4")...), ErrSuspicious},
+		{"GIF 图像数据", testGIF(nil, noise(20000, "<svg")), nil},
+		{"GIF 注释", testGIF(noise(10000, "<iframe"), padding), ErrSuspicious},
+		{"WebP 图像数据", testWebP(webpChunk("VP8L", noise(20001, "<svg"))), nil},
+		{"WebP XMP", testWebP(webpChunk("VP8L", padding), webpChunk("XMP ", noise(5000, "<script>")), webpChunk("EXIF", padding)), ErrSuspicious},
+		// 像素数据的位置与长度由文件自己声明，其中完整的 HTML、脚本与 PDF 标记同样拒绝
+		{"PNG IDAT 中的脚本", testPNG(noise(20000, "<ScRiPt>alert(1)</script>")), ErrSuspicious},
+		{"PNG 超长 IDAT 中的 HTML", testPNG(noise(1<<20, "<html><body>")), ErrSuspicious},
+		{"JPEG 熵编码数据中的 HTML", testJPEG(noise(100, ""), noise(20000, "<html>")), ErrSuspicious},
+		{"GIF 图像数据中的 iframe", testGIF(nil, noise(20000, "<iframe src=x>")), ErrSuspicious},
+		{"WebP 图像数据中的 PDF", testWebP(webpChunk("VP8L", noise(20001, "%PDF-1.7"))), ErrSuspicious},
+	}
+	for _, tt := range tests {
+		for _, read := range []func(io.Reader) io.Reader{iotest.HalfReader, iotest.OneByteReader} {
+			r, _, err := avatar.Inspect(read(bytes.NewReader(tt.content)), "a.img")
+			if err != nil {
+				t.Fatalf("%s: Inspect 失败: %v", tt.name, err)
+			}
+			if _, err := io.Copy(io.Discard, r); !errors.Is(err, tt.want) {
+				t.Errorf("%s: err = %v, want %v", tt.name, err, tt.want)
+			}
+		}
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	tests := map[int64]string{
+		0:               "0B",
+		1023:            "1023B",
+		1536:            "1.5KB",
+		10 << 20:        "10.0MB",
+		2<<30 + 512<<20: "2.5GB",
+	}
+	for n, want := range tests {
+		if got := FormatSize(n); got != want {
+			t.Errorf("FormatSize(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
+
+func TestClamAV(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "clamd.sock")
+	ln, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("监听 unix socket 失败: %v", err)
+	}
+	defer ln.Close()
+	go serveClamd(ln)
+
+	scanner, err := NewScanner(config.ScannerConfig{Driver: "clamav", Address: "unix://" + socket, Timeout: 5})
+	if err != nil {
+		t.Fatalf("创建扫描器失败: %v", err)
+	}
+	ctx := context.Background()
+
+	// 超过一个数据块的干净内容
+	result, err := scanner.Scan(ctx, bytes.NewReader(bytes.Repeat([]byte("a"), 3*clamChunkSize+10)))
+	if err != nil || result.Infected {
+		t.Errorf("干净内容: %+v, %v", result, err)
+	}
+
+	result, err = scanner.Scan(ctx, strings.NewReader(eicar))
+	if err != nil || !result.Infected || result.Signature != "Eicar-Test-Signature" {
+		t.Errorf("EICAR: %+v, %v", result, err)
+	}
+
+	ln.Close()
+	if _, err := scanner.Scan(ctx, strings.NewReader("x")); err == nil {
+		t.Error("clamd 不可用时应返回错误")
+	}
+
+	if _, err := NewScanner(config.ScannerConfig{Driver: "clamav", Address: "http://localhost:3310"}); err == nil {
+		t.Error("不支持的地址应返回错误")
+	}
+	if _, err := NewScanner(config.ScannerConfig{Driver: "unknown"}); err == nil {
+		t.Error("不支持的扫描器应返回错误")
+	}
+}
+
+// serveClamd 模拟 clamd 的 INSTREAM 命令：内容包含 EICAR 时报毒
+func serveClamd(ln net.Listener) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		go func(conn net.Conn) {
+			defer conn.Close()
+			conn.SetDeadline(time.Now().Add(5 * time.Second))
+			r := bufio.NewReader(conn)
+			if cmd, err := r.ReadString(0); err != nil || cmd != "zINSTREAM\x00" {
+				conn.Write([]byte("UNKNOWN COMMAND\x00"))
+				return
+			}
+			var data []byte
+			for {
+				var size uint32
+				if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+					return
+				}
+				if size == 0 {
+					break
+				}
+				chunk := make([]byte, size)
+				if _, err := io.ReadFull(r, chunk); err != nil {
+					return
+				}
+				data = append(data, chunk...)
+			}
+			if bytes.Contains(data, []byte(eicar)) {
+				conn.Write([]byte("stream: Eicar-Test-Signature FOUND\x00"))
+			} else {
+				conn.Write([]byte("stream: OK\x00"))
+			}
+		}(conn)
+	}
+}
@@ -0,0 +1,257 @@
+package utils
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync/atomic"
+
+	"github.com/kataras/iris/v12"
+)
+
+// clientIPKey 客户端IP在请求上下文中的缓存键
+const clientIPKey = "client_ip"
+
+// ClientIPResolver 客户端IP解析器
+//
+// 只有直接连接的对端是可信代理时才读取转发头；转发链从右向左解析，跳过可信代理，
+// 遇到第一个不可信的地址即视为客户端IP，避免客户端伪造转发头影响限流、审计日志与登录锁定。
+type ClientIPResolver struct {
+	trusted []netip.Prefix
+	headers []string
+}
+
+// resolver 全局客户端IP解析器（默认不信任任何代理，只使用连接的对端地址）
+var resolver atomic.Pointer[ClientIPResolver]
+
+func init() {
+	resolver.Store(&ClientIPResolver{})
+}
+
+// NewClientIPResolver 创建客户端IP解析器
+//
+// trustedProxies 支持 CIDR（10.0.0.0/8）与单个IP；headers 为按顺序读取的转发头，
+// 支持 X-Forwarded-For、Forwarded（RFC 7239）与 X-Real-IP，应只配置可信代理会覆盖或追加的头。
+func NewClientIPResolver(trustedProxies, headers []string) (*ClientIPResolver, error) {
+	r := &ClientIPResolver{}
+
+	for _, proxy := range trustedProxies {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+		if !strings.Contains(proxy, "/") {
+			addr, err := netip.ParseAddr(proxy)
+			if err != nil {
+				return nil, fmt.Errorf("可信代理地址无效 %q: %v", proxy, err)
+			}
+			r.trusted = append(r.trusted, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("可信代理网段无效 %q: %v", proxy, err)
+		}
+		r.trusted = append(r.trusted, prefix.Masked())
+	}
+
+	for _, header := range headers {
+		header = http.CanonicalHeaderKey(strings.TrimSpace(header))
+		switch header {
+		case "X-Forwarded-For", "Forwarded", "X-Real-Ip":
+			r.headers = append(r.headers, header)
+		case "":
+		default:
+			return nil, fmt.Errorf("不支持的客户端IP请求头: %s", header)
+		}
+	}
+
+	return r, nil
+}
+
+// SetTrustedProxies 配置全局客户端IP解析器
+func SetTrustedProxies(trustedProxies, headers []string) error {
+	r, err := NewClientIPResolver(trustedProxies, headers)
+	if err != nil {
+		return err
+	}
+	resolver.Store(r)
+	return nil
+}
+
+// ClientIP 获取请求的客户端IP（结果缓存在请求上下文中）
+func ClientIP(ctx iris.Context) string {
+	if ip := ctx.Values().GetString(clientIPKey); ip != "" {
+		return ip
+	}
+	ip := resolver.Load().Resolve(ctx.Request())
+	ctx.Values().Set(clientIPKey, ip)
+	return ip
+}
+
+// Resolve 解析请求的客户端IP
+func (r *ClientIPResolver) Resolve(req *http.Request) string {
+	peer, ok := parseIP(req.RemoteAddr)
+	if !ok {
+		host, _, err := net.SplitHostPort(req.RemoteAddr)
+		if err != nil {
+			return req.RemoteAddr
+		}
+		return host
+	}
+
+	if !r.isTrusted(peer) {
+		return peer.String()
+	}
+
+	for _, header := range r.headers {
+		values := req.Header.Values(header)
+		if len(values) == 0 {
+			continue
+		}
+
+		switch header {
+		case "X-Real-Ip":
+			if ip, ok := parseIP(values[0]); ok {
+				return ip.String()
+			}
+		case "Forwarded":
+			return r.walk(peer, forwardedFor(values)).String()
+		default:
+			return r.walk(peer, splitList(values)).String()
+		}
+	}
+
+	return peer.String()
+}
+
+// IsHTTPS 判断请求是否通过 HTTPS 到达（使用全局解析器的可信代理配置）
+func IsHTTPS(req *http.Request) bool {
+	return resolver.Load().IsHTTPS(req)
+}
+
+// IsHTTPS 判断请求是否通过 HTTPS 到达：直接的 TLS 连接，或可信代理通过 X-Forwarded-Proto、Forwarded 声明的 https
+//
+// 与 ClientIP 一样从右向左遍历转发链，只采用接收客户端连接的那一级可信代理写入的协议；
+// 更靠左的值可能由客户端伪造（例如客户端自带 X-Forwarded-Proto: https，代理在其后追加 http）。
+func (r *ClientIPResolver) IsHTTPS(req *http.Request) bool {
+	if req.TLS != nil {
+		return true
+	}
+	peer, ok := parseIP(req.RemoteAddr)
+	if !ok || !r.isTrusted(peer) {
+		return false
+	}
+
+	if protos := splitList(req.Header.Values("X-Forwarded-Proto")); len(protos) > 0 {
+		// 每级代理在 X-Forwarded-For 与 X-Forwarded-Proto 中各追加一个值，两者从右侧对齐；
+		// 代理覆盖而不是追加时值更少，取最左侧的值（由可信代理写入）
+		hops := splitList(req.Header.Values("X-Forwarded-For"))
+		i := len(protos) - (len(hops) - r.clientHop(hops))
+		if i >= len(protos) {
+			i = len(protos) - 1
+		}
+		if i < 0 {
+			i = 0
+		}
+		return strings.EqualFold(protos[i], "https")
+	}
+	if values := req.Header.Values("Forwarded"); len(values) > 0 {
+		// 每一跳的 for 与 proto 在同一个元素中
+		elements := splitList(values)
+		i := r.clientHop(forwardedFor(values))
+		if i == len(elements) {
+			i--
+		}
+		return strings.EqualFold(forwardedParam(elements[i], "proto"), "https")
+	}
+	return false
+}
+
+// walk 从右向左遍历转发链，返回第一个不可信的地址；全部可信时返回最左侧的地址
+func (r *ClientIPResolver) walk(peer netip.Addr, hops []string) netip.Addr {
+	if i := r.clientHop(hops); i < len(hops) {
+		ip, _ := parseIP(hops[i])
+		return ip
+	}
+	return peer
+}
+
+// clientHop 从右向左遍历转发链，返回客户端在其中的位置：第一个不可信的地址，全部可信时为最左侧的地址；
+// 返回 len(hops) 表示客户端就是对端（没有转发链，或最右侧的地址无法解析）
+func (r *ClientIPResolver) clientHop(hops []string) int {
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip, ok := parseIP(hops[i])
+		if !ok {
+			// 无法解析的地址（如 unknown 或混淆标识）之前的内容都不可信
+			return i + 1
+		}
+		if !r.isTrusted(ip) {
+			return i
+		}
+	}
+	return 0
+}
+
+// isTrusted 判断地址是否属于可信代理
+func (r *ClientIPResolver) isTrusted(ip netip.Addr) bool {
+	for _, prefix := range r.trusted {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitList 拆分逗号分隔的请求头（同名请求头可能出现多次）
+func splitList(values []string) []string {
+	var items []string
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			items = append(items, strings.TrimSpace(item))
+		}
+	}
+	return items
+}
+
+// forwardedFor 提取 Forwarded 请求头中每一跳的 for 参数，例如：
+//
+//	Forwarded: for=192.0.2.60;proto=http, for="[2001:db8:cafe::17]:4711"
+func forwardedFor(values []string) []string {
+	var hops []string
+	for _, element := range splitList(values) {
+		// 缺少 for 参数的一跳同样视为无法解析
+		hops = append(hops, forwardedParam(element, "for"))
+	}
+	return hops
+}
+
+// forwardedParam 返回 Forwarded 请求头中一跳的参数值，没有该参数时返回空字符串
+func forwardedParam(element, name string) string {
+	for _, pair := range strings.Split(element, ";") {
+		key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if ok && strings.EqualFold(strings.TrimSpace(key), name) {
+			return strings.Trim(strings.TrimSpace(value), `"`)
+		}
+	}
+	return ""
+}
+
+// parseIP 解析 IP，兼容带端口与带方括号的 IPv6 形式
+func parseIP(s string) (netip.Addr, bool) {
+	s = strings.TrimSpace(s)
+	if addr, err := netip.ParseAddr(s); err == nil {
+		return addr.Unmap(), true
+	}
+	if addrPort, err := netip.ParseAddrPort(s); err == nil {
+		return addrPort.Addr().Unmap(), true
+	}
+	if strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]") {
+		if addr, err := netip.ParseAddr(s[1 : len(s)-1]); err == nil {
+			return addr.Unmap(), true
+		}
+	}
+	return netip.Addr{}, false
+}
@@ -0,0 +1,168 @@
+package utils
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+// TestClientIPResolver 验证可信代理、转发链解析与伪造转发头的处理
+func TestClientIPResolver(t *testing.T) {
+	r, err := NewClientIPResolver(
+		[]string{"10.0.0.0/8", "192.168.1.1", "::1/128"},
+		[]string{"Forwarded", "X-Forwarded-For", "X-Real-IP"},
+	)
+	if err != nil {
+		t.Fatalf("创建解析器失败: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string]string
+		want       string
+	}{
+		{
+			name:       "不可信对端忽略转发头",
+			remoteAddr: "203.0.113.9:5000",
+			headers:    map[string]string{"X-Forwarded-For": "1.2.3.4"},
+			want:       "203.0.113.9",
+		},
+		{
+			name:       "没有转发头时使用对端地址",
+			remoteAddr: "10.0.0.1:5000",
+			want:       "10.0.0.1",
+		},
+		{
+			name:       "XFF 取最右侧的不可信地址",
+			remoteAddr: "10.0.0.1:5000",
+			headers:    map[string]string{"X-Forwarded-For": "6.6.6.6, 198.51.100.7, 10.0.0.2"},
+			want:       "198.51.100.7",
+		},
+		{
+			name:       "XFF 全部可信时取最左侧地址",
+			remoteAddr: "10.0.0.1:5000",
+			headers:    map[string]string{"X-Forwarded-For": "192.168.1.1, 10.0.0.2"},
+			want:       "192.168.1.1",
+		},
+		{
+			name:       "XFF 遇到无法解析的地址时停止",
+			remoteAddr: "10.0.0.1:5000",
+			headers:    map[string]string{"X-Forwarded-For": "6.6.6.6, unknown, 10.0.0.2"},
+			want:       "10.0.0.2",
+		},
+		{
+			name:       "XFF 带端口的 IPv6",
+			remoteAddr: "[::1]:5000",
+			headers:    map[string]string{"X-Forwarded-For": "[2001:db8::1]:443"},
+			want:       "2001:db8::1",
+		},
+		{
+			name:       "Forwarded 优先于 XFF",
+			remoteAddr: "10.0.0.1:5000",
+			headers: map[string]string{
+				"Forwarded":       `for=6.6.6.6, for="[2001:db8:cafe::17]:4711";proto=https, for=10.0.0.3`,
+				"X-Forwarded-For": "198.51.100.7",
+			},
+			want: "2001:db8:cafe::17",
+		},
+		{
+			name:       "Forwarded 混淆标识",
+			remoteAddr: "10.0.0.1:5000",
+			headers:    map[string]string{"Forwarded": "for=6.6.6.6, for=_hidden"},
+			want:       "10.0.0.1",
+		},
+		{
+			name:       "X-Real-IP",
+			remoteAddr: "10.0.0.1:5000",
+			headers:    map[string]string{"X-Real-IP": "198.51.100.7"},
+			want:       "198.51.100.7",
+		},
+		{
+			name:       "IPv4 映射的 IPv6 对端",
+			remoteAddr: "[::ffff:10.0.0.1]:5000",
+			headers:    map[string]string{"X-Forwarded-For": "198.51.100.7"},
+			want:       "198.51.100.7",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			if got := r.Resolve(req); got != tt.want {
+				t.Errorf("Resolve() = %q，期望 %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestClientIPResolverHeaders 验证只读取配置的请求头
+func TestClientIPResolverHeaders(t *testing.T) {
+	r, err := NewClientIPResolver([]string{"10.0.0.0/8"}, []string{"X-Forwarded-For"})
+	if err != nil {
+		t.Fatalf("创建解析器失败: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "10.0.0.1:5000"
+	req.Header.Set("X-Real-IP", "6.6.6.6")
+	req.Header.Set("Forwarded", "for=6.6.6.6")
+	if got := r.Resolve(req); got != "10.0.0.1" {
+		t.Errorf("Resolve() = %q，期望忽略未配置的请求头", got)
+	}
+}
+
+// TestIsHTTPS 验证只采用接收客户端连接的可信代理声明的协议
+func TestIsHTTPS(t *testing.T) {
+	r, err := NewClientIPResolver([]string{"10.0.0.0/8"}, []string{"X-Forwarded-For"})
+	if err != nil {
+		t.Fatalf("创建解析器失败: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string]string
+		want       bool
+	}{
+		{"不可信对端忽略协议头", "203.0.113.9:5000", map[string]string{"X-Forwarded-Proto": "https"}, false},
+		{"单级代理", "10.0.0.1:5000", map[string]string{"X-Forwarded-Proto": "https"}, true},
+		{"单级代理 HTTP", "10.0.0.1:5000", map[string]string{"X-Forwarded-Proto": "http"}, false},
+		{"客户端伪造最左侧的值", "10.0.0.1:5000", map[string]string{"X-Forwarded-For": "198.51.100.7", "X-Forwarded-Proto": "https, http"}, false},
+		{"客户端伪造转发链与协议", "10.0.0.1:5000", map[string]string{"X-Forwarded-For": "6.6.6.6, 198.51.100.7", "X-Forwarded-Proto": "https, https, http"}, false},
+		{"多级代理取外层可信代理的值", "10.0.0.1:5000", map[string]string{"X-Forwarded-For": "198.51.100.7, 10.0.0.2", "X-Forwarded-Proto": "https, http"}, true},
+		{"代理覆盖协议头", "10.0.0.1:5000", map[string]string{"X-Forwarded-For": "198.51.100.7, 10.0.0.2", "X-Forwarded-Proto": "http"}, false},
+		{"Forwarded", "10.0.0.1:5000", map[string]string{"Forwarded": "for=198.51.100.7;proto=https"}, true},
+		{"Forwarded 客户端伪造", "10.0.0.1:5000", map[string]string{"Forwarded": "for=6.6.6.6;proto=https, for=198.51.100.7;proto=http"}, false},
+		{"Forwarded 多级代理", "10.0.0.1:5000", map[string]string{"Forwarded": "for=198.51.100.7;proto=https, for=10.0.0.2;proto=http"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			if got := r.IsHTTPS(req); got != tt.want {
+				t.Errorf("IsHTTPS() = %v，期望 %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestNewClientIPResolverInvalid 验证无效配置
+func TestNewClientIPResolverInvalid(t *testing.T) {
+	if _, err := NewClientIPResolver([]string{"10.0.0.0/33"}, nil); err == nil {
+		t.Error("无效网段应返回错误")
+	}
+	if _, err := NewClientIPResolver([]string{"proxy.local"}, nil); err == nil {
+		t.Error("无效地址应返回错误")
+	}
+	if _, err := NewClientIPResolver(nil, []string{"CF-Connecting-IP"}); err == nil {
+		t.Error("不支持的请求头应返回错误")
+	}
+}
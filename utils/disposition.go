@@ -0,0 +1,42 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ContentDisposition 生成 Content-Disposition 响应头，disposition 为 attachment 或 inline
+//
+// 按 RFC 6266 给出 ASCII 的 filename（非 ASCII 字符、控制字符、引号、反斜杠与 % 替换为 _），
+// 文件名因此有改动时再附加 RFC 5987 编码的 filename*，客户端提供的文件名不会破坏响应头；文件名为空时只返回 disposition。
+func ContentDisposition(disposition, filename string) string {
+	if filename == "" {
+		return disposition
+	}
+	fallback := strings.Map(func(r rune) rune {
+		if r < 0x20 || r >= 0x7f || r == '"' || r == '\\' || r == '%' {
+			return '_'
+		}
+		return r
+	}, filename)
+
+	header := disposition + `; filename="` + fallback + `"`
+	if fallback != filename {
+		header += "; filename*=UTF-8''" + encodeExtValue(filename)
+	}
+	return header
+}
+
+// encodeExtValue 按 RFC 5987 的 attr-char 对值做百分号编码
+func encodeExtValue(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.IndexByte("!#$&+-.^_`|~", c) >= 0 {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
@@ -0,0 +1,23 @@
+package utils
+
+import "testing"
+
+// TestContentDisposition 验证文件名中的特殊字符不会破坏响应头
+func TestContentDisposition(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		want     string
+	}{
+		{"空文件名", "", "attachment"},
+		{"ASCII 文件名", "report-2024.pdf", `attachment; filename="report-2024.pdf"`},
+		{"中文文件名", "报告.pdf", `attachment; filename="__.pdf"; filename*=UTF-8''%E6%8A%A5%E5%91%8A.pdf`},
+		{"引号与换行", "a\"b\r\nSet-Cookie: x.txt", `attachment; filename="a_b__Set-Cookie: x.txt"; filename*=UTF-8''a%22b%0D%0ASet-Cookie%3A%20x.txt`},
+		{"百分号", "100%.txt", `attachment; filename="100_.txt"; filename*=UTF-8''100%25.txt`},
+	}
+	for _, tt := range tests {
+		if got := ContentDisposition("attachment", tt.filename); got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
@@ -2,7 +2,6 @@ package utils
 
 import (
 	"encoding/json"
-	"net"
 	"net/http"
 	"strings"
 	"time"
@@ -45,9 +44,10 @@ func (r *ResponseUtil) SuccessWithMessage(message string, data interface{}) {
 // Error 返回错误响应
 func (r *ResponseUtil) Error(code int, message string) {
 	response := map[string]interface{}{
-		"code":      code,
-		"message":   message,
-		"timestamp": time.Now().Format("2006-01-02 15:04:05"),
+		"code":       code,
+		"message":    message,
+		"request_id": RequestID(r.ctx),
+		"timestamp":  time.Now().Format("2006-01-02 15:04:05"),
 	}
 	r.ctx.StatusCode(code)
 	r.ctx.JSON(response)
@@ -56,10 +56,11 @@ func (r *ResponseUtil) Error(code int, message string) {
 // ErrorWithData 返回带数据的错误响应
 func (r *ResponseUtil) ErrorWithData(code int, message string, data interface{}) {
 	response := map[string]interface{}{
-		"code":      code,
-		"message":   message,
-		"data":      data,
-		"timestamp": time.Now().Format("2006-01-02 15:04:05"),
+		"code":       code,
+		"message":    message,
+		"data":       data,
+		"request_id": RequestID(r.ctx),
+		"timestamp":  time.Now().Format("2006-01-02 15:04:05"),
 	}
 	r.ctx.StatusCode(code)
 	r.ctx.JSON(response)
@@ -68,10 +69,11 @@ func (r *ResponseUtil) ErrorWithData(code int, message string, data interface{})
 // ValidationError 返回验证错误响应
 func (r *ResponseUtil) ValidationError(errors interface{}) {
 	response := map[string]interface{}{
-		"code":      400,
-		"message":   "输入数据验证失败",
-		"errors":    errors,
-		"timestamp": time.Now().Format("2006-01-02 15:04:05"),
+		"code":       400,
+		"message":    "输入数据验证失败",
+		"errors":     errors,
+		"request_id": RequestID(r.ctx),
+		"timestamp":  time.Now().Format("2006-01-02 15:04:05"),
 	}
 	r.ctx.StatusCode(iris.StatusBadRequest)
 	r.ctx.JSON(response)
@@ -138,9 +140,10 @@ func (r *ResponseUtil) Unauthorized(message string) {
 		message = "未授权访问"
 	}
 	response := map[string]interface{}{
-		"code":      401,
-		"message":   message,
-		"timestamp": time.Now().Format("2006-01-02 15:04:05"),
+		"code":       401,
+		"message":    message,
+		"request_id": RequestID(r.ctx),
+		"timestamp":  time.Now().Format("2006-01-02 15:04:05"),
 	}
 	r.ctx.StatusCode(iris.StatusUnauthorized)
 	r.ctx.JSON(response)
@@ -152,9 +155,10 @@ func (r *ResponseUtil) Forbidden(message string) {
 		message = "权限不足"
 	}
 	response := map[string]interface{}{
-		"code":      403,
-		"message":   message,
-		"timestamp": time.Now().Format("2006-01-02 15:04:05"),
+		"code":       403,
+		"message":    message,
+		"request_id": RequestID(r.ctx),
+		"timestamp":  time.Now().Format("2006-01-02 15:04:05"),
 	}
 	r.ctx.StatusCode(iris.StatusForbidden)
 	r.ctx.JSON(response)
@@ -166,9 +170,10 @@ func (r *ResponseUtil) NotFound(message string) {
 		message = "资源不存在"
 	}
 	response := map[string]interface{}{
-		"code":      404,
-		"message":   message,
-		"timestamp": time.Now().Format("2006-01-02 15:04:05"),
+		"code":       404,
+		"message":    message,
+		"request_id": RequestID(r.ctx),
+		"timestamp":  time.Now().Format("2006-01-02 15:04:05"),
 	}
 	r.ctx.StatusCode(iris.StatusNotFound)
 	r.ctx.JSON(response)
@@ -180,62 +185,18 @@ func (r *ResponseUtil) InternalServerError(message string) {
 		message = "服务器内部错误"
 	}
 	response := map[string]interface{}{
-		"code":      500,
-		"message":   message,
-		"timestamp": time.Now().Format("2006-01-02 15:04:05"),
+		"code":       500,
+		"message":    message,
+		"request_id": RequestID(r.ctx),
+		"timestamp":  time.Now().Format("2006-01-02 15:04:05"),
 	}
 	r.ctx.StatusCode(iris.StatusInternalServerError)
 	r.ctx.JSON(response)
 }
 
-// GetClientIP 获取客户端真实IP地址
-func GetClientIP(r *http.Request) string {
-	// 尝试从 X-Forwarded-For 头获取
-	xForwardedFor := r.Header.Get("X-Forwarded-For")
-	if xForwardedFor != "" {
-		// X-Forwarded-For 可能包含多个IP，取第一个
-		ips := strings.Split(xForwardedFor, ",")
-		if len(ips) > 0 {
-			ip := strings.TrimSpace(ips[0])
-			if ip != "" && ip != "unknown" {
-				return ip
-			}
-		}
-	}
-
-	// 尝试从 X-Real-IP 头获取
-	xRealIP := r.Header.Get("X-Real-IP")
-	if xRealIP != "" {
-		ip := strings.TrimSpace(xRealIP)
-		if ip != "" && ip != "unknown" {
-			return ip
-		}
-	}
-
-	// 尝试从 X-Forwarded 头获取
-	xForwarded := r.Header.Get("X-Forwarded")
-	if xForwarded != "" {
-		ip := strings.TrimSpace(xForwarded)
-		if ip != "" && ip != "unknown" {
-			return ip
-		}
-	}
-
-	// 尝试从 Forwarded-For 头获取
-	forwardedFor := r.Header.Get("Forwarded-For")
-	if forwardedFor != "" {
-		ip := strings.TrimSpace(forwardedFor)
-		if ip != "" && ip != "unknown" {
-			return ip
-		}
-	}
-
-	// 最后使用 RemoteAddr
-	ip, _, err := net.SplitHostPort(r.RemoteAddr)
-	if err != nil {
-		return r.RemoteAddr
-	}
-	return ip
+// RequestID 获取当前请求的请求ID（由请求ID中间件设置）
+func RequestID(ctx iris.Context) string {
+	return ctx.Values().GetString("request_id")
 }
 
 // IsAJAXRequest 检查是否为AJAX请求
@@ -262,7 +223,7 @@ func GetRequestInfo(r *http.Request) map[string]interface{} {
 	info["referer"] = r.Header.Get("Referer")
 	info["content_type"] = r.Header.Get("Content-Type")
 	info["content_length"] = r.ContentLength
-	info["client_ip"] = GetClientIP(r)
+	info["client_ip"] = resolver.Load().Resolve(r)
 	info["is_ajax"] = IsAJAXRequest(r)
 	info["is_api"] = IsAPIRequest(r.URL.Path)
 	
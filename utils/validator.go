@@ -34,6 +34,15 @@ func InitValidator() {
 
 // ValidateStruct 验证结构体
 func ValidateStruct(s interface{}) error {
+    errorMap := ValidateStructFields(s)
+    if len(errorMap) == 0 {
+        return nil
+    }
+    return fmt.Errorf("%v", errorMap)
+}
+
+// ValidateStructFields 验证结构体，返回 字段名（json 名称）→ 错误提示，验证通过时返回 nil
+func ValidateStructFields(s interface{}) map[string]string {
     if Validator == nil {
         InitValidator()
     }
@@ -45,7 +54,7 @@ func ValidateStruct(s interface{}) error {
     
     // 转换验证错误为更友好的格式
     validationErrors := err.(validator.ValidationErrors)
-    errorMap := make(map[string]interface{})
+    errorMap := make(map[string]string)
     
     for _, e := range validationErrors {
         field := e.Field()
@@ -86,7 +95,7 @@ func ValidateStruct(s interface{}) error {
         errorMap[field] = message
     }
     
-    return fmt.Errorf("%v", errorMap)
+    return errorMap
 }
 
 // ValidateVar 验证单个变量
@@ -0,0 +1,43 @@
+package version
+
+import (
+	"runtime"
+	"time"
+)
+
+// 以下变量在构建时通过 -ldflags 注入，例如：
+//
+//	go build -ldflags "-X iris-cn-sample-project/version.Version=v1.2.0 \
+//	  -X iris-cn-sample-project/version.Commit=$(git rev-parse --short HEAD) \
+//	  -X iris-cn-sample-project/version.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+var (
+	// Version 版本号
+	Version = "dev"
+	// Commit 构建时的 git 提交
+	Commit = "unknown"
+	// BuildTime 构建时间
+	BuildTime = "unknown"
+)
+
+// startTime 进程启动时间
+var startTime = time.Now()
+
+// StartTime 获取进程启动时间
+func StartTime() time.Time {
+	return startTime
+}
+
+// Uptime 获取进程运行时长
+func Uptime() time.Duration {
+	return time.Since(startTime)
+}
+
+// Info 获取版本信息
+func Info() map[string]string {
+	return map[string]string{
+		"version":    Version,
+		"commit":     Commit,
+		"build_time": BuildTime,
+		"go_version": runtime.Version(),
+	}
+}